@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,7 +13,15 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/billing"
+	"github.com/makosai/backend/internal/email"
+	"github.com/makosai/backend/internal/gencache"
 	"github.com/makosai/backend/internal/handlers"
+	"github.com/makosai/backend/internal/moderation"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/ratelimit"
+	"github.com/makosai/backend/internal/store"
+	"github.com/makosai/backend/internal/tokens"
 )
 
 func main() {
@@ -45,14 +54,154 @@ func main() {
 		log.Println("⚠ No AI API key found, using mock generator")
 	}
 
+	// Content-safety screening: the word list always runs, the model only when enabled
+	var extraRules []moderation.Rule
+	if path := os.Getenv("MODERATION_WORDLIST"); path != "" {
+		rules, err := moderation.LoadRules(path)
+		if err != nil {
+			log.Fatalf("Failed to load moderation word list: %v", err)
+		}
+		extraRules = rules
+	}
+	wordList, err := moderation.NewWordListClassifier(extraRules...)
+	if err != nil {
+		log.Fatalf("Invalid moderation word list: %v", err)
+	}
+	classifiers := []moderation.Classifier{wordList}
+	if os.Getenv("MODERATION_LLM") == "true" {
+		if model, ok := generator.(moderation.Completer); ok {
+			classifiers = append(classifiers, moderation.NewLLMClassifier(model))
+			log.Println("✓ Using the AI model for content moderation")
+		} else {
+			log.Println("⚠ MODERATION_LLM needs the Anthropic generator, using the word list only")
+		}
+	}
+
+	// Plan limits: the built-in plans unless QUOTA_PLANS names a JSON file of them
+	plans := quota.DefaultPlans
+	if path := os.Getenv("QUOTA_PLANS"); path != "" {
+		loaded, err := quota.LoadPlans(path)
+		if err != nil {
+			log.Fatalf("Failed to load plans: %v", err)
+		}
+		plans = loaded
+	}
+	userStore := store.NewMemoryUserStore()
+	quotas, err := quota.New(plans, userStore, store.NewMemoryCounterStore())
+	if err != nil {
+		log.Fatalf("Invalid plans: %v", err)
+	}
+
+	// Recent clean generations are served again to identical requests
+	cacheTTL := gencache.DefaultTTL
+	if value := os.Getenv("GENERATION_CACHE_TTL"); value != "" {
+		if cacheTTL, err = time.ParseDuration(value); err != nil || cacheTTL < 0 {
+			log.Fatalf("Invalid GENERATION_CACHE_TTL %q: want a duration like 1h, or 0 to turn reuse off", value)
+		}
+	}
+	var generationCache gencache.Cache = gencache.NewMemoryCache(cacheTTL)
+	if url := os.Getenv("REDIS_URL"); url != "" && cacheTTL > 0 {
+		redisCache, err := gencache.NewRedisCache(url, cacheTTL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		generationCache = redisCache
+	}
+
+	// Mail goes through Resend, SMTP, or in development a capture transport
+	// that keeps emails (and writes them to MAIL_CAPTURE_DIR) instead of sending them
+	production := os.Getenv("ENVIRONMENT") == "production"
+	smtpPort := 0
+	if value := os.Getenv("SMTP_PORT"); value != "" {
+		if smtpPort, err = strconv.Atoi(value); err != nil {
+			log.Fatalf("Invalid SMTP_PORT %q", value)
+		}
+	}
+	mailer, err := email.NewMailer(email.Config{
+		Transport:    os.Getenv("MAIL_TRANSPORT"),
+		Production:   production,
+		From:         email.FromAddress(os.Getenv("MAIL_FROM"), os.Getenv("RESEND_FROM_EMAIL")),
+		ResendAPIKey: os.Getenv("RESEND_API_KEY"),
+		ResendURL:    os.Getenv("RESEND_API_URL"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     smtpPort,
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		CaptureDir:   os.Getenv("MAIL_CAPTURE_DIR"),
+	})
+	if err != nil {
+		log.Fatalf("Mail is not configured: %v", err)
+	}
+	if _, ok := mailer.(*email.CaptureMailer); ok {
+		log.Println("📬 Capturing emails instead of sending them (set RESEND_API_KEY or SMTP_HOST to deliver)")
+	}
+
+	// Email verification and password reset links are signed with TOKEN_SECRET
+	// and open pages on APP_URL
+	tokenSecret := []byte(os.Getenv("TOKEN_SECRET"))
+	if len(tokenSecret) == 0 && production {
+		log.Fatal("TOKEN_SECRET is required in production")
+	}
+	if len(tokenSecret) == 0 {
+		log.Println("⚠️ TOKEN_SECRET not set, emailed links will stop working when the server restarts")
+		tokenSecret = tokens.RandomSecret()
+	}
+	tokenIssuer := tokens.New(tokenSecret, store.NewMemoryCounterStore())
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:3000"
+	}
+
 	// Initialize handlers
-	worksheetHandler := handlers.NewWorksheetHandler(generator)
-	authHandler := handlers.NewAuthHandler()
-	emailHandler := handlers.NewEmailHandler()
+	worksheetStore := store.NewMemoryWorksheetStore()
+	usageStore := store.NewMemoryUsageStore()
+
+	worksheetHandler := handlers.NewWorksheetHandler(generator, worksheetStore, store.NewMemoryRevisionStore(), moderation.New(classifiers...), usageStore, quotas, generationCache)
+	authHandler := handlers.NewAuthHandler(userStore, quotas, tokenIssuer, mailer, appURL)
+	emailHandler := handlers.NewEmailHandler(mailer)
+	adminHandler := handlers.NewAdminHandler(usageStore, os.Getenv("ADMIN_TOKEN"))
+
+	// Subscription webhooks keep users' plans in sync with the payment provider
+	pricePlans, err := billing.ParsePricePlans(os.Getenv("BILLING_PRICE_PLANS"))
+	if err != nil {
+		log.Fatalf("Invalid BILLING_PRICE_PLANS: %v", err)
+	}
+	billingHandler := handlers.NewBillingHandler(
+		billing.NewProcessor(userStore, store.NewMemoryCounterStore(), plans, pricePlans),
+		os.Getenv("BILLING_WEBHOOK_SECRET"),
+	)
+
+	// Rate limits per user, or per IP for anonymous requests; buckets live in
+	// Redis when it's configured so every instance shares them
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		redisLimiter, err := ratelimit.NewRedisLimiter(url)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		limiter = redisLimiter
+	}
+	rateLimit := func(name, env, fallback string) fiber.Handler {
+		value := os.Getenv(env)
+		if value == "" {
+			value = fallback
+		}
+		policy, err := ratelimit.ParsePolicy(name, value)
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", env, err)
+		}
+		log.Printf("🐢 Rate limit %s: %s", name, policy)
+		return handlers.RateLimit(limiter, policy, quotas)
+	}
+	apiLimit := rateLimit("api", "RATE_LIMIT_API", "120/1m,60")
+	authLimit := rateLimit("auth", "RATE_LIMIT_AUTH", "10/1m,5")
+	generationLimit := rateLimit("generation", "RATE_LIMIT_GENERATION", "6/1m,3")
+	importLimit := rateLimit("import", "RATE_LIMIT_IMPORT", "20/1m,5")
 
 	// Initialize Fiber
 	app := fiber.New(fiber.Config{
-		AppName: "Makos.ai API v1.0",
+		AppName:   "Makos.ai API v1.0",
+		BodyLimit: handlers.MaxBodySize,
 	})
 
 	// Middleware
@@ -61,7 +210,7 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: os.Getenv("ALLOWED_ORIGINS"),
 		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowMethods: "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 	}))
 
 	// Health check
@@ -74,31 +223,63 @@ func main() {
 		})
 	})
 
+	// Curated image library, linked from questions via LOCAL_IMAGE_BASE_URL
+	if dir := os.Getenv("LOCAL_IMAGE_DIR"); dir != "" {
+		app.Static("/images/library", dir)
+	}
+
+	// Billing webhooks are signed and retried by the provider from shared IPs,
+	// so they are registered ahead of the per-IP API limit
+	app.Post("/api/billing/webhook", billingHandler.Webhook)
+
 	// API routes
-	api := app.Group("/api")
+	api := app.Group("/api", apiLimit)
 
 	// Auth routes
 	auth := api.Group("/auth")
-	auth.Post("/register", authHandler.Register)
-	auth.Post("/login", authHandler.Login)
+	auth.Post("/register", authLimit, authHandler.Register)
+	auth.Post("/login", authLimit, authHandler.Login)
 	auth.Post("/logout", authHandler.Logout)
 	auth.Get("/profile", authHandler.GetProfile)
 	auth.Put("/profile", authHandler.UpdateProfile)
+	auth.Post("/verify-email/request", authLimit, authHandler.RequestEmailVerification)
+	auth.Post("/verify-email/confirm", authLimit, authHandler.ConfirmEmailVerification)
+	auth.Post("/password-reset/request", authLimit, authHandler.RequestPasswordReset)
+	auth.Post("/password-reset/confirm", authLimit, authHandler.ConfirmPasswordReset)
 
 	// Worksheet routes
 	worksheets := api.Group("/worksheets")
-	worksheets.Post("/generate", worksheetHandler.GenerateWorksheet)
+	worksheets.Post("/generate", generationLimit, worksheetHandler.GenerateWorksheet)
+	worksheets.Post("/generate/upload", generationLimit, worksheetHandler.GenerateFromUpload)
+	worksheets.Post("/import", importLimit, worksheetHandler.ImportWorksheet)
 	worksheets.Get("/", worksheetHandler.GetWorksheets)
 	worksheets.Get("/options", worksheetHandler.GetOptions)
 	worksheets.Get("/:id", worksheetHandler.GetWorksheet)
 	worksheets.Put("/:id", worksheetHandler.UpdateWorksheet)
+	worksheets.Patch("/:id", worksheetHandler.PatchWorksheet)
 	worksheets.Delete("/:id", worksheetHandler.DeleteWorksheet)
 	worksheets.Get("/:id/export/pdf", worksheetHandler.ExportWorksheetPDF)
+	worksheets.Get("/:id/moderation", worksheetHandler.GetModeration)
+	worksheets.Post("/:id/moderation/review", worksheetHandler.ReviewModeration)
+	worksheets.Post("/:id/questions", worksheetHandler.AddQuestion)
+	worksheets.Post("/:id/questions/generate", generationLimit, worksheetHandler.GenerateMoreQuestions)
+	worksheets.Patch("/:id/questions/:qid", worksheetHandler.UpdateQuestion)
+	worksheets.Delete("/:id/questions/:qid", worksheetHandler.DeleteQuestion)
+	worksheets.Post("/:id/questions/:qid/move", worksheetHandler.MoveQuestion)
+	worksheets.Post("/:id/questions/:qid/regenerate", generationLimit, worksheetHandler.RegenerateQuestion)
+	worksheets.Get("/:id/revisions", worksheetHandler.GetRevisions)
+	worksheets.Get("/:id/revisions/diff", worksheetHandler.DiffRevisions)
+	worksheets.Get("/:id/revisions/:rev", worksheetHandler.GetRevision)
+	worksheets.Post("/:id/revisions/:rev/restore", worksheetHandler.RestoreRevision)
 
 	// Email routes
 	emailRoutes := api.Group("/email")
 	emailRoutes.Post("/welcome", emailHandler.SendWelcomeEmail)
 
+	// Admin routes
+	admin := api.Group("/admin", adminHandler.RequireAdmin)
+	admin.Get("/usage", adminHandler.GetUsage)
+
 	// Start server
 	log.Fatal(app.Listen(":" + port))
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Recording is one saved API exchange, named by the hash of its request body
+type Recording struct {
+	Request  json.RawMessage `json:"request"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Recorder is a local stand-in for the Messages API. It replays saved
+// responses for requests it has seen, or forwards requests upstream and saves
+// the responses when recording.
+type Recorder struct {
+	dir      string
+	record   bool
+	upstream string
+	apiKey   string
+	client   *http.Client
+	server   *httptest.Server
+}
+
+func NewRecorder(dir string, record bool, upstream, apiKey string) (*Recorder, error) {
+	if record {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("recordings: %w", err)
+	}
+	r := &Recorder{
+		dir:      dir,
+		record:   record,
+		upstream: upstream,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+	return r, nil
+}
+
+func (r *Recorder) URL() string { return r.server.URL }
+
+func (r *Recorder) Close() { r.server.Close() }
+
+func (r *Recorder) serve(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := requestKey(body)
+	path := filepath.Join(r.dir, key+".json")
+
+	if r.record {
+		rec, err := r.forward(req, body)
+		if err != nil {
+			log.Printf("   ⚠️ Upstream request failed: %v", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if rec.Status == http.StatusOK {
+			if err := saveRecording(path, rec); err != nil {
+				log.Printf("   ⚠️ Failed to save recording %s: %v", key, err)
+			}
+		}
+		writeRecording(w, rec)
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("   ⚠️ No recording %s for %s; run with -mode record", key, req.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"type":"error","error":{"type":"not_found_error","message":"no recording %s"}}`, key)
+		return
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		http.Error(w, fmt.Sprintf("recording %s: %v", key, err), http.StatusInternalServerError)
+		return
+	}
+	writeRecording(w, rec)
+}
+
+// forward sends the request to the real API with the recorder's key
+func (r *Recorder) forward(req *http.Request, body []byte) (Recording, error) {
+	out, err := http.NewRequestWithContext(req.Context(), req.Method, r.upstream+req.URL.Path, bytes.NewReader(body))
+	if err != nil {
+		return Recording{}, err
+	}
+	out.Header.Set("Content-Type", "application/json")
+	out.Header.Set("x-api-key", r.apiKey)
+	out.Header.Set("anthropic-version", req.Header.Get("anthropic-version"))
+
+	resp, err := r.client.Do(out)
+	if err != nil {
+		return Recording{}, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Recording{}, err
+	}
+	if !json.Valid(respBody) {
+		respBody, _ = json.Marshal(string(respBody))
+	}
+	return Recording{Request: body, Status: resp.StatusCode, Response: respBody}, nil
+}
+
+func saveRecording(path string, rec Recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func writeRecording(w http.ResponseWriter, rec Recording) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rec.Status)
+	w.Write(rec.Response)
+}
+
+// requestKey names a recording after the request it answers. Bodies are
+// compacted first so formatting differences don't matter.
+func requestKey(body []byte) string {
+	var compact bytes.Buffer
+	if json.Compact(&compact, body) == nil {
+		body = compact.Bytes()
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:8])
+}
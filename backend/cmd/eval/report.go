@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// Report is the outcome of one run of the suite
+type Report struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Generator   string             `json:"generator"`
+	Mode        string             `json:"mode,omitempty"`
+	Summary     map[string]float64 `json:"summary"` // average score per scorer, plus "overall"
+	Results     []Result           `json:"results"`
+}
+
+// Result is the scores for one fixture
+type Result struct {
+	Fixture        string           `json:"fixture"`
+	PromptTemplate string           `json:"prompt_template,omitempty"`
+	PromptVersion  int              `json:"prompt_version,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	Scores         map[string]Score `json:"scores"`
+}
+
+// evaluate scores one generated worksheet; a generation error scores 0 everywhere
+func evaluate(f Fixture, ws *models.Worksheet, genErr error) Result {
+	result := Result{Fixture: f.Name, Scores: map[string]Score{}}
+	if genErr != nil {
+		result.Error = genErr.Error()
+		for _, s := range scorers {
+			result.Scores[s.name] = Score{Score: 0, Checked: 1}
+		}
+		return result
+	}
+	result.PromptTemplate, result.PromptVersion = ws.PromptTemplate, ws.PromptVersion
+	for _, s := range scorers {
+		result.Scores[s.name] = s.score(f, ws)
+	}
+	return result
+}
+
+// summarize averages each scorer over the fixtures it could judge
+func (r *Report) summarize() {
+	r.Summary = map[string]float64{}
+	total, n := 0.0, 0
+	for _, s := range scorers {
+		sum, count := 0.0, 0
+		for _, res := range r.Results {
+			if score := res.Scores[s.name]; score.Checked > 0 {
+				sum += score.Score
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		r.Summary[s.name] = sum / float64(count)
+		total += r.Summary[s.name]
+		n++
+	}
+	if n > 0 {
+		r.Summary["overall"] = total / float64(n)
+	}
+}
+
+// Regressions lists the average scores that fell by more than maxDrop since the baseline
+func (r *Report) Regressions(baseline *Report, maxDrop float64) []string {
+	var drops []string
+	for _, name := range summaryNames(r, baseline) {
+		before, hadBefore := baseline.Summary[name]
+		after, hasAfter := r.Summary[name]
+		if hadBefore && hasAfter && before-after > maxDrop {
+			drops = append(drops, fmt.Sprintf("%s fell from %.2f to %.2f", name, before, after))
+		}
+	}
+	return drops
+}
+
+// Markdown renders the summary and every fixture's scores, with changes against the baseline when given
+func (r *Report) Markdown(baseline *Report) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Worksheet evaluation (%s", r.Generator)
+	if r.Mode != "" {
+		fmt.Fprintf(&sb, ", %s", r.Mode)
+	}
+	fmt.Fprintf(&sb, ")\n\n| Scorer | Score |")
+	if baseline != nil {
+		sb.WriteString(" Baseline | Change |")
+	}
+	sb.WriteString("\n|---|---|")
+	if baseline != nil {
+		sb.WriteString("---|---|")
+	}
+	sb.WriteString("\n")
+	for _, name := range summaryNames(r, baseline) {
+		fmt.Fprintf(&sb, "| %s | %s |", name, formatScore(r.Summary, name))
+		if baseline != nil {
+			fmt.Fprintf(&sb, " %s | %s |", formatScore(baseline.Summary, name), formatChange(r.Summary, baseline.Summary, name))
+		}
+		sb.WriteString("\n")
+	}
+
+	var before map[string]Result
+	if baseline != nil {
+		before = map[string]Result{}
+		for _, res := range baseline.Results {
+			before[res.Fixture] = res
+		}
+	}
+	for _, res := range r.Results {
+		fmt.Fprintf(&sb, "\n## %s", res.Fixture)
+		if res.PromptTemplate != "" {
+			fmt.Fprintf(&sb, " (template %s v%d)", res.PromptTemplate, res.PromptVersion)
+		}
+		sb.WriteString("\n\n")
+		if res.Error != "" {
+			fmt.Fprintf(&sb, "Generation failed: %s\n", res.Error)
+			continue
+		}
+		for _, s := range scorers {
+			score := res.Scores[s.name]
+			if score.Checked == 0 {
+				fmt.Fprintf(&sb, "- %s: n/a", s.name)
+			} else {
+				fmt.Fprintf(&sb, "- %s: %.2f", s.name, score.Score)
+				if old, ok := before[res.Fixture].Scores[s.name]; ok && old.Checked > 0 && old.Score != score.Score {
+					fmt.Fprintf(&sb, " (was %.2f)", old.Score)
+				}
+			}
+			if len(score.Notes) > 0 {
+				fmt.Fprintf(&sb, " — %s", strings.Join(score.Notes, "; "))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// summaryNames lists the scorers in either report, "overall" last
+func summaryNames(reports ...*Report) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, r := range reports {
+		if r == nil {
+			continue
+		}
+		for name := range r.Summary {
+			if !seen[name] && name != "overall" {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return append(names, "overall")
+}
+
+func formatScore(summary map[string]float64, name string) string {
+	if v, ok := summary[name]; ok {
+		return fmt.Sprintf("%.2f", v)
+	}
+	return "n/a"
+}
+
+func formatChange(after, before map[string]float64, name string) string {
+	a, okA := after[name]
+	b, okB := before[name]
+	if !okA || !okB {
+		return ""
+	}
+	return fmt.Sprintf("%+.2f", a-b)
+}
+
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func loadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
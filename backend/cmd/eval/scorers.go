@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/readability"
+)
+
+// Score is one scorer's result for a worksheet, from 0 (worst) to 1
+type Score struct {
+	Score   float64  `json:"score"`
+	Checked int      `json:"checked"` // how many items the scorer could judge; 0 means not applicable
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// scorer judges one aspect of a generated worksheet
+type scorer struct {
+	name  string
+	score func(f Fixture, ws *models.Worksheet) Score
+}
+
+var scorers = []scorer{
+	{"schema", scoreSchema},
+	{"compliance", scoreCompliance},
+	{"duplicates", scoreDuplicates},
+	{"answer_in_options", scoreAnswerInOptions},
+	{"readability", scoreReadability},
+	{"math", scoreMath},
+}
+
+// ratio turns passes out of checked into a Score
+func ratio(passed, checked int, notes []string) Score {
+	if checked == 0 {
+		return Score{Score: 1, Notes: notes}
+	}
+	return Score{Score: float64(passed) / float64(checked), Checked: checked, Notes: notes}
+}
+
+// scoreSchema counts questions that pass the same validation as manual edits
+func scoreSchema(f Fixture, ws *models.Worksheet) Score {
+	passed := 0
+	var notes []string
+	for _, q := range ws.Questions {
+		if err := q.Validate(); err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", q.ID, err))
+			continue
+		}
+		passed++
+	}
+	if len(ws.Questions) == 0 {
+		return Score{Score: 0, Checked: 1, Notes: []string{"no questions"}}
+	}
+	return ratio(passed, len(ws.Questions), notes)
+}
+
+// scoreCompliance checks the question count and that every question has a requested type
+func scoreCompliance(f Fixture, ws *models.Worksheet) Score {
+	passed, checked := 0, 1
+	var notes []string
+	if len(ws.Questions) == f.Input.QuestionCount {
+		passed++
+	} else {
+		notes = append(notes, fmt.Sprintf("%d questions, requested %d", len(ws.Questions), f.Input.QuestionCount))
+	}
+
+	requested := map[string]bool{}
+	for _, t := range f.Input.QuestionTypes {
+		requested[t] = true
+	}
+	for _, q := range ws.Questions {
+		checked++
+		if requested[q.Type] {
+			passed++
+		} else {
+			notes = append(notes, fmt.Sprintf("%s: type %q was not requested", q.ID, q.Type))
+		}
+	}
+	return ratio(passed, checked, notes)
+}
+
+// scoreDuplicates penalizes questions whose normalized text repeats an earlier one
+func scoreDuplicates(f Fixture, ws *models.Worksheet) Score {
+	seen := map[string]string{}
+	passed := 0
+	var notes []string
+	for _, q := range ws.Questions {
+		key := normalizeText(q.Question)
+		if first, ok := seen[key]; ok {
+			notes = append(notes, fmt.Sprintf("%s repeats %s", q.ID, first))
+			continue
+		}
+		seen[key] = q.ID
+		passed++
+	}
+	return ratio(passed, len(ws.Questions), notes)
+}
+
+// normalizeText drops case, punctuation, numbering like "Question 3:" and extra spaces
+func normalizeText(s string) string {
+	s = questionNumber.ReplaceAllString(strings.ToLower(s), "")
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+var questionNumber = regexp.MustCompile(`^\s*(question\s*)?\d+\s*[:.)]`)
+
+// scoreAnswerInOptions checks choice questions have exactly one option equal to the answer
+func scoreAnswerInOptions(f Fixture, ws *models.Worksheet) Score {
+	passed, checked := 0, 0
+	var notes []string
+	for _, q := range ws.Questions {
+		if q.Type != string(models.MultipleChoice) && q.Type != string(models.TrueFalse) {
+			continue
+		}
+		checked++
+		answer := strings.TrimSpace(q.AnswerText())
+		matches := 0
+		for _, opt := range q.Options {
+			if strings.EqualFold(strings.TrimSpace(opt), answer) {
+				matches++
+			}
+		}
+		if matches == 1 {
+			passed++
+		} else {
+			notes = append(notes, fmt.Sprintf("%s: answer %q matches %d options", q.ID, answer, matches))
+		}
+	}
+	return ratio(passed, checked, notes)
+}
+
+// scoreReadability compares the Flesch-Kincaid grade of the passage, or of
+// the question text when there is none, with the requested grade. Only English
+// worksheets are judged, and bare computations ("7 × 8 = ?") are left out
+// because they have no prose to score.
+func scoreReadability(f Fixture, ws *models.Worksheet) Score {
+	if ws.Language != "" && ws.Language != "en" {
+		return Score{Score: 1}
+	}
+	target, ok := readability.ParseGrade(f.Input.GradeLevel)
+	if !ok {
+		return Score{Score: 1}
+	}
+
+	var text string
+	if ws.Passage != nil {
+		text = strings.Join(ws.Passage.Paragraphs, "\n\n")
+	} else {
+		var parts []string
+		for _, q := range ws.Questions {
+			if isComputation(q.Question) {
+				continue
+			}
+			parts = append(parts, stripMath(q.Question))
+		}
+		text = strings.Join(parts, "\n")
+	}
+	stats := readability.Analyze(text)
+	if stats.Words < 20 {
+		return Score{Score: 1, Notes: []string{"too little text to judge"}}
+	}
+
+	// Full marks within tolerance, then one point lost per grade level further out
+	off := math.Abs(stats.GradeLevel-target) - readability.GradeTolerance
+	score := 1.0
+	if off > 0 {
+		score = math.Max(0, 1-off/3)
+	}
+	note := fmt.Sprintf("Flesch-Kincaid grade %.1f for target %s", stats.GradeLevel, f.Input.GradeLevel)
+	return Score{Score: score, Checked: 1, Notes: []string{note}}
+}
+
+var inlineMath = regexp.MustCompile(`\$\$?[^$]*\$\$?`)
+
+func stripMath(s string) string {
+	return inlineMath.ReplaceAllString(s, "x")
+}
+
+var proseWord = regexp.MustCompile(`[A-Za-z]{2,}`)
+
+// isComputation reports whether a question is an arithmetic exercise rather
+// than prose, e.g. "What is $6 \times 7$?" or "56 ÷ 8 = ___"
+func isComputation(question string) bool {
+	if _, ok := arithmeticExpression(question); ok {
+		return true
+	}
+	return len(proseWord.FindAllString(inlineMath.ReplaceAllString(question, " "), -1)) < 4
+}
+
+// scoreMath recomputes plain arithmetic questions ("What is 12 × 3 + 4?") and
+// compares the result with the answer. Questions it can't parse are skipped.
+func scoreMath(f Fixture, ws *models.Worksheet) Score {
+	passed, checked := 0, 0
+	var notes []string
+	for _, q := range ws.Questions {
+		expr, ok := arithmeticExpression(q.Question)
+		if !ok {
+			continue
+		}
+		want, err := calculate(expr)
+		if err != nil {
+			continue
+		}
+		got, ok := parseNumber(q.AnswerText())
+		if !ok {
+			continue
+		}
+		checked++
+		if math.Abs(got-want) < 1e-6 {
+			passed++
+		} else {
+			notes = append(notes, fmt.Sprintf("%s: %s = %s, answer says %s", q.ID, expr, formatNumber(want), formatNumber(got)))
+		}
+	}
+	return ratio(passed, checked, notes)
+}
+
+var (
+	mathWords = strings.NewReplacer(
+		`\times`, "*", `\cdot`, "*", `\div`, "/", "×", "*", "÷", "/", "−", "-",
+		`\left(`, "(", `\right)`, ")", "$", " ", `\,`, "",
+	)
+	// An arithmetic question asks for the value of the expression and nothing else
+	arithmeticQuestion = regexp.MustCompile(`(?i)^(?:question \d+:\s*)?(?:what is|calculate|compute|evaluate|find|solve|simplify)?:?\s*([-+*/().\d\s]+?)\s*(?:=\s*(?:\?|_+)?)?\s*\??$`)
+	fractionAnswer     = regexp.MustCompile(`^(-?\d+)\s*/\s*(\d+)$`)
+	latexFraction      = regexp.MustCompile(`\\[dt]?frac\{(-?\d+)\}\{(\d+)\}`)
+)
+
+func arithmeticExpression(question string) (string, bool) {
+	text := mathWords.Replace(latexFraction.ReplaceAllString(question, "($1/$2)"))
+	m := arithmeticQuestion.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return "", false
+	}
+	expr := strings.Join(strings.Fields(m[1]), " ")
+	if !strings.ContainsAny(strings.TrimLeft(expr, "-"), "+-*/") {
+		return "", false
+	}
+	return expr, true
+}
+
+func parseNumber(answer string) (float64, bool) {
+	s := strings.TrimSpace(mathWords.Replace(latexFraction.ReplaceAllString(answer, "$1/$2")))
+	s = strings.TrimSpace(strings.TrimSuffix(strings.ReplaceAll(s, ",", ""), "."))
+	if m := fractionAnswer.FindStringSubmatch(s); m != nil {
+		num, _ := strconv.ParseFloat(m[1], 64)
+		den, _ := strconv.ParseFloat(m[2], 64)
+		if den == 0 {
+			return 0, false
+		}
+		return num / den, true
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'g', 10, 64)
+}
+
+// calculate computes + - * / with parentheses and the usual precedence
+func calculate(expr string) (float64, error) {
+	p := &exprParser{src: strings.ReplaceAll(expr, " ", "")}
+	v, err := p.sum()
+	if err == nil && p.pos < len(p.src) {
+		err = fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	return v, err
+}
+
+type exprParser struct {
+	src string
+	pos int
+}
+
+func (p *exprParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) sum() (float64, error) {
+	v, err := p.product()
+	for err == nil && (p.peek() == '+' || p.peek() == '-') {
+		op := p.peek()
+		p.pos++
+		var rhs float64
+		if rhs, err = p.product(); op == '+' {
+			v += rhs
+		} else {
+			v -= rhs
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) product() (float64, error) {
+	v, err := p.factor()
+	for err == nil && (p.peek() == '*' || p.peek() == '/') {
+		op := p.peek()
+		p.pos++
+		var rhs float64
+		if rhs, err = p.factor(); err != nil {
+			break
+		}
+		if op == '*' {
+			v *= rhs
+		} else if rhs == 0 {
+			return 0, fmt.Errorf("division by zero")
+		} else {
+			v /= rhs
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) factor() (float64, error) {
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		v, err := p.factor()
+		return -v, err
+	case c == '(':
+		p.pos++
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return v, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, fmt.Errorf("expected a number at %d", start)
+	}
+	return strconv.ParseFloat(p.src[start:p.pos], 64)
+}
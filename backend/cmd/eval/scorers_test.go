@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestIsComputation(t *testing.T) {
+	tests := []struct {
+		question string
+		want     bool
+	}{
+		{"What is $6 \\times 7$?", true},
+		{"7 × 8 = ?", true},
+		{"$56 \\div 8 = \\_\\_\\_$", true},
+		{"Solve: 48 ÷ 6", true},
+		{"Maria has 4 bags with 6 apples in each bag. How many apples does she have?", false},
+		{"Which planet is closest to the sun?", false},
+	}
+	for _, tt := range tests {
+		if got := isComputation(tt.question); got != tt.want {
+			t.Errorf("isComputation(%q) = %v, want %v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestScoreReadabilitySkipsComputation(t *testing.T) {
+	f := Fixture{Input: models.WorksheetGeneratorInput{GradeLevel: "3"}}
+	ws := &models.Worksheet{Questions: []models.Question{
+		{Question: "What is $6 \\times 7$?"},
+		{Question: "7 × 8 = ?"},
+		{Question: "$9 \\times 4 = $ ___"},
+	}}
+	if s := scoreReadability(f, ws); s.Checked != 0 || s.Score != 1 {
+		t.Errorf("arithmetic drill scored %+v, want not applicable", s)
+	}
+
+	ws.Questions = append(ws.Questions,
+		models.Question{Question: "Sam has three boxes of crayons. Each box holds eight crayons. How many crayons does Sam have?"},
+		models.Question{Question: "A baker puts six cupcakes on each tray. She fills five trays. How many cupcakes are there?"},
+	)
+	if s := scoreReadability(f, ws); s.Checked != 1 || s.Score < 0.5 {
+		t.Errorf("grade 3 word problems scored %+v", s)
+	}
+}
@@ -0,0 +1,64 @@
+// Command webhook signs billing event fixtures with a local secret and posts
+// them to the API, to try the subscription webhook without the provider:
+//
+//	BILLING_WEBHOOK_SECRET=whsec_local go run ./cmd/api
+//	BILLING_WEBHOOK_SECRET=whsec_local go run ./cmd/webhook -user user_1a2b3c4d \
+//	    internal/billing/testdata/subscription_created.json
+//
+// Fixtures name the user "user_demo"; -user substitutes a registered user's ID.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/makosai/backend/internal/billing"
+)
+
+// fixtureUser is the placeholder user ID in the fixtures
+const fixtureUser = "user_demo"
+
+func main() {
+	var (
+		url    = flag.String("url", "http://localhost:8080/api/billing/webhook", "webhook endpoint")
+		secret = flag.String("secret", os.Getenv("BILLING_WEBHOOK_SECRET"), "signing secret (default $BILLING_WEBHOOK_SECRET)")
+		user   = flag.String("user", fixtureUser, "user ID to put in the events' metadata")
+	)
+	flag.Parse()
+	if *secret == "" || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: webhook -secret SECRET [-user ID] [-url URL] event.json...")
+		os.Exit(2)
+	}
+
+	userID, _ := json.Marshal(*user)
+	placeholder, _ := json.Marshal(fixtureUser)
+	for _, path := range flag.Args() {
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		payload = bytes.ReplaceAll(payload, placeholder, userID)
+
+		req, err := http.NewRequest(http.MethodPost, *url, bytes.NewReader(payload))
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(billing.SignatureHeader, billing.Sign(payload, *secret, time.Now()))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		fmt.Printf("%s: %d %s\n", path, resp.StatusCode, bytes.TrimSpace(body))
+	}
+}
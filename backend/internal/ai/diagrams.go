@@ -0,0 +1,246 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/makosai/backend/internal/diagram"
+	"github.com/makosai/backend/internal/latex"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/readability"
+)
+
+// elementaryKeywords maps topic keywords to the elementary visual that suits them
+var elementaryKeywords = []struct {
+	kind     string
+	keywords []string
+}{
+	{models.DiagramFraction, []string{"fraction", "half", "halves", "third", "quarter", "equal parts"}},
+	{models.DiagramClock, []string{"time", "clock", "hour", "minute", "o'clock"}},
+	{models.DiagramBaseTen, []string{"place value", "base ten", "base-ten", "tens", "hundreds", "regroup"}},
+	{models.DiagramCoins, []string{"money", "coin", "cent", "dollar", "penny", "nickel", "dime"}},
+	{models.DiagramTally, []string{"tally", "data", "survey", "graph", "chart", "sorting"}},
+	{models.DiagramNumberLine, []string{"number line", "addition", "subtraction", "add", "subtract", "counting", "skip count", "compare", "rounding", "negative"}},
+}
+
+var elementaryInstructions = map[string]string{
+	models.DiagramNumberLine: `• Number line: {"type": "number_line", "number_line": {"min": 0, "max": 20, "step": 2,
+    "points": [{"value": 6, "label": "A"}], "jumps": [{"from": 6, "to": 10, "label": "+4"}]}}
+  - at most 40 ticks; points and jumps must lie between min and max`,
+	models.DiagramFraction: `• Fraction model: {"type": "fraction", "fraction": {"numerator": 3, "denominator": 4, "style": "bar"}}
+  - style is "bar" (denominator up to 24) or "circle" (up to 12); the first numerator parts are shaded
+  - improper fractions draw extra wholes (at most 4)`,
+	models.DiagramClock: `• Clock: {"type": "clock", "clock": {"hour": 3, "minute": 30}}
+  - hour 1-12, minute 0-59; set "hide_hands": true for "draw the hands" questions`,
+	models.DiagramBaseTen: `• Base-ten blocks: {"type": "base_ten", "base_ten": {"hundreds": 1, "tens": 4, "ones": 7}}
+  - up to 9 hundreds, 20 tens and 20 ones`,
+	models.DiagramCoins: `• Coins: {"type": "coins", "coins": {"coins": ["quarter", "dime", "penny"]}}
+  - US coins only: penny, nickel, dime, quarter, half_dollar, dollar_coin; at most 20`,
+	models.DiagramTally: `• Tally chart: {"type": "tally", "tally": {"rows": [{"label": "Apples", "count": 7}, {"label": "Pears", "count": 3}]}}
+  - up to 8 rows with counts from 0 to 50`,
+}
+
+const chartInstructions = `• Chart: {"type": "chart", "chart": {"kind": "bar", "x_label": "Fruit", "y_label": "Votes",
+    "data": [{"label": "Apples", "value": 8}, {"label": "Pears", "value": 5}]}}
+  - kinds: bar, pictograph, table (labelled "data", up to 12 rows; tables up to 15),
+    line_plot, histogram (raw "values", up to 40; histograms take "bin_width" and optional "bin_start")
+  - pictographs take "per_symbol" (default 1); every value must be a whole or half number of symbols
+  - line plot values must share a spacing of 1, ½, ¼, ⅛ or 0.1
+  - the mean, median, mode, range, total, maximum, minimum and differences are recomputed from the data
+    and checked against correct_answer, so give each question its own small dataset`
+
+// chartKeywords mark topics that call for data displays
+var chartKeywords = []string{
+	"data", "statistic", "chart", "bar graph", "pictograph", "picture graph", "line plot",
+	"dot plot", "histogram", "mean", "median", "mode", "average", "survey", "frequency",
+}
+
+// needsCharts checks if the subject/topic calls for data displays
+func needsCharts(subject, topic string) bool {
+	text := strings.ToLower(subject + " " + topic)
+	for _, keyword := range chartKeywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// isElementaryGrade reports whether the grade is kindergarten through grade 5
+func isElementaryGrade(gradeLevel string) bool {
+	grade, ok := readability.ParseGrade(gradeLevel)
+	return ok && grade <= 5
+}
+
+// elementaryVisuals picks the elementary diagram types that fit the topic.
+// Early-grade math with no matching keyword gets a number line.
+func elementaryVisuals(gradeLevel, subject, topic string) []string {
+	if !isElementaryGrade(gradeLevel) {
+		return nil
+	}
+	text := strings.ToLower(subject + " " + topic)
+
+	var kinds []string
+	for _, entry := range elementaryKeywords {
+		for _, keyword := range entry.keywords {
+			if strings.Contains(text, keyword) {
+				kinds = append(kinds, entry.kind)
+				break
+			}
+		}
+	}
+	if len(kinds) == 0 && isEarlyGrade(gradeLevel) && strings.Contains(text, "math") {
+		kinds = append(kinds, models.DiagramNumberLine)
+	}
+	return kinds
+}
+
+// diagramInstructions returns the prompt section describing the structured
+// diagram specs the backend can draw for this grade and topic
+func diagramInstructions(gradeLevel, subject, topic string) string {
+	var sections []string
+	if needsDiagrams(subject, topic) {
+		sections = append(sections, advancedDiagramInstructions)
+	}
+	if needsCharts(subject, topic) {
+		sections = append(sections, chartInstructions)
+	}
+	for _, kind := range elementaryVisuals(gradeLevel, subject, topic) {
+		sections = append(sections, elementaryInstructions[kind])
+	}
+	if len(sections) == 0 {
+		return ""
+	}
+
+	return `
+
+📐 DIAGRAMS:
+━━━━━━━━━━━━━━━━━━━━━━━━━━
+When a question needs a figure, add a "diagram" object to it. The figure is drawn
+to scale from these numbers, so they MUST be consistent with the question and answer.
+Do NOT describe the figure in the question text instead, and do NOT include drawing code.
+
+` + strings.Join(sections, "\n")
+}
+
+// diagramPrompt is diagramInstructions, or nothing when the plan has no diagrams
+func diagramPrompt(limits models.GenerationLimits, gradeLevel, subject, topic string) string {
+	if limits.NoDiagrams {
+		return ""
+	}
+	return diagramInstructions(gradeLevel, subject, topic)
+}
+
+// dropDiagrams removes any diagrams the model added anyway
+func dropDiagrams(questions []models.Question) []models.Question {
+	for i := range questions {
+		questions[i].Diagram = nil
+		questions[i].LatexDiagram = ""
+	}
+	return questions
+}
+
+const advancedDiagramInstructions = `• Triangle: {"type": "triangle", "shape": {"sides": [a, b, c], "angles": [A, B, C],
+  "labels": ["A", "B", "C"], "unit": "cm", "right_angle": "C", "side_labels": ["", "", "x"]}}
+  - side a is opposite vertex A; angles are in degrees; use 0 for values the student must find
+  - give three sides, two sides and an angle, or two angles and a side
+  - the drawing only shows values you give; use side_labels/angle_labels (e.g. "x", "θ") for unknowns
+• Rectangle: {"type": "rectangle", "shape": {"sides": [width, height], "unit": "m"}}
+• Regular polygon: {"type": "regular_polygon", "shape": {"num_sides": 6, "sides": [4], "unit": "cm"}}
+• Circle: {"type": "circle", "shape": {"radius": 5, "unit": "cm", "show_diameter": false}}
+• Circuit: {"type": "circuit", "circuit": {"voltage": 12, "network": {"kind": "series", "elements": [
+    {"kind": "resistor", "label": "R1", "value": 4},
+    {"kind": "parallel", "elements": [{"kind": "bulb", "label": "L1", "value": 6}, {"kind": "resistor", "label": "R2", "value": 3}]},
+    {"kind": "ammeter", "label": "A1"}]}}}
+  - the battery is drawn automatically from "voltage"; "network" is what it drives
+  - kinds: series, parallel, resistor, bulb, switch ("open": true/false), ammeter, voltmeter, capacitor
+  - "value" is in ohms for resistors and bulbs and in microfarads for capacitors; omit values the student must find
+  - ammeters go in series; a voltmeter goes in a parallel group with the component it measures
+  - equivalent resistance and current are recomputed from the spec and checked against correct_answer
+• Graph: {"type": "graph", "graph": {"x_min": -5, "x_max": 5, "y_min": -5, "y_max": 10, "x_step": 1, "y_step": 1,
+    "functions": [{"expression": "2x + 1", "label": "A"}], "points": [{"x": 0, "y": 1, "label": "(0, 1)"}]}}
+  - expressions use x with + - * / ^, parentheses, pi, and sin cos tan sqrt abs ln log exp, e.g. "x^2 - 4", "2sin(x)"
+  - trig functions take radians; use "x_step": 1.5707963 (π/2) for π-labelled ticks
+  - choose ranges so every function and point is visible
+  - labels are shown on the graph, so do not label a function with its equation when the student must identify it
+• Anything else: only if none of the types above fit, add "latex_diagram" with the body of a TikZ picture
+  - libraries arrows.meta, angles, quotes, calc, positioning and shapes.geometric are loaded
+  - \usepackage, \input, \def and file commands are rejected`
+
+// hasDiagrams reports whether any question carries a diagram spec
+func hasDiagrams(questions []models.Question) bool {
+	for _, q := range questions {
+		if q.Diagram != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// renderDiagrams renders each question's diagram spec to SVG. Specs that
+// describe impossible figures are dropped with a warning rather than drawn wrong.
+func renderDiagrams(questions []models.Question) []models.Question {
+	for i := range questions {
+		q := &questions[i]
+
+		if q.Diagram != nil {
+			if err := diagram.Apply(q); err != nil {
+				log.Printf("   ⚠️ Rejected diagram for question %d: %v", i+1, err)
+				q.Warnings = append(q.Warnings, "Diagram removed: "+err.Error())
+				q.Diagram = nil
+				continue
+			}
+			log.Printf("   ✅ Rendered %s diagram for question %d", q.Diagram.Type, i+1)
+			for _, w := range q.Warnings {
+				log.Printf("   ⚠️ Question %d: %s", i+1, w)
+			}
+		}
+	}
+
+	return questions
+}
+
+// checkDiagramAnswers cross-checks each answer against the circuit or chart it is about
+func checkDiagramAnswers(questions []models.Question) {
+	for i := range questions {
+		diagram.CheckAnswer(&questions[i])
+	}
+}
+
+// hasLatexDiagrams reports whether any question carries a TikZ diagram
+func hasLatexDiagrams(questions []models.Question) bool {
+	for _, q := range questions {
+		if q.LatexDiagram != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderLatexDiagrams compiles TikZ diagrams for questions that have no other
+// image. Snippets that fail to compile are dropped with a warning; without a
+// TeX toolchain they are left for the client.
+func renderLatexDiagrams(ctx context.Context, tikz *latex.Renderer, questions []models.Question) []models.Question {
+	for i := range questions {
+		q := &questions[i]
+		if q.LatexDiagram == "" || q.Image != "" {
+			continue
+		}
+
+		svg, err := tikz.Render(ctx, q.LatexDiagram)
+		if errors.Is(err, latex.ErrUnavailable) {
+			return questions
+		}
+		if err != nil {
+			log.Printf("   ⚠️ Rejected TikZ diagram for question %d: %v", i+1, err)
+			q.Warnings = append(q.Warnings, "Diagram removed: "+err.Error())
+			q.LatexDiagram = ""
+			continue
+		}
+		q.Image = svg
+		log.Printf("   ✅ Rendered TikZ diagram for question %d", i+1)
+	}
+	return questions
+}
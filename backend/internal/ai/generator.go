@@ -8,12 +8,14 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"regexp"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/makosai/backend/internal/latex"
 	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/prompts"
 )
 
 // Generator interface for AI worksheet generation
@@ -45,6 +47,11 @@ func (g *MockGenerator) GenerateWorksheet(ctx context.Context, input models.Work
 		UpdatedAt:              time.Now(),
 	}
 
+	if wantsPassage(input) {
+		worksheet.Passage = demoPassage(input)
+		scorePassage(worksheet.Passage, input.GradeLevel)
+	}
+
 	// Generate demo questions
 	questions := make([]models.Question, input.QuestionCount)
 	for i := 0; i < input.QuestionCount; i++ {
@@ -53,12 +60,36 @@ func (g *MockGenerator) GenerateWorksheet(ctx context.Context, input models.Work
 			qType = input.QuestionTypes[i%len(input.QuestionTypes)]
 		}
 		questions[i] = generateDemoQuestion(i+1, qType, input.Topic)
+		if worksheet.Passage != nil {
+			questions[i].ParagraphRefs = []int{i%len(worksheet.Passage.Paragraphs) + 1}
+		}
+		if len(input.SourceExcerpts) > 0 {
+			questions[i].SourceExcerpt = input.SourceExcerpts[i%len(input.SourceExcerpts)].ID
+		}
 	}
 	worksheet.Questions = questions
+	worksheet.SourceFile = input.SourceFile
+	worksheet.SourceExcerpts = input.SourceExcerpts
 
 	return worksheet, nil
 }
 
+// demoPassage returns the teacher's passage or a short placeholder passage
+func demoPassage(input models.WorksheetGeneratorInput) *models.Passage {
+	if strings.TrimSpace(input.Passage) != "" {
+		return teacherPassage(input.Passage)
+	}
+	return &models.Passage{
+		Title: input.Topic,
+		Paragraphs: []string{
+			fmt.Sprintf("Many people want to learn about %s. It is an interesting topic.", input.Topic),
+			fmt.Sprintf("There are a few big ideas to know about %s. Each idea helps us understand the world.", input.Topic),
+			fmt.Sprintf("When you study %s, ask questions and look for examples around you.", input.Topic),
+		},
+		Source: "generated",
+	}
+}
+
 func generateDemoQuestion(num int, qType string, topic string) models.Question {
 	q := models.Question{
 		ID:     fmt.Sprintf("q_%d", num),
@@ -105,125 +136,56 @@ func generateDemoQuestion(num int, qType string, topic string) models.Question {
 	return q
 }
 
+// anthropicModel is the model every Messages API request is sent to
+const anthropicModel = "claude-sonnet-4-5-20250929"
+
 // AnthropicGenerator uses Claude API for AI generation
 type AnthropicGenerator struct {
-	apiKey string
-	client *http.Client
+	apiKey  string
+	baseURL string
+	client  *http.Client
+	tikz    *latex.Renderer
+	images  *ImageFinder
+	prompts *prompts.Registry
 }
 
 func NewAnthropicGenerator(apiKey string) *AnthropicGenerator {
-	return &AnthropicGenerator{
-		apiKey: apiKey,
-		client: &http.Client{Timeout: 90 * time.Second},
+	tikz := latex.NewRenderer(latex.ConfigFromEnv())
+	if !tikz.Available() {
+		log.Println("⚠ latex/dvisvgm not found, TikZ diagrams will not be rendered")
 	}
-}
-
-// System prompt for Claude - ensures quality and accuracy
-const systemPrompt = `You are an expert educational content creator and curriculum specialist with deep knowledge across all academic subjects. Your role is to create high-quality, pedagogically sound worksheets for students.
-
-CRITICAL REQUIREMENTS:
-
-1. ACCURACY IS PARAMOUNT:
-   - Every question MUST have a factually correct answer
-   - Double-check all facts, dates, formulas, and scientific information
-   - For math problems: solve each problem yourself and verify the answer is correct
-   - For science: ensure all scientific facts are accurate and up-to-date
-   - For history: verify dates, names, and events
-   - For language: ensure grammar and spelling are perfect
-
-2. ANSWER VERIFICATION PROCESS:
-   - After creating each question, mentally solve/answer it
-   - Verify the correct_answer field matches your solution
-   - For multiple choice: ensure exactly ONE option is correct
-   - For true/false: verify the statement's truthfulness
-   - For fill-in-blank: ensure the answer logically completes the sentence
-   - For math: show your work mentally and confirm the numerical answer
-
-3. QUALITY STANDARDS:
-   - Questions should be clear, unambiguous, and age-appropriate
-   - Avoid trick questions unless specifically requested
-   - Explanations should help students understand WHY the answer is correct
-   - Distractors (wrong options) should be plausible but clearly incorrect
-
-4. EDUCATIONAL VALUE:
-   - Align with curriculum standards for the specified grade level
-   - Progress from easier to harder questions when appropriate
-   - Include a mix of recall, comprehension, and application questions
-   - Make content engaging and relevant to students
-
-5. OUTPUT FORMAT:
-   - Always output valid JSON only, no markdown or extra text
-   - Follow the exact structure requested
-   - Ensure all required fields are present
-
-6. MATHEMATICAL NOTATION (LaTeX):
-   - For math questions, use LaTeX notation within the question text
-   - Wrap inline math with $...$ (e.g., $x^2 + y^2 = z^2$)
-   - Wrap display math with $$...$$ (e.g., $$\frac{a}{b} = c$$)
-   - For geometry/diagrams, include a "latex_diagram" field with TikZ code
-   - TikZ example: "\\begin{tikzpicture}\\draw (0,0) -- (2,0) -- (1,1.7) -- cycle;\\end{tikzpicture}"
-   - Use LaTeX for: fractions, exponents, roots, integrals, summations, matrices
-   - Keep diagrams simple and educational`
-
-func (g *AnthropicGenerator) GenerateWorksheet(ctx context.Context, input models.WorksheetGeneratorInput) (*models.Worksheet, error) {
-	prompt := g.buildPrompt(input)
-
-	requestBody := map[string]interface{}{
-		"model":      "claude-sonnet-4-5-20250929",
-		"max_tokens": 4096,
-		"system":     systemPrompt,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
+	registry, err := prompts.FromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		log.Printf("⚠ Prompt templates not loaded, using the built-in ones: %v", err)
+		registry = prompts.Embedded()
 	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	baseURL := os.Getenv("ANTHROPIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", g.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := g.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
+	return &AnthropicGenerator{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 90 * time.Second},
+		tikz:    tikz,
+		images:  NewImageFinderFromEnv(),
+		prompts: registry,
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (g *AnthropicGenerator) GenerateWorksheet(ctx context.Context, input models.WorksheetGeneratorInput) (*models.Worksheet, error) {
+	tmpl, system, prompt, err := g.buildPrompt(input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var apiResp struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
-	}
-
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, err
 	}
+	log.Printf("📜 Using prompt template %s v%d", tmpl.ID, tmpl.Version)
 
-	if len(apiResp.Content) == 0 {
-		return nil, fmt.Errorf("empty response from API")
+	responseText, err := g.sendMessage(ctx, system, prompt)
+	if err != nil {
+		return nil, err
 	}
 
 	// Extract JSON from response
-	responseText := apiResp.Content[0].Text
 	log.Printf("📝 AI Response (first 500 chars): %.500s", responseText)
 
 	jsonStr := extractJSON(responseText)
@@ -234,14 +196,23 @@ func (g *AnthropicGenerator) GenerateWorksheet(ctx context.Context, input models
 
 	// Parse generated worksheet
 	var generated struct {
-		Title     string            `json:"title"`
-		Questions []models.Question `json:"questions"`
+		Title      string            `json:"title"`
+		Subject    string            `json:"subject"`
+		GradeLevel string            `json:"grade_level"`
+		Passage    *models.Passage   `json:"passage"`
+		Questions  []models.Question `json:"questions"`
 	}
 
 	if err := json.Unmarshal([]byte(jsonStr), &generated); err != nil {
 		return nil, fmt.Errorf("failed to parse generated worksheet: %w", err)
 	}
 
+	// A worksheet that drifted from the request suggests the teacher text steered the model
+	generated.Questions, err = checkConformance(input, generated.Subject, generated.GradeLevel, generated.Questions)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build worksheet
 	worksheet := &models.Worksheet{
 		ID:                     "ws_" + uuid.New().String()[:8],
@@ -258,6 +229,24 @@ func (g *AnthropicGenerator) GenerateWorksheet(ctx context.Context, input models
 		Downloads:              0,
 		CreatedAt:              time.Now(),
 		UpdatedAt:              time.Now(),
+		PromptTemplate:         tmpl.ID,
+		PromptVersion:          tmpl.Version,
+	}
+
+	// Attach the reading passage: the teacher's text wins over anything the model wrote
+	if strings.TrimSpace(input.Passage) != "" {
+		worksheet.Passage = teacherPassage(input.Passage)
+	} else if input.IncludePassage && generated.Passage != nil && len(generated.Passage.Paragraphs) > 0 {
+		worksheet.Passage = generated.Passage
+		worksheet.Passage.Source = "generated"
+	}
+	scorePassage(worksheet.Passage, input.GradeLevel)
+	checkParagraphRefs(worksheet.Questions, worksheet.Passage)
+
+	if len(input.SourceExcerpts) > 0 {
+		worksheet.SourceFile = input.SourceFile
+		worksheet.SourceExcerpts = input.SourceExcerpts
+		checkSourceRefs(worksheet.Questions, input.SourceExcerpts)
 	}
 
 	// Assign IDs if missing
@@ -270,83 +259,69 @@ func (g *AnthropicGenerator) GenerateWorksheet(ctx context.Context, input models
 		}
 	}
 
-	// Add SVG diagrams for geometry/physics/circuit topics (FIRST priority)
-	if needsDiagrams(input.Subject, input.Topic) {
-		log.Println("📐 Adding SVG diagrams for geometry/physics questions...")
-		worksheet.Questions = g.addDiagramsIfNeeded(worksheet.Questions, input.Topic)
-	}
-
-	// Add images for kindergarten/early grades (only if no SVG was added)
-	if isEarlyGrade(input.GradeLevel) && !needsDiagrams(input.Subject, input.Topic) {
-		log.Println("🖼️ Adding images for early grade worksheet...")
-		for i := range worksheet.Questions {
-			if worksheet.Questions[i].Image == "" {
-				imageURL := GetImageForQuestion(input.Topic, worksheet.Questions[i].Question)
-				if imageURL != "" {
-					worksheet.Questions[i].Image = imageURL
-					log.Printf("   ✅ Added image for question %d", i+1)
-				}
-			}
-		}
-	}
-
-	// Double-check answers for accuracy
-	log.Println("🔍 Double-checking answers for accuracy...")
-	worksheet.Questions = g.verifyAnswers(ctx, worksheet.Questions, input.Subject, input.Topic)
+	worksheet.Questions = g.finishQuestions(ctx, worksheet, worksheet.Questions, input.Limits)
 
 	return worksheet, nil
 }
 
-// verifyAnswers sends questions to AI for answer verification
-func (g *AnthropicGenerator) verifyAnswers(ctx context.Context, questions []models.Question, subject, topic string) []models.Question {
-	// Build verification prompt
-	questionsJSON, err := json.Marshal(questions)
-	if err != nil {
-		log.Printf("⚠️ Failed to marshal questions for verification: %v", err)
-		return questions
+// finishQuestions adds diagrams and images to freshly generated questions and
+// double-checks their answers against the rest of the worksheet
+func (g *AnthropicGenerator) finishQuestions(ctx context.Context, ws *models.Worksheet, questions []models.Question, limits models.GenerationLimits) []models.Question {
+	if limits.NoDiagrams {
+		questions = dropDiagrams(questions)
 	}
 
-	verifyPrompt := fmt.Sprintf(`You are an expert fact-checker and educator. Review these questions and their answers for accuracy.
-
-SUBJECT: %s
-TOPIC: %s
+	// Render diagram specs to SVG (FIRST priority)
+	if hasDiagrams(questions) {
+		log.Println("📐 Rendering SVG diagrams...")
+		questions = renderDiagrams(questions)
+	}
+	if hasLatexDiagrams(questions) {
+		log.Println("📐 Compiling TikZ diagrams...")
+		questions = renderLatexDiagrams(ctx, g.tikz, questions)
+	}
 
-QUESTIONS TO VERIFY:
-%s
+	// Add images for kindergarten/early grades (only where no SVG was added)
+	if isEarlyGrade(ws.GradeLevel) && !needsDiagrams(ws.Subject, ws.Topic) && !limits.NoImages {
+		log.Println("🖼️ Adding images for early grade worksheet...")
+		g.images.AddImages(ctx, ws.Topic, questions)
+	}
 
-TASK:
-1. Check each question's correct_answer for factual accuracy
-2. For math problems: solve them yourself and verify the answer
-3. For science/history: verify facts are correct
-4. If an answer is WRONG, fix it with the correct answer
-5. Return the corrected questions array in the same JSON format
+	// Double-check answers for accuracy
+	log.Println("🔍 Double-checking answers for accuracy...")
+	questions = g.verifyAnswers(ctx, questions, ws.Subject, ws.Topic, verificationReference(ws))
 
-IMPORTANT:
-- Only output the JSON array of questions
-- Keep the exact same structure
-- Only change correct_answer and explanation if there's an error
-- If all answers are correct, return them unchanged
+	// Verification can change answers, so circuits and charts are checked against the final ones
+	checkDiagramAnswers(questions)
+	return questions
+}
 
-Output ONLY valid JSON array, no markdown or extra text.`, subject, topic, string(questionsJSON))
+// Complete sends a single prompt to Claude, for callers outside the generation pipeline such as moderation
+func (g *AnthropicGenerator) Complete(ctx context.Context, system, prompt string) (string, error) {
+	return g.sendMessage(ctx, system, prompt)
+}
 
+// sendMessage posts a single-turn request to the Messages API and returns the text of the reply
+func (g *AnthropicGenerator) sendMessage(ctx context.Context, system, prompt string) (string, error) {
 	requestBody := map[string]interface{}{
-		"model":      "claude-sonnet-4-5-20250929",
+		"model":      anthropicModel,
 		"max_tokens": 4096,
 		"messages": []map[string]string{
-			{"role": "user", "content": verifyPrompt},
+			{"role": "user", "content": prompt},
 		},
 	}
+	if system != "" {
+		requestBody["system"] = system
+	}
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		log.Printf("⚠️ Failed to create verification request: %v", err)
-		return questions
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/v1/messages", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		log.Printf("⚠️ Failed to create verification HTTP request: %v", err)
-		return questions
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -355,42 +330,99 @@ Output ONLY valid JSON array, no markdown or extra text.`, subject, topic, strin
 
 	resp, err := g.client.Do(req)
 	if err != nil {
-		log.Printf("⚠️ Verification request failed: %v", err)
-		return questions
+		return "", fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("⚠️ Verification API error: status %d", resp.StatusCode)
-		return questions
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("⚠️ Failed to read verification response: %v", err)
-		return questions
+		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
 	var apiResp struct {
+		Model   string `json:"model"`
 		Content []struct {
 			Type string `json:"type"`
 			Text string `json:"text"`
 		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		log.Printf("⚠️ Failed to parse verification response: %v", err)
-		return questions
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	model := apiResp.Model
+	if model == "" {
+		model = anthropicModel
 	}
+	recordUsage(ctx, model, apiResp.Usage.InputTokens, apiResp.Usage.OutputTokens)
 
 	if len(apiResp.Content) == 0 {
-		log.Printf("⚠️ Empty verification response")
+		return "", fmt.Errorf("empty response from API")
+	}
+
+	return apiResp.Content[0].Text, nil
+}
+
+// verifyAnswers sends questions to AI for answer verification. Only corrected
+// answers and explanations are taken from the reply; a reply that doesn't match
+// the questions one for one is ignored.
+func (g *AnthropicGenerator) verifyAnswers(ctx context.Context, questions []models.Question, subject, topic, reference string) []models.Question {
+	// Rendered SVGs and diagram specs can run to kilobytes per question and crowd
+	// out the reply, so the checker only sees the text
+	payload := make([]models.Question, len(questions))
+	for i, q := range questions {
+		q.Image, q.ImageCredit, q.Diagram, q.LatexDiagram = "", nil, nil, ""
+		payload[i] = q
+	}
+
+	// Build verification prompt
+	questionsJSON, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("⚠️ Failed to marshal questions for verification: %v", err)
+		return questions
+	}
+
+	verifyPrompt := fmt.Sprintf(`You are an expert fact-checker and educator. Review these questions and their answers for accuracy.
+
+SUBJECT: %s
+TOPIC: %s
+%s
+QUESTIONS TO VERIFY:
+%s
+
+TASK:
+1. Check each question's correct_answer for factual accuracy
+2. For math problems: solve them yourself and verify the answer
+3. For science/history: verify facts are correct
+4. If an answer is WRONG, fix it with the correct answer
+5. Return the corrected questions array in the same JSON format
+
+IMPORTANT:
+- Only output the JSON array of questions
+- Keep the exact same structure
+- Only change correct_answer and explanation if there's an error
+- If all answers are correct, return them unchanged
+
+Output ONLY valid JSON array, no markdown or extra text.`, subject, topic, reference, string(questionsJSON))
+
+	responseText, err := g.sendMessage(ctx, "", verifyPrompt)
+	if err != nil {
+		log.Printf("⚠️ Verification request failed: %v", err)
 		return questions
 	}
 
 	// Extract JSON from response
-	responseText := apiResp.Content[0].Text
-	jsonStr := extractJSON(responseText)
+	jsonStr := extractJSONArray(responseText)
 	if jsonStr == "" {
 		log.Printf("⚠️ No JSON found in verification response")
 		return questions
@@ -403,8 +435,31 @@ Output ONLY valid JSON array, no markdown or extra text.`, subject, topic, strin
 		return questions
 	}
 
-	log.Printf("✅ Answer verification complete - %d questions verified", len(verifiedQuestions))
-	return verifiedQuestions
+	if len(verifiedQuestions) != len(questions) {
+		log.Printf("⚠️ Verification returned %d questions for %d, keeping the originals", len(verifiedQuestions), len(questions))
+		return questions
+	}
+
+	checked := make([]models.Question, len(questions))
+	corrected := 0
+	for i, q := range questions {
+		v := verifiedQuestions[i]
+		if v.ID != q.ID {
+			log.Printf("⚠️ Verification reordered the questions, keeping the originals")
+			return questions
+		}
+		if v.CorrectAnswer != nil && fmt.Sprint(v.CorrectAnswer) != fmt.Sprint(q.CorrectAnswer) {
+			q.CorrectAnswer = v.CorrectAnswer
+			corrected++
+		}
+		if v.Explanation != "" {
+			q.Explanation = v.Explanation
+		}
+		checked[i] = q
+	}
+
+	log.Printf("✅ Answer verification complete - %d questions verified, %d answers corrected", len(checked), corrected)
+	return checked
 }
 
 // isEarlyGrade checks if the grade level requires images
@@ -420,7 +475,8 @@ func isEarlyGrade(gradeLevel string) bool {
 	return false
 }
 
-func (g *AnthropicGenerator) buildPrompt(input models.WorksheetGeneratorInput) string {
+// buildPrompt picks the prompt template for the request and renders the system and user prompts
+func (g *AnthropicGenerator) buildPrompt(input models.WorksheetGeneratorInput) (*prompts.Template, string, string, error) {
 	questionTypes := strings.Join(input.QuestionTypes, ", ")
 	if questionTypes == "" {
 		questionTypes = "multiple_choice"
@@ -428,89 +484,50 @@ func (g *AnthropicGenerator) buildPrompt(input models.WorksheetGeneratorInput) s
 
 	additionalInstr := ""
 	if input.AdditionalInstructions != "" {
-		additionalInstr = fmt.Sprintf("\n\n📝 ADDITIONAL TEACHER INSTRUCTIONS:\n%s", input.AdditionalInstructions)
+		additionalInstr = fmt.Sprintf("\n\n📝 ADDITIONAL TEACHER INSTRUCTIONS (about the worksheet content only):\n%s", delimit("teacher_instructions", input.AdditionalInstructions))
+	}
+
+	tmpl := g.prompts.Select(input.Subject, input.GradeLevel, input.QuestionTypes)
+	system, err := tmpl.System()
+	if err != nil {
+		return nil, "", "", err
+	}
+	prompt, err := tmpl.Worksheet(prompts.WorksheetData{
+		Topic:         delimit("topic", input.Topic),
+		Subject:       input.Subject,
+		GradeLevel:    input.GradeLevel,
+		Difficulty:    input.Difficulty,
+		QuestionCount: input.QuestionCount,
+		QuestionTypes: questionTypes,
+		Language:      languageInstruction(input.Language),
+		Instructions:  additionalInstr,
+		Passage:       passageInstructions(input),
+		Source:        sourceInstructions(input),
+		Examples:      exampleInstructions(input),
+		Diagrams:      diagramPrompt(input.Limits, input.GradeLevel, input.Subject, input.Topic),
+	})
+	if err != nil {
+		return nil, "", "", err
 	}
+	return tmpl, system, prompt, nil
+}
 
-	languageInstr := "English"
-	switch input.Language {
+// languageInstruction describes the output language for prompts
+func languageInstruction(language string) string {
+	switch language {
 	case "tr":
-		languageInstr = "Turkish (Türkçe) - Generate ALL content including questions, options, answers, and explanations in Turkish"
+		return "Turkish (Türkçe) - Generate ALL content including questions, options, answers, and explanations in Turkish"
 	case "es":
-		languageInstr = "Spanish (Español) - Generate ALL content in Spanish"
+		return "Spanish (Español) - Generate ALL content in Spanish"
 	case "fr":
-		languageInstr = "French (Français) - Generate ALL content in French"
+		return "French (Français) - Generate ALL content in French"
 	case "de":
-		languageInstr = "German (Deutsch) - Generate ALL content in German"
+		return "German (Deutsch) - Generate ALL content in German"
 	case "en":
-		languageInstr = "English - Generate all content in English"
+		return "English - Generate all content in English"
 	default:
-		languageInstr = fmt.Sprintf("%s - Generate ALL content in this language", input.Language)
-	}
-
-	return fmt.Sprintf(`Generate an educational worksheet with the following specifications:
-
-📚 WORKSHEET SPECIFICATIONS:
-━━━━━━━━━━━━━━━━━━━━━━━━━━
-• Topic: %s
-• Subject: %s
-• Grade Level: %s
-• Difficulty: %s
-• Number of Questions: %d
-• Question Types: %s
-• Language: %s%s
-
-🎯 OUTPUT REQUIREMENTS:
-━━━━━━━━━━━━━━━━━━━━━━━━━━
-Generate a JSON object with this EXACT structure:
-
-{
-  "title": "Creative and descriptive worksheet title",
-  "questions": [
-    {
-      "id": "q_1",
-      "type": "multiple_choice",
-      "question": "Question text with $LaTeX$ math notation if needed",
-      "options": ["Option A with $math$", "Option B", "Option C", "Option D"],
-      "correct_answer": "The correct option (must match exactly one of the options)",
-      "explanation": "Educational explanation with $math$ if needed",
-      "points": 10
-    }
-  ]
-}
-
-📐 MATHEMATICAL NOTATION:
-━━━━━━━━━━━━━━━━━━━━━━━━━━
-- Use LaTeX for ALL mathematical expressions
-- Inline math: $x^2$, $\frac{1}{2}$, $\sqrt{16}$
-- Display math: $$\sum_{i=1}^{n} i = \frac{n(n+1)}{2}$$
-- Fractions: $\frac{a}{b}$
-- Exponents: $x^2$, $2^{10}$
-- Roots: $\sqrt{x}$, $\sqrt[3]{8}$
-- Greek letters: $\pi$, $\theta$, $\alpha$
-- Geometry: $\angle ABC$, $\triangle ABC$, $\perp$, $\parallel$
-- For early grades: Keep it simple - use LaTeX only for basic operations
-
-📋 QUESTION TYPE FORMATS:
-━━━━━━━━━━━━━━━━━━━━━━━━━━
-• multiple_choice: 4 options array, correct_answer = exact option text
-• true_false: options = ["True", "False"], correct_answer = "True" or "False"
-• fill_blank: use __________ for blank, correct_answer = the word/phrase
-• short_answer: no options, correct_answer = sample correct response
-• essay: no options, points = higher value, correct_answer = grading criteria
-• matching: options = ["Term A → Definition 1", ...], correct_answer = ["A-1", ...]
-
-⚠️ VERIFICATION CHECKLIST (Do this for EACH question):
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-□ Is the question factually accurate?
-□ Is the correct_answer actually correct? (Solve/verify it yourself)
-□ For math: Did you calculate the answer and verify it's right?
-□ For science: Is the scientific information accurate?
-□ For multiple choice: Is there exactly ONE correct answer?
-□ Does the explanation help students understand the concept?
-
-Output ONLY the JSON object. No markdown, no code blocks, no extra text.`,
-		input.Topic, input.Subject, input.GradeLevel, input.Difficulty,
-		input.QuestionCount, questionTypes, languageInstr, additionalInstr)
+		return fmt.Sprintf("%s - Generate ALL content in this language", language)
+	}
 }
 
 func extractJSON(text string) string {
@@ -540,6 +557,18 @@ func extractJSON(text string) string {
 	return ""
 }
 
+// extractJSONArray is extractJSON for replies that are a JSON array
+func extractJSONArray(text string) string {
+	if strings.Contains(text, "```json") {
+		return extractJSON(text)
+	}
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start == -1 || end < start {
+		return ""
+	}
+	return text[start : end+1]
+}
+
 func getDefaultPoints(qType string) int {
 	switch qType {
 	case "essay":
@@ -573,102 +602,6 @@ func needsDiagrams(subject, topic string) bool {
 	return false
 }
 
-// addDiagramsIfNeeded adds SVG diagrams to questions that need them
-func (g *AnthropicGenerator) addDiagramsIfNeeded(questions []models.Question, topic string) []models.Question {
-	topic = strings.ToLower(topic)
-
-	for i := range questions {
-		q := &questions[i]
-		questionLower := strings.ToLower(q.Question)
-
-		// Skip if already has an image
-		if q.Image != "" {
-			continue
-		}
-
-		// Triangle questions (including trigonometry problems)
-		if strings.Contains(questionLower, "triangle") || strings.Contains(questionLower, "△") ||
-			strings.Contains(questionLower, "law of cosines") || strings.Contains(questionLower, "law of sines") ||
-			strings.Contains(questionLower, "cosine rule") || strings.Contains(questionLower, "sine rule") ||
-			(strings.Contains(questionLower, "sides") && strings.Contains(questionLower, "angle")) {
-			q.Image = generateTriangleSVG(q.Question)
-			log.Printf("   ✅ Added triangle SVG for question %d", i+1)
-		}
-
-		// Circle questions
-		if strings.Contains(questionLower, "circle") || strings.Contains(questionLower, "radius") {
-			q.Image = generateCircleSVG(q.Question)
-			log.Printf("   ✅ Added circle SVG for question %d", i+1)
-		}
-
-		// Circuit questions
-		if strings.Contains(questionLower, "circuit") || strings.Contains(questionLower, "resistor") {
-			q.Image = generateCircuitSVG(q.Question)
-			log.Printf("   ✅ Added circuit SVG for question %d", i+1)
-		}
-	}
-
-	return questions
-}
-
-// generateTriangleSVG creates an SVG for triangle problems
-func generateTriangleSVG(question string) string {
-	// Extract values from question if possible
-	a, b, c := "a", "b", "c"
-
-	// Try to extract side lengths
-	re := regexp.MustCompile(`[abc]\s*=\s*(\d+)`)
-	matches := re.FindAllStringSubmatch(question, -1)
-	if len(matches) >= 3 {
-		a = matches[0][1]
-		b = matches[1][1]
-		c = matches[2][1]
-	}
-
-	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 180" width="200" height="180">
-  <polygon points="100,20 30,160 170,160" fill="none" stroke="#0d9488" stroke-width="2.5"/>
-  <text x="100" y="12" text-anchor="middle" font-size="14" font-weight="bold" fill="#1e293b">A</text>
-  <text x="20" y="175" text-anchor="middle" font-size="14" font-weight="bold" fill="#1e293b">B</text>
-  <text x="180" y="175" text-anchor="middle" font-size="14" font-weight="bold" fill="#1e293b">C</text>
-  <text x="55" y="85" text-anchor="middle" font-size="13" fill="#0f766e">%s</text>
-  <text x="145" y="85" text-anchor="middle" font-size="13" fill="#0f766e">%s</text>
-  <text x="100" y="178" text-anchor="middle" font-size="13" fill="#0f766e">%s</text>
-</svg>`, a, b, c)
-}
-
-// generateCircleSVG creates an SVG for circle problems
-func generateCircleSVG(question string) string {
-	radius := "r"
-
-	// Try to extract radius
-	re := regexp.MustCompile(`radius\s*(?:of|is|=)?\s*(\d+)`)
-	if match := re.FindStringSubmatch(strings.ToLower(question)); len(match) > 1 {
-		radius = match[1]
-	}
-
-	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 200" width="200" height="200">
-  <circle cx="100" cy="100" r="70" fill="none" stroke="#0d9488" stroke-width="2.5"/>
-  <circle cx="100" cy="100" r="3" fill="#0d9488"/>
-  <line x1="100" y1="100" x2="170" y2="100" stroke="#f97316" stroke-width="2" stroke-dasharray="5,3"/>
-  <text x="100" y="95" text-anchor="middle" font-size="12" fill="#1e293b">O</text>
-  <text x="135" y="95" text-anchor="middle" font-size="13" font-weight="bold" fill="#f97316">r = %s</text>
-</svg>`, radius)
-}
-
-// generateCircuitSVG creates an SVG for circuit problems
-func generateCircuitSVG(question string) string {
-	return `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 240 120" width="240" height="120">
-  <rect x="20" y="30" width="200" height="60" fill="none" stroke="#0d9488" stroke-width="2"/>
-  <rect x="80" y="25" width="30" height="10" fill="#f97316" stroke="#ea580c" stroke-width="1"/>
-  <text x="95" y="20" text-anchor="middle" font-size="10" fill="#1e293b">R₁</text>
-  <rect x="130" y="25" width="30" height="10" fill="#f97316" stroke="#ea580c" stroke-width="1"/>
-  <text x="145" y="20" text-anchor="middle" font-size="10" fill="#1e293b">R₂</text>
-  <text x="30" y="65" font-size="14" fill="#1e293b">+</text>
-  <text x="200" y="65" font-size="14" fill="#1e293b">−</text>
-  <text x="120" y="110" text-anchor="middle" font-size="11" fill="#64748b">Series Circuit</text>
-</svg>`
-}
-
 // OpenAIGenerator uses OpenAI API (placeholder)
 type OpenAIGenerator struct {
 	apiKey string
@@ -0,0 +1,155 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/makosai/backend/internal/diagram"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/prompts"
+)
+
+// fakeClaude answers every Messages API request with reply and keeps the prompts it was sent
+func fakeClaude(t *testing.T, reply string) (*AnthropicGenerator, *[]string) {
+	t.Helper()
+	var prompts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+		prompts = append(prompts, req.Messages[0].Content)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": reply}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return &AnthropicGenerator{baseURL: srv.URL, client: srv.Client()}, &prompts
+}
+
+// chartQuestion asks for the total of a rendered bar chart whose values add up to 13
+func chartQuestion(t *testing.T, answer string) models.Question {
+	t.Helper()
+	q := models.Question{
+		ID:            "q_1",
+		Type:          "short_answer",
+		Question:      "How many votes were there in total?",
+		CorrectAnswer: answer,
+		Points:        2,
+		Diagram: &models.Diagram{Type: models.DiagramChart, Chart: &models.Chart{
+			Kind: models.ChartBar,
+			Data: []models.ChartDatum{{Label: "Apples", Value: 8}, {Label: "Pears", Value: 5}},
+		}},
+	}
+	if err := diagram.Apply(&q); err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestVerifyAnswersSendsTextOnly(t *testing.T) {
+	g, prompts := fakeClaude(t, `[{"id":"q_1","type":"short_answer","question":"How many votes were there in total?","correct_answer":"13","explanation":"8 + 5 = 13","points":2}]`)
+	q := chartQuestion(t, "13")
+	q.ImageCredit = &models.ImageCredit{Provider: "local"}
+
+	got := g.verifyAnswers(context.Background(), []models.Question{q}, "math", "data", "")
+	if len(*prompts) != 1 {
+		t.Fatalf("sent %d prompts, want 1", len(*prompts))
+	}
+	if prompt := (*prompts)[0]; strings.Contains(prompt, "<svg") || strings.Contains(prompt, `"diagram"`) || strings.Contains(prompt, "image_credit") {
+		t.Errorf("verification prompt includes figures:\n%s", prompt)
+	}
+	if got[0].Image != q.Image || got[0].Diagram != q.Diagram || got[0].ImageCredit != q.ImageCredit {
+		t.Error("figures were not kept on the verified question")
+	}
+	if got[0].Explanation != "8 + 5 = 13" {
+		t.Errorf("explanation = %q", got[0].Explanation)
+	}
+}
+
+func TestVerifyAnswersKeepsOriginals(t *testing.T) {
+	original := models.Question{ID: "q_1", Type: "short_answer", Question: "2 + 2?", CorrectAnswer: "4", Points: 1}
+	for name, reply := range map[string]string{
+		"truncated":      `[{"id":"q_1","type":"short_answer","question":"2 + 2?","correct_ans`,
+		"missing":        `[]`,
+		"extra":          `[{"id":"q_1","correct_answer":"4"},{"id":"q_2","correct_answer":"5"}]`,
+		"other question": `[{"id":"q_9","correct_answer":"5"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			g, _ := fakeClaude(t, reply)
+			got := g.verifyAnswers(context.Background(), []models.Question{original}, "math", "addition", "")
+			if len(got) != 1 || got[0].CorrectAnswer != "4" {
+				t.Errorf("questions = %+v, want the original", got)
+			}
+		})
+	}
+}
+
+func TestVerifyAnswersOnlyChangesAnswers(t *testing.T) {
+	g, _ := fakeClaude(t, `[{"id":"q_1","type":"essay","question":"Rewritten","options":["x"],"correct_answer":"5","points":9}]`)
+	original := models.Question{ID: "q_1", Type: "short_answer", Question: "2 + 3?", CorrectAnswer: "6", Explanation: "Add them", Points: 1}
+
+	got := g.verifyAnswers(context.Background(), []models.Question{original}, "math", "addition", "")[0]
+	if got.CorrectAnswer != "5" {
+		t.Errorf("answer = %v, want the correction", got.CorrectAnswer)
+	}
+	if got.Type != "short_answer" || got.Question != "2 + 3?" || got.Options != nil || got.Points != 1 || got.Explanation != "Add them" {
+		t.Errorf("verification changed more than the answer: %+v", got)
+	}
+}
+
+func TestCheckDiagramAnswersAfterVerification(t *testing.T) {
+	// The checker "corrects" a right answer to a wrong one
+	g, _ := fakeClaude(t, `[{"id":"q_1","correct_answer":"14"}]`)
+	q := chartQuestion(t, "13")
+	if len(q.Warnings) != 0 {
+		t.Fatalf("warnings before verification: %v", q.Warnings)
+	}
+
+	questions := g.verifyAnswers(context.Background(), []models.Question{q}, "math", "data", "")
+	checkDiagramAnswers(questions)
+	if len(questions[0].Warnings) != 1 || !strings.HasPrefix(questions[0].Warnings[0], "Chart check: ") {
+		t.Errorf("warnings = %v, want a chart check on the verified answer", questions[0].Warnings)
+	}
+}
+
+func TestGenerateWorksheetRecordsPromptTemplate(t *testing.T) {
+	tests := []struct {
+		subject, grade, qType string
+		template              string
+	}{
+		{"math", "5", "short_answer", "math"},
+		{"science", "7", "multiple_choice", "science"},
+		{"history", "6", "short_answer", "written-response"},
+		{"history", "6", "multiple_choice", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			reply, _ := json.Marshal(map[string]interface{}{
+				"title": "Quiz", "subject": tt.subject, "grade_level": tt.grade,
+				"questions": []models.Question{{Type: tt.qType, Question: "Which is true?", Options: []string{"A", "B", "C", "D"}, CorrectAnswer: "A", Points: 2}},
+			})
+			g, _ := fakeClaude(t, string(reply))
+			g.prompts = prompts.Embedded()
+
+			ws, err := g.GenerateWorksheet(context.Background(), models.WorksheetGeneratorInput{
+				Topic: "Review", Subject: tt.subject, GradeLevel: tt.grade, QuestionCount: 1, QuestionTypes: []string{tt.qType},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := g.prompts.Select(tt.subject, tt.grade, []string{tt.qType})
+			if ws.PromptTemplate != tt.template || ws.PromptVersion != want.Version || ws.PromptVersion < 1 {
+				t.Errorf("recorded %s v%d, want %s v%d", ws.PromptTemplate, ws.PromptVersion, tt.template, want.Version)
+			}
+		})
+	}
+}
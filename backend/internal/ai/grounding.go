@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// maxExampleQuestions caps how many question-bank examples are added to a prompt
+const maxExampleQuestions = 15
+
+// formatExcerpts renders source excerpts with their IDs for use in prompts
+func formatExcerpts(excerpts []models.SourceExcerpt) string {
+	var sb strings.Builder
+	for _, ex := range excerpts {
+		fmt.Fprintf(&sb, "[%s]\n%s\n\n", ex.ID, ex.Text)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// sourceInstructions returns the prompt section that restricts questions to uploaded material
+func sourceInstructions(input models.WorksheetGeneratorInput) string {
+	if len(input.SourceExcerpts) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+
+📎 SOURCE MATERIAL (uploaded by the teacher, split into excerpts):
+━━━━━━━━━━━━━━━━━━━━━━━━━━
+%s
+
+- Draw every question ONLY from the source material above; do not add outside facts
+- Set "source_excerpt_id" on each question to the ID of the excerpt it is based on, e.g. "S3"
+- Correct answers must be stated or directly supported by that excerpt`, delimit("source_material", formatExcerpts(input.SourceExcerpts)))
+}
+
+// exampleInstructions returns the prompt section that shows existing questions
+// (e.g. from an imported question bank) as style examples
+func exampleInstructions(input models.WorksheetGeneratorInput) string {
+	if len(input.ExampleQuestions) == 0 {
+		return ""
+	}
+
+	examples := input.ExampleQuestions
+	if len(examples) > maxExampleQuestions {
+		examples = examples[:maxExampleQuestions]
+	}
+
+	var sb strings.Builder
+	for i, q := range examples {
+		fmt.Fprintf(&sb, "%d. (%s) %s", i+1, q.Type, q.Question)
+		if answer := q.AnswerText(); answer != "" {
+			fmt.Fprintf(&sb, " → %s", answer)
+		}
+		sb.WriteString("\n")
+	}
+
+	return fmt.Sprintf(`
+
+🗂️ EXISTING QUESTIONS FROM THE TEACHER'S QUESTION BANK:
+━━━━━━━━━━━━━━━━━━━━━━━━━━
+%s
+- Match the style, vocabulary and difficulty of these questions
+- Do NOT repeat or trivially reword any of them`, delimit("existing_questions", sb.String()))
+}
+
+// checkSourceRefs clears excerpt references that do not match any uploaded excerpt
+func checkSourceRefs(questions []models.Question, excerpts []models.SourceExcerpt) {
+	if len(excerpts) == 0 {
+		return
+	}
+
+	known := make(map[string]bool, len(excerpts))
+	for _, ex := range excerpts {
+		known[ex.ID] = true
+	}
+
+	for i := range questions {
+		ref := strings.Trim(strings.TrimSpace(questions[i].SourceExcerpt), "[]")
+		if ref != "" && !known[ref] {
+			log.Printf("⚠️ Question %d references unknown source excerpt %q", i+1, ref)
+			ref = ""
+		}
+		questions[i].SourceExcerpt = ref
+	}
+}
+
+// checkParagraphRefs drops paragraph references outside the passage, or all of
+// them when the worksheet has no passage
+func checkParagraphRefs(questions []models.Question, passage *models.Passage) {
+	n := 0
+	if passage != nil {
+		n = len(passage.Paragraphs)
+	}
+
+	for i := range questions {
+		refs := questions[i].ParagraphRefs[:0]
+		for _, ref := range questions[i].ParagraphRefs {
+			if ref < 1 || ref > n {
+				log.Printf("⚠️ Question %d references paragraph %d of a %d-paragraph passage", i+1, ref, n)
+				continue
+			}
+			refs = append(refs, ref)
+		}
+		if len(refs) == 0 {
+			refs = nil
+		}
+		questions[i].ParagraphRefs = refs
+	}
+}
+
+// verificationReference returns the passage and source material the verifier should check answers against
+func verificationReference(ws *models.Worksheet) string {
+	var sb strings.Builder
+	if ws.Passage != nil {
+		fmt.Fprintf(&sb, "\nREADING PASSAGE (answers must be supported by it; paragraph_refs point to these numbers):\n%s\n", numberedPassage(ws.Passage))
+	}
+	if len(ws.SourceExcerpts) > 0 {
+		fmt.Fprintf(&sb, "\nSOURCE MATERIAL (answers must be supported by the excerpt named in source_excerpt_id):\n%s\n", formatExcerpts(ws.SourceExcerpts))
+	}
+	return sb.String()
+}
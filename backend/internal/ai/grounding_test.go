@@ -0,0 +1,35 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestCheckParagraphRefs(t *testing.T) {
+	passage := &models.Passage{Paragraphs: []string{"One.", "Two.", "Three."}}
+
+	tests := []struct {
+		name    string
+		passage *models.Passage
+		refs    []int
+		want    []int
+	}{
+		{"in range", passage, []int{1, 3}, []int{1, 3}},
+		{"past the end", passage, []int{2, 4, 7}, []int{2}},
+		{"zero and negative", passage, []int{0, -1, 1}, []int{1}},
+		{"all out of range", passage, []int{4}, nil},
+		{"no passage", nil, []int{1}, nil},
+		{"no refs", passage, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			questions := []models.Question{{ID: "q_1", ParagraphRefs: tt.refs}}
+			checkParagraphRefs(questions, tt.passage)
+			if got := questions[0].ParagraphRefs; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("refs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
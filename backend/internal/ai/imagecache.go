@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ImageCache stores provider search results by provider and query. Empty
+// results are cached too, so a query with no matches is not retried on every question.
+type ImageCache interface {
+	Get(ctx context.Context, key string) ([]ImageResult, bool)
+	Set(ctx context.Context, key string, results []ImageResult)
+}
+
+// imageCacheTTL keeps results well inside the hotlinking window providers allow
+const imageCacheTTL = 24 * time.Hour
+
+// maxMemoryImageEntries bounds the memory cache; expired entries are swept
+// first, then the entry closest to expiring is dropped
+const maxMemoryImageEntries = 2000
+
+func imageCacheKey(provider, query string) string {
+	sum := sha256.Sum256([]byte(provider + "\x00" + query))
+	return "images:" + hex.EncodeToString(sum[:16])
+}
+
+type cachedImages struct {
+	results []ImageResult
+	expires time.Time
+}
+
+// MemoryImageCache keeps results in process memory
+type MemoryImageCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedImages
+}
+
+func NewMemoryImageCache() *MemoryImageCache {
+	return &MemoryImageCache{entries: make(map[string]cachedImages)}
+}
+
+func (c *MemoryImageCache) Get(ctx context.Context, key string) ([]ImageResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.results, true
+}
+
+func (c *MemoryImageCache) Set(ctx context.Context, key string, results []ImageResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxMemoryImageEntries {
+		oldest := ""
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= maxMemoryImageEntries {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = cachedImages{results: results, expires: now.Add(imageCacheTTL)}
+}
+
+// DiskImageCache stores results as JSON files in a directory, expiring them by modification time
+type DiskImageCache struct {
+	dir string
+}
+
+func NewDiskImageCache(dir string) (*DiskImageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskImageCache{dir: dir}, nil
+}
+
+func (c *DiskImageCache) path(key string) string {
+	return filepath.Join(c.dir, key[len("images:"):]+".json")
+}
+
+func (c *DiskImageCache) Get(ctx context.Context, key string) ([]ImageResult, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > imageCacheTTL {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var results []ImageResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, false
+	}
+	return results, true
+}
+
+func (c *DiskImageCache) Set(ctx context.Context, key string, results []ImageResult) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return
+	}
+	// Write a uniquely named file then rename it, so concurrent readers never
+	// see a partial file and concurrent writers never share one
+	tmp, err := os.CreateTemp(c.dir, "images-*.tmp")
+	if err != nil {
+		log.Printf("⚠️ Image cache write failed: %v", err)
+		return
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("⚠️ Image cache write failed: %v", err)
+	}
+}
+
+// RedisImageCache stores results in Redis with an expiry, so they are shared across instances
+type RedisImageCache struct {
+	client *redis.Client
+}
+
+// NewRedisImageCache connects to a redis:// URL
+func NewRedisImageCache(redisURL string) (*RedisImageCache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisImageCache{client: redis.NewClient(opts)}, nil
+}
+
+func (c *RedisImageCache) Get(ctx context.Context, key string) ([]ImageResult, bool) {
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("⚠️ Image cache read failed: %v", err)
+		}
+		return nil, false
+	}
+	var results []ImageResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, false
+	}
+	return results, true
+}
+
+func (c *RedisImageCache) Set(ctx context.Context, key string, results []ImageResult) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return
+	}
+	if err := c.client.Set(ctx, key, data, imageCacheTTL).Err(); err != nil {
+		log.Printf("⚠️ Image cache write failed: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryImageCacheBounded(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryImageCache()
+	c.entries["expired"] = cachedImages{expires: time.Now().Add(-time.Minute)}
+	for i := 0; len(c.entries) < maxMemoryImageEntries; i++ {
+		c.Set(ctx, fmt.Sprint("key", i), nil)
+	}
+
+	c.Set(ctx, "new", []ImageResult{{URL: "https://example.com/a.jpg"}})
+	if _, ok := c.entries["expired"]; ok {
+		t.Error("expired entry was not swept")
+	}
+	if len(c.entries) != maxMemoryImageEntries {
+		t.Errorf("%d entries, want %d", len(c.entries), maxMemoryImageEntries)
+	}
+
+	c.Set(ctx, "newer", nil)
+	if len(c.entries) != maxMemoryImageEntries {
+		t.Errorf("%d entries after a full Set, want %d", len(c.entries), maxMemoryImageEntries)
+	}
+	if _, ok := c.Get(ctx, "new"); !ok {
+		t.Error("a fresh entry was evicted before older ones")
+	}
+}
+
+func TestDiskImageCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c, err := NewDiskImageCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := imageCacheKey("pixabay", "volcano")
+	want := []ImageResult{{URL: "https://example.com/volcano.jpg"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Set(ctx, key, want)
+		}()
+	}
+	wg.Wait()
+
+	got, ok := c.Get(ctx, key)
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Get = %v, %v", got, ok)
+	}
+	if leftover, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftover) > 0 {
+		t.Errorf("temporary files left behind: %v", leftover)
+	}
+
+	old := time.Now().Add(-imageCacheTTL - time.Minute)
+	os.Chtimes(c.path(key), old, old)
+	if _, ok := c.Get(ctx, key); ok {
+		t.Error("expired entry was returned")
+	}
+}
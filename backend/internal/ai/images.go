@@ -0,0 +1,311 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/makosai/backend/internal/clipart"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/moderation"
+)
+
+// ImageResult is a candidate picture for a question and the credit its license requires
+type ImageResult struct {
+	URL         string              `json:"url"`                   // or inline SVG for bundled clipart
+	Description string              `json:"description,omitempty"` // provider's caption or tags, screened before use
+	Credit      *models.ImageCredit `json:"credit,omitempty"`
+	TrackURL    string              `json:"track_url,omitempty"` // provider endpoint to notify when the image is used
+}
+
+// ImageProvider searches a source of pictures for a query
+type ImageProvider interface {
+	Name() string
+	Search(ctx context.Context, query string) ([]ImageResult, error)
+}
+
+// imageTracker is implemented by providers that must be told when an image is used
+type imageTracker interface {
+	TrackUse(ctx context.Context, result ImageResult) error
+}
+
+const defaultImageConcurrency = 4
+
+// ImageFinder picks pictures for questions from a list of providers, caching
+// every search and looking up several questions at once
+type ImageFinder struct {
+	providers []ImageProvider
+	cache     ImageCache
+	limit     int
+	moderator *moderation.Moderator
+}
+
+func NewImageFinder(cache ImageCache, limit int, providers ...ImageProvider) *ImageFinder {
+	if cache == nil {
+		cache = NewMemoryImageCache()
+	}
+	if limit < 1 {
+		limit = defaultImageConcurrency
+	}
+	// Photos are screened at the strictest threshold since they are only added for the youngest students
+	wordList, err := moderation.NewWordListClassifier()
+	if err != nil {
+		panic(err)
+	}
+	return &ImageFinder{providers: providers, cache: cache, limit: limit, moderator: moderation.New(wordList)}
+}
+
+// NewImageFinderFromEnv builds a finder from IMAGE_PROVIDERS (default
+// "local,unsplash,pixabay"; providers without credentials are skipped),
+// UNSPLASH_ACCESS_KEY, PIXABAY_API_KEY, LOCAL_IMAGE_DIR and LOCAL_IMAGE_BASE_URL,
+// with results cached in Redis (REDIS_URL), on disk (IMAGE_CACHE_DIR) or in memory.
+func NewImageFinderFromEnv() *ImageFinder {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	names := os.Getenv("IMAGE_PROVIDERS")
+	if names == "" {
+		names = "local,unsplash,pixabay"
+	}
+	var providers []ImageProvider
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "local":
+			if dir := os.Getenv("LOCAL_IMAGE_DIR"); dir != "" {
+				library, err := LoadLocalImageLibrary(dir, os.Getenv("LOCAL_IMAGE_BASE_URL"))
+				if err != nil {
+					log.Printf("⚠ Local image library not loaded: %v", err)
+					continue
+				}
+				providers = append(providers, library)
+			}
+		case "unsplash":
+			if key := os.Getenv("UNSPLASH_ACCESS_KEY"); key != "" {
+				providers = append(providers, NewUnsplashProvider(key, client))
+			}
+		case "pixabay":
+			if key := os.Getenv("PIXABAY_API_KEY"); key != "" {
+				providers = append(providers, NewPixabayProvider(key, client))
+			}
+		default:
+			log.Printf("⚠ Unknown image provider %q", name)
+		}
+	}
+
+	var cache ImageCache
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		if c, err := NewRedisImageCache(redisURL); err == nil {
+			cache = c
+		} else {
+			log.Printf("⚠ Redis image cache not available: %v", err)
+		}
+	}
+	if dir := os.Getenv("IMAGE_CACHE_DIR"); cache == nil && dir != "" {
+		if c, err := NewDiskImageCache(dir); err == nil {
+			cache = c
+		} else {
+			log.Printf("⚠ Disk image cache not available: %v", err)
+		}
+	}
+
+	limit, _ := strconv.Atoi(os.Getenv("IMAGE_LOOKUP_CONCURRENCY"))
+	return NewImageFinder(cache, limit, providers...)
+}
+
+// AddImages looks up a picture for every question that has no image yet
+func (f *ImageFinder) AddImages(ctx context.Context, topic string, questions []models.Question) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, f.limit)
+	for i := range questions {
+		if questions[i].Image != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(q *models.Question, n int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if result, ok := f.Find(ctx, topic, q.Question); ok {
+				q.Image = result.URL
+				q.ImageCredit = result.Credit
+				log.Printf("   ✅ Added image for question %d", n)
+			}
+		}(&questions[i], i+1)
+	}
+	wg.Wait()
+}
+
+// Find picks bundled clipart for a noun in the question or topic when there is
+// one, and otherwise searches the providers for the question, falling back to
+// the topic and then to a generic classroom picture
+func (f *ImageFinder) Find(ctx context.Context, topic, questionText string) (ImageResult, bool) {
+	for _, text := range []string{questionText, topic} {
+		if clip, ok := clipart.Match(text); ok {
+			return ImageResult{URL: clip.SVG}, true
+		}
+	}
+
+	var queries []string
+	if keywords := extractKeywords(questionText); len(keywords) > 0 {
+		queries = append(queries, strings.Join(keywords, " ")+" kids colorful")
+	} else {
+		queries = append(queries, topic+" kids education")
+	}
+	queries = append(queries, topic+" children", "kids learning colorful")
+
+	for _, query := range queries {
+		for _, p := range f.providers {
+			results, err := f.search(ctx, p, query)
+			if err != nil {
+				log.Printf("   ⚠️ %s search for %q failed: %v", p.Name(), query, err)
+				continue
+			}
+			results = f.safeResults(ctx, results)
+			if len(results) == 0 {
+				continue
+			}
+
+			// Pick a random result for variety
+			result := results[rand.Intn(len(results))]
+			if tracker, ok := p.(imageTracker); ok {
+				if err := tracker.TrackUse(ctx, result); err != nil {
+					log.Printf("   ⚠️ %s usage tracking failed: %v", p.Name(), err)
+				}
+			}
+			return result, true
+		}
+	}
+	return ImageResult{}, false
+}
+
+// safeResults drops results whose caption or tags are flagged as unsuitable for kindergarten
+func (f *ImageFinder) safeResults(ctx context.Context, results []ImageResult) []ImageResult {
+	texts := make([]moderation.Text, 0, len(results))
+	for i, r := range results {
+		if r.Description != "" {
+			texts = append(texts, moderation.Text{Field: strconv.Itoa(i), Content: r.Description})
+		}
+	}
+	if len(texts) == 0 {
+		return results
+	}
+	flagged := map[string]bool{}
+	for _, flag := range f.moderator.Screen(ctx, "K", texts) {
+		flagged[flag.Field] = true
+	}
+	safe := make([]ImageResult, 0, len(results))
+	for i, r := range results {
+		if flagged[strconv.Itoa(i)] {
+			log.Printf("   🚫 Skipped image %q: flagged description %q", r.URL, r.Description)
+			continue
+		}
+		safe = append(safe, r)
+	}
+	return safe
+}
+
+func (f *ImageFinder) search(ctx context.Context, p ImageProvider, query string) ([]ImageResult, error) {
+	key := imageCacheKey(p.Name(), strings.ToLower(strings.TrimSpace(query)))
+	if results, ok := f.cache.Get(ctx, key); ok {
+		return results, nil
+	}
+	results, err := p.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	f.cache.Set(ctx, key, results)
+	return results, nil
+}
+
+// LocalImage is one entry of a curated image library's index.json
+type LocalImage struct {
+	File   string              `json:"file"`
+	Tags   []string            `json:"tags"`
+	Credit *models.ImageCredit `json:"credit,omitempty"`
+}
+
+// LocalImageLibrary serves pictures from a curated directory, matched by tag
+type LocalImageLibrary struct {
+	baseURL string
+	images  []LocalImage
+}
+
+// LoadLocalImageLibrary reads dir/index.json; image URLs are baseURL + "/" + file
+func LoadLocalImageLibrary(dir, baseURL string) (*LocalImageLibrary, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("LOCAL_IMAGE_BASE_URL is required with LOCAL_IMAGE_DIR")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+	var images []LocalImage
+	if err := json.Unmarshal(data, &images); err != nil {
+		return nil, fmt.Errorf("index.json: %w", err)
+	}
+	return NewLocalImageLibrary(baseURL, images), nil
+}
+
+func NewLocalImageLibrary(baseURL string, images []LocalImage) *LocalImageLibrary {
+	return &LocalImageLibrary{baseURL: strings.TrimRight(baseURL, "/"), images: images}
+}
+
+func (l *LocalImageLibrary) Name() string { return "local" }
+
+// Search returns the images sharing the most tags with the query's words
+func (l *LocalImageLibrary) Search(ctx context.Context, query string) ([]ImageResult, error) {
+	words := map[string]bool{}
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		words[w] = true
+		words[strings.TrimSuffix(w, "s")] = true
+	}
+
+	type match struct {
+		image LocalImage
+		score int
+	}
+	var matches []match
+	for _, img := range l.images {
+		score := 0
+		for _, tag := range img.Tags {
+			if words[strings.ToLower(tag)] {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, match{img, score})
+		}
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	var results []ImageResult
+	for _, m := range matches {
+		if m.score < matches[0].score {
+			break
+		}
+		results = append(results, ImageResult{URL: l.baseURL + "/" + m.image.File, Credit: m.image.Credit})
+	}
+	return results, nil
+}
+
+// extractKeywords returns up to three nouns from question text to search for
+func extractKeywords(text string) []string {
+	keywords := clipart.Nouns(text)
+	if len(keywords) > 3 {
+		return keywords[:3]
+	}
+	return keywords
+}
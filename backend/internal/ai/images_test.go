@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeImages returns one photo per search and keeps the queries it was sent
+type fakeImages struct {
+	queries []string
+}
+
+func (p *fakeImages) Name() string { return "fake" }
+
+func (p *fakeImages) Search(ctx context.Context, query string) ([]ImageResult, error) {
+	p.queries = append(p.queries, query)
+	return []ImageResult{{URL: "https://images.example/photo.jpg", Description: "volcano erupting"}}, nil
+}
+
+func TestFindPrefersClipart(t *testing.T) {
+	provider := &fakeImages{}
+	finder := NewImageFinder(nil, 1, provider)
+
+	tests := []struct {
+		name, topic, question string
+	}{
+		{"noun in the question", "Counting", "How many apples are in the basket?"},
+		{"noun in the topic", "Farm animals: ducks", "How many are swimming?"},
+	}
+	for _, tt := range tests {
+		result, ok := finder.Find(context.Background(), tt.topic, tt.question)
+		if !ok || !strings.HasPrefix(strings.TrimSpace(result.URL), "<svg") || result.Credit != nil {
+			t.Errorf("%s: got %+v, %v, want bundled clipart", tt.name, result, ok)
+		}
+	}
+	if len(provider.queries) != 0 {
+		t.Errorf("providers searched for %q despite a clipart match", provider.queries)
+	}
+}
+
+func TestFindFallsThroughToProviders(t *testing.T) {
+	provider := &fakeImages{}
+	finder := NewImageFinder(nil, 1, provider)
+
+	result, ok := finder.Find(context.Background(), "Geology", "Which volcano erupted in 1980?")
+	if !ok || result.URL != "https://images.example/photo.jpg" {
+		t.Fatalf("got %+v, %v, want the provider's photo", result, ok)
+	}
+	if len(provider.queries) != 1 || provider.queries[0] != "volcano erupted kids colorful" {
+		t.Errorf("queries = %q, want one search for the question's keywords", provider.queries)
+	}
+}
@@ -0,0 +1,216 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/readability"
+	"github.com/makosai/backend/internal/source"
+)
+
+// Length caps for teacher-supplied text that is pasted into prompts
+const (
+	MaxTopicLength        = 200
+	MaxInstructionsLength = 2000
+	MaxPassageLength      = 12000
+	maxOptionLength       = 40 // subject, grade level, difficulty, language, excerpt IDs
+)
+
+// roleMarkers are chat-format control sequences. Teacher text is pasted into
+// the prompt as data, so these are refused outright: no worksheet needs them.
+var roleMarkers = regexp.MustCompile(`(?im)</?\s*(system|assistant|user|human)\s*>|^\s*(system|assistant|human)\s*:|\[/?(inst|system)\]|<\|im_(start|end)\|>`)
+
+// suspiciousPatterns match wording common in attempts to override the prompt.
+// They also match legitimate lessons ("prompt injection" in a security unit,
+// "students return the answer in HTML" in a web design one), so they are only
+// logged; the text is still delimited as data and the output checked for
+// conformance.
+var suspiciousPatterns = []struct {
+	reason string
+	re     *regexp.Regexp
+}{
+	{"asks to ignore earlier instructions", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\b.{0,20}\b(previous|prior|above|earlier|your|all|these|the system|any)\s+(instructions?|rules|prompts?|guidelines)\b`)},
+	{"tries to reassign the model's role", regexp.MustCompile(`(?i)\b(you are now|from now on,? you|act as (an? )?(ai|assistant|system|developer)|pretend (to be|you are)|new (role|persona))\b`)},
+	{"mentions the system prompt", regexp.MustCompile(`(?i)\b(system|developer) (prompt|message|instructions?)\b|\breveal (your|the) (prompt|instructions)\b`)},
+	{"tries to change the output format", regexp.MustCompile(`(?i)\b(respond|reply|output|return)\b.{0,30}\b(only|instead|in)\b.{0,20}\b(yaml|xml|html|markdown|plain text|csv)\b|\b(do not|don't|stop) (output|return|use|respond in) json\b`)},
+	{"names a jailbreak", regexp.MustCompile(`(?i)\b(jailbreak|DAN mode|developer mode|prompt injection)\b`)},
+}
+
+// DetectInjection returns why text can't be pasted into a prompt, or "" when
+// it holds no prompt delimiter tags or chat role markers
+func DetectInjection(text string) string {
+	if promptTags.MatchString(text) {
+		return "contains prompt delimiter tags"
+	}
+	if roleMarkers.MatchString(text) {
+		return "contains chat role markers"
+	}
+	return ""
+}
+
+// suspicion returns why text reads like an override attempt, or ""
+func suspicion(text string) string {
+	for _, p := range suspiciousPatterns {
+		if p.re.MatchString(text) {
+			return p.reason
+		}
+	}
+	return ""
+}
+
+// InputError explains why teacher input was refused before generation
+type InputError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Reason)
+}
+
+// CheckText enforces a length cap on one piece of teacher text, refuses prompt
+// control sequences and logs wording that looks like an override attempt
+func CheckText(field, text string, maxLength int) *InputError {
+	if n := len([]rune(text)); n > maxLength {
+		return &InputError{Field: field, Reason: fmt.Sprintf("must be %d characters or fewer (got %d)", maxLength, n)}
+	}
+	if reason := DetectInjection(text); reason != "" {
+		return &InputError{Field: field, Reason: reason}
+	}
+	if reason := suspicion(text); reason != "" {
+		log.Printf("🕵️ Passing on %s that %s: %.200q", field, reason, text)
+	}
+	return nil
+}
+
+// CheckInput screens every generation field that is pasted into the prompt as free text
+func CheckInput(input models.WorksheetGeneratorInput) *InputError {
+	fields := []struct {
+		name, text string
+		max        int
+	}{
+		{"topic", input.Topic, MaxTopicLength},
+		{"subject", input.Subject, maxOptionLength},
+		{"grade_level", input.GradeLevel, maxOptionLength},
+		{"difficulty", input.Difficulty, maxOptionLength},
+		{"language", input.Language, maxOptionLength},
+		{"additional_instructions", input.AdditionalInstructions, MaxInstructionsLength},
+		{"passage", input.Passage, MaxPassageLength},
+	}
+	for _, f := range fields {
+		if err := CheckText(f.name, f.text, f.max); err != nil {
+			return err
+		}
+	}
+
+	total := 0
+	for i, ex := range input.SourceExcerpts {
+		if err := CheckText(fmt.Sprintf("source_excerpts[%d].id", i), ex.ID, maxOptionLength); err != nil {
+			return err
+		}
+		if err := CheckText(fmt.Sprintf("source_excerpts[%d].text", i), ex.Text, source.MaxContextChars); err != nil {
+			return err
+		}
+		total += len([]rune(ex.Text))
+	}
+	if total > source.MaxContextChars {
+		return &InputError{Field: "source_excerpts", Reason: fmt.Sprintf("must be %d characters or fewer in total (got %d)", source.MaxContextChars, total)}
+	}
+	for _, t := range input.QuestionTypes {
+		if !models.IsValidQuestionType(t) {
+			return &InputError{Field: "question_types", Reason: fmt.Sprintf("has unknown type %q", t)}
+		}
+	}
+	return nil
+}
+
+// promptTags are the delimiters teacher text is wrapped in; teacher text may not close them early
+var promptTags = regexp.MustCompile(`(?i)<\s*/?\s*(topic|worksheet_title|teacher_instructions|teacher_passage|source_material|existing_questions)\b[^>]*>`)
+
+// delimit wraps teacher-supplied text in a tag the system prompt tells the
+// model to treat as data, stripping any copy of the tags from the text itself
+func delimit(tag, text string) string {
+	text = strings.TrimSpace(promptTags.ReplaceAllString(text, ""))
+	if strings.Contains(text, "\n") {
+		return fmt.Sprintf("<%s>\n%s\n</%s>", tag, text, tag)
+	}
+	return fmt.Sprintf("<%s>%s</%s>", tag, text, tag)
+}
+
+// ConformanceError reports a generated worksheet that doesn't match what was
+// requested, which suggests the prompt was steered off course
+type ConformanceError struct {
+	Problems []string
+}
+
+func (e *ConformanceError) Error() string {
+	return "generated worksheet does not match the request: " + strings.Join(e.Problems, "; ")
+}
+
+// checkConformance compares the model's output with the request. Extra
+// questions are trimmed; anything else that doesn't match is an error.
+func checkConformance(input models.WorksheetGeneratorInput, subject, gradeLevel string, questions []models.Question) ([]models.Question, error) {
+	var problems []string
+	if !sameSubject(subject, input.Subject) {
+		problems = append(problems, fmt.Sprintf("subject is %q, requested %q", subject, input.Subject))
+	}
+	if !sameGrade(gradeLevel, input.GradeLevel) {
+		problems = append(problems, fmt.Sprintf("grade level is %q, requested %q", gradeLevel, input.GradeLevel))
+	}
+	if len(questions) > input.QuestionCount {
+		questions = questions[:input.QuestionCount]
+	}
+	if len(questions) < input.QuestionCount {
+		problems = append(problems, fmt.Sprintf("%d questions, requested %d", len(questions), input.QuestionCount))
+	}
+	if bad := unrequestedTypes(questions, input.QuestionTypes); len(bad) > 0 {
+		problems = append(problems, fmt.Sprintf("question types %s were not requested", strings.Join(bad, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return nil, &ConformanceError{Problems: problems}
+	}
+	return questions, nil
+}
+
+// unrequestedTypes lists the question types that aren't among the requested ones
+func unrequestedTypes(questions []models.Question, requested []string) []string {
+	if len(requested) == 0 {
+		return nil
+	}
+	allowed := map[string]bool{}
+	for _, t := range requested {
+		allowed[t] = true
+	}
+	seen := map[string]bool{}
+	var bad []string
+	for _, q := range questions {
+		if !allowed[q.Type] && !seen[q.Type] {
+			seen[q.Type] = true
+			bad = append(bad, fmt.Sprintf("%q", q.Type))
+		}
+	}
+	return bad
+}
+
+// sameSubject tolerates the model wording the subject a little differently,
+// e.g. "Math" for "math" or "Life Science" for "science"
+func sameSubject(got, want string) bool {
+	got, want = strings.ToLower(strings.TrimSpace(got)), strings.ToLower(strings.TrimSpace(want))
+	if got == "" {
+		return false
+	}
+	return strings.Contains(got, want) || strings.Contains(want, got)
+}
+
+func sameGrade(got, want string) bool {
+	g, gok := readability.ParseGrade(got)
+	w, wok := readability.ParseGrade(want)
+	if gok && wok {
+		return g == w
+	}
+	return strings.EqualFold(strings.TrimSpace(got), strings.TrimSpace(want))
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/readability"
+)
+
+// wantsPassage reports whether the worksheet should be built around a reading passage
+func wantsPassage(input models.WorksheetGeneratorInput) bool {
+	return input.IncludePassage || strings.TrimSpace(input.Passage) != ""
+}
+
+// teacherPassage builds a passage from text pasted by the teacher
+func teacherPassage(text string) *models.Passage {
+	return &models.Passage{
+		Paragraphs: splitParagraphs(text),
+		Source:     "teacher",
+	}
+}
+
+// splitParagraphs splits text on blank lines, falling back to single line breaks
+func splitParagraphs(text string) []string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "\r\n", "\n")
+
+	parts := strings.Split(text, "\n\n")
+	if len(parts) == 1 {
+		parts = strings.Split(text, "\n")
+	}
+
+	var paragraphs []string
+	for _, p := range parts {
+		p = strings.Join(strings.Fields(p), " ")
+		if p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// scorePassage computes Flesch-Kincaid readability for the passage against the target grade
+func scorePassage(p *models.Passage, gradeLevel string) {
+	if p == nil || len(p.Paragraphs) == 0 {
+		return
+	}
+
+	stats := readability.Analyze(p.Text())
+	p.Readability = &models.Readability{
+		Words:         stats.Words,
+		Sentences:     stats.Sentences,
+		Syllables:     stats.Syllables,
+		FleschKincaid: stats.GradeLevel,
+		ReadingEase:   stats.ReadingEase,
+		TargetGrade:   gradeLevel,
+		WithinRange:   readability.WithinGrade(stats.GradeLevel, gradeLevel),
+	}
+
+	if !p.Readability.WithinRange {
+		log.Printf("⚠️ Passage reads at grade %.1f, target is %s", stats.GradeLevel, gradeLevel)
+	}
+}
+
+// numberedPassage renders the passage with paragraph numbers for use in prompts
+func numberedPassage(p *models.Passage) string {
+	var sb strings.Builder
+	if p.Title != "" {
+		sb.WriteString(p.Title + "\n\n")
+	}
+	for i, para := range p.Paragraphs {
+		fmt.Fprintf(&sb, "[%d] %s\n\n", i+1, para)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// passageInstructions returns the prompt section for reading-comprehension worksheets
+func passageInstructions(input models.WorksheetGeneratorInput) string {
+	if !wantsPassage(input) {
+		return ""
+	}
+
+	if strings.TrimSpace(input.Passage) != "" {
+		return fmt.Sprintf(`
+
+📖 READING PASSAGE (provided by the teacher, paragraphs are numbered):
+━━━━━━━━━━━━━━━━━━━━━━━━━━
+%s
+
+- Every question must be answerable from this passage
+- Set "paragraph_refs" on each question to the paragraph numbers it is based on, e.g. [2] or [1, 3]
+- Do NOT include a "passage" field in your output`, delimit("teacher_passage", numberedPassage(teacherPassage(input.Passage))))
+	}
+
+	return fmt.Sprintf(`
+
+📖 READING PASSAGE:
+━━━━━━━━━━━━━━━━━━━━━━━━━━
+- Write an original reading passage about the topic before the questions
+- Include it as "passage": {"title": "...", "paragraphs": ["...", "..."]} in the JSON object
+- Use 4-6 paragraphs written for a grade %s reader (Flesch-Kincaid grade level within %.0f of %s)
+- Every question must be answerable from the passage
+- Set "paragraph_refs" on each question to the 1-based paragraph numbers it is based on, e.g. [2] or [1, 3]`,
+		input.GradeLevel, readability.GradeTolerance, input.GradeLevel)
+}
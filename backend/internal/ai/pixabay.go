@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+type pixabayHit struct {
+	PageURL      string `json:"pageURL"`
+	Tags         string `json:"tags"`
+	WebformatURL string `json:"webformatURL"`
+	User         string `json:"user"`
+	UserID       int    `json:"user_id"`
+}
+
+type pixabaySearchResponse struct {
+	Total int          `json:"total"`
+	Hits  []pixabayHit `json:"hits"`
+}
+
+// PixabayProvider searches Pixabay photos with safe search on
+type PixabayProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func NewPixabayProvider(apiKey string, client *http.Client) *PixabayProvider {
+	return &PixabayProvider{apiKey: apiKey, baseURL: "https://pixabay.com/api/", client: client}
+}
+
+func (p *PixabayProvider) Name() string { return "pixabay" }
+
+// Search returns up to ten horizontal photos for the query
+func (p *PixabayProvider) Search(ctx context.Context, query string) ([]ImageResult, error) {
+	params := url.Values{
+		"key":         {p.apiKey},
+		"q":           {query},
+		"image_type":  {"photo"},
+		"orientation": {"horizontal"},
+		"safesearch":  {"true"},
+		"per_page":    {"10"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Pixabay API error: status %d", resp.StatusCode)
+	}
+
+	var searchResp pixabaySearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, err
+	}
+
+	results := make([]ImageResult, 0, len(searchResp.Hits))
+	for _, hit := range searchResp.Hits {
+		results = append(results, ImageResult{
+			URL:         hit.WebformatURL,
+			Description: hit.Tags,
+			Credit: &models.ImageCredit{
+				Provider:  "Pixabay",
+				Author:    hit.User,
+				AuthorURL: fmt.Sprintf("https://pixabay.com/users/%s-%d/", url.PathEscape(hit.User), hit.UserID),
+				SourceURL: hit.PageURL,
+			},
+		})
+	}
+	return results, nil
+}
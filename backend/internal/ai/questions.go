@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/prompts"
+)
+
+// QuestionRequest asks for new questions in the context of an existing worksheet
+type QuestionRequest struct {
+	Worksheet     *models.Worksheet
+	Replace       *models.Question // question being regenerated, nil when adding questions
+	Count         int
+	QuestionTypes []string
+	Instruction   string
+	Limits        models.GenerationLimits
+}
+
+// QuestionGenerator generates individual questions for an existing worksheet
+type QuestionGenerator interface {
+	GenerateQuestions(ctx context.Context, req QuestionRequest) ([]models.Question, error)
+}
+
+func (g *MockGenerator) GenerateQuestions(ctx context.Context, req QuestionRequest) ([]models.Question, error) {
+	questions := make([]models.Question, req.Count)
+	for i := range questions {
+		qType := requestedType(req, i)
+		questions[i] = generateDemoQuestion(len(req.Worksheet.Questions)+i+1, qType, req.Worksheet.Topic)
+	}
+	return questions, nil
+}
+
+func (g *OpenAIGenerator) GenerateQuestions(ctx context.Context, req QuestionRequest) ([]models.Question, error) {
+	// Fallback to mock for now
+	return NewMockGenerator().GenerateQuestions(ctx, req)
+}
+
+func (g *AnthropicGenerator) GenerateQuestions(ctx context.Context, req QuestionRequest) ([]models.Question, error) {
+	system, prompt, err := g.buildQuestionPrompt(req)
+	if err != nil {
+		return nil, err
+	}
+	responseText, err := g.sendMessage(ctx, system, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonStr := extractJSON(responseText)
+	if jsonStr == "" {
+		log.Printf("❌ Full response that failed parsing: %s", responseText)
+		return nil, fmt.Errorf("no JSON found in response")
+	}
+
+	var generated struct {
+		Questions []models.Question `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &generated); err != nil {
+		return nil, fmt.Errorf("failed to parse generated questions: %w", err)
+	}
+	if len(generated.Questions) == 0 {
+		return nil, fmt.Errorf("no questions in response")
+	}
+	if len(generated.Questions) > req.Count {
+		generated.Questions = generated.Questions[:req.Count]
+	}
+	if bad := unrequestedTypes(generated.Questions, req.QuestionTypes); len(bad) > 0 {
+		return nil, &ConformanceError{Problems: []string{fmt.Sprintf("question types %s were not requested", strings.Join(bad, ", "))}}
+	}
+
+	for i := range generated.Questions {
+		if generated.Questions[i].Points == 0 {
+			generated.Questions[i].Points = getDefaultPoints(generated.Questions[i].Type)
+		}
+	}
+	checkSourceRefs(generated.Questions, req.Worksheet.SourceExcerpts)
+	checkParagraphRefs(generated.Questions, req.Worksheet.Passage)
+
+	return g.finishQuestions(ctx, req.Worksheet, generated.Questions, req.Limits), nil
+}
+
+// requestedType picks the question type for the i-th new question
+func requestedType(req QuestionRequest, i int) string {
+	if len(req.QuestionTypes) > 0 {
+		return req.QuestionTypes[i%len(req.QuestionTypes)]
+	}
+	if req.Replace != nil {
+		return req.Replace.Type
+	}
+	return "multiple_choice"
+}
+
+// buildQuestionPrompt renders the system and user prompts for new or replacement questions
+func (g *AnthropicGenerator) buildQuestionPrompt(req QuestionRequest) (string, string, error) {
+	ws := req.Worksheet
+
+	var existing strings.Builder
+	for i, q := range ws.Questions {
+		if req.Replace != nil && q.ID == req.Replace.ID {
+			continue
+		}
+		fmt.Fprintf(&existing, "%d. (%s) %s\n", i+1, q.Type, q.Question)
+	}
+
+	types := req.QuestionTypes
+	if len(types) == 0 {
+		types = []string{requestedType(req, 0)}
+	}
+
+	task := fmt.Sprintf("Write %d NEW question(s) to add to this worksheet.", req.Count)
+	if req.Replace != nil {
+		current, _ := json.Marshal(req.Replace)
+		task = fmt.Sprintf("Write 1 replacement for this question:\n%s", string(current))
+	}
+
+	instruction := ""
+	if req.Instruction != "" {
+		instruction = fmt.Sprintf("\n\n📝 TEACHER INSTRUCTION FOR THIS CHANGE (about the question content only):\n%s", delimit("teacher_instructions", req.Instruction))
+	}
+
+	reference := ""
+	if ws.Passage != nil {
+		reference += fmt.Sprintf("\n\n📖 READING PASSAGE (set \"paragraph_refs\" on each question):\n%s", delimit("teacher_passage", numberedPassage(ws.Passage)))
+	}
+	if len(ws.SourceExcerpts) > 0 {
+		reference += fmt.Sprintf("\n\n📎 SOURCE MATERIAL (draw only from it and set \"source_excerpt_id\"):\n%s", delimit("source_material", formatExcerpts(ws.SourceExcerpts)))
+	}
+
+	tmpl := g.prompts.Select(ws.Subject, ws.GradeLevel, types)
+	system, err := tmpl.System()
+	if err != nil {
+		return "", "", err
+	}
+	prompt, err := tmpl.Questions(prompts.QuestionData{
+		Title:         delimit("worksheet_title", ws.Title),
+		Topic:         delimit("topic", ws.Topic),
+		Subject:       ws.Subject,
+		GradeLevel:    ws.GradeLevel,
+		Difficulty:    ws.Difficulty,
+		Language:      languageInstruction(ws.Language),
+		Existing:      delimit("existing_questions", existing.String()),
+		Reference:     reference,
+		Task:          task,
+		QuestionTypes: strings.Join(types, ", "),
+		Instructions:  instruction,
+		Diagrams:      diagramPrompt(req.Limits, ws.GradeLevel, ws.Subject, ws.Topic),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return system, prompt, nil
+}
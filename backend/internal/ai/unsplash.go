@@ -1,28 +1,36 @@
 package ai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
-	"time"
+
+	"github.com/makosai/backend/internal/models"
 )
 
 type UnsplashImage struct {
-	ID          string `json:"id"`
-	Description string `json:"description"`
-	URLs        struct {
+	ID             string `json:"id"`
+	Description    string `json:"description"`
+	AltDescription string `json:"alt_description"`
+	URLs           struct {
 		Raw     string `json:"raw"`
 		Full    string `json:"full"`
 		Regular string `json:"regular"`
 		Small   string `json:"small"`
 		Thumb   string `json:"thumb"`
 	} `json:"urls"`
+	Links struct {
+		HTML             string `json:"html"`
+		DownloadLocation string `json:"download_location"`
+	} `json:"links"`
 	User struct {
-		Name string `json:"name"`
+		Name  string `json:"name"`
+		Links struct {
+			HTML string `json:"html"`
+		} `json:"links"`
 	} `json:"user"`
 }
 
@@ -32,111 +40,77 @@ type UnsplashSearchResponse struct {
 	Results    []UnsplashImage `json:"results"`
 }
 
-// SearchUnsplashImage searches for an image on Unsplash and returns a random URL from results
-func SearchUnsplashImage(query string) (string, error) {
-	accessKey := os.Getenv("UNSPLASH_ACCESS_KEY")
-	if accessKey == "" {
-		return "", fmt.Errorf("UNSPLASH_ACCESS_KEY not set")
-	}
-
-	// Build the search URL - get more results for variety
-	searchURL := fmt.Sprintf(
-		"https://api.unsplash.com/search/photos?query=%s&per_page=10&orientation=landscape",
-		url.QueryEscape(query),
-	)
+// unsplashReferral is appended to Unsplash links as its API guidelines require
+const unsplashReferral = "?utm_source=makosai&utm_medium=referral"
 
-	req, err := http.NewRequest("GET", searchURL, nil)
-	if err != nil {
-		return "", err
-	}
+// UnsplashProvider searches Unsplash photos
+type UnsplashProvider struct {
+	accessKey string
+	baseURL   string
+	client    *http.Client
+}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Client-ID %s", accessKey))
+func NewUnsplashProvider(accessKey string, client *http.Client) *UnsplashProvider {
+	return &UnsplashProvider{accessKey: accessKey, baseURL: "https://api.unsplash.com", client: client}
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+func (p *UnsplashProvider) Name() string { return "unsplash" }
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Unsplash API error: status %d", resp.StatusCode)
-	}
+// Search returns up to ten landscape photos for the query
+func (p *UnsplashProvider) Search(ctx context.Context, query string) ([]ImageResult, error) {
+	searchURL := fmt.Sprintf("%s/search/photos?query=%s&per_page=10&orientation=landscape&content_filter=high",
+		p.baseURL, url.QueryEscape(query))
 
 	var searchResp UnsplashSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
-		return "", err
+	if err := p.get(ctx, searchURL, &searchResp); err != nil {
+		return nil, err
 	}
 
-	if len(searchResp.Results) == 0 {
-		return "", fmt.Errorf("no images found for query: %s", query)
+	results := make([]ImageResult, 0, len(searchResp.Results))
+	for _, img := range searchResp.Results {
+		results = append(results, ImageResult{
+			URL:         img.URLs.Small,
+			Description: strings.TrimSpace(img.Description + " " + img.AltDescription),
+			Credit: &models.ImageCredit{
+				Provider:  "Unsplash",
+				Author:    img.User.Name,
+				AuthorURL: img.User.Links.HTML + unsplashReferral,
+				SourceURL: img.Links.HTML + unsplashReferral,
+			},
+			TrackURL: img.Links.DownloadLocation,
+		})
 	}
-
-	// Return a random image from results for variety
-	rand.Seed(time.Now().UnixNano())
-	randomIndex := rand.Intn(len(searchResp.Results))
-	return searchResp.Results[randomIndex].URLs.Small, nil
+	return results, nil
 }
 
-// extractKeywords extracts meaningful keywords from question text
-func extractKeywords(text string) []string {
-	// Common words to ignore
-	stopWords := map[string]bool{
-		"the": true, "a": true, "an": true, "is": true, "are": true,
-		"what": true, "which": true, "how": true, "many": true, "much": true,
-		"does": true, "do": true, "has": true, "have": true, "can": true,
-		"will": true, "would": true, "should": true, "could": true,
-		"this": true, "that": true, "these": true, "those": true,
-		"and": true, "or": true, "but": true, "of": true, "to": true,
-		"in": true, "on": true, "at": true, "for": true, "with": true,
-		"from": true, "by": true, "about": true, "into": true,
-		"says": true, "give": true, "gives": true, "us": true, "we": true,
+// TrackUse tells Unsplash a photo was used, which its API guidelines require
+// whenever a photo is inserted into content
+func (p *UnsplashProvider) TrackUse(ctx context.Context, result ImageResult) error {
+	if result.TrackURL == "" {
+		return nil
 	}
+	return p.get(ctx, result.TrackURL, nil)
+}
 
-	words := strings.Fields(strings.ToLower(text))
-	var keywords []string
-
-	for _, word := range words {
-		// Clean punctuation
-		word = strings.Trim(word, ".,?!:;\"'()[]")
-		if len(word) > 2 && !stopWords[word] {
-			keywords = append(keywords, word)
-		}
+func (p *UnsplashProvider) get(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Authorization", fmt.Sprintf("Client-ID %s", p.accessKey))
+	req.Header.Set("Accept-Version", "v1")
 
-	// Return first 3 keywords max
-	if len(keywords) > 3 {
-		return keywords[:3]
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
 	}
-	return keywords
-}
-
-// GetImageForQuestion gets an appropriate image for a kindergarten question
-func GetImageForQuestion(topic string, questionText string) string {
-	// Extract keywords from question text for more specific search
-	keywords := extractKeywords(questionText)
+	defer resp.Body.Close()
 
-	var searchQuery string
-	if len(keywords) > 0 {
-		// Use question keywords for specific image
-		searchQuery = strings.Join(keywords, " ") + " kids colorful"
-	} else {
-		// Fallback to topic
-		searchQuery = topic + " kids education"
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unsplash API error: status %d", resp.StatusCode)
 	}
-
-	imageURL, err := SearchUnsplashImage(searchQuery)
-	if err != nil {
-		// Fallback to topic-based search
-		imageURL, err = SearchUnsplashImage(topic + " children")
-		if err != nil {
-			// Final fallback
-			imageURL, err = SearchUnsplashImage("kids learning colorful")
-			if err != nil {
-				return ""
-			}
-		}
+	if out == nil {
+		return nil
 	}
-	return imageURL
+	return json.NewDecoder(resp.Body).Decode(out)
 }
-
@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// ModelPrice is a model's list price in USD per million tokens
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// modelPrices is matched by prefix so dated snapshots share their family's price
+var modelPrices = []struct {
+	prefix string
+	price  ModelPrice
+}{
+	{"claude-opus-4", ModelPrice{Input: 15, Output: 75}},
+	{"claude-sonnet-4", ModelPrice{Input: 3, Output: 15}},
+	{"claude-3-7-sonnet", ModelPrice{Input: 3, Output: 15}},
+	{"claude-haiku-4", ModelPrice{Input: 1, Output: 5}},
+	{"claude-3-5-haiku", ModelPrice{Input: 0.8, Output: 4}},
+}
+
+// PriceFor returns the price of a model, or false when it isn't in the table
+func PriceFor(model string) (ModelPrice, bool) {
+	for _, p := range modelPrices {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// Cost prices a call's tokens; unknown models cost 0 so usage is still counted
+func Cost(model string, inputTokens, outputTokens int) float64 {
+	price, ok := PriceFor(model)
+	if !ok {
+		warnUnpriced(model)
+		return 0
+	}
+	return (float64(inputTokens)*price.Input + float64(outputTokens)*price.Output) / 1e6
+}
+
+var unpriced sync.Map
+
+func warnUnpriced(model string) {
+	if _, seen := unpriced.LoadOrStore(model, true); !seen {
+		log.Printf("⚠️ No price for model %q, its usage is recorded at $0", model)
+	}
+}
+
+// UsageMeter adds up the tokens of every model call made with its context,
+// so a handler can see what one request cost across generation and verification
+type UsageMeter struct {
+	mu    sync.Mutex
+	usage models.Usage
+	model string
+}
+
+type meterKey struct{}
+
+// WithUsageMeter returns a context whose model calls are counted by the returned meter
+func WithUsageMeter(ctx context.Context) (context.Context, *UsageMeter) {
+	m := &UsageMeter{}
+	return context.WithValue(ctx, meterKey{}, m), m
+}
+
+// Usage returns the totals so far
+func (m *UsageMeter) Usage() models.Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// Model returns the model of the most recent call
+func (m *UsageMeter) Model() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.model
+}
+
+// recordUsage counts a call against the context's meter, if it has one
+func recordUsage(ctx context.Context, model string, inputTokens, outputTokens int) {
+	cost := Cost(model, inputTokens, outputTokens)
+	log.Printf("💰 %s: %d input + %d output tokens ($%.4f)", model, inputTokens, outputTokens, cost)
+
+	m, ok := ctx.Value(meterKey{}).(*UsageMeter)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.Add(models.Usage{Calls: 1, InputTokens: inputTokens, OutputTokens: outputTokens, CostUSD: cost})
+	m.model = model
+}
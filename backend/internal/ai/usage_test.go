@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		model         string
+		input, output int
+		want          float64
+	}{
+		{"claude-sonnet-4-20250514", 1_000_000, 1_000_000, 18},
+		{"claude-opus-4-1", 2000, 1000, 0.105},
+		{"claude-3-5-haiku-latest", 1_000_000, 0, 0.8},
+		{"claude-haiku-4-5", 0, 1_000_000, 5},
+		{"some-other-model", 1_000_000, 1_000_000, 0},
+	}
+	for _, tt := range tests {
+		if got := Cost(tt.model, tt.input, tt.output); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Cost(%s, %d, %d) = %v, want %v", tt.model, tt.input, tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestUsageMeter(t *testing.T) {
+	// Calls without a meter are only logged
+	recordUsage(context.Background(), "claude-sonnet-4-20250514", 10, 10)
+
+	ctx, meter := WithUsageMeter(context.Background())
+	recordUsage(ctx, "claude-sonnet-4-20250514", 1000, 200)
+	recordUsage(ctx, "claude-haiku-4-5", 500, 100)
+
+	got := meter.Usage()
+	want := models.Usage{Calls: 2, InputTokens: 1500, OutputTokens: 300, CostUSD: 0.003 + 0.003 + 0.0005 + 0.0005}
+	if got.Calls != want.Calls || got.InputTokens != want.InputTokens || got.OutputTokens != want.OutputTokens || math.Abs(got.CostUSD-want.CostUSD) > 1e-9 {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+	if meter.Model() != "claude-haiku-4-5" {
+		t.Errorf("model = %q, want the last call's", meter.Model())
+	}
+}
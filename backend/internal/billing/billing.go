@@ -0,0 +1,319 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/store"
+)
+
+// Event types that change a user's plan or billing state
+const (
+	SubscriptionCreated = "customer.subscription.created"
+	SubscriptionUpdated = "customer.subscription.updated"
+	SubscriptionDeleted = "customer.subscription.deleted"
+	PaymentFailed       = "invoice.payment_failed"
+)
+
+// Outcome says what Handle did with an event
+type Outcome string
+
+const (
+	Applied   Outcome = "applied"
+	Duplicate Outcome = "duplicate" // the event ID was handled before
+	Stale     Outcome = "stale"     // a newer event for the user was already applied
+	Ignored   Outcome = "ignored"   // an event type we don't handle, or no matching user
+)
+
+// ErrInvalidEvent is returned for payloads that will never apply, however often they're sent
+var ErrInvalidEvent = errors.New("invalid event")
+
+// errSkip leaves a user unchanged when an event turns out not to apply
+var errSkip = errors.New("skip event")
+
+// Event is the envelope of every webhook
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Subscription is the part of a subscription object we read
+type Subscription struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Status            string            `json:"status"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	Metadata          map[string]string `json:"metadata"`
+	Items             struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            Price `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// Price is the part of a price object we read
+type Price struct {
+	ID        string            `json:"id"`
+	LookupKey string            `json:"lookup_key"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// Invoice is the part of an invoice object we read
+type Invoice struct {
+	ID                  string `json:"id"`
+	Customer            string `json:"customer"`
+	CustomerEmail       string `json:"customer_email"`
+	Subscription        string `json:"subscription"`
+	SubscriptionDetails struct {
+		Metadata map[string]string `json:"metadata"`
+	} `json:"subscription_details"`
+}
+
+// Processor applies webhook events to users. Each event ID is applied at
+// most once, and events older than the last one applied to a user are skipped,
+// so redelivered and out-of-order webhooks are harmless.
+type Processor struct {
+	users      store.UserStore
+	events     store.CounterStore
+	plans      map[string]quota.Plan
+	pricePlans map[string]string
+}
+
+// NewProcessor creates a processor. pricePlans maps price IDs or lookup keys to
+// plan IDs, for prices that don't name their plan in metadata.
+func NewProcessor(users store.UserStore, events store.CounterStore, plans map[string]quota.Plan, pricePlans map[string]string) *Processor {
+	return &Processor{users: users, events: events, plans: plans, pricePlans: pricePlans}
+}
+
+// ParsePricePlans reads "price_123=starter,pro_monthly=pro" into a map
+func ParsePricePlans(s string) (map[string]string, error) {
+	plans := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		price, plan, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(price) == "" || strings.TrimSpace(plan) == "" {
+			return nil, fmt.Errorf("invalid price mapping %q, want price=plan", pair)
+		}
+		plans[strings.TrimSpace(price)] = strings.TrimSpace(plan)
+	}
+	return plans, nil
+}
+
+// Handle parses and applies a verified webhook payload. Events that fail are
+// not marked as handled, so the provider's redelivery can apply them.
+func (p *Processor) Handle(payload []byte) (Outcome, error) {
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if ev.ID == "" || ev.Type == "" {
+		return "", fmt.Errorf("%w: id and type are required", ErrInvalidEvent)
+	}
+
+	switch ev.Type {
+	case SubscriptionCreated, SubscriptionUpdated, SubscriptionDeleted, PaymentFailed:
+	default:
+		return Ignored, nil
+	}
+
+	// Claim the event ID first so concurrent redeliveries can't both apply it
+	key := "billing-event:" + ev.ID
+	if _, first := p.events.Increment(key, 1); !first {
+		return Duplicate, nil
+	}
+	var outcome Outcome
+	var err error
+	if ev.Type == PaymentFailed {
+		outcome, err = p.paymentFailed(ev)
+	} else {
+		outcome, err = p.subscriptionChanged(ev)
+	}
+	if err != nil || outcome == Ignored {
+		// Let a redelivery try again, e.g. once the user has signed up
+		p.events.Decrement(key)
+	}
+	if err != nil {
+		return "", err
+	}
+	log.Printf("💳 %s %s: %s", ev.Type, ev.ID, outcome)
+	return outcome, nil
+}
+
+func (p *Processor) subscriptionChanged(ev Event) (Outcome, error) {
+	var sub Subscription
+	if err := json.Unmarshal(ev.Data.Object, &sub); err != nil {
+		return "", fmt.Errorf("%w: subscription: %v", ErrInvalidEvent, err)
+	}
+	user := p.findUser(sub.Metadata["user_id"], sub.Customer, "")
+	if user == nil {
+		log.Printf("⚠️ No user for subscription %s (customer %s)", sub.ID, sub.Customer)
+		return Ignored, nil
+	}
+	at := time.Unix(ev.Created, 0).UTC()
+
+	// Apply under the store's lock so concurrent edits of other fields, like a
+	// password reset, aren't lost
+	outcome := Applied
+	_, err := p.users.Update(user.ID, func(updated *models.User) error {
+		if updated.Billing != nil && at.Before(updated.Billing.LastEventAt) {
+			outcome = Stale
+			return errSkip
+		}
+		// Ending an old subscription mustn't downgrade a user who has since started a new one
+		if current := updated.Billing; current != nil && current.SubscriptionID != "" && current.SubscriptionID != sub.ID &&
+			isLive(current.Status) && (ev.Type == SubscriptionDeleted || !isLive(sub.Status)) {
+			log.Printf("⚠️ Ignoring %s for %s, which replaced it with %s", ev.Type, sub.ID, current.SubscriptionID)
+			outcome = Ignored
+			return errSkip
+		}
+
+		billing := models.Billing{}
+		if updated.Billing != nil {
+			billing = *updated.Billing
+		}
+		billing.CustomerID = sub.Customer
+		billing.SubscriptionID = sub.ID
+		billing.Status = sub.Status
+		billing.CancelAtPeriodEnd = sub.CancelAtPeriodEnd
+		billing.LastEventAt = at
+		if end := periodEnd(sub); end > 0 {
+			t := time.Unix(end, 0).UTC()
+			billing.CurrentPeriodEnd = &t
+		}
+		if ev.Type == SubscriptionDeleted {
+			billing.Status = "canceled"
+		}
+
+		switch billing.Status {
+		case "active", "trialing":
+			billing.PaymentFailedAt = nil
+			fallthrough
+		case "past_due":
+			// Keep the plan while the provider retries a failed payment
+			if plan := p.planFor(sub); plan != "" {
+				updated.Plan = plan
+			} else {
+				log.Printf("⚠️ Subscription %s has no known plan, keeping %s on %q", sub.ID, updated.ID, updated.Plan)
+			}
+		case "canceled", "unpaid", "incomplete_expired":
+			updated.Plan = quota.FreePlan
+		}
+		updated.Billing = &billing
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSkip) {
+		return "", err
+	}
+	return outcome, nil
+}
+
+func (p *Processor) paymentFailed(ev Event) (Outcome, error) {
+	var inv Invoice
+	if err := json.Unmarshal(ev.Data.Object, &inv); err != nil {
+		return "", fmt.Errorf("%w: invoice: %v", ErrInvalidEvent, err)
+	}
+	user := p.findUser(inv.SubscriptionDetails.Metadata["user_id"], inv.Customer, inv.CustomerEmail)
+	if user == nil {
+		log.Printf("⚠️ No user for failed invoice %s (customer %s)", inv.ID, inv.Customer)
+		return Ignored, nil
+	}
+	at := time.Unix(ev.Created, 0).UTC()
+
+	outcome := Applied
+	_, err := p.users.Update(user.ID, func(updated *models.User) error {
+		if updated.Billing != nil && at.Before(updated.Billing.LastEventAt) {
+			outcome = Stale
+			return errSkip
+		}
+		billing := models.Billing{}
+		if updated.Billing != nil {
+			billing = *updated.Billing
+		}
+		billing.CustomerID = inv.Customer
+		if inv.Subscription != "" {
+			billing.SubscriptionID = inv.Subscription
+		}
+		billing.Status = "past_due"
+		billing.PaymentFailedAt = &at
+		billing.LastEventAt = at
+		updated.Billing = &billing
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSkip) {
+		return "", err
+	}
+	return outcome, nil
+}
+
+// findUser matches the user ID set in checkout metadata, then the customer ID
+// from an earlier event, then the customer's email
+func (p *Processor) findUser(userID, customerID, email string) *models.User {
+	if userID != "" {
+		if user, ok := p.users.Get(userID); ok {
+			return user
+		}
+	}
+	if customerID != "" {
+		if user, ok := p.users.GetByBillingCustomer(customerID); ok {
+			return user
+		}
+	}
+	if email != "" {
+		if user, ok := p.users.GetByEmail(email); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// planFor names the subscription's plan from its metadata, its price's
+// metadata, the configured price mapping or the price's lookup key
+func (p *Processor) planFor(sub Subscription) string {
+	candidates := []string{sub.Metadata["plan"]}
+	for _, item := range sub.Items.Data {
+		candidates = append(candidates,
+			item.Price.Metadata["plan"],
+			p.pricePlans[item.Price.ID],
+			p.pricePlans[item.Price.LookupKey],
+			item.Price.LookupKey)
+	}
+	for _, plan := range candidates {
+		if _, ok := p.plans[plan]; ok && plan != "" {
+			return plan
+		}
+	}
+	return ""
+}
+
+// isLive reports whether a subscription status still grants its plan
+func isLive(status string) bool {
+	return status == "active" || status == "trialing" || status == "past_due"
+}
+
+// periodEnd reads the period end from the subscription, or from its first
+// item in API versions that moved it there
+func periodEnd(sub Subscription) int64 {
+	if sub.CurrentPeriodEnd > 0 {
+		return sub.CurrentPeriodEnd
+	}
+	for _, item := range sub.Items.Data {
+		if item.CurrentPeriodEnd > 0 {
+			return item.CurrentPeriodEnd
+		}
+	}
+	return 0
+}
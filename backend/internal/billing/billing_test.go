@@ -0,0 +1,179 @@
+package billing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/store"
+)
+
+// fixture reads a recorded webhook payload from testdata
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+type billingTest struct {
+	t         *testing.T
+	users     *store.MemoryUserStore
+	events    *store.MemoryCounterStore
+	processor *Processor
+}
+
+func newBillingTest(t *testing.T, withUser bool) *billingTest {
+	t.Helper()
+	b := &billingTest{t: t, users: store.NewMemoryUserStore(), events: store.NewMemoryCounterStore()}
+	if withUser {
+		b.addUser()
+	}
+	b.processor = NewProcessor(b.users, b.events, quota.DefaultPlans, nil)
+	return b
+}
+
+func (b *billingTest) addUser() {
+	b.t.Helper()
+	if err := b.users.Create(&models.User{ID: "user_demo", Email: "teacher@example.com", Plan: quota.FreePlan}); err != nil {
+		b.t.Fatal(err)
+	}
+}
+
+// handle applies a fixture and checks the outcome
+func (b *billingTest) handle(name string, want Outcome) {
+	b.t.Helper()
+	outcome, err := b.processor.Handle(fixture(b.t, name))
+	if err != nil {
+		b.t.Fatalf("%s: %v", name, err)
+	}
+	if outcome != want {
+		b.t.Fatalf("%s: outcome %s, want %s", name, outcome, want)
+	}
+}
+
+func (b *billingTest) user() *models.User {
+	b.t.Helper()
+	user, ok := b.users.Get("user_demo")
+	if !ok {
+		b.t.Fatal("user_demo missing")
+	}
+	return user
+}
+
+func TestHandleLifecycle(t *testing.T) {
+	b := newBillingTest(t, true)
+
+	b.handle("subscription_created", Applied)
+	u := b.user()
+	if u.Plan != "starter" || u.Billing == nil || u.Billing.CustomerID != "cus_QxDemo0001" || u.Billing.Status != "active" {
+		t.Fatalf("after created: plan %s, billing %+v", u.Plan, u.Billing)
+	}
+	if u.Billing.CurrentPeriodEnd == nil || u.Billing.CurrentPeriodEnd.Unix() != 1728592000 {
+		t.Errorf("period end = %v", u.Billing.CurrentPeriodEnd)
+	}
+
+	b.handle("subscription_updated", Applied)
+	if u := b.user(); u.Plan != "pro" {
+		t.Fatalf("after upgrade: plan %s", u.Plan)
+	}
+
+	// A failed payment keeps the plan while the provider retries
+	b.handle("invoice_payment_failed", Applied)
+	u = b.user()
+	if u.Plan != "pro" || u.Billing.Status != "past_due" || u.Billing.PaymentFailedAt == nil {
+		t.Fatalf("after failed payment: plan %s, billing %+v", u.Plan, u.Billing)
+	}
+
+	b.handle("subscription_deleted", Applied)
+	u = b.user()
+	if u.Plan != quota.FreePlan || u.Billing.Status != "canceled" {
+		t.Fatalf("after deletion: plan %s, billing %+v", u.Plan, u.Billing)
+	}
+}
+
+func TestHandleDuplicate(t *testing.T) {
+	b := newBillingTest(t, true)
+	b.handle("subscription_created", Applied)
+
+	// A manual change after the event must survive its redelivery
+	if _, err := b.users.Update("user_demo", func(u *models.User) error { u.Plan = "ultra"; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	b.handle("subscription_created", Duplicate)
+	if u := b.user(); u.Plan != "ultra" {
+		t.Errorf("redelivery changed the plan to %s", u.Plan)
+	}
+}
+
+func TestHandleStale(t *testing.T) {
+	b := newBillingTest(t, true)
+	b.handle("subscription_updated", Applied)
+	b.handle("subscription_created", Stale)
+	if u := b.user(); u.Plan != "pro" || u.Billing.LastEventAt.Unix() != 1726100000 {
+		t.Errorf("older event was applied: plan %s, last event %v", u.Plan, u.Billing.LastEventAt)
+	}
+	b.handle("invoice_payment_failed", Applied)
+	b.handle("subscription_updated", Duplicate)
+}
+
+func TestHandleUnknownUserIsRetried(t *testing.T) {
+	b := newBillingTest(t, false)
+	b.handle("subscription_created", Ignored)
+
+	// The claim was released, so the redelivery after sign-up applies
+	b.addUser()
+	b.handle("subscription_created", Applied)
+	if u := b.user(); u.Plan != "starter" {
+		t.Errorf("plan = %s", u.Plan)
+	}
+}
+
+// failingUsers refuses every update
+type failingUsers struct {
+	*store.MemoryUserStore
+}
+
+func (failingUsers) Update(string, func(*models.User) error) (*models.User, error) {
+	return nil, errors.New("database down")
+}
+
+func TestHandleErrorIsRetried(t *testing.T) {
+	b := newBillingTest(t, true)
+	failing := NewProcessor(failingUsers{b.users}, b.events, quota.DefaultPlans, nil)
+	if _, err := failing.Handle(fixture(t, "subscription_created")); err == nil {
+		t.Fatal("store failure was not reported")
+	}
+	if n := b.events.Get("billing-event:evt_1PzSubCreated0001"); n != 0 {
+		t.Fatalf("failed event is still claimed (%d)", n)
+	}
+	b.handle("subscription_created", Applied)
+}
+
+func TestHandleInvalid(t *testing.T) {
+	b := newBillingTest(t, true)
+	for _, payload := range []string{`not json`, `{"type":"customer.subscription.created"}`, `{"id":"evt_1"}`} {
+		if _, err := b.processor.Handle([]byte(payload)); !errors.Is(err, ErrInvalidEvent) {
+			t.Errorf("Handle(%s) error = %v, want ErrInvalidEvent", payload, err)
+		}
+	}
+	outcome, err := b.processor.Handle([]byte(`{"id":"evt_2","type":"charge.refunded","data":{"object":{}}}`))
+	if err != nil || outcome != Ignored {
+		t.Errorf("unhandled type: %s, %v", outcome, err)
+	}
+}
+
+func TestParsePricePlans(t *testing.T) {
+	plans, err := ParsePricePlans(" price_123=starter, pro_monthly = pro ,")
+	if err != nil || plans["price_123"] != "starter" || plans["pro_monthly"] != "pro" || len(plans) != 2 {
+		t.Errorf("ParsePricePlans = %v, %v", plans, err)
+	}
+	if _, err := ParsePricePlans("price_123"); err == nil {
+		t.Error("accepted a mapping without a plan")
+	}
+}
@@ -0,0 +1,79 @@
+// Package billing applies signed subscription webhooks from a
+// Stripe-compatible payment provider to users' plans and billing state.
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the webhook signature: "t=<unix time>,v1=<hex HMAC-SHA256>"
+const SignatureHeader = "Stripe-Signature"
+
+// DefaultTolerance is how old a signature may be before it is treated as a replay
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrNoSignature    = errors.New("no v1 signature in header")
+	ErrBadSignature   = errors.New("signature does not match the payload")
+	ErrStaleSignature = errors.New("signature timestamp is outside the tolerance")
+)
+
+// Sign returns the signature header the provider would send for payload at t
+func Sign(payload []byte, secret string, t time.Time) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), computeSignature(payload, secret, t.Unix()))
+}
+
+// Verify checks the signature header against the payload. The signed text is
+// "<t>.<payload>"; any one v1 signature may match, so secrets can be rotated.
+func Verify(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	var timestamp int64 = -1
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid signature timestamp %q", value)
+			}
+			timestamp = t
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp < 0 || len(signatures) == 0 {
+		return ErrNoSignature
+	}
+
+	expected := computeSignature(payload, secret, timestamp)
+	matched := false
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			matched = true
+		}
+	}
+	if !matched {
+		return ErrBadSignature
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return ErrStaleSignature
+	}
+	return nil
+}
+
+func computeSignature(payload []byte, secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,54 @@
+package billing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// Computed independently: HMAC-SHA256("whsec_test", `1700000000.{"id":"evt_1"}`)
+	want := "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+	if got := Sign([]byte(`{"id":"evt_1"}`), "whsec_test", time.Unix(1700000000, 0)); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"customer.subscription.updated"}`)
+	now := time.Unix(1700000000, 0)
+	signed := Sign(payload, "whsec_test", now)
+	other := Sign(payload, "whsec_old", now)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  string
+		now     time.Time
+		want    error
+	}{
+		{name: "valid", payload: payload, header: signed, now: now},
+		{name: "within tolerance", payload: payload, header: signed, now: now.Add(DefaultTolerance)},
+		{name: "rotated secret", payload: payload, header: other + "," + signed[len("t=1700000000,"):], now: now},
+		{name: "tampered payload", payload: []byte(`{"id":"evt_2"}`), header: signed, now: now, want: ErrBadSignature},
+		{name: "wrong secret", payload: payload, header: other, now: now, want: ErrBadSignature},
+		{name: "replayed", payload: payload, header: signed, now: now.Add(DefaultTolerance + time.Second), want: ErrStaleSignature},
+		{name: "from the future", payload: payload, header: signed, now: now.Add(-DefaultTolerance - time.Second), want: ErrStaleSignature},
+		{name: "no timestamp", payload: payload, header: signed[len("t=1700000000,"):], now: now, want: ErrNoSignature},
+		{name: "no v1", payload: payload, header: "t=1700000000,v0=abc", now: now, want: ErrNoSignature},
+		{name: "empty", payload: payload, header: "", now: now, want: ErrNoSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.payload, tt.header, "whsec_test", DefaultTolerance, tt.now); err != tt.want {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := Verify(payload, "t=soon,v1=abc", "whsec_test", DefaultTolerance, now); err == nil {
+		t.Error("accepted a malformed timestamp")
+	}
+	if err := Verify(payload, signed, "whsec_test", 0, now.Add(24*time.Hour)); err != nil {
+		t.Errorf("zero tolerance should skip the age check: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+// Package clipart is a bundled library of simple SVG pictures for early-grade
+// worksheets, matched to questions by the nouns they mention.
+package clipart
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// Clip is one picture in the library
+type Clip struct {
+	Name string   `json:"name"`
+	File string   `json:"file"`
+	Tags []string `json:"tags"` // singular nouns the picture shows
+	SVG  string   `json:"-"`
+}
+
+var (
+	clips []Clip
+	byTag = map[string]*Clip{}
+)
+
+func init() {
+	data, err := assets.ReadFile("assets/index.json")
+	if err != nil {
+		panic(fmt.Sprintf("clipart: %v", err))
+	}
+	if err := json.Unmarshal(data, &clips); err != nil {
+		panic(fmt.Sprintf("clipart: index.json: %v", err))
+	}
+	for i := range clips {
+		svg, err := assets.ReadFile("assets/" + clips[i].File)
+		if err != nil {
+			panic(fmt.Sprintf("clipart: %v", err))
+		}
+		clips[i].SVG = string(svg)
+		for _, tag := range clips[i].Tags {
+			byTag[tag] = &clips[i]
+		}
+	}
+}
+
+// Match picks the clip for the noun the text mentions most often, preferring
+// the earliest on a tie, e.g. "Sam has 3 red apples and 1 banana" → apple
+func Match(text string) (Clip, bool) {
+	var best *Clip
+	bestCount := 0
+	counts := map[string]int{}
+	for _, noun := range words(text) {
+		counts[noun]++
+	}
+	for _, noun := range Nouns(text) {
+		clip, ok := byTag[noun]
+		if ok && counts[noun] > bestCount {
+			best, bestCount = clip, counts[noun]
+		}
+	}
+	if best == nil {
+		return Clip{}, false
+	}
+	return *best, true
+}
+
+// Nouns returns the likely nouns in text, singular and in order of first use.
+// It drops function words, question words, numbers, colors, sizes and the
+// instructions early-grade questions are phrased with ("count", "circle", ...).
+func Nouns(text string) []string {
+	seen := map[string]bool{}
+	var nouns []string
+	for _, w := range words(text) {
+		if !seen[w] {
+			seen[w] = true
+			nouns = append(nouns, w)
+		}
+	}
+	return nouns
+}
+
+// words splits text into lowercase singular candidate nouns, with repeats
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	var out []string
+	for _, f := range fields {
+		f = strings.TrimSuffix(strings.Trim(f, "'"), "'s")
+		if len(f) < 3 || notNouns[f] {
+			continue
+		}
+		f = singular(f)
+		if notNouns[f] {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+var irregular = map[string]string{
+	"mice": "mouse", "children": "child", "geese": "goose", "feet": "foot", "teeth": "tooth",
+	"people": "person", "leaves": "leaf", "knives": "knife", "wolves": "wolf", "loaves": "loaf",
+	"potatoes": "potato", "tomatoes": "tomato", "heroes": "hero", "women": "woman", "men": "man",
+}
+
+// singular turns a plural noun into its singular form with common English rules
+func singular(w string) string {
+	if s, ok := irregular[w]; ok {
+		return s
+	}
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		return w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "ches"), strings.HasSuffix(w, "shes"),
+		strings.HasSuffix(w, "xes"), strings.HasSuffix(w, "zzes"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"), strings.HasSuffix(w, "us"), strings.HasSuffix(w, "is"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+var notNouns = setOf(
+	// function and question words
+	"the", "and", "but", "for", "with", "from", "into", "onto", "about", "are", "was", "were", "has", "have",
+	"had", "does", "did", "can", "could", "will", "would", "should", "this", "that", "these", "those", "there",
+	"here", "what", "which", "who", "whose", "how", "when", "where", "why", "many", "much", "each", "every",
+	"some", "all", "any", "other", "than", "then", "them", "they", "their", "his", "her", "its", "our",
+	"your", "you", "she", "him", "not", "now", "one", "ones", "yes", "out", "off", "very", "too", "also",
+	// numbers
+	"two", "three", "four", "five", "six", "seven", "eight", "nine", "ten", "eleven", "twelve", "twenty",
+	"zero", "first", "second", "third", "last", "number", "numeral",
+	// instructions and question vocabulary
+	"count", "circle", "color", "colour", "draw", "match", "write", "find", "show", "look", "picture",
+	"pictures", "image", "point", "put", "make", "tell", "say", "says", "give", "gives", "get", "gets",
+	"more", "fewer", "less", "left", "total", "altogether", "together", "answer", "question", "word",
+	"same", "different", "add", "added", "take", "takes", "away", "eat", "eats", "ate", "see", "sees",
+	"saw", "buy", "buys", "bought", "need", "needs", "want", "wants", "like", "likes", "fly", "flies", "swim",
+	"run", "runs", "jump", "jumps", "sit", "sits", "still", "start", "starts", "end", "ends",
+	"kid", "kids", "child", "friend", "student", "teacher", "class", "mom", "dad", "sound", "letter",
+	"begin", "begins", "rhyme", "rhymes", "pattern", "next", "come", "comes", "goes", "went", "long",
+	// colors and sizes
+	"red", "blue", "green", "yellow", "purple", "pink", "brown", "black", "white", "gray",
+	"grey", "big", "bigger", "biggest", "small", "smaller", "smallest", "little", "large", "tall",
+	"short", "colorful", "happy", "sad", "new", "old", "hot", "cold",
+)
+
+func setOf(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,67 @@
+package clipart
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLibrary(t *testing.T) {
+	if len(clips) == 0 {
+		t.Fatal("no clips loaded")
+	}
+	for _, c := range clips {
+		if !strings.Contains(c.SVG, "<svg") {
+			t.Errorf("%s: %s is not an SVG", c.Name, c.File)
+		}
+		if len(c.Tags) == 0 {
+			t.Errorf("%s has no tags", c.Name)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // clip name, "" for no match
+	}{
+		{"Sam has 3 red apples and 1 banana. How many apples are left?", "apple"},
+		{"Sam has 3 red apples and 1 banana", "apple"},
+		{"A banana and an apple", "banana"},
+		{"Count the kittens", "cat"},
+		{"How many puppies are in the park?", "dog"},
+		{"How many leaves fell from the tree?", "leaf"},
+		{"Mia's umbrella keeps her dry", "umbrella"},
+		{"It is a rainy day", "umbrella"},
+		{"Circle the number that comes next: 2, 4, 6", ""},
+		{"What is 7 + 5?", ""},
+		{"Describe the causes of the French Revolution", ""},
+	}
+	for _, tt := range tests {
+		clip, ok := Match(tt.text)
+		if got := clip.Name; ok != (tt.want != "") || got != tt.want {
+			t.Errorf("Match(%q) = %q, %v, want %q", tt.text, got, ok, tt.want)
+		}
+		if ok && clip.SVG == "" {
+			t.Errorf("Match(%q) returned no SVG", tt.text)
+		}
+	}
+}
+
+func TestNouns(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Sam has 3 red apples and 1 banana.", []string{"sam", "apple", "banana"}},
+		{"How many mice and geese are there?", []string{"mouse", "goose"}},
+		{"Count the boxes, dishes and berries", []string{"box", "dish", "berry"}},
+		{"The bus stops by the glass house", []string{"bus", "stop", "glass", "house"}},
+		{"Circle the two big ones", nil},
+	}
+	for _, tt := range tests {
+		if got := Nouns(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Nouns(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,513 @@
+package diagram
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+const (
+	maxCategories    = 12
+	maxTableRows     = 15
+	maxPlotValues    = 40
+	maxHistogramBins = 12
+	maxSymbols       = 20
+
+	chartHeight = 260.0
+	chartLeft   = 48.0
+	chartTop    = 20.0
+	chartBottom = 48.0
+)
+
+var barColors = []string{strokeColor, accentColor, "#6366f1", "#db2777", "#65a30d", "#0284c7"}
+
+func renderChart(ch *models.Chart) (string, error) {
+	if err := validateChart(ch); err != nil {
+		return "", err
+	}
+	ch.Statistics = chartStatistics(chartValues(ch))
+
+	switch ch.Kind {
+	case models.ChartBar:
+		return renderBarChart(ch), nil
+	case models.ChartPictograph:
+		return renderPictograph(ch), nil
+	case models.ChartTable:
+		return renderTable(ch), nil
+	case models.ChartLinePlot:
+		return renderLinePlot(ch)
+	default:
+		return renderHistogram(ch)
+	}
+}
+
+func validateChart(ch *models.Chart) error {
+	switch ch.Kind {
+	case models.ChartBar, models.ChartPictograph, models.ChartTable:
+		limit := maxCategories
+		if ch.Kind == models.ChartTable {
+			limit = maxTableRows
+		}
+		if len(ch.Data) == 0 || len(ch.Data) > limit {
+			return fmt.Errorf("%s needs between 1 and %d data rows", ch.Kind, limit)
+		}
+		for _, d := range ch.Data {
+			if strings.TrimSpace(d.Label) == "" {
+				return fmt.Errorf("every %s data row needs a label", ch.Kind)
+			}
+			if math.IsNaN(d.Value) || math.IsInf(d.Value, 0) || (d.Value < 0 && ch.Kind != models.ChartTable) {
+				return fmt.Errorf("%s value for %q must be a non-negative number", ch.Kind, d.Label)
+			}
+		}
+	case models.ChartLinePlot, models.ChartHistogram:
+		if len(ch.Values) == 0 || len(ch.Values) > maxPlotValues {
+			return fmt.Errorf("%s needs between 1 and %d values", ch.Kind, maxPlotValues)
+		}
+		for _, v := range ch.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("%s values must be finite numbers", ch.Kind)
+			}
+		}
+	case "":
+		return fmt.Errorf("chart kind is required")
+	default:
+		return fmt.Errorf("unknown chart kind %q (use bar, pictograph, line_plot, histogram or table)", ch.Kind)
+	}
+
+	if ch.Kind == models.ChartPictograph {
+		if ch.PerSymbol == 0 {
+			ch.PerSymbol = 1
+		}
+		if ch.PerSymbol < 0 || math.IsNaN(ch.PerSymbol) || math.IsInf(ch.PerSymbol, 0) {
+			return fmt.Errorf("pictograph per_symbol must be positive")
+		}
+		for _, d := range ch.Data {
+			halves := d.Value / ch.PerSymbol * 2
+			if math.Abs(halves-math.Round(halves)) > 1e-9 {
+				return fmt.Errorf("pictograph value %s for %q is not a whole or half number of symbols worth %s",
+					measure(d.Value, ""), d.Label, measure(ch.PerSymbol, ""))
+			}
+			if d.Value/ch.PerSymbol > maxSymbols {
+				return fmt.Errorf("pictograph row %q needs more than %d symbols; increase per_symbol", d.Label, maxSymbols)
+			}
+		}
+	}
+	if ch.Kind == models.ChartHistogram {
+		if ch.BinWidth < 0 || math.IsNaN(ch.BinWidth) || math.IsInf(ch.BinWidth, 0) {
+			return fmt.Errorf("histogram bin_width must be positive")
+		}
+		if math.IsNaN(ch.BinStart) || math.IsInf(ch.BinStart, 0) {
+			return fmt.Errorf("histogram bin_start must be a finite number")
+		}
+	}
+	return nil
+}
+
+// chartValues returns the numbers a chart's statistics are computed from
+func chartValues(ch *models.Chart) []float64 {
+	if len(ch.Data) > 0 && ch.Kind != models.ChartLinePlot && ch.Kind != models.ChartHistogram {
+		values := make([]float64, len(ch.Data))
+		for i, d := range ch.Data {
+			values[i] = d.Value
+		}
+		return values
+	}
+	return append([]float64(nil), ch.Values...)
+}
+
+func chartStatistics(values []float64) *models.ChartStatistics {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	s := &models.ChartStatistics{Count: len(sorted), Min: sorted[0], Max: sorted[len(sorted)-1]}
+	for _, v := range sorted {
+		s.Total += v
+	}
+	s.Mean = s.Total / float64(len(sorted))
+	s.Range = s.Max - s.Min
+	mid := len(sorted) / 2
+	s.Median = sorted[mid]
+	if len(sorted)%2 == 0 {
+		s.Median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	counts := map[float64]int{}
+	best := 1
+	for _, v := range sorted {
+		counts[v]++
+		best = max(best, counts[v])
+	}
+	if best > 1 {
+		for i, v := range sorted {
+			if counts[v] == best && (i == 0 || sorted[i-1] != v) {
+				s.Mode = append(s.Mode, v)
+			}
+		}
+	}
+	return s
+}
+
+// valueAxis draws a vertical axis from zero to top and returns the y position of a value
+func valueAxis(c *canvas, width, top float64, label string) func(float64) float64 {
+	step := niceStep(top)
+	if step < 1 && top >= 5 {
+		step = 1
+	}
+	top = math.Max(step, math.Ceil(top/step-1e-9)*step)
+	plotH := chartHeight - chartTop - chartBottom
+	y := func(v float64) float64 { return chartTop + plotH - v/top*plotH }
+
+	ys := ticks(0, top, step)
+	every := labelEvery(len(ys))
+	for i, v := range ys {
+		if i%every != 0 {
+			continue
+		}
+		c.line(point{chartLeft, y(v)}, point{width - 12, y(v)}, gridColor, 1, "")
+		c.text(point{chartLeft - 6, y(v)}, measure(v, ""), 10, mutedColor, "end", false)
+	}
+	c.line(point{chartLeft, chartTop}, point{chartLeft, y(0)}, textColor, 1.5, "")
+	c.line(point{chartLeft, y(0)}, point{width - 12, y(0)}, textColor, 1.5, "")
+	if label != "" {
+		c.raw(fmt.Sprintf(`<text x="12" y="%s" transform="rotate(-90 12 %s)" text-anchor="middle" dominant-baseline="middle" font-size="11" fill="%s">%s</text>`,
+			num(chartTop+plotH/2), num(chartTop+plotH/2), textColor, html.EscapeString(label)))
+	}
+	return y
+}
+
+func renderBarChart(ch *models.Chart) string {
+	const barW, gap = 36.0, 18.0
+	width := math.Max(chartLeft+float64(len(ch.Data))*(barW+gap)+gap, 240)
+	c := newCanvas(width, chartHeight)
+
+	y := valueAxis(c, width, ch.Statistics.Max, ch.YLabel)
+	for i, d := range ch.Data {
+		x := chartLeft + gap + float64(i)*(barW+gap)
+		if d.Value > 0 {
+			c.rect(x, y(d.Value), barW, y(0)-y(d.Value), barColors[i%len(barColors)], barColors[i%len(barColors)], 1)
+		}
+		c.text(point{x + barW/2, y(0) + 12}, d.Label, 11, textColor, "middle", false)
+	}
+	if ch.XLabel != "" {
+		c.text(point{(chartLeft + width) / 2, chartHeight - 12}, ch.XLabel, 11, textColor, "middle", true)
+	}
+	return c.String()
+}
+
+func renderPictograph(ch *models.Chart) string {
+	const labelW, rowH, cell, r, pad = 110.0, 30.0, 22.0, 9.0, 10.0
+	most := 0.0
+	for _, d := range ch.Data {
+		most = math.Max(most, math.Ceil(d.Value/ch.PerSymbol))
+	}
+	width := math.Max(labelW+most*cell+2*pad+10, 240)
+	height := float64(len(ch.Data))*rowH + 2*pad + 30
+	if ch.XLabel != "" {
+		height += rowH
+	}
+	c := newCanvas(width, height)
+
+	top := pad
+	if ch.XLabel != "" {
+		c.text(point{pad + 8, top + rowH/2}, ch.XLabel, 12, textColor, "start", true)
+		top += rowH
+	}
+	for i, d := range ch.Data {
+		rowY := top + float64(i)*rowH
+		c.rect(pad, rowY, width-2*pad, rowH, "#cbd5e1", "none", 1)
+		c.line(point{pad + labelW, rowY}, point{pad + labelW, rowY + rowH}, "#cbd5e1", 1, "")
+		c.text(point{pad + 8, rowY + rowH/2}, d.Label, 12, textColor, "start", false)
+
+		symbols := d.Value / ch.PerSymbol
+		for k := 0; float64(k) < symbols-1e-9; k++ {
+			center := point{pad + labelW + 14 + float64(k)*cell, rowY + rowH/2}
+			if symbols-float64(k) < 1-1e-9 {
+				// Half symbol
+				c.path(fmt.Sprintf("M %s %s A %s %s 0 0 0 %s %s Z", num(center.X), num(center.Y-r), num(r), num(r),
+					num(center.X), num(center.Y+r)), strokeColor, strokeColor, 1)
+				continue
+			}
+			c.circle(center, r, strokeColor, strokeColor, 1)
+		}
+	}
+
+	keyY := top + float64(len(ch.Data))*rowH + 20
+	c.circle(point{pad + 14, keyY}, r, strokeColor, strokeColor, 1)
+	key := "= " + measure(ch.PerSymbol, "")
+	if ch.YLabel != "" {
+		key += " " + ch.YLabel
+	}
+	c.text(point{pad + 30, keyY}, key, 12, textColor, "start", true)
+	return c.String()
+}
+
+func renderTable(ch *models.Chart) string {
+	const colW, rowH, pad = 130.0, 26.0, 10.0
+	c := newCanvas(2*colW+2*pad, float64(len(ch.Data)+1)*rowH+2*pad)
+
+	header := []string{ch.XLabel, ch.YLabel}
+	if header[0] == "" {
+		header[0] = "Item"
+	}
+	if header[1] == "" {
+		header[1] = "Value"
+	}
+	c.rect(pad, pad, 2*colW, rowH, strokeColor, shadeColor, 1)
+	for col, h := range header {
+		c.text(point{pad + float64(col)*colW + colW/2, pad + rowH/2}, h, 12, textColor, "middle", true)
+	}
+	for i, d := range ch.Data {
+		rowY := pad + float64(i+1)*rowH
+		c.rect(pad, rowY, colW, rowH, strokeColor, "white", 1)
+		c.rect(pad+colW, rowY, colW, rowH, strokeColor, "white", 1)
+		c.text(point{pad + colW/2, rowY + rowH/2}, d.Label, 12, textColor, "middle", false)
+		c.text(point{pad + colW + colW/2, rowY + rowH/2}, strings.Replace(measure(d.Value, ""), "-", "−", 1), 12, textColor, "middle", false)
+	}
+	return c.String()
+}
+
+func renderLinePlot(ch *models.Chart) (string, error) {
+	stats := ch.Statistics
+	step := plotStep(ch.Values, stats.Range)
+	if stats.Range/step > maxNumberLineTicks {
+		step = niceStep(stats.Range)
+	}
+	lo := math.Floor(stats.Min/step) * step
+	hi := math.Max(math.Ceil(stats.Max/step)*step, lo+step)
+	for _, v := range ch.Values {
+		if k := (v - lo) / step; math.Abs(k-math.Round(k)) > 1e-9 {
+			return "", fmt.Errorf("line plot values must fall on ticks %s apart; %s does not", measure(step, ""), measure(v, ""))
+		}
+	}
+
+	counts := map[int]int{}
+	tallest := 0
+	for _, v := range ch.Values {
+		k := int(math.Round((v - lo) / step))
+		counts[k]++
+		tallest = max(tallest, counts[k])
+	}
+
+	const width, pad, dot = 440.0, 30.0, 14.0
+	lineY := 40 + float64(tallest)*dot
+	x := func(v float64) float64 { return pad + (v-lo)/(hi-lo)*(width-2*pad) }
+	c := newCanvas(width, lineY+50)
+
+	c.line(point{pad - 10, lineY}, point{width - pad + 10, lineY}, textColor, 2, "")
+	marks := ticks(lo, hi, step)
+	every := labelEvery(len(marks))
+	for i, v := range marks {
+		c.line(point{x(v), lineY - 5}, point{x(v), lineY + 5}, textColor, 1.5, "")
+		if i%every == 0 {
+			c.text(point{x(v), lineY + 16}, tickLabel(v, step), 11, textColor, "middle", false)
+		}
+	}
+	for k, n := range counts {
+		v := lo + float64(k)*step
+		for j := 0; j < n; j++ {
+			c.text(point{x(v), lineY - 10 - float64(j)*dot}, "×", 16, strokeColor, "middle", true)
+		}
+	}
+	if ch.XLabel != "" {
+		c.text(point{width / 2, lineY + 36}, ch.XLabel, 11, textColor, "middle", true)
+	}
+	return c.String(), nil
+}
+
+// plotStep picks the tick spacing for a line plot: 1 for whole numbers, or the
+// fraction (½, ¼, ⅛, 0.1) that every value is a multiple of
+func plotStep(values []float64, span float64) float64 {
+	for _, den := range []float64{1, 2, 4, 8, 10} {
+		ok := true
+		for _, v := range values {
+			if math.Abs(v*den-math.Round(v*den)) > 1e-9 {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return 1 / den
+		}
+	}
+	return niceStep(math.Max(span, 1))
+}
+
+func renderHistogram(ch *models.Chart) (string, error) {
+	stats := ch.Statistics
+	if ch.BinWidth == 0 {
+		ch.BinWidth = niceStep(math.Max(stats.Range, 1) * 2)
+	}
+	// Without an explicit start, bins begin at the multiple of the width below the smallest value
+	if ch.BinStart == 0 && (stats.Min < 0 || stats.Min >= ch.BinWidth) {
+		ch.BinStart = math.Floor(stats.Min/ch.BinWidth) * ch.BinWidth
+	}
+	if stats.Min < ch.BinStart {
+		return "", fmt.Errorf("histogram value %s is below bin_start %s", measure(stats.Min, ""), measure(ch.BinStart, ""))
+	}
+	// Compare as a float first: a tiny width makes the ratio overflow an int
+	if r := (stats.Max - ch.BinStart) / ch.BinWidth; !(r < maxHistogramBins) {
+		return "", fmt.Errorf("histogram needs more than %d bins; use a larger bin_width", maxHistogramBins)
+	}
+	bins := int(math.Floor((stats.Max-ch.BinStart)/ch.BinWidth)) + 1
+
+	counts := make([]int, bins)
+	tallest := 0
+	for _, v := range ch.Values {
+		b := int(math.Floor((v - ch.BinStart) / ch.BinWidth))
+		counts[b]++
+		tallest = max(tallest, counts[b])
+	}
+
+	const barW = 40.0
+	width := math.Max(chartLeft+float64(bins)*barW+30, 240)
+	c := newCanvas(width, chartHeight)
+	yLabel := ch.YLabel
+	if yLabel == "" {
+		yLabel = "Frequency"
+	}
+	y := valueAxis(c, width, float64(tallest), yLabel)
+	for i, n := range counts {
+		x := chartLeft + 8 + float64(i)*barW
+		if n > 0 {
+			c.rect(x, y(float64(n)), barW, y(0)-y(float64(n)), "white", strokeColor, 1.5)
+		}
+		c.text(point{x, y(0) + 12}, measure(ch.BinStart+float64(i)*ch.BinWidth, ""), 10, textColor, "middle", false)
+	}
+	c.text(point{chartLeft + 8 + float64(bins)*barW, y(0) + 12}, measure(ch.BinStart+float64(bins)*ch.BinWidth, ""), 10, textColor, "middle", false)
+	if ch.XLabel != "" {
+		c.text(point{(chartLeft + width) / 2, chartHeight - 12}, ch.XLabel, 11, textColor, "middle", true)
+	}
+	return c.String(), nil
+}
+
+// chartWarningPrefix marks warnings produced by the chart cross-check
+const chartWarningPrefix = "Chart check: "
+
+// chartQuestions maps phrases in a question to the statistic it asks for.
+// Earlier entries win, so "how many more" is read as a difference, not a maximum.
+var chartQuestions = []struct {
+	statistic string
+	pattern   *regexp.Regexp
+}{
+	{"difference", regexp.MustCompile(`\b(difference|how many (more|fewer|less))\b`)},
+	{"mean", regexp.MustCompile(`\b(mean|average)\b`)},
+	{"median", regexp.MustCompile(`\bmedian\b`)},
+	{"mode", regexp.MustCompile(`\b(mode|most (often|common|frequent))\b`)},
+	{"range", regexp.MustCompile(`\brange\b`)},
+	{"total", regexp.MustCompile(`\b(total|in all|altogether|sum)\b`)},
+	{"max", regexp.MustCompile(`\b(most|greatest|highest|largest|maximum|tallest)\b`)},
+	{"min", regexp.MustCompile(`\b(least|fewest|lowest|smallest|minimum|shortest)\b`)},
+	{"value", regexp.MustCompile(`\bhow (many|much)\b`)},
+}
+
+// checkChartAnswer works out which statistic the question asks for, computes it
+// from the dataset and warns when the correct answer does not match
+func checkChartAnswer(q *models.Question, ch *models.Chart) {
+	dropWarnings(q, chartWarningPrefix)
+	answer := q.AnswerText()
+	stats := ch.Statistics
+	if strings.TrimSpace(answer) == "" || stats == nil {
+		return
+	}
+
+	text := strings.ToLower(q.Question)
+	var named []models.ChartDatum
+	for _, d := range ch.Data {
+		if strings.Contains(text, strings.ToLower(d.Label)) {
+			named = append(named, d)
+		}
+	}
+	labelled := len(ch.Data) > 0 && ch.Kind != models.ChartLinePlot && ch.Kind != models.ChartHistogram
+
+	for _, cq := range chartQuestions {
+		if !cq.pattern.MatchString(text) {
+			continue
+		}
+
+		var want []float64
+		var labels []string
+		switch cq.statistic {
+		case "difference":
+			switch len(named) {
+			case 2:
+				want = []float64{math.Abs(named[0].Value - named[1].Value)}
+			case 0:
+				want = []float64{stats.Range}
+			}
+		case "mean":
+			want = []float64{stats.Mean}
+		case "median":
+			want = []float64{stats.Median}
+		case "mode":
+			if labelled {
+				labels = labelsWith(ch.Data, stats.Max)
+				want = []float64{stats.Max}
+			} else if len(stats.Mode) > 0 {
+				want = stats.Mode
+			}
+		case "range":
+			want = []float64{stats.Range}
+		case "total":
+			want = []float64{stats.Total}
+		case "max", "min":
+			want = []float64{stats.Max}
+			if cq.statistic == "min" {
+				want = []float64{stats.Min}
+			}
+			if labelled {
+				labels = labelsWith(ch.Data, want[0])
+			}
+		case "value":
+			if len(named) != 1 {
+				continue
+			}
+			want = []float64{named[0].Value}
+		}
+		if len(want) == 0 {
+			return
+		}
+
+		if !chartAnswerMatches(answer, want, labels) {
+			expected := make([]string, 0, len(want)+len(labels))
+			expected = append(expected, labels...)
+			for _, w := range want {
+				expected = append(expected, measure(w, ""))
+			}
+			q.Warnings = append(q.Warnings, fmt.Sprintf("%sthe data gives a %s of %s but the answer is %q",
+				chartWarningPrefix, cq.statistic, strings.Join(expected, " / "), answer))
+		}
+		return
+	}
+}
+
+func labelsWith(data []models.ChartDatum, value float64) []string {
+	var labels []string
+	for _, d := range data {
+		if d.Value == value {
+			labels = append(labels, d.Label)
+		}
+	}
+	return labels
+}
+
+// chartAnswerMatches accepts an answer naming one of the labels or giving one of the values
+func chartAnswerMatches(answer string, want []float64, labels []string) bool {
+	lower := strings.ToLower(answer)
+	for _, l := range labels {
+		if strings.Contains(lower, strings.ToLower(l)) {
+			return true
+		}
+	}
+	numbers := answerNumbers(answer)
+	for _, w := range want {
+		if matchesAny(numbers, w, "") {
+			return true
+		}
+	}
+	return false
+}
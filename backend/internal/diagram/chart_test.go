@@ -0,0 +1,113 @@
+package diagram
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestChartStatistics(t *testing.T) {
+	s := chartStatistics([]float64{3, 1, 4, 1, 5, 9})
+	want := models.ChartStatistics{Count: 6, Total: 23, Median: 3.5, Mode: []float64{1}, Min: 1, Max: 9, Range: 8}
+	if math.Abs(s.Mean-23.0/6) > 1e-9 {
+		t.Errorf("mean = %v", s.Mean)
+	}
+	s.Mean = 0
+	if !reflect.DeepEqual(*s, want) {
+		t.Errorf("statistics = %+v, want %+v", *s, want)
+	}
+
+	if s := chartStatistics([]float64{2, 7, 4}); s.Median != 4 || s.Mode != nil {
+		t.Errorf("odd count: median %v, mode %v", s.Median, s.Mode)
+	}
+	if s := chartStatistics([]float64{1, 1, 2, 2, 3}); !reflect.DeepEqual(s.Mode, []float64{1, 2}) {
+		t.Errorf("two modes = %v", s.Mode)
+	}
+}
+
+func TestRenderCharts(t *testing.T) {
+	data := []models.ChartDatum{{Label: "Apples", Value: 8}, {Label: "Pears", Value: 5}}
+	for _, ch := range []models.Chart{
+		{Kind: models.ChartBar, Data: data, XLabel: "Fruit", YLabel: "Votes"},
+		{Kind: models.ChartPictograph, Data: []models.ChartDatum{{Label: "Apples", Value: 6}, {Label: "Pears", Value: 3}}, PerSymbol: 2},
+		{Kind: models.ChartTable, Data: data},
+		{Kind: models.ChartLinePlot, Values: []float64{1, 1.5, 1.5, 2, 3}},
+		{Kind: models.ChartHistogram, Values: []float64{1, 4, 6, 7, 12}, BinWidth: 5},
+	} {
+		t.Run(ch.Kind, func(t *testing.T) {
+			svg, err := Render(&models.Diagram{Type: models.DiagramChart, Chart: &ch})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(svg, "<svg") {
+				t.Errorf("not an SVG document: %.60s", svg)
+			}
+			if ch.Statistics == nil {
+				t.Error("statistics were not filled in")
+			}
+		})
+	}
+
+	tests := []struct {
+		name  string
+		chart models.Chart
+		want  string
+	}{
+		{"no data", models.Chart{Kind: models.ChartBar}, "between 1 and"},
+		{"negative bar", models.Chart{Kind: models.ChartBar, Data: []models.ChartDatum{{Label: "A", Value: -1}}}, "non-negative"},
+		{"missing label", models.Chart{Kind: models.ChartBar, Data: []models.ChartDatum{{Value: 1}}}, "needs a label"},
+		{"pictograph fraction", models.Chart{Kind: models.ChartPictograph, Data: []models.ChartDatum{{Label: "A", Value: 3}}, PerSymbol: 4}, "whole or half"},
+		{"unknown kind", models.Chart{Kind: "pie", Data: data}, "unknown chart kind"},
+		{"negative bin", models.Chart{Kind: models.ChartHistogram, Values: []float64{1}, BinWidth: -1}, "bin_width"},
+		{"infinite bin", models.Chart{Kind: models.ChartHistogram, Values: []float64{1}, BinWidth: math.Inf(1)}, "bin_width"},
+		{"NaN bin start", models.Chart{Kind: models.ChartHistogram, Values: []float64{1}, BinWidth: 1, BinStart: math.NaN()}, "bin_start"},
+		{"overflowing bins", models.Chart{Kind: models.ChartHistogram, Values: []float64{1, 2, 1e300}, BinWidth: 1e-300}, "more than"},
+		{"huge span", models.Chart{Kind: models.ChartHistogram, Values: []float64{-1e308, 1e308}}, "more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(&models.Diagram{Type: models.DiagramChart, Chart: &tt.chart})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckChartAnswer(t *testing.T) {
+	bar := func() *models.Diagram {
+		return &models.Diagram{Type: models.DiagramChart, Chart: &models.Chart{Kind: models.ChartBar, Data: []models.ChartDatum{
+			{Label: "Apples", Value: 8}, {Label: "Pears", Value: 5}, {Label: "Plums", Value: 2},
+		}}}
+	}
+	tests := []struct {
+		question, answer string
+		warn             bool
+	}{
+		{"How many votes were there in total?", "15", false},
+		{"How many votes were there in total?", "16", true},
+		{"Which fruit got the most votes?", "Apples", false},
+		{"Which fruit got the most votes?", "Pears", true},
+		{"Which fruit got the fewest votes?", "Plums (2 votes)", false},
+		{"How many more votes did Apples get than Pears?", "3", false},
+		{"How many more votes did Apples get than Pears?", "13", true},
+		{"What is the mean number of votes?", "5", false},
+		{"How many votes did Pears get?", "5", false},
+		{"How many votes did Pears get?", "8", true},
+		{"Describe the chart.", "Apples are popular", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.question+" "+tt.answer, func(t *testing.T) {
+			q := models.Question{Type: "short_answer", Question: tt.question, CorrectAnswer: tt.answer, Diagram: bar()}
+			if err := Apply(&q); err != nil {
+				t.Fatal(err)
+			}
+			if warned := len(q.Warnings) > 0; warned != tt.warn {
+				t.Errorf("warnings = %v, want warning: %v", q.Warnings, tt.warn)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package diagram
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+const (
+	componentWidth  = 80
+	componentHeight = 56
+	componentWire   = 34 // distance from the top of a component box to its wire
+	branchGap       = 8
+	busPad          = 16
+	circuitMargin   = 30
+	batteryDrop     = 44 // distance from the network's bottom to the battery wire
+
+	maxCircuitDepth      = 4
+	maxCircuitComponents = 12
+)
+
+// validateCircuit checks the structure of a circuit spec
+func validateCircuit(c *models.Circuit) error {
+	if c.Network == nil {
+		return fmt.Errorf("circuit needs a \"network\"")
+	}
+	if c.Voltage < 0 || math.IsNaN(c.Voltage) || math.IsInf(c.Voltage, 0) {
+		return fmt.Errorf("voltage must be a positive number")
+	}
+
+	count := 0
+	var walk func(n *models.CircuitNode, parent string, depth int) error
+	walk = func(n *models.CircuitNode, parent string, depth int) error {
+		if depth > maxCircuitDepth {
+			return fmt.Errorf("circuit is nested more than %d levels deep", maxCircuitDepth)
+		}
+		if n.Value < 0 || math.IsNaN(n.Value) || math.IsInf(n.Value, 0) {
+			return fmt.Errorf("%s value must be a positive number", componentName(n))
+		}
+
+		switch n.Kind {
+		case models.CircuitSeries, models.CircuitParallel:
+			min := 1
+			if n.Kind == models.CircuitParallel {
+				min = 2
+			}
+			if len(n.Elements) < min {
+				return fmt.Errorf("%s group needs at least %d elements", n.Kind, min)
+			}
+			for i := range n.Elements {
+				if err := walk(&n.Elements[i], n.Kind, depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		case models.CircuitResistor, models.CircuitBulb, models.CircuitSwitch, models.CircuitCapacitor:
+		case models.CircuitAmmeter:
+			if parent == models.CircuitParallel {
+				return fmt.Errorf("%s is connected in parallel and would short-circuit the other branches", componentName(n))
+			}
+		case models.CircuitVoltmeter:
+			if parent != models.CircuitParallel {
+				return fmt.Errorf("%s is connected in series; connect it in parallel with the component it measures", componentName(n))
+			}
+		case "":
+			return fmt.Errorf("circuit node kind is required")
+		default:
+			return fmt.Errorf("unknown circuit component %q", n.Kind)
+		}
+
+		if len(n.Elements) > 0 {
+			return fmt.Errorf("%s cannot contain other elements", componentName(n))
+		}
+		count++
+		if count > maxCircuitComponents {
+			return fmt.Errorf("circuit has more than %d components", maxCircuitComponents)
+		}
+		return nil
+	}
+
+	return walk(c.Network, models.CircuitSeries, 1)
+}
+
+// analyzeCircuit computes the equivalent resistance and battery current, and the
+// equivalent capacitance of capacitor-only networks, treating meters as ideal
+func analyzeCircuit(c *models.Circuit) *models.CircuitAnalysis {
+	analysis := &models.CircuitAnalysis{}
+
+	if r, ok := resistance(c.Network); ok {
+		if !math.IsInf(r, 1) {
+			analysis.EquivalentResistance = &r
+		}
+		// An open circuit draws no current; a short circuit has no meaningful value
+		if c.Voltage > 0 && r > 0 {
+			current := c.Voltage / r
+			analysis.Current = &current
+		}
+	}
+
+	if farads, ok := capacitance(c.Network); ok {
+		analysis.EquivalentCapacitance = &farads
+	}
+	return analysis
+}
+
+// resistance returns a node's DC resistance in ohms (+Inf for an open path);
+// ok is false when a resistor or bulb has no value
+func resistance(n *models.CircuitNode) (float64, bool) {
+	switch n.Kind {
+	case models.CircuitResistor, models.CircuitBulb:
+		return n.Value, n.Value > 0
+	case models.CircuitAmmeter:
+		return 0, true
+	case models.CircuitSwitch:
+		if n.Open {
+			return math.Inf(1), true
+		}
+		return 0, true
+	case models.CircuitVoltmeter, models.CircuitCapacitor:
+		return math.Inf(1), true
+	case models.CircuitSeries:
+		total := 0.0
+		for i := range n.Elements {
+			r, ok := resistance(&n.Elements[i])
+			if !ok {
+				return 0, false
+			}
+			total += r
+		}
+		return total, true
+	case models.CircuitParallel:
+		conductance := 0.0
+		for i := range n.Elements {
+			r, ok := resistance(&n.Elements[i])
+			if !ok {
+				return 0, false
+			}
+			if r == 0 {
+				return 0, true
+			}
+			conductance += 1 / r
+		}
+		if conductance == 0 {
+			return math.Inf(1), true
+		}
+		return 1 / conductance, true
+	}
+	return 0, false
+}
+
+// capacitance returns the equivalent capacitance of a network made only of
+// capacitors (and closed switches or ammeters), in microfarads
+func capacitance(n *models.CircuitNode) (float64, bool) {
+	switch n.Kind {
+	case models.CircuitCapacitor:
+		return n.Value, n.Value > 0
+	case models.CircuitSeries:
+		inverse := 0.0
+		found := false
+		for i := range n.Elements {
+			e := &n.Elements[i]
+			if e.Kind == models.CircuitAmmeter || (e.Kind == models.CircuitSwitch && !e.Open) {
+				continue
+			}
+			c, ok := capacitance(e)
+			if !ok {
+				return 0, false
+			}
+			inverse += 1 / c
+			found = true
+		}
+		if !found {
+			return 0, false
+		}
+		return 1 / inverse, true
+	case models.CircuitParallel:
+		total := 0.0
+		for i := range n.Elements {
+			c, ok := capacitance(&n.Elements[i])
+			if !ok {
+				return 0, false
+			}
+			total += c
+		}
+		return total, true
+	}
+	return 0, false
+}
+
+// block is a laid-out piece of schematic; its wire enters on the left and
+// leaves on the right at height wire below its top edge
+type block struct {
+	w, h, wire float64
+	draw       func(c *canvas, x, y float64)
+}
+
+func layout(n *models.CircuitNode) block {
+	switch n.Kind {
+	case models.CircuitSeries:
+		children := make([]block, len(n.Elements))
+		above, below, width := 0.0, 0.0, 0.0
+		for i := range n.Elements {
+			children[i] = layout(&n.Elements[i])
+			above = math.Max(above, children[i].wire)
+			below = math.Max(below, children[i].h-children[i].wire)
+			width += children[i].w
+		}
+		return block{w: width, h: above + below, wire: above, draw: func(c *canvas, x, y float64) {
+			for _, child := range children {
+				child.draw(c, x, y+above-child.wire)
+				x += child.w
+			}
+		}}
+
+	case models.CircuitParallel:
+		children := make([]block, len(n.Elements))
+		inner, height := 0.0, 0.0
+		for i := range n.Elements {
+			children[i] = layout(&n.Elements[i])
+			inner = math.Max(inner, children[i].w)
+			height += children[i].h
+		}
+		height += branchGap * float64(len(children)-1)
+		width := inner + 2*busPad
+		return block{w: width, h: height, wire: children[0].wire, draw: func(c *canvas, x, y float64) {
+			top, offset := 0.0, 0.0
+			for i, child := range children {
+				wireY := y + offset + child.wire
+				if i == 0 {
+					top = wireY
+				}
+				left := x + busPad + (inner-child.w)/2
+				child.draw(c, left, y+offset)
+				c.line(point{x, wireY}, point{left, wireY}, textColor, 1.5, "")
+				c.line(point{left + child.w, wireY}, point{x + width, wireY}, textColor, 1.5, "")
+				if i == len(children)-1 {
+					c.line(point{x, top}, point{x, wireY}, textColor, 1.5, "")
+					c.line(point{x + width, top}, point{x + width, wireY}, textColor, 1.5, "")
+					c.circle(point{x, top}, 2.5, textColor, textColor, 1)
+					c.circle(point{x + width, top}, 2.5, textColor, textColor, 1)
+				}
+				offset += child.h + branchGap
+			}
+		}}
+	}
+
+	node := *n
+	return block{w: componentWidth, h: componentHeight, wire: componentWire, draw: func(c *canvas, x, y float64) {
+		drawComponent(c, &node, x, y+componentWire)
+	}}
+}
+
+// drawComponent draws a schematic symbol centred in a component box whose wire runs at height wy
+func drawComponent(c *canvas, n *models.CircuitNode, x, wy float64) {
+	cx := x + componentWidth/2
+	lead := func(halfWidth float64) {
+		c.line(point{x, wy}, point{cx - halfWidth, wy}, textColor, 1.5, "")
+		c.line(point{cx + halfWidth, wy}, point{x + componentWidth, wy}, textColor, 1.5, "")
+	}
+
+	switch n.Kind {
+	case models.CircuitResistor:
+		lead(16)
+		c.rect(cx-16, wy-6, 32, 12, "#ea580c", accentColor, 1)
+	case models.CircuitBulb:
+		lead(11)
+		c.circle(point{cx, wy}, 11, strokeColor, "#fef9c3", 1.5)
+		d := 11 / math.Sqrt2
+		c.line(point{cx - d, wy - d}, point{cx + d, wy + d}, strokeColor, 1.5, "")
+		c.line(point{cx - d, wy + d}, point{cx + d, wy - d}, strokeColor, 1.5, "")
+	case models.CircuitAmmeter, models.CircuitVoltmeter:
+		lead(11)
+		c.circle(point{cx, wy}, 11, strokeColor, "white", 1.5)
+		letter := "A"
+		if n.Kind == models.CircuitVoltmeter {
+			letter = "V"
+		}
+		c.text(point{cx, wy + 1}, letter, 12, strokeColor, "middle", true)
+	case models.CircuitCapacitor:
+		lead(4)
+		c.line(point{cx - 4, wy - 12}, point{cx - 4, wy + 12}, strokeColor, 2.5, "")
+		c.line(point{cx + 4, wy - 12}, point{cx + 4, wy + 12}, strokeColor, 2.5, "")
+	case models.CircuitSwitch:
+		lead(14)
+		c.circle(point{cx - 14, wy}, 2.5, textColor, textColor, 1)
+		c.circle(point{cx + 14, wy}, 2.5, textColor, textColor, 1)
+		end := point{cx + 14, wy}
+		if n.Open {
+			end = point{cx + 11, wy - 14}
+		}
+		c.line(point{cx - 14, wy}, end, textColor, 1.5, "")
+	}
+
+	if label := componentLabel(n); label != "" {
+		c.text(point{cx, wy - 22}, label, 11, textColor, "middle", false)
+	}
+}
+
+// componentLabel is the text shown above a symbol, e.g. "R1 = 4 Ω"
+func componentLabel(n *models.CircuitNode) string {
+	value := ""
+	if n.Value > 0 {
+		switch n.Kind {
+		case models.CircuitResistor, models.CircuitBulb:
+			value = measure(n.Value, "Ω")
+		case models.CircuitCapacitor:
+			value = measure(n.Value, "μF")
+		}
+	}
+	switch {
+	case n.Label != "" && value != "":
+		return n.Label + " = " + value
+	case n.Label != "":
+		return n.Label
+	default:
+		return value
+	}
+}
+
+func componentName(n *models.CircuitNode) string {
+	if n.Label != "" {
+		return fmt.Sprintf("%s %s", n.Kind, n.Label)
+	}
+	return n.Kind
+}
+
+func renderCircuit(spec *models.Circuit) (string, error) {
+	if err := validateCircuit(spec); err != nil {
+		return "", err
+	}
+	spec.Analysis = analyzeCircuit(spec)
+
+	net := layout(spec.Network)
+	width := math.Max(net.w+2*circuitMargin, 200)
+	left := (width - net.w) / 2
+	right := left + net.w
+	top := circuitMargin + net.wire
+	bottom := circuitMargin + net.h + batteryDrop
+	c := newCanvas(width, bottom+circuitMargin)
+
+	net.draw(c, left, circuitMargin)
+
+	// Return path with the battery in the middle of the bottom wire
+	cx := width / 2
+	c.line(point{left, top}, point{left, bottom}, textColor, 1.5, "")
+	c.line(point{right, top}, point{right, bottom}, textColor, 1.5, "")
+	c.line(point{left, bottom}, point{cx - 5, bottom}, textColor, 1.5, "")
+	c.line(point{cx + 5, bottom}, point{right, bottom}, textColor, 1.5, "")
+	c.line(point{cx - 5, bottom - 14}, point{cx - 5, bottom + 14}, textColor, 2, "")
+	c.line(point{cx + 5, bottom - 7}, point{cx + 5, bottom + 7}, textColor, 3, "")
+	c.text(point{cx - 14, bottom - 14}, "+", 12, textColor, "middle", true)
+	c.text(point{cx + 14, bottom - 14}, "−", 12, textColor, "middle", true)
+
+	battery := "V"
+	if spec.Voltage > 0 {
+		battery = measure(spec.Voltage, "V")
+	}
+	c.text(point{cx, bottom + 22}, battery, 12, textColor, "middle", true)
+
+	return c.String(), nil
+}
+
+// circuitWarningPrefix marks warnings produced by the circuit cross-check so
+// they can be replaced when the question is edited
+const circuitWarningPrefix = "Circuit check: "
+
+// checkCircuitAnswer compares the computed resistance, current or capacitance
+// with the numbers in the correct answer for the quantity the question asks about
+func checkCircuitAnswer(q *models.Question, a *models.CircuitAnalysis) {
+	dropWarnings(q, circuitWarningPrefix)
+
+	numbers := answerNumbers(q.AnswerText())
+	if len(numbers) == 0 {
+		return
+	}
+
+	type quantity struct {
+		keyword string
+		value   *float64
+		unit    string
+	}
+	text := strings.ToLower(q.Question)
+	for _, qty := range []quantity{
+		{"current", a.Current, "A"},
+		{"resistance", a.EquivalentResistance, "Ω"},
+		{"capacitance", a.EquivalentCapacitance, "μF"},
+	} {
+		if qty.value == nil || !strings.Contains(text, qty.keyword) {
+			continue
+		}
+		if !matchesAny(numbers, *qty.value, qty.unit) {
+			q.Warnings = append(q.Warnings, fmt.Sprintf("%sthe circuit gives a %s of %s but the answer is %q",
+				circuitWarningPrefix, qty.keyword, measure(*qty.value, qty.unit), q.AnswerText()))
+		}
+		return
+	}
+}
+
+// answerNumberPattern matches a number and an SI prefix when it is followed by a unit, e.g. "2.5 mA"
+var answerNumberPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:(k|M|m|μ|µ|u)(?:A|Ω|F|V|ohm))?`)
+
+// answerNumbers extracts the numbers in an answer together with any SI prefix that follows them
+func answerNumbers(answer string) []float64 {
+	var numbers []float64
+	for _, m := range answerNumberPattern.FindAllStringSubmatch(answer, -1) {
+		var v float64
+		if _, err := fmt.Sscan(m[1], &v); err != nil {
+			continue
+		}
+		switch m[2] {
+		case "k":
+			v *= 1e3
+		case "M":
+			v *= 1e6
+		case "m":
+			v *= 1e-3
+		case "μ", "µ", "u":
+			v *= 1e-6
+		}
+		numbers = append(numbers, v)
+	}
+	return numbers
+}
+
+// matchesAny reports whether any number is within 2% of want, accounting for
+// capacitances already being expressed in microfarads
+func matchesAny(numbers []float64, want float64, unit string) bool {
+	for _, n := range numbers {
+		if unit == "μF" && n < 1e-3 {
+			n *= 1e6
+		}
+		if math.Abs(n-want) <= 0.02*math.Max(math.Abs(want), 1e-9) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package diagram
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func resistor(label string, ohms float64) models.CircuitNode {
+	return models.CircuitNode{Kind: models.CircuitResistor, Label: label, Value: ohms}
+}
+
+func group(kind string, elements ...models.CircuitNode) models.CircuitNode {
+	return models.CircuitNode{Kind: kind, Elements: elements}
+}
+
+func TestAnalyzeCircuit(t *testing.T) {
+	capacitor := func(uf float64) models.CircuitNode {
+		return models.CircuitNode{Kind: models.CircuitCapacitor, Value: uf}
+	}
+	tests := []struct {
+		name                          string
+		circuit                       models.Circuit
+		resistance, current, capacity float64 // -1 when not determined
+	}{
+		{
+			name:       "series",
+			circuit:    models.Circuit{Voltage: 12, Network: ptr(group(models.CircuitSeries, resistor("R1", 2), resistor("R2", 4), models.CircuitNode{Kind: models.CircuitAmmeter}))},
+			resistance: 6, current: 2, capacity: -1,
+		},
+		{
+			name:       "series with parallel branch",
+			circuit:    models.Circuit{Voltage: 12, Network: ptr(group(models.CircuitSeries, resistor("R1", 4), group(models.CircuitParallel, resistor("R2", 6), resistor("R3", 3))))},
+			resistance: 6, current: 2, capacity: -1,
+		},
+		{
+			name:       "open switch",
+			circuit:    models.Circuit{Voltage: 9, Network: ptr(group(models.CircuitSeries, resistor("R1", 3), models.CircuitNode{Kind: models.CircuitSwitch, Open: true}))},
+			resistance: -1, current: 0, capacity: -1,
+		},
+		{
+			name:       "unknown resistor",
+			circuit:    models.Circuit{Voltage: 9, Network: ptr(group(models.CircuitSeries, resistor("R1", 3), resistor("R2", 0)))},
+			resistance: -1, current: -1, capacity: -1,
+		},
+		{
+			name:       "capacitors",
+			circuit:    models.Circuit{Network: ptr(group(models.CircuitSeries, capacitor(6), group(models.CircuitParallel, capacitor(2), capacitor(1))))},
+			resistance: -1, current: -1, capacity: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := analyzeCircuit(&tt.circuit)
+			for _, v := range []struct {
+				name string
+				got  *float64
+				want float64
+			}{
+				{"resistance", a.EquivalentResistance, tt.resistance},
+				{"current", a.Current, tt.current},
+				{"capacitance", a.EquivalentCapacitance, tt.capacity},
+			} {
+				switch {
+				case v.want < 0 && v.got != nil:
+					t.Errorf("%s = %v, want undetermined", v.name, *v.got)
+				case v.want >= 0 && (v.got == nil || math.Abs(*v.got-v.want) > 1e-9):
+					t.Errorf("%s = %v, want %v", v.name, v.got, v.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCircuit(t *testing.T) {
+	tests := []struct {
+		name    string
+		network models.CircuitNode
+		want    string
+	}{
+		{"ammeter in parallel", group(models.CircuitSeries, resistor("R1", 2), group(models.CircuitParallel, resistor("R2", 2), models.CircuitNode{Kind: models.CircuitAmmeter, Label: "A1"})), "short-circuit"},
+		{"voltmeter in series", group(models.CircuitSeries, resistor("R1", 2), models.CircuitNode{Kind: models.CircuitVoltmeter, Label: "V1"}), "connected in series"},
+		{"parallel of one", group(models.CircuitSeries, group(models.CircuitParallel, resistor("R1", 2))), "at least 2"},
+		{"negative value", group(models.CircuitSeries, resistor("R1", -2)), "positive"},
+		{"unknown kind", group(models.CircuitSeries, models.CircuitNode{Kind: "diode"}), "unknown circuit component"},
+		{"component with children", group(models.CircuitSeries, models.CircuitNode{Kind: models.CircuitResistor, Elements: []models.CircuitNode{resistor("R2", 1)}}), "cannot contain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCircuit(&models.Circuit{Voltage: 6, Network: &tt.network})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCircuitAnswer(t *testing.T) {
+	circuit := func() *models.Diagram {
+		return &models.Diagram{Type: models.DiagramCircuit, Circuit: &models.Circuit{
+			Voltage: 12,
+			Network: ptr(group(models.CircuitSeries, resistor("R1", 2), resistor("R2", 4))),
+		}}
+	}
+	tests := []struct {
+		question, answer string
+		warn             bool
+	}{
+		{"What current flows through the circuit?", "2 A", false},
+		{"What current flows through the circuit?", "2000 mA", false},
+		{"What current flows through the circuit?", "3 A", true},
+		{"What is the total resistance?", "6 Ω", false},
+		{"What is the total resistance?", "8 ohms", true},
+		{"Which resistor is larger?", "R2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.question+" "+tt.answer, func(t *testing.T) {
+			q := models.Question{Type: "short_answer", Question: tt.question, CorrectAnswer: tt.answer, Diagram: circuit()}
+			if err := Apply(&q); err != nil {
+				t.Fatal(err)
+			}
+			if warned := len(q.Warnings) > 0; warned != tt.warn {
+				t.Errorf("warnings = %v, want warning: %v", q.Warnings, tt.warn)
+			}
+
+			// Checking again replaces the warning rather than adding another
+			CheckAnswer(&q)
+			if len(q.Warnings) > 1 {
+				t.Errorf("warnings piled up: %v", q.Warnings)
+			}
+		})
+	}
+}
+
+func ptr(n models.CircuitNode) *models.CircuitNode { return &n }
@@ -0,0 +1,120 @@
+// Package diagram renders structured diagram specs to SVG and rejects specs
+// that describe impossible figures.
+package diagram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// Render validates a diagram spec and draws it as an SVG document
+func Render(d *models.Diagram) (string, error) {
+	switch d.Type {
+	case models.DiagramTriangle, models.DiagramRectangle, models.DiagramCircle, models.DiagramRegularPolygon:
+		if d.Shape == nil {
+			return "", fmt.Errorf("%s diagram needs a \"shape\"", d.Type)
+		}
+		return renderShape(d.Type, d.Shape)
+	case models.DiagramCircuit:
+		if d.Circuit == nil {
+			return "", fmt.Errorf("circuit diagram needs a \"circuit\"")
+		}
+		return renderCircuit(d.Circuit)
+	case models.DiagramGraph:
+		if d.Graph == nil {
+			return "", fmt.Errorf("graph diagram needs a \"graph\"")
+		}
+		return renderGraph(d.Graph)
+	case models.DiagramNumberLine:
+		if d.NumberLine == nil {
+			return "", fmt.Errorf("number_line diagram needs a \"number_line\"")
+		}
+		return renderNumberLine(d.NumberLine)
+	case models.DiagramFraction:
+		if d.Fraction == nil {
+			return "", fmt.Errorf("fraction diagram needs a \"fraction\"")
+		}
+		return renderFraction(d.Fraction)
+	case models.DiagramClock:
+		if d.Clock == nil {
+			return "", fmt.Errorf("clock diagram needs a \"clock\"")
+		}
+		return renderClock(d.Clock)
+	case models.DiagramBaseTen:
+		if d.BaseTen == nil {
+			return "", fmt.Errorf("base_ten diagram needs a \"base_ten\"")
+		}
+		return renderBaseTen(d.BaseTen)
+	case models.DiagramCoins:
+		if d.Coins == nil {
+			return "", fmt.Errorf("coins diagram needs a \"coins\"")
+		}
+		return renderCoins(d.Coins)
+	case models.DiagramTally:
+		if d.Tally == nil {
+			return "", fmt.Errorf("tally diagram needs a \"tally\"")
+		}
+		return renderTally(d.Tally)
+	case models.DiagramChart:
+		if d.Chart == nil {
+			return "", fmt.Errorf("chart diagram needs a \"chart\"")
+		}
+		return renderChart(d.Chart)
+	case "":
+		return "", fmt.Errorf("diagram type is required")
+	default:
+		return "", fmt.Errorf("unknown diagram type %q", d.Type)
+	}
+}
+
+// Validate reports whether a diagram spec describes a figure that can be drawn
+func Validate(d *models.Diagram) error {
+	_, err := Render(d)
+	return err
+}
+
+// Apply renders the question's diagram spec, if any, into its Image field.
+// Circuits and charts are also analysed and the result cross-checked against
+// the answer, with any mismatch added to the question's warnings.
+func Apply(q *models.Question) error {
+	if q.Diagram == nil {
+		return nil
+	}
+	svg, err := Render(q.Diagram)
+	if err != nil {
+		return fmt.Errorf("diagram: %w", err)
+	}
+	q.Image = svg
+	q.ImageCredit = nil
+	CheckAnswer(q)
+	return nil
+}
+
+// CheckAnswer cross-checks the answer of a question with a rendered circuit or
+// chart against the values computed from it, replacing the warnings of any
+// earlier check. Call it again whenever the answer changes.
+func CheckAnswer(q *models.Question) {
+	if q.Diagram == nil {
+		return
+	}
+	if q.Diagram.Circuit != nil && q.Diagram.Circuit.Analysis != nil {
+		checkCircuitAnswer(q, q.Diagram.Circuit.Analysis)
+	}
+	if q.Diagram.Chart != nil {
+		checkChartAnswer(q, q.Diagram.Chart)
+	}
+}
+
+// dropWarnings removes the warnings a previous cross-check added, so that
+// editing a question replaces them instead of piling up duplicates
+func dropWarnings(q *models.Question, prefix string) {
+	warnings := q.Warnings[:0:0]
+	for _, w := range q.Warnings {
+		if !strings.HasPrefix(w, prefix) {
+			warnings = append(warnings, w)
+		}
+	}
+	q.Warnings = warnings
+}
@@ -0,0 +1,327 @@
+package diagram
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+const (
+	shadeColor = "#99f6e4"
+	faceColor  = "#f8fafc"
+
+	maxNumberLineTicks = 40
+	maxFractionWholes  = 4
+	maxCoins           = 20
+	maxTallyRows       = 8
+	maxTallyCount      = 50
+)
+
+func renderNumberLine(n *models.NumberLine) (string, error) {
+	if n.Step == 0 {
+		n.Step = 1
+	}
+	if n.Min >= n.Max || n.Step < 0 {
+		return "", fmt.Errorf("number line needs min < max and a positive step")
+	}
+	count := (n.Max - n.Min) / n.Step
+	if count > maxNumberLineTicks {
+		return "", fmt.Errorf("number line has more than %d ticks; use a larger step", maxNumberLineTicks)
+	}
+	inRange := func(v float64) bool { return v >= n.Min-1e-9 && v <= n.Max+1e-9 }
+	for _, p := range n.Points {
+		if !inRange(p.Value) {
+			return "", fmt.Errorf("point %s is outside the number line", measure(p.Value, ""))
+		}
+	}
+	for _, j := range n.Jumps {
+		if !inRange(j.From) || !inRange(j.To) || j.From == j.To {
+			return "", fmt.Errorf("jump from %s to %s does not fit on the number line", measure(j.From, ""), measure(j.To, ""))
+		}
+	}
+
+	const width, height, pad, lineY = 440.0, 120.0, 24.0, 80.0
+	x := func(v float64) float64 { return pad + 10 + (v-n.Min)/(n.Max-n.Min)*(width-2*pad-20) }
+
+	c := newCanvas(width, height)
+	c.line(point{pad, lineY}, point{width - pad, lineY}, textColor, 2, "")
+	c.polygon([]point{{pad - 6, lineY}, {pad + 2, lineY - 5}, {pad + 2, lineY + 5}}, textColor, textColor, 1)
+	c.polygon([]point{{width - pad + 6, lineY}, {width - pad - 2, lineY - 5}, {width - pad - 2, lineY + 5}}, textColor, textColor, 1)
+
+	every := labelEvery(int(count) + 1)
+	for i := 0; float64(i) <= count+1e-9; i++ {
+		v := n.Min + float64(i)*n.Step
+		c.line(point{x(v), lineY - 7}, point{x(v), lineY + 7}, textColor, 1.5, "")
+		if i%every == 0 {
+			c.text(point{x(v), lineY + 20}, strings.Replace(measure(v, ""), "-", "−", 1), 12, textColor, "middle", false)
+		}
+	}
+
+	for _, j := range n.Jumps {
+		from, to := x(j.From), x(j.To)
+		rise := math.Min(40, math.Abs(to-from)/2+8)
+		mid := (from + to) / 2
+		c.path(fmt.Sprintf("M %s %s Q %s %s %s %s", num(from), num(lineY-4), num(mid), num(lineY-4-2*rise), num(to), num(lineY-4)),
+			accentColor, "none", 1.8)
+		// Arrow head at the landing point
+		dir := 1.0
+		if to < from {
+			dir = -1
+		}
+		c.polyline([]point{{to - dir*8, lineY - 10}, {to, lineY - 4}, {to + dir*1, lineY - 13}}, accentColor, 1.8)
+		if j.Label != "" {
+			c.text(point{mid, lineY - 10 - rise}, j.Label, 12, accentColor, "middle", true)
+		}
+	}
+
+	for _, p := range n.Points {
+		c.circle(point{x(p.Value), lineY}, 5, strokeColor, strokeColor, 1)
+		if p.Label != "" {
+			c.text(point{x(p.Value), lineY - 16}, p.Label, 12, strokeColor, "middle", true)
+		}
+	}
+
+	return c.String(), nil
+}
+
+func renderFraction(f *models.FractionModel) (string, error) {
+	style := f.Style
+	if style == "" {
+		style = "bar"
+	}
+	maxParts := 24
+	if style == "circle" {
+		maxParts = 12
+	} else if style != "bar" {
+		return "", fmt.Errorf("fraction style must be bar or circle")
+	}
+	if f.Denominator < 1 || f.Denominator > maxParts {
+		return "", fmt.Errorf("%s fractions need a denominator from 1 to %d", style, maxParts)
+	}
+	if f.Numerator < 0 {
+		return "", fmt.Errorf("numerator cannot be negative")
+	}
+	wholes := int(math.Max(1, math.Ceil(float64(f.Numerator)/float64(f.Denominator))))
+	if wholes > maxFractionWholes {
+		return "", fmt.Errorf("fraction needs more than %d wholes", maxFractionWholes)
+	}
+
+	shaded := f.Numerator
+	if style == "circle" {
+		const r, gap = 48.0, 24.0
+		c := newCanvas(float64(wholes)*(2*r+gap)+gap, 2*r+2*gap)
+		for w := 0; w < wholes; w++ {
+			center := point{gap + r + float64(w)*(2*r+gap), r + gap}
+			for i := 0; i < f.Denominator; i++ {
+				fill := "white"
+				if shaded > 0 {
+					fill, shaded = shadeColor, shaded-1
+				}
+				if f.Denominator == 1 {
+					c.circle(center, r, strokeColor, fill, 2)
+					continue
+				}
+				a0 := -math.Pi/2 + 2*math.Pi*float64(i)/float64(f.Denominator)
+				a1 := -math.Pi/2 + 2*math.Pi*float64(i+1)/float64(f.Denominator)
+				p0 := center.add(point{r * math.Cos(a0), r * math.Sin(a0)})
+				p1 := center.add(point{r * math.Cos(a1), r * math.Sin(a1)})
+				large := 0
+				if a1-a0 > math.Pi {
+					large = 1
+				}
+				c.path(fmt.Sprintf("M %s %s L %s %s A %s %s 0 %d 1 %s %s Z", num(center.X), num(center.Y),
+					num(p0.X), num(p0.Y), num(r), num(r), large, num(p1.X), num(p1.Y)), strokeColor, fill, 2)
+			}
+		}
+		return c.String(), nil
+	}
+
+	const barW, barH, gap = 320.0, 40.0, 16.0
+	c := newCanvas(barW+2*gap, float64(wholes)*(barH+gap)+gap)
+	partW := barW / float64(f.Denominator)
+	for w := 0; w < wholes; w++ {
+		y := gap + float64(w)*(barH+gap)
+		for i := 0; i < f.Denominator; i++ {
+			fill := "white"
+			if shaded > 0 {
+				fill, shaded = shadeColor, shaded-1
+			}
+			c.rect(gap+float64(i)*partW, y, partW, barH, strokeColor, fill, 2)
+		}
+	}
+	return c.String(), nil
+}
+
+func renderClock(k *models.Clock) (string, error) {
+	if k.Hour < 1 || k.Hour > 12 || k.Minute < 0 || k.Minute > 59 {
+		return "", fmt.Errorf("clock needs an hour from 1 to 12 and minutes from 0 to 59")
+	}
+
+	const size, r = 220.0, 90.0
+	center := point{size / 2, size / 2}
+	at := func(angle, radius float64) point {
+		// angle in degrees clockwise from 12 o'clock
+		a := rad(angle - 90)
+		return center.add(point{radius * math.Cos(a), radius * math.Sin(a)})
+	}
+
+	c := newCanvas(size, size)
+	c.circle(center, r, textColor, faceColor, 3)
+	for m := 0; m < 60; m++ {
+		inner := r - 5
+		width := 1.0
+		if m%5 == 0 {
+			inner, width = r-11, 2.5
+		}
+		c.line(at(float64(m)*6, inner), at(float64(m)*6, r-2), textColor, width, "")
+	}
+	if !k.HideNumbers {
+		for h := 1; h <= 12; h++ {
+			c.text(at(float64(h)*30, r-24), strconv.Itoa(h), 15, textColor, "middle", true)
+		}
+	}
+	if !k.HideHands {
+		hourAngle := float64(k.Hour%12)*30 + float64(k.Minute)*0.5
+		c.line(center, at(hourAngle, r*0.5), textColor, 5, "")
+		c.line(center, at(float64(k.Minute)*6, r*0.78), accentColor, 3, "")
+	}
+	c.circle(center, 4, textColor, textColor, 1)
+	return c.String(), nil
+}
+
+func renderBaseTen(b *models.BaseTen) (string, error) {
+	if b.Hundreds < 0 || b.Tens < 0 || b.Ones < 0 {
+		return "", fmt.Errorf("base-ten blocks cannot be negative")
+	}
+	if b.Hundreds > 9 || b.Tens > 20 || b.Ones > 20 {
+		return "", fmt.Errorf("base-ten blocks allow up to 9 hundreds, 20 tens and 20 ones")
+	}
+	if b.Hundreds+b.Tens+b.Ones == 0 {
+		return "", fmt.Errorf("base-ten diagram needs at least one block")
+	}
+
+	const unit, gap, pad = 7.0, 8.0, 16.0
+	block := unit * 10
+	width := pad
+	width += float64(b.Hundreds) * (block + gap)
+	width += float64(b.Tens) * (unit + gap/2)
+	if b.Tens > 0 {
+		width += gap
+	}
+	width += math.Ceil(float64(b.Ones)/5) * (unit + 3)
+	width = math.Max(width+pad, 120)
+
+	c := newCanvas(width, block+2*pad)
+	x := pad
+	for i := 0; i < b.Hundreds; i++ {
+		c.rect(x, pad, block, block, strokeColor, shadeColor, 1.5)
+		for k := 1; k < 10; k++ {
+			c.line(point{x + float64(k)*unit, pad}, point{x + float64(k)*unit, pad + block}, strokeColor, 0.6, "")
+			c.line(point{x, pad + float64(k)*unit}, point{x + block, pad + float64(k)*unit}, strokeColor, 0.6, "")
+		}
+		x += block + gap
+	}
+	for i := 0; i < b.Tens; i++ {
+		c.rect(x, pad, unit, block, strokeColor, "#fed7aa", 1.5)
+		for k := 1; k < 10; k++ {
+			c.line(point{x, pad + float64(k)*unit}, point{x + unit, pad + float64(k)*unit}, strokeColor, 0.6, "")
+		}
+		x += unit + gap/2
+	}
+	if b.Tens > 0 {
+		x += gap
+	}
+	// Ones stacked in columns of five from the bottom
+	for i := 0; i < b.Ones; i++ {
+		col, row := i/5, i%5
+		c.rect(x+float64(col)*(unit+3), pad+block-float64(row+1)*(unit+3)+3, unit, unit, strokeColor, "#fde68a", 1.2)
+	}
+	return c.String(), nil
+}
+
+type coin struct {
+	label  string
+	radius float64
+	fill   string
+}
+
+var usCoins = map[string]coin{
+	"penny":       {"1¢", 15, "#d97706"},
+	"nickel":      {"5¢", 18, "#cbd5e1"},
+	"dime":        {"10¢", 14, "#e2e8f0"},
+	"quarter":     {"25¢", 21, "#cbd5e1"},
+	"half_dollar": {"50¢", 25, "#e2e8f0"},
+	"dollar_coin": {"$1", 22, "#fbbf24"},
+}
+
+func renderCoins(set *models.CoinSet) (string, error) {
+	if len(set.Coins) == 0 || len(set.Coins) > maxCoins {
+		return "", fmt.Errorf("coin diagram needs between 1 and %d coins", maxCoins)
+	}
+	coins := make([]coin, len(set.Coins))
+	for i, name := range set.Coins {
+		cn, ok := usCoins[strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))]
+		if !ok {
+			return "", fmt.Errorf("unknown coin %q (use penny, nickel, dime, quarter, half_dollar or dollar_coin)", name)
+		}
+		coins[i] = cn
+	}
+
+	const perRow, cell, pad = 6, 60.0, 10.0
+	rows := (len(coins) + perRow - 1) / perRow
+	cols := int(math.Min(float64(len(coins)), perRow))
+	c := newCanvas(float64(cols)*cell+2*pad, float64(rows)*cell+2*pad)
+	for i, cn := range coins {
+		center := point{pad + cell/2 + float64(i%perRow)*cell, pad + cell/2 + float64(i/perRow)*cell}
+		c.circle(center, cn.radius, "#64748b", cn.fill, 2)
+		c.circle(center, cn.radius-3, "#94a3b8", "none", 0.8)
+		c.text(center, cn.label, 11, textColor, "middle", true)
+	}
+	return c.String(), nil
+}
+
+func renderTally(t *models.Tally) (string, error) {
+	if len(t.Rows) == 0 || len(t.Rows) > maxTallyRows {
+		return "", fmt.Errorf("tally chart needs between 1 and %d rows", maxTallyRows)
+	}
+	maxCount := 0
+	for _, row := range t.Rows {
+		if strings.TrimSpace(row.Label) == "" {
+			return "", fmt.Errorf("every tally row needs a label")
+		}
+		if row.Count < 0 || row.Count > maxTallyCount {
+			return "", fmt.Errorf("tally counts must be between 0 and %d", maxTallyCount)
+		}
+		maxCount = int(math.Max(float64(maxCount), float64(row.Count)))
+	}
+
+	const labelW, rowH, stroke, groupGap, pad = 110.0, 36.0, 7.0, 14.0, 10.0
+	groups := (maxCount + 4) / 5
+	width := labelW + float64(groups)*(5*stroke+groupGap) + 2*pad
+	width = math.Max(width, 220)
+	c := newCanvas(width, float64(len(t.Rows))*rowH+2*pad)
+
+	for i, row := range t.Rows {
+		y := pad + float64(i)*rowH
+		c.rect(pad, y, width-2*pad, rowH, "#cbd5e1", "none", 1)
+		c.line(point{pad + labelW, y}, point{pad + labelW, y + rowH}, "#cbd5e1", 1, "")
+		c.text(point{pad + 8, y + rowH/2}, row.Label, 13, textColor, "start", false)
+
+		x := pad + labelW + 10
+		for n := 0; n < row.Count; n++ {
+			if n%5 == 4 {
+				// The fifth mark crosses the group of four
+				groupX := x - 4*stroke
+				c.line(point{groupX - 3, y + rowH - 10}, point{x - stroke + 3, y + 10}, textColor, 2, "")
+				x += groupGap
+				continue
+			}
+			c.line(point{x, y + 8}, point{x, y + rowH - 8}, textColor, 2, "")
+			x += stroke
+		}
+	}
+	return c.String(), nil
+}
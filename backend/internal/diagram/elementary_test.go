@@ -0,0 +1,68 @@
+package diagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestRenderElementary(t *testing.T) {
+	tests := []struct {
+		name    string
+		diagram models.Diagram
+	}{
+		{"number line", models.Diagram{Type: models.DiagramNumberLine, NumberLine: &models.NumberLine{
+			Min: 0, Max: 20, Step: 2,
+			Points: []models.NumberLinePoint{{Value: 6, Label: "A"}},
+			Jumps:  []models.NumberLineJump{{From: 6, To: 10, Label: "+4"}},
+		}}},
+		{"fraction bar", models.Diagram{Type: models.DiagramFraction, Fraction: &models.FractionModel{Numerator: 3, Denominator: 4}}},
+		{"improper fraction circle", models.Diagram{Type: models.DiagramFraction, Fraction: &models.FractionModel{Numerator: 7, Denominator: 4, Style: "circle"}}},
+		{"clock", models.Diagram{Type: models.DiagramClock, Clock: &models.Clock{Hour: 3, Minute: 30}}},
+		{"base ten", models.Diagram{Type: models.DiagramBaseTen, BaseTen: &models.BaseTen{Hundreds: 1, Tens: 4, Ones: 7}}},
+		{"coins", models.Diagram{Type: models.DiagramCoins, Coins: &models.CoinSet{Coins: []string{"quarter", "dime", "penny"}}}},
+		{"tally", models.Diagram{Type: models.DiagramTally, Tally: &models.Tally{Rows: []models.TallyRow{{Label: "Apples", Count: 7}, {Label: "Pears", Count: 0}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg, err := Render(&tt.diagram)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(strings.TrimSpace(svg), "</svg>") {
+				t.Errorf("not an SVG document: %.60s", svg)
+			}
+		})
+	}
+}
+
+func TestRenderElementaryRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		diagram models.Diagram
+		want    string
+	}{
+		{"number line range", models.Diagram{Type: models.DiagramNumberLine, NumberLine: &models.NumberLine{Min: 5, Max: 1}}, "min < max"},
+		{"number line ticks", models.Diagram{Type: models.DiagramNumberLine, NumberLine: &models.NumberLine{Min: 0, Max: 100, Step: 1}}, "more than 40 ticks"},
+		{"point off the line", models.Diagram{Type: models.DiagramNumberLine, NumberLine: &models.NumberLine{Min: 0, Max: 10, Points: []models.NumberLinePoint{{Value: 11}}}}, "outside the number line"},
+		{"jump off the line", models.Diagram{Type: models.DiagramNumberLine, NumberLine: &models.NumberLine{Min: 0, Max: 10, Jumps: []models.NumberLineJump{{From: 8, To: 12}}}}, "does not fit"},
+		{"circle denominator", models.Diagram{Type: models.DiagramFraction, Fraction: &models.FractionModel{Numerator: 1, Denominator: 16, Style: "circle"}}, "denominator from 1 to 12"},
+		{"fraction style", models.Diagram{Type: models.DiagramFraction, Fraction: &models.FractionModel{Numerator: 1, Denominator: 2, Style: "pie"}}, "bar or circle"},
+		{"too many wholes", models.Diagram{Type: models.DiagramFraction, Fraction: &models.FractionModel{Numerator: 9, Denominator: 2}}, "more than 4 wholes"},
+		{"clock hour", models.Diagram{Type: models.DiagramClock, Clock: &models.Clock{Hour: 13}}, "hour from 1 to 12"},
+		{"empty base ten", models.Diagram{Type: models.DiagramBaseTen, BaseTen: &models.BaseTen{}}, "at least one block"},
+		{"unknown coin", models.Diagram{Type: models.DiagramCoins, Coins: &models.CoinSet{Coins: []string{"euro"}}}, "unknown coin"},
+		{"tally count", models.Diagram{Type: models.DiagramTally, Tally: &models.Tally{Rows: []models.TallyRow{{Label: "A", Count: 51}}}}, "between 0 and 50"},
+		{"tally label", models.Diagram{Type: models.DiagramTally, Tally: &models.Tally{Rows: []models.TallyRow{{Count: 1}}}}, "needs a label"},
+		{"missing spec", models.Diagram{Type: models.DiagramClock}, "needs a \"clock\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(&tt.diagram)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package diagram
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expr is a compiled expression in the single variable x
+type expr func(x float64) float64
+
+var exprFuncs = map[string]func(float64) float64{
+	"sin": math.Sin, "cos": math.Cos, "tan": math.Tan,
+	"asin": math.Asin, "acos": math.Acos, "atan": math.Atan,
+	"sqrt": math.Sqrt, "abs": math.Abs, "exp": math.Exp,
+	"ln": math.Log, "log": math.Log10,
+}
+
+var exprConsts = map[string]float64{"pi": math.Pi, "e": math.E}
+
+// parseExpr compiles an expression such as "y = 2x^2 - 3x + 1" or "sin(2x)".
+// It supports + - * / ^, parentheses, implicit multiplication, pi, e and the
+// functions in exprFuncs.
+func parseExpr(s string) (expr, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "="); i >= 0 {
+		lhs := strings.ToLower(strings.TrimSpace(s[:i]))
+		if lhs != "y" && lhs != "f(x)" {
+			return nil, fmt.Errorf("expression %q must be of the form y = ...", s)
+		}
+		s = s[i+1:]
+	}
+
+	p := &exprParser{src: strings.NewReplacer("·", "*", "×", "*", "−", "-", "**", "^", "π", "pi").Replace(s)}
+	p.next()
+	e, err := p.sum()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read expression %q: %w", s, err)
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("cannot read expression %q: unexpected %q", s, p.tok)
+	}
+	return e, nil
+}
+
+type exprParser struct {
+	src string
+	pos int
+	tok string
+}
+
+// next advances to the next token: a number, a name, or a single symbol
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	start := p.pos
+	r := rune(p.src[p.pos])
+	switch {
+	case unicode.IsDigit(r) || r == '.':
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	case unicode.IsLetter(r):
+		for p.pos < len(p.src) && unicode.IsLetter(rune(p.src[p.pos])) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func (p *exprParser) sum() (expr, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok
+		p.next()
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "+" {
+			left = func(x float64) float64 { return l(x) + right(x) }
+		} else {
+			left = func(x float64) float64 { return l(x) - right(x) }
+		}
+	}
+	return left, nil
+}
+
+// product handles *, / and implicit multiplication such as 2x or 3(x+1)
+func (p *exprParser) product() (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.tok
+		switch {
+		case op == "*" || op == "/":
+			p.next()
+		case op == "(" || (op != "" && (unicode.IsLetter(rune(op[0])) || unicode.IsDigit(rune(op[0])))):
+			op = "*"
+		default:
+			return left, nil
+		}
+
+		right, err := p.power()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "*" {
+			left = func(x float64) float64 { return l(x) * right(x) }
+		} else {
+			left = func(x float64) float64 { return l(x) / right(x) }
+		}
+	}
+}
+
+func (p *exprParser) unary() (expr, error) {
+	if p.tok == "-" {
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(x float64) float64 { return -operand(x) }, nil
+	}
+	if p.tok == "+" {
+		p.next()
+	}
+	return p.power()
+}
+
+// power is right-associative and binds tighter than unary minus on its left: -x^2 = -(x^2)
+func (p *exprParser) power() (expr, error) {
+	base, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "^" {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(x float64) float64 { return math.Pow(base(x), exponent(x)) }, nil
+}
+
+func (p *exprParser) atom() (expr, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end")
+	case tok == "(":
+		p.next()
+		inner, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.next()
+		return inner, nil
+	case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", tok)
+		}
+		p.next()
+		return func(float64) float64 { return v }, nil
+	}
+
+	if !unicode.IsLetter(rune(tok[0])) {
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+
+	name := strings.ToLower(tok)
+	if name == "x" {
+		p.next()
+		return func(x float64) float64 { return x }, nil
+	}
+	if v, ok := exprConsts[name]; ok {
+		p.next()
+		return func(float64) float64 { return v }, nil
+	}
+	if fn, ok := exprFuncs[name]; ok {
+		p.next()
+		// sin(x)^2 squares the sine; sin 2x without parentheses reads as sin(2)·x
+		var arg expr
+		var err error
+		if p.tok == "(" {
+			arg, err = p.atom()
+		} else {
+			arg, err = p.power()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(x float64) float64 { return fn(arg(x)) }, nil
+	}
+
+	return nil, fmt.Errorf("unknown name %q", tok)
+}
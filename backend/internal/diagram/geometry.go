@@ -0,0 +1,388 @@
+package diagram
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+const (
+	shapeWidth  = 260
+	shapeHeight = 220
+	shapePad    = 36
+
+	// angleTolerance is how far (in degrees) over-specified measurements may disagree
+	angleTolerance = 1.0
+	// sideTolerance is the relative disagreement allowed between over-specified sides
+	sideTolerance = 0.02
+)
+
+func renderShape(kind string, s *models.Shape) (string, error) {
+	for _, v := range append(append([]float64{}, s.Sides...), s.Angles...) {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("measurements must be positive numbers")
+		}
+	}
+
+	switch kind {
+	case models.DiagramTriangle:
+		return renderTriangle(s)
+	case models.DiagramRectangle:
+		return renderRectangle(s)
+	case models.DiagramRegularPolygon:
+		return renderRegularPolygon(s)
+	default:
+		return renderCircle(s)
+	}
+}
+
+// triangle holds a fully solved triangle; given marks the measurements that came from the spec
+type triangle struct {
+	sides, angles         [3]float64
+	sideGiven, angleGiven [3]bool
+	scaled                bool // false when only angles were given, so side lengths are arbitrary
+}
+
+// solveTriangle fills in the missing sides and angles of a triangle spec,
+// rejecting measurements that no triangle can have
+func solveTriangle(s *models.Shape, labels [3]string) (*triangle, error) {
+	if len(s.Sides) > 3 || len(s.Angles) > 3 {
+		return nil, fmt.Errorf("a triangle has 3 sides and 3 angles")
+	}
+
+	t := &triangle{scaled: true}
+	for i, v := range s.Sides {
+		t.sides[i], t.sideGiven[i] = v, v > 0
+	}
+	for i, v := range s.Angles {
+		t.angles[i], t.angleGiven[i] = v, v > 0
+	}
+
+	if s.RightAngle != "" {
+		r := vertexIndex(labels[:], s.RightAngle)
+		if r < 0 {
+			return nil, fmt.Errorf("right_angle %q is not one of the vertices %s", s.RightAngle, strings.Join(labels[:], ", "))
+		}
+		if t.angleGiven[r] && math.Abs(t.angles[r]-90) > angleTolerance {
+			return nil, fmt.Errorf("angle %s is marked as a right angle but given as %s°", labels[r], measure(t.angles[r], ""))
+		}
+		t.angles[r], t.angleGiven[r] = 90, true
+	}
+
+	known := func(v [3]float64) (n int) {
+		for _, x := range v {
+			if x > 0 {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Two angles determine the third
+	if known(t.angles) == 2 {
+		for i := range t.angles {
+			if t.angles[i] == 0 {
+				t.angles[i] = 180 - t.angles[(i+1)%3] - t.angles[(i+2)%3]
+				if t.angles[i] <= 0 {
+					return nil, fmt.Errorf("the angles add up to more than 180°")
+				}
+			}
+		}
+	}
+	if known(t.angles) == 3 {
+		if sum := t.angles[0] + t.angles[1] + t.angles[2]; math.Abs(sum-180) > angleTolerance {
+			return nil, fmt.Errorf("the angles add up to %s°, not 180°", measure(sum, ""))
+		}
+	}
+
+	// Two sides: use the included angle (law of cosines) or an opposite angle (law of sines)
+	if known(t.sides) == 2 && known(t.angles) < 3 {
+		k := unknownIndex(t.sides)
+		i, j := (k+1)%3, (k+2)%3
+		switch {
+		case t.angles[k] > 0:
+			t.sides[k] = math.Sqrt(t.sides[i]*t.sides[i] + t.sides[j]*t.sides[j] -
+				2*t.sides[i]*t.sides[j]*math.Cos(rad(t.angles[k])))
+		case t.angles[i] > 0 || t.angles[j] > 0:
+			if t.angles[j] > 0 {
+				i, j = j, i
+			}
+			// angle i is known and faces known side i; solve angle j from side j
+			sinJ := t.sides[j] * math.Sin(rad(t.angles[i])) / t.sides[i]
+			if sinJ > 1+1e-9 {
+				return nil, fmt.Errorf("no triangle has angle %s = %s° with these side lengths", labels[i], measure(t.angles[i], ""))
+			}
+			t.angles[j] = deg(math.Asin(math.Min(sinJ, 1)))
+			t.angles[k] = 180 - t.angles[i] - t.angles[j]
+			if t.angles[k] <= 0 {
+				return nil, fmt.Errorf("no triangle has angle %s = %s° with these side lengths", labels[i], measure(t.angles[i], ""))
+			}
+		default:
+			return nil, errUnderdetermined
+		}
+	}
+
+	switch {
+	case known(t.sides) == 3:
+		a, b, c := t.sides[0], t.sides[1], t.sides[2]
+		if a+b <= c || a+c <= b || b+c <= a {
+			return nil, fmt.Errorf("sides %s, %s and %s break the triangle inequality", measure(a, ""), measure(b, ""), measure(c, ""))
+		}
+		for i := range t.sides {
+			x, y, z := t.sides[i], t.sides[(i+1)%3], t.sides[(i+2)%3]
+			angle := deg(math.Acos(clamp((y*y + z*z - x*x) / (2 * y * z))))
+			if t.angles[i] > 0 && math.Abs(t.angles[i]-angle) > angleTolerance {
+				return nil, fmt.Errorf("angle %s is given as %s° but the side lengths make it %s°",
+					labels[i], measure(t.angles[i], ""), measure(angle, ""))
+			}
+			t.angles[i] = angle
+		}
+	case known(t.angles) == 3:
+		ratio := 0.0
+		for i := range t.sides {
+			if t.sides[i] > 0 {
+				r := t.sides[i] / math.Sin(rad(t.angles[i]))
+				if ratio > 0 && math.Abs(r-ratio)/ratio > sideTolerance {
+					return nil, fmt.Errorf("side %s does not match the given angles", labels[i])
+				}
+				if ratio == 0 {
+					ratio = r
+				}
+			}
+		}
+		if ratio == 0 {
+			ratio, t.scaled = 1, false
+		}
+		for i := range t.sides {
+			t.sides[i] = ratio * math.Sin(rad(t.angles[i]))
+		}
+	default:
+		return nil, errUnderdetermined
+	}
+
+	return t, nil
+}
+
+var errUnderdetermined = fmt.Errorf("not enough measurements to draw the triangle (give three sides, two sides and an angle, or two angles and a side)")
+
+func renderTriangle(s *models.Shape) (string, error) {
+	labels := [3]string{"A", "B", "C"}
+	for i := 0; i < 3 && i < len(s.Labels); i++ {
+		if s.Labels[i] != "" {
+			labels[i] = s.Labels[i]
+		}
+	}
+
+	t, err := solveTriangle(s, labels)
+	if err != nil {
+		return "", err
+	}
+
+	// B at the origin, C along the x axis, A above
+	a, c := t.sides[0], t.sides[2]
+	angleB := rad(t.angles[1])
+	world := []point{{c * math.Cos(angleB), c * math.Sin(angleB)}, {0, 0}, {a, 0}}
+
+	sideText := make([]string, 3)
+	for i := range sideText {
+		if t.sideGiven[i] && t.scaled {
+			sideText[i] = measure(t.sides[i], s.Unit)
+		}
+	}
+	angleText := make([]string, 3)
+	for i := range angleText {
+		if t.angleGiven[i] {
+			angleText[i] = measure(t.angles[i], "") + "°"
+		}
+	}
+
+	c2 := newCanvas(shapeWidth, shapeHeight)
+	drawPolygon(c2, world, labels[:], sideOpposite(sideText), sideOpposite(overrides(s.SideLabels, 3)),
+		angleText, overrides(s.AngleLabels, 3), vertexIndex(labels[:], s.RightAngle))
+	return c2.String(), nil
+}
+
+// sideOpposite reorders triangle side labels [a, b, c] into edge order
+// [AB, BC, CA] as used by drawPolygon
+func sideOpposite(sides []string) []string {
+	return []string{sides[2], sides[0], sides[1]}
+}
+
+func renderRectangle(s *models.Shape) (string, error) {
+	if len(s.Sides) != 2 || s.Sides[0] <= 0 || s.Sides[1] <= 0 {
+		return "", fmt.Errorf("rectangle needs sides [width, height]")
+	}
+	w, h := s.Sides[0], s.Sides[1]
+	world := []point{{0, h}, {0, 0}, {w, 0}, {w, h}}
+
+	custom := overrides(s.SideLabels, 2)
+	sides := []string{"", measure(w, s.Unit), measure(h, s.Unit), ""}
+	overridden := []string{"", custom[0], custom[1], ""}
+
+	c := newCanvas(shapeWidth, shapeHeight)
+	drawPolygon(c, world, overrides(s.Labels, 4), sides, overridden, nil, nil, -1)
+	tr := fit(world, shapeWidth, shapeHeight, shapePad)
+	for i := range world {
+		drawRightAngle(c, tr(world[i]), tr(world[(i+3)%4]), tr(world[(i+1)%4]))
+	}
+	return c.String(), nil
+}
+
+func renderRegularPolygon(s *models.Shape) (string, error) {
+	n := s.NumSides
+	if n < 3 || n > 12 {
+		return "", fmt.Errorf("regular_polygon needs num_sides between 3 and 12")
+	}
+	if len(s.Sides) != 1 || s.Sides[0] <= 0 {
+		return "", fmt.Errorf("regular_polygon needs sides [side length]")
+	}
+
+	// Vertices on the circumcircle, rotated so the first edge is horizontal at the bottom
+	world := make([]point, n)
+	step := 2 * math.Pi / float64(n)
+	for i := range world {
+		theta := -math.Pi/2 - step/2 + float64(i)*step
+		world[i] = point{math.Cos(theta), math.Sin(theta)}
+	}
+
+	sides := make([]string, n)
+	sides[0] = measure(s.Sides[0], s.Unit)
+	custom := make([]string, n)
+	custom[0] = overrides(s.SideLabels, 1)[0]
+
+	c := newCanvas(shapeWidth, shapeHeight)
+	drawPolygon(c, world, overrides(s.Labels, n), sides, custom, nil, nil, -1)
+	return c.String(), nil
+}
+
+func renderCircle(s *models.Shape) (string, error) {
+	if s.Radius <= 0 {
+		return "", fmt.Errorf("circle needs a positive radius")
+	}
+
+	const r = 70
+	center := point{shapeWidth / 2, shapeHeight / 2}
+	c := newCanvas(shapeWidth, shapeHeight)
+	c.circle(center, r, strokeColor, "none", 2.5)
+	c.circle(center, 3, strokeColor, strokeColor, 1)
+
+	centerLabel := "O"
+	if len(s.Labels) > 0 && s.Labels[0] != "" {
+		centerLabel = s.Labels[0]
+	}
+
+	label := overrides(s.SideLabels, 1)[0]
+	if s.ShowDiameter {
+		c.line(center.add(point{-r, 0}), center.add(point{r, 0}), accentColor, 2, "5,3")
+		if label == "" {
+			label = "d = " + measure(2*s.Radius, s.Unit)
+		}
+		c.text(center.add(point{0, -12}), label, 13, accentColor, "middle", true)
+		c.text(center.add(point{0, 14}), centerLabel, 12, textColor, "middle", false)
+	} else {
+		c.line(center, center.add(point{r, 0}), accentColor, 2, "5,3")
+		if label == "" {
+			label = "r = " + measure(s.Radius, s.Unit)
+		}
+		c.text(center.add(point{r / 2, -12}), label, 13, accentColor, "middle", true)
+		c.text(center.add(point{-10, -10}), centerLabel, 12, textColor, "middle", false)
+	}
+	return c.String(), nil
+}
+
+// drawPolygon draws a closed figure scaled to the canvas. Edge i joins vertex i
+// and vertex i+1; a custom side label wins over the measured one. right is the
+// index of a vertex to mark with a right-angle box, or -1.
+func drawPolygon(c *canvas, world []point, labels, sides, custom, angles, angleCustom []string, right int) {
+	tr := fit(world, c.width, c.height, shapePad)
+	pts := make([]point, len(world))
+	var centroid point
+	for i, p := range world {
+		pts[i] = tr(p)
+		centroid = centroid.add(pts[i])
+	}
+	centroid = centroid.scale(1 / float64(len(pts)))
+
+	c.polygon(pts, strokeColor, "none", 2.5)
+
+	for i := range pts {
+		if i == right {
+			drawRightAngle(c, pts[i], pts[(i+len(pts)-1)%len(pts)], pts[(i+1)%len(pts)])
+		} else if text := pick(angleCustom, angles, i); text != "" {
+			drawAngle(c, pts[i], pts[(i+len(pts)-1)%len(pts)], pts[(i+1)%len(pts)], text)
+		}
+
+		if i < len(labels) && labels[i] != "" {
+			out := pts[i].sub(centroid).unit().scale(16)
+			c.text(pts[i].add(out), labels[i], 14, textColor, "middle", true)
+		}
+
+		if text := pick(custom, sides, i); text != "" {
+			mid := midpoint(pts[i], pts[(i+1)%len(pts)])
+			out := mid.sub(centroid).unit().scale(16)
+			c.text(mid.add(out), text, 13, labelColor, "middle", false)
+		}
+	}
+}
+
+// drawAngle marks the angle at vertex v between rays towards p and q with an arc and label
+func drawAngle(c *canvas, v, p, q point, label string) {
+	const r = 20
+	u1, u2 := p.sub(v).unit(), q.sub(v).unit()
+	start, end := v.add(u1.scale(r)), v.add(u2.scale(r))
+	sweep := 0
+	if u1.cross(u2) > 0 {
+		sweep = 1
+	}
+	c.path(fmt.Sprintf("M %s %s A %d %d 0 0 %d %s %s", num(start.X), num(start.Y), r, r, sweep, num(end.X), num(end.Y)),
+		accentColor, "none", 1.5)
+	c.text(v.add(u1.add(u2).unit().scale(r+14)), label, 11, accentColor, "middle", false)
+}
+
+// drawRightAngle marks vertex v with a small square between rays towards p and q
+func drawRightAngle(c *canvas, v, p, q point) {
+	const size = 12
+	u1, u2 := p.sub(v).unit().scale(size), q.sub(v).unit().scale(size)
+	c.polyline([]point{v.add(u1), v.add(u1).add(u2), v.add(u2)}, accentColor, 1.5)
+}
+
+// overrides returns exactly n labels, padding with "" when fewer are given
+func overrides(labels []string, n int) []string {
+	out := make([]string, n)
+	copy(out, labels)
+	return out
+}
+
+func pick(first, second []string, i int) string {
+	if i < len(first) && first[i] != "" {
+		return first[i]
+	}
+	if i < len(second) {
+		return second[i]
+	}
+	return ""
+}
+
+func vertexIndex(labels []string, name string) int {
+	for i, l := range labels {
+		if name != "" && strings.EqualFold(l, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func unknownIndex(v [3]float64) int {
+	for i, x := range v {
+		if x == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func clamp(x float64) float64 { return math.Max(-1, math.Min(1, x)) }
+func rad(d float64) float64   { return d * math.Pi / 180 }
+func deg(r float64) float64   { return r * 180 / math.Pi }
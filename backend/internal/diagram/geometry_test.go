@@ -0,0 +1,101 @@
+package diagram
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestSolveTriangle(t *testing.T) {
+	labels := [3]string{"A", "B", "C"}
+	tests := []struct {
+		name   string
+		shape  models.Shape
+		sides  [3]float64
+		angles [3]float64
+	}{
+		{name: "three sides", shape: models.Shape{Sides: []float64{3, 4, 5}}, sides: [3]float64{3, 4, 5}, angles: [3]float64{36.87, 53.13, 90}},
+		{name: "right angle and two legs", shape: models.Shape{Sides: []float64{3, 4}, RightAngle: "C"}, sides: [3]float64{3, 4, 5}, angles: [3]float64{36.87, 53.13, 90}},
+		{name: "two sides and included angle", shape: models.Shape{Sides: []float64{5, 5}, Angles: []float64{0, 0, 60}}, sides: [3]float64{5, 5, 5}, angles: [3]float64{60, 60, 60}},
+		{name: "two angles and a side", shape: models.Shape{Sides: []float64{0, 0, 10}, Angles: []float64{45, 45}}, sides: [3]float64{7.071, 7.071, 10}, angles: [3]float64{45, 45, 90}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tri, err := solveTriangle(&tt.shape, labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tri.sides {
+				if math.Abs(tri.sides[i]-tt.sides[i]) > 0.01 || math.Abs(tri.angles[i]-tt.angles[i]) > 0.01 {
+					t.Fatalf("sides %v angles %v, want %v %v", tri.sides, tri.angles, tt.sides, tt.angles)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveTriangleRejects(t *testing.T) {
+	labels := [3]string{"A", "B", "C"}
+	tests := []struct {
+		name  string
+		shape models.Shape
+		want  string
+	}{
+		{name: "triangle inequality", shape: models.Shape{Sides: []float64{1, 2, 5}}, want: "triangle inequality"},
+		{name: "angles over 180", shape: models.Shape{Sides: []float64{4}, Angles: []float64{100, 90}}, want: "more than 180"},
+		{name: "angles under 180", shape: models.Shape{Sides: []float64{4}, Angles: []float64{50, 50, 50}}, want: "not 180"},
+		{name: "right angle conflict", shape: models.Shape{Sides: []float64{3, 4}, Angles: []float64{0, 0, 80}, RightAngle: "C"}, want: "marked as a right angle"},
+		{name: "sides contradict angle", shape: models.Shape{Sides: []float64{3, 4, 5}, Angles: []float64{0, 0, 80}}, want: "side lengths make it"},
+		{name: "underdetermined", shape: models.Shape{Sides: []float64{4}}, want: "not enough measurements"},
+		{name: "unknown vertex", shape: models.Shape{Sides: []float64{3, 4}, RightAngle: "Z"}, want: "not one of the vertices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := solveTriangle(&tt.shape, labels)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderShapes(t *testing.T) {
+	tests := []struct {
+		diagram models.Diagram
+		want    []string // text that must appear in the SVG
+	}{
+		{models.Diagram{Type: models.DiagramTriangle, Shape: &models.Shape{Sides: []float64{3, 4, 5}, Unit: "cm", SideLabels: []string{"", "", "x"}}}, []string{"3 cm", "4 cm", ">x<"}},
+		{models.Diagram{Type: models.DiagramRectangle, Shape: &models.Shape{Sides: []float64{6, 2}, Unit: "m"}}, []string{"6 m", "2 m"}},
+		{models.Diagram{Type: models.DiagramCircle, Shape: &models.Shape{Radius: 5, Unit: "cm"}}, []string{"5 cm"}},
+		{models.Diagram{Type: models.DiagramRegularPolygon, Shape: &models.Shape{NumSides: 6, Sides: []float64{4}, Unit: "cm"}}, []string{"4 cm"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.diagram.Type, func(t *testing.T) {
+			svg, err := Render(&tt.diagram)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(svg, "<svg") {
+				t.Errorf("not an SVG document: %.60s", svg)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(svg, want) {
+					t.Errorf("SVG is missing %q", want)
+				}
+			}
+		})
+	}
+
+	for _, bad := range []models.Diagram{
+		{Type: models.DiagramRectangle, Shape: &models.Shape{Sides: []float64{-1, 2}}},
+		{Type: models.DiagramTriangle},
+		{Type: "hexagon-ish"},
+		{},
+	} {
+		if _, err := Render(&bad); err == nil {
+			t.Errorf("Render(%+v) accepted an invalid spec", bad)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package diagram
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+const (
+	graphSize     = 320
+	graphLeft     = 36
+	graphRight    = 18
+	graphTop      = 18
+	graphBottom   = 30
+	graphSamples  = 480
+	maxGraphTicks = 50
+	maxFunctions  = 5
+	maxPoints     = 20
+
+	gridColor = "#e2e8f0"
+)
+
+var functionColors = []string{strokeColor, accentColor, "#6366f1", "#db2777", "#65a30d"}
+
+func renderGraph(g *models.Graph) (string, error) {
+	if g.XMin == 0 && g.XMax == 0 {
+		g.XMin, g.XMax = -10, 10
+	}
+	if g.YMin == 0 && g.YMax == 0 {
+		g.YMin, g.YMax = -10, 10
+	}
+	for _, v := range []float64{g.XMin, g.XMax, g.YMin, g.YMax, g.XStep, g.YStep} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("graph ranges must be finite numbers")
+		}
+	}
+	if g.XMin >= g.XMax || g.YMin >= g.YMax {
+		return "", fmt.Errorf("graph ranges need x_min < x_max and y_min < y_max")
+	}
+	if g.XStep < 0 || g.YStep < 0 {
+		return "", fmt.Errorf("graph steps must be positive")
+	}
+	if g.XStep == 0 {
+		g.XStep = niceStep(g.XMax - g.XMin)
+	}
+	if g.YStep == 0 {
+		g.YStep = niceStep(g.YMax - g.YMin)
+	}
+	if (g.XMax-g.XMin)/g.XStep > maxGraphTicks || (g.YMax-g.YMin)/g.YStep > maxGraphTicks {
+		return "", fmt.Errorf("tick spacing is too small for the range (at most %d ticks per axis)", maxGraphTicks)
+	}
+	if len(g.Functions) > maxFunctions {
+		return "", fmt.Errorf("graph can show at most %d functions", maxFunctions)
+	}
+	if len(g.Points) > maxPoints {
+		return "", fmt.Errorf("graph can show at most %d points", maxPoints)
+	}
+
+	fns := make([]expr, len(g.Functions))
+	for i, f := range g.Functions {
+		fn, err := parseExpr(f.Expression)
+		if err != nil {
+			return "", err
+		}
+		if len(f.Domain) != 0 && (len(f.Domain) != 2 || f.Domain[0] >= f.Domain[1]) {
+			return "", fmt.Errorf("domain of %q must be [from, to]", f.Expression)
+		}
+		fns[i] = fn
+	}
+	for _, pt := range g.Points {
+		if pt.X < g.XMin || pt.X > g.XMax || pt.Y < g.YMin || pt.Y > g.YMax {
+			return "", fmt.Errorf("point (%s, %s) is outside the graph", measure(pt.X, ""), measure(pt.Y, ""))
+		}
+	}
+
+	plotW := float64(graphSize - graphLeft - graphRight)
+	plotH := float64(graphSize - graphTop - graphBottom)
+	px := func(x float64) float64 { return graphLeft + (x-g.XMin)/(g.XMax-g.XMin)*plotW }
+	py := func(y float64) float64 { return graphTop + (g.YMax-y)/(g.YMax-g.YMin)*plotH }
+	tr := func(x, y float64) point { return point{px(x), py(y)} }
+
+	c := newCanvas(graphSize, graphSize)
+	c.raw(fmt.Sprintf(`<defs><clipPath id="plot"><rect x="%s" y="%s" width="%s" height="%s"/></clipPath></defs>`,
+		num(graphLeft), num(graphTop), num(plotW), num(plotH)))
+
+	// Grid
+	for _, x := range ticks(g.XMin, g.XMax, g.XStep) {
+		c.line(tr(x, g.YMin), tr(x, g.YMax), gridColor, 1, "")
+	}
+	for _, y := range ticks(g.YMin, g.YMax, g.YStep) {
+		c.line(tr(g.XMin, y), tr(g.XMax, y), gridColor, 1, "")
+	}
+
+	// Axes sit at zero when it is in range, otherwise along the edge
+	axisY := math.Max(g.YMin, math.Min(g.YMax, 0))
+	axisX := math.Max(g.XMin, math.Min(g.XMax, 0))
+	c.line(tr(g.XMin, axisY), tr(g.XMax, axisY), textColor, 1.5, "")
+	c.line(tr(axisX, g.YMin), tr(axisX, g.YMax), textColor, 1.5, "")
+
+	xTicks := ticks(g.XMin, g.XMax, g.XStep)
+	every := labelEvery(len(xTicks))
+	for i, x := range xTicks {
+		if i%every != 0 || x == axisX {
+			continue
+		}
+		p := tr(x, axisY)
+		c.line(p.add(point{0, -3}), p.add(point{0, 3}), textColor, 1, "")
+		c.text(p.add(point{0, 12}), tickLabel(x, g.XStep), 10, mutedColor, "middle", false)
+	}
+	yTicks := ticks(g.YMin, g.YMax, g.YStep)
+	every = labelEvery(len(yTicks))
+	for i, y := range yTicks {
+		if i%every != 0 || y == axisY {
+			continue
+		}
+		p := tr(axisX, y)
+		c.line(p.add(point{-3, 0}), p.add(point{3, 0}), textColor, 1, "")
+		c.text(p.add(point{-6, 0}), tickLabel(y, g.YStep), 10, mutedColor, "end", false)
+	}
+	if axisX == 0 && axisY == 0 {
+		c.text(tr(0, 0).add(point{-6, 10}), "0", 10, mutedColor, "end", false)
+	}
+
+	xLabel, yLabel := g.XLabel, g.YLabel
+	if xLabel == "" {
+		xLabel = "x"
+	}
+	if yLabel == "" {
+		yLabel = "y"
+	}
+	c.text(tr(g.XMax, axisY).add(point{-4, -10}), xLabel, 12, textColor, "end", true)
+	c.text(tr(axisX, g.YMax).add(point{8, 6}), yLabel, 12, textColor, "start", true)
+
+	// Functions, clipped to the plot area
+	c.raw(`<g clip-path="url(#plot)">`)
+	var labels []func()
+	for i, f := range g.Functions {
+		color := functionColors[i%len(functionColors)]
+		from, to := g.XMin, g.XMax
+		if len(f.Domain) == 2 {
+			from, to = math.Max(from, f.Domain[0]), math.Min(to, f.Domain[1])
+		}
+
+		d, last, visible := plotPath(fns[i], from, to, g.YMin, g.YMax, tr)
+		if !visible {
+			return "", fmt.Errorf("%q does not appear within the graph window", f.Expression)
+		}
+		c.path(d, color, "none", 2.2)
+
+		if f.Label != "" {
+			label, at := f.Label, last
+			labels = append(labels, func() { c.text(at.add(point{-4, -10}), label, 11, color, "end", true) })
+		}
+	}
+	c.raw(`</g>`)
+	for _, draw := range labels {
+		draw()
+	}
+
+	for _, pt := range g.Points {
+		p := tr(pt.X, pt.Y)
+		c.circle(p, 3.5, accentColor, accentColor, 1)
+		if pt.Label != "" {
+			c.text(p.add(point{6, -9}), pt.Label, 11, textColor, "start", true)
+		}
+	}
+
+	return c.String(), nil
+}
+
+// plotPath samples fn between from and to and returns an SVG path, breaking it
+// at undefined values and asymptotes. last is the last sample inside the window.
+func plotPath(fn expr, from, to, yMin, yMax float64, tr func(x, y float64) point) (d string, last point, visible bool) {
+	span := yMax - yMin
+	var sb strings.Builder
+	drawing := false
+	prevY := 0.0
+
+	for i := 0; i <= graphSamples; i++ {
+		x := from + (to-from)*float64(i)/graphSamples
+		y := fn(x)
+
+		ok := !math.IsNaN(y) && !math.IsInf(y, 0) && y > yMin-10*span && y < yMax+10*span
+		// A jump across most of the window with a sign change is an asymptote, not a steep curve
+		if ok && drawing && math.Abs(y-prevY) > 2*span && (y > 0) != (prevY > 0) {
+			drawing = false
+		}
+		if !ok {
+			drawing = false
+			continue
+		}
+
+		p := tr(x, y)
+		if drawing {
+			fmt.Fprintf(&sb, " L %s %s", num(p.X), num(p.Y))
+		} else {
+			fmt.Fprintf(&sb, " M %s %s", num(p.X), num(p.Y))
+		}
+		drawing, prevY = true, y
+
+		if y >= yMin && y <= yMax {
+			last, visible = p, true
+		}
+	}
+	return strings.TrimSpace(sb.String()), last, visible
+}
+
+// niceStep picks a tick spacing of 1, 2 or 5 times a power of ten giving about ten ticks
+func niceStep(span float64) float64 {
+	raw := span / 10
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// ticks returns the multiples of step between min and max
+func ticks(min, max, step float64) []float64 {
+	var out []float64
+	first := math.Ceil(min/step - 1e-9)
+	for i := 0.0; (first+i)*step <= max+step*1e-9; i++ {
+		v := (first + i) * step
+		if math.Abs(v) < step*1e-9 {
+			v = 0
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func labelEvery(n int) int {
+	if n <= 12 {
+		return 1
+	}
+	return (n + 11) / 12
+}
+
+// tickLabel formats a tick value, using multiples of π when the step is one
+func tickLabel(v, step float64) string {
+	halfPi := math.Pi / 2
+	if k := step / halfPi; math.Abs(k-math.Round(k)) < 1e-6 && math.Round(k) >= 1 {
+		n := int(math.Round(v / halfPi))
+		if n == 0 {
+			return "0"
+		}
+		coef, suffix := n, "/2"
+		if n%2 == 0 {
+			coef, suffix = n/2, ""
+		}
+		label := strconv.Itoa(coef) + "π" + suffix
+		switch coef {
+		case 1:
+			label = "π" + suffix
+		case -1:
+			label = "-π" + suffix
+		}
+		return strings.Replace(label, "-", "−", 1)
+	}
+	return strings.Replace(measure(v, ""), "-", "−", 1)
+}
@@ -0,0 +1,82 @@
+package diagram
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		src  string
+		x    float64
+		want float64
+	}{
+		{"2x + 1", 3, 7},
+		{"y = x^2 - 4", 3, 5},
+		{"f(x) = -x^2", 2, -4},
+		{"2^3^2", 0, 512}, // right associative
+		{"3(x + 1)(x - 1)", 2, 9},
+		{"2sin(x)", math.Pi / 2, 2},
+		{"sqrt(abs(x))", -16, 4},
+		{"π x", 2, 2 * math.Pi},
+		{"x ** 2 × 3 − 1", 2, 11},
+		{"ln(e) + log(100)", 0, 3},
+		{"1 / x", 4, 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			fn, err := parseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fn(tt.x); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("f(%v) = %v, want %v", tt.x, got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "2x +", "z = x", "foo(x)", "(x + 1", "x $ 2"} {
+		if _, err := parseExpr(bad); err == nil {
+			t.Errorf("parseExpr(%q) accepted an invalid expression", bad)
+		}
+	}
+}
+
+func TestRenderGraph(t *testing.T) {
+	svg, err := Render(&models.Diagram{Type: models.DiagramGraph, Graph: &models.Graph{
+		XMin: -5, XMax: 5, YMin: -5, YMax: 10,
+		Functions: []models.GraphFunction{{Expression: "x^2 - 4", Label: "A"}},
+		Points:    []models.GraphPoint{{X: 2, Y: 0, Label: "(2, 0)"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<svg", "<path", ">A<", ">(2, 0)<"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG is missing %q", want)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		graph models.Graph
+		want  string
+	}{
+		{"reversed range", models.Graph{XMin: 5, XMax: -5}, "x_min < x_max"},
+		{"too many ticks", models.Graph{XMin: 0, XMax: 1000, XStep: 1}, "tick spacing"},
+		{"point outside", models.Graph{Points: []models.GraphPoint{{X: 20, Y: 0}}}, "outside the graph"},
+		{"bad expression", models.Graph{Functions: []models.GraphFunction{{Expression: "x +"}}}, "cannot read expression"},
+		{"bad domain", models.Graph{Functions: []models.GraphFunction{{Expression: "x", Domain: []float64{3, 1}}}}, "domain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(&models.Diagram{Type: models.DiagramGraph, Graph: &tt.graph})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
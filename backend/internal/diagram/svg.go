@@ -0,0 +1,150 @@
+package diagram
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Colours shared by every diagram, matching the rest of the worksheet styling
+const (
+	strokeColor = "#0d9488"
+	textColor   = "#1e293b"
+	labelColor  = "#0f766e"
+	accentColor = "#f97316"
+	mutedColor  = "#64748b"
+)
+
+type point struct{ X, Y float64 }
+
+func (p point) add(q point) point     { return point{p.X + q.X, p.Y + q.Y} }
+func (p point) sub(q point) point     { return point{p.X - q.X, p.Y - q.Y} }
+func (p point) scale(k float64) point { return point{p.X * k, p.Y * k} }
+func (p point) length() float64       { return math.Hypot(p.X, p.Y) }
+func (p point) cross(q point) float64 { return p.X*q.Y - p.Y*q.X }
+func midpoint(p, q point) point       { return point{(p.X + q.X) / 2, (p.Y + q.Y) / 2} }
+func (p point) unit() point {
+	if l := p.length(); l > 0 {
+		return p.scale(1 / l)
+	}
+	return point{}
+}
+
+// canvas accumulates SVG elements for a fixed-size drawing
+type canvas struct {
+	width, height float64
+	sb            strings.Builder
+}
+
+func newCanvas(width, height float64) *canvas {
+	return &canvas{width: width, height: height}
+}
+
+func (c *canvas) line(a, b point, color string, width float64, dash string) {
+	fmt.Fprintf(&c.sb, `  <line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-width="%s"%s/>`+"\n",
+		num(a.X), num(a.Y), num(b.X), num(b.Y), color, num(width), dashAttr(dash))
+}
+
+func (c *canvas) polygon(pts []point, stroke, fill string, width float64) {
+	coords := make([]string, len(pts))
+	for i, p := range pts {
+		coords[i] = num(p.X) + "," + num(p.Y)
+	}
+	fmt.Fprintf(&c.sb, `  <polygon points="%s" fill="%s" stroke="%s" stroke-width="%s"/>`+"\n",
+		strings.Join(coords, " "), fill, stroke, num(width))
+}
+
+func (c *canvas) polyline(pts []point, stroke string, width float64) {
+	coords := make([]string, len(pts))
+	for i, p := range pts {
+		coords[i] = num(p.X) + "," + num(p.Y)
+	}
+	fmt.Fprintf(&c.sb, `  <polyline points="%s" fill="none" stroke="%s" stroke-width="%s"/>`+"\n",
+		strings.Join(coords, " "), stroke, num(width))
+}
+
+func (c *canvas) rect(x, y, w, h float64, stroke, fill string, width float64) {
+	fmt.Fprintf(&c.sb, `  <rect x="%s" y="%s" width="%s" height="%s" fill="%s" stroke="%s" stroke-width="%s"/>`+"\n",
+		num(x), num(y), num(w), num(h), fill, stroke, num(width))
+}
+
+func (c *canvas) circle(center point, r float64, stroke, fill string, width float64) {
+	fmt.Fprintf(&c.sb, `  <circle cx="%s" cy="%s" r="%s" fill="%s" stroke="%s" stroke-width="%s"/>`+"\n",
+		num(center.X), num(center.Y), num(r), fill, stroke, num(width))
+}
+
+func (c *canvas) path(d, stroke, fill string, width float64) {
+	fmt.Fprintf(&c.sb, `  <path d="%s" fill="%s" stroke="%s" stroke-width="%s"/>`+"\n", d, fill, stroke, num(width))
+}
+
+// text draws a label; anchor is "start", "middle" or "end"
+func (c *canvas) text(p point, s string, size float64, color, anchor string, bold bool) {
+	weight := ""
+	if bold {
+		weight = ` font-weight="bold"`
+	}
+	fmt.Fprintf(&c.sb, `  <text x="%s" y="%s" text-anchor="%s" dominant-baseline="middle" font-size="%s"%s fill="%s">%s</text>`+"\n",
+		num(p.X), num(p.Y), anchor, num(size), weight, color, html.EscapeString(s))
+}
+
+// raw appends markup that has no helper, such as clip paths and groups
+func (c *canvas) raw(markup string) {
+	c.sb.WriteString("  " + markup + "\n")
+}
+
+func (c *canvas) String() string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %s %s" width="%s" height="%s">`+"\n%s</svg>",
+		num(c.width), num(c.height), num(c.width), num(c.height), c.sb.String())
+}
+
+func dashAttr(dash string) string {
+	if dash == "" {
+		return ""
+	}
+	return fmt.Sprintf(` stroke-dasharray="%s"`, dash)
+}
+
+// num formats a coordinate with at most one decimal place
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10)/10, 'f', -1, 64)
+}
+
+// measure formats a measurement for a label, e.g. 7.5 and "cm" gives "7.5 cm"
+func measure(f float64, unit string) string {
+	s := strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+	if unit != "" {
+		s += " " + unit
+	}
+	return s
+}
+
+// fit returns a transform that scales world coordinates (y pointing up) into
+// a width×height box with the given padding, keeping the aspect ratio
+func fit(pts []point, width, height, pad float64) func(point) point {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range pts {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+
+	spanX, spanY := maxX-minX, maxY-minY
+	k := math.Inf(1)
+	if spanX > 0 {
+		k = (width - 2*pad) / spanX
+	}
+	if spanY > 0 {
+		k = math.Min(k, (height-2*pad)/spanY)
+	}
+	if math.IsInf(k, 1) {
+		k = 1
+	}
+
+	offX := (width - spanX*k) / 2
+	offY := (height - spanY*k) / 2
+	return func(p point) point {
+		return point{offX + (p.X-minX)*k, height - offY - (p.Y-minY)*k}
+	}
+}
@@ -0,0 +1,120 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// accountEmail is the content of an account email with one call-to-action button
+type accountEmail struct {
+	Title   string
+	Name    string
+	Intro   string
+	Button  string
+	Link    string
+	Expires string
+	Footer  string
+}
+
+var accountEmailTemplate = template.Must(template.New("account").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+</head>
+<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #f3f4f6;">
+    <table role="presentation" style="width: 100%; border-collapse: collapse;">
+        <tr>
+            <td align="center" style="padding: 40px 20px;">
+                <table role="presentation" style="width: 100%; max-width: 600px; border-collapse: collapse;">
+
+                    <!-- Header -->
+                    <tr>
+                        <td style="background: linear-gradient(135deg, #14b8a6 0%, #0891b2 100%); padding: 40px; border-radius: 16px 16px 0 0; text-align: center;">
+                            <img src="https://makos.ai/logo.png" alt="Makos.ai" style="height: 60px; margin-bottom: 20px;">
+                            <h1 style="color: #ffffff; font-size: 28px; margin: 0; font-weight: 700;">{{.Title}}</h1>
+                        </td>
+                    </tr>
+
+                    <!-- Main Content -->
+                    <tr>
+                        <td style="background-color: #ffffff; padding: 40px; border-radius: 0 0 16px 16px; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);">
+                            <p style="font-size: 18px; color: #374151; margin: 0 0 20px 0; line-height: 1.6;">Hi {{.Name}},</p>
+                            <p style="font-size: 16px; color: #6b7280; margin: 0 0 32px 0; line-height: 1.7;">{{.Intro}}</p>
+
+                            <!-- CTA Button -->
+                            <table role="presentation" style="width: 100%; border-collapse: collapse;">
+                                <tr>
+                                    <td align="center">
+                                        <a href="{{.Link}}" style="display: inline-block; background: linear-gradient(135deg, #14b8a6 0%, #0891b2 100%); color: #ffffff; font-size: 18px; font-weight: 700; text-decoration: none; padding: 16px 48px; border-radius: 12px; box-shadow: 0 4px 14px rgba(20, 184, 166, 0.4);">{{.Button}}</a>
+                                    </td>
+                                </tr>
+                            </table>
+
+                            <p style="font-size: 14px; color: #9ca3af; margin: 32px 0 8px 0; line-height: 1.6;">This link expires in {{.Expires}} and works once. If the button doesn't work, paste this address into your browser:</p>
+                            <p style="font-size: 13px; color: #0891b2; margin: 0 0 24px 0; word-break: break-all;">{{.Link}}</p>
+                            <p style="font-size: 14px; color: #9ca3af; margin: 0;">{{.Footer}}</p>
+                        </td>
+                    </tr>
+
+                    <!-- Footer -->
+                    <tr>
+                        <td style="padding: 30px; text-align: center;">
+                            <p style="font-size: 12px; color: #d1d5db; margin: 0;">© 2026 Makos.ai. All rights reserved.</p>
+                        </td>
+                    </tr>
+
+                </table>
+            </td>
+        </tr>
+    </table>
+</body>
+</html>
+`))
+
+// SendVerificationEmail asks a new user to confirm their address by opening link
+func SendVerificationEmail(m Mailer, to, userName, link, expires string) error {
+	html, err := renderAccountEmail(accountEmail{
+		Title:   "Confirm your email",
+		Name:    userName,
+		Intro:   "Thanks for signing up for Makos.ai! Please confirm this is your email address so we can keep your account secure.",
+		Button:  "Confirm email",
+		Link:    link,
+		Expires: expires,
+		Footer:  "If you didn't create a Makos.ai account, you can ignore this email.",
+	})
+	if err != nil {
+		return err
+	}
+	return m.SendEmail(to, "Confirm your Makos.ai email", html)
+}
+
+// SendPasswordResetEmail sends a link for choosing a new password
+func SendPasswordResetEmail(m Mailer, to, userName, link, expires string) error {
+	html, err := renderAccountEmail(accountEmail{
+		Title:   "Reset your password",
+		Name:    userName,
+		Intro:   "We received a request to reset the password for your Makos.ai account. Choose a new one with the button below.",
+		Button:  "Reset password",
+		Link:    link,
+		Expires: expires,
+		Footer:  "If you didn't ask to reset your password, you can ignore this email; your password won't change.",
+	})
+	if err != nil {
+		return err
+	}
+	return m.SendEmail(to, "Reset your Makos.ai password", html)
+}
+
+func renderAccountEmail(e accountEmail) (string, error) {
+	if e.Name == "" {
+		e.Name = "there"
+	}
+	var buf bytes.Buffer
+	if err := accountEmailTemplate.Execute(&buf, e); err != nil {
+		return "", fmt.Errorf("failed to render email: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,84 @@
+package email
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is an email the capture transport kept
+type Message struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	HTML    string    `json:"html"`
+	SentAt  time.Time `json:"sent_at"`
+	File    string    `json:"file,omitempty"`
+}
+
+// CaptureMailer keeps emails in memory instead of sending them, and also
+// writes each to an .html file when it has a directory, so development
+// servers and tests can open the links they contain
+type CaptureMailer struct {
+	dir  string
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewCaptureMailer creates a capture mailer; an empty dir keeps emails in memory only
+func NewCaptureMailer(dir string) (*CaptureMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &CaptureMailer{dir: dir}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]+`)
+
+// SendEmail records the email
+func (m *CaptureMailer) SendEmail(to, subject, html string) error {
+	msg := Message{To: to, Subject: subject, HTML: html, SentAt: time.Now()}
+	if m.dir != "" {
+		name := fmt.Sprintf("%s_%s.html", msg.SentAt.Format("20060102-150405.000000"), unsafeFileChars.ReplaceAllString(to, "_"))
+		msg.File = filepath.Join(m.dir, name)
+		header := fmt.Sprintf("<!-- To: %s\n     Subject: %s -->\n", strings.ReplaceAll(to, "--", ""), strings.ReplaceAll(subject, "--", ""))
+		if err := os.WriteFile(msg.File, []byte(header+html), 0o644); err != nil {
+			return fmt.Errorf("failed to capture email: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	m.sent = append(m.sent, msg)
+	m.mu.Unlock()
+	if msg.File != "" {
+		log.Printf("📬 Captured email to %s: %q (%s)", to, subject, msg.File)
+	} else {
+		log.Printf("📬 Captured email to %s: %q", to, subject)
+	}
+	return nil
+}
+
+// Sent returns the captured emails, oldest first
+func (m *CaptureMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+// Last returns the most recent email to an address
+func (m *CaptureMailer) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if strings.EqualFold(m.sent[i].To, to) {
+			return m.sent[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,91 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Mailer sends an HTML email
+type Mailer interface {
+	SendEmail(to, subject, html string) error
+}
+
+// Transports NewMailer can build
+const (
+	TransportResend  = "resend"
+	TransportSMTP    = "smtp"
+	TransportCapture = "capture" // keeps emails instead of sending them, for development
+)
+
+// DefaultFrom is the sender when none is configured
+const DefaultFrom = "Makos.ai <support@makos.ai>"
+
+// FromAddress picks the configured sender: MAIL_FROM, else the deprecated
+// RESEND_FROM_EMAIL, else empty so NewMailer uses DefaultFrom
+func FromAddress(mailFrom, resendFrom string) string {
+	if mailFrom == "" && resendFrom != "" {
+		log.Println("⚠️ RESEND_FROM_EMAIL is deprecated, rename it to MAIL_FROM")
+		return resendFrom
+	}
+	return mailFrom
+}
+
+// Config chooses and configures the mail transport
+type Config struct {
+	Transport  string // empty picks resend with an API key, smtp with a host, else capture
+	Production bool   // refuse the capture transport and missing credentials
+	From       string
+
+	ResendAPIKey string
+	ResendURL    string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	CaptureDir string // where the capture transport writes emails; empty keeps them in memory only
+}
+
+// NewMailer builds the configured transport
+func NewMailer(cfg Config) (Mailer, error) {
+	if cfg.From == "" {
+		cfg.From = DefaultFrom
+	}
+	transport := cfg.Transport
+	if transport == "" {
+		switch {
+		case cfg.ResendAPIKey != "":
+			transport = TransportResend
+		case cfg.SMTPHost != "":
+			transport = TransportSMTP
+		default:
+			transport = TransportCapture
+		}
+	}
+
+	switch transport {
+	case TransportResend:
+		if cfg.ResendAPIKey == "" {
+			return nil, errors.New("the resend transport needs an API key")
+		}
+		client := NewResendClient(cfg.ResendAPIKey, cfg.From)
+		client.BaseURL = cfg.ResendURL
+		return client, nil
+	case TransportSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("the smtp transport needs a host")
+		}
+		if cfg.Production && (cfg.SMTPUsername == "" || cfg.SMTPPassword == "") {
+			return nil, errors.New("the smtp transport needs a username and password in production")
+		}
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case TransportCapture:
+		if cfg.Production {
+			return nil, errors.New("the capture transport doesn't deliver email, configure resend or smtp in production")
+		}
+		return NewCaptureMailer(cfg.CaptureDir)
+	}
+	return nil, fmt.Errorf("unknown mail transport %q, want resend, smtp or capture", transport)
+}
@@ -0,0 +1,89 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    string // the transport built, or "" for an error
+		wantErr string
+	}{
+		{"capture by default", Config{}, TransportCapture, ""},
+		{"resend with a key", Config{ResendAPIKey: "re_test"}, TransportResend, ""},
+		{"smtp with a host", Config{SMTPHost: "mail.example.com"}, TransportSMTP, ""},
+		{"resend preferred over smtp", Config{ResendAPIKey: "re_test", SMTPHost: "mail.example.com"}, TransportResend, ""},
+		{"resend without a key", Config{Transport: TransportResend}, "", "API key"},
+		{"smtp without a host", Config{Transport: TransportSMTP}, "", "host"},
+		{"unknown transport", Config{Transport: "pigeon"}, "", "unknown mail transport"},
+		{"production refuses capture", Config{Production: true}, "", "capture transport"},
+		{"production refuses explicit capture", Config{Transport: TransportCapture, Production: true}, "", "capture transport"},
+		{"production smtp without credentials", Config{SMTPHost: "mail.example.com", Production: true}, "", "username and password"},
+		{"production smtp without password", Config{SMTPHost: "mail.example.com", SMTPUsername: "app", Production: true}, "", "username and password"},
+		{"production smtp", Config{SMTPHost: "mail.example.com", SMTPUsername: "app", SMTPPassword: "secret", Production: true}, TransportSMTP, ""},
+		{"production resend", Config{ResendAPIKey: "re_test", Production: true}, TransportResend, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMailer(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			switch m.(type) {
+			case *ResendClient:
+				got = TransportResend
+			case *SMTPMailer:
+				got = TransportSMTP
+			case *CaptureMailer:
+				got = TransportCapture
+			}
+			if got != tt.want {
+				t.Errorf("built %T, want the %s transport", m, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMailerFrom(t *testing.T) {
+	m, err := NewMailer(Config{SMTPHost: "mail.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from := m.(*SMTPMailer).from; from != DefaultFrom {
+		t.Errorf("from = %q, want the default %q", from, DefaultFrom)
+	}
+
+	m, err = NewMailer(Config{ResendAPIKey: "re_test", From: "School <office@school.example>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from := m.(*ResendClient).FromEmail; from != "School <office@school.example>" {
+		t.Errorf("from = %q, want the configured sender", from)
+	}
+}
+
+func TestFromAddress(t *testing.T) {
+	tests := []struct {
+		mailFrom, resendFrom, want string
+	}{
+		{"", "", ""},
+		{"a@example.com", "", "a@example.com"},
+		{"", "legacy@example.com", "legacy@example.com"},
+		{"a@example.com", "legacy@example.com", "a@example.com"},
+	}
+	for _, tt := range tests {
+		if got := FromAddress(tt.mailFrom, tt.resendFrom); got != tt.want {
+			t.Errorf("FromAddress(%q, %q) = %q, want %q", tt.mailFrom, tt.resendFrom, got, tt.want)
+		}
+	}
+}
@@ -4,12 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
+	"strings"
 )
 
+// DefaultResendURL is Resend's API
+const DefaultResendURL = "https://api.resend.com"
+
+// ResendClient sends email through the Resend API
 type ResendClient struct {
 	APIKey    string
 	FromEmail string
+	BaseURL   string // e.g. a regional endpoint or a local stub; empty uses DefaultResendURL
+	// HTTPClient sends the API requests; nil uses http.DefaultClient. Swap its
+	// transport to capture emails instead of sending them.
+	HTTPClient *http.Client
 }
 
 type EmailRequest struct {
@@ -43,7 +53,11 @@ func (r *ResendClient) SendEmail(to, subject, html string) error {
 		return fmt.Errorf("failed to marshal email request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.resend.com/emails", bytes.NewBuffer(jsonData))
+	baseURL := r.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultResendURL
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(baseURL, "/")+"/emails", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -51,7 +65,10 @@ func (r *ResendClient) SendEmail(to, subject, html string) error {
 	req.Header.Set("Authorization", "Bearer "+r.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
@@ -65,16 +82,18 @@ func (r *ResendClient) SendEmail(to, subject, html string) error {
 	return nil
 }
 
-func (r *ResendClient) SendWelcomeEmail(to, userName string) error {
+// SendWelcomeEmail greets a new user
+func SendWelcomeEmail(m Mailer, to, userName string) error {
 	subject := "Welcome to Makos.ai! 🎉"
-	html := generateWelcomeEmailHTML(userName)
-	return r.SendEmail(to, subject, html)
+	body := generateWelcomeEmailHTML(userName)
+	return m.SendEmail(to, subject, body)
 }
 
 func generateWelcomeEmailHTML(userName string) string {
 	if userName == "" {
 		userName = "there"
 	}
+	userName = html.EscapeString(userName)
 
 	return fmt.Sprintf(`
 <!DOCTYPE html>
@@ -0,0 +1,49 @@
+package email
+
+import (
+	"encoding/base64"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "Makos.ai", Address: "support@makos.ai"}
+	to := &mail.Address{Name: "Zoë Müller", Address: "zoe@school.example"}
+	html := "<p>" + strings.Repeat("Grüße ", 40) + "</p>"
+	msg := string(buildMessage(from, to, "Bestätige deine E-Mail", html))
+
+	head, body, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no blank line between headers and body:\n%s", msg)
+	}
+	for _, want := range []string{
+		"From: \"Makos.ai\" <support@makos.ai>",
+		"To: =?utf-8?q?Zo=C3=AB_M=C3=BCller?= <zoe@school.example>",
+		"Subject: =?utf-8?q?Best=C3=A4tige_deine_E-Mail?=",
+		"Content-Type: text/html; charset=utf-8",
+		"Content-Transfer-Encoding: base64",
+	} {
+		if !strings.Contains(head+"\r\n", want+"\r\n") {
+			t.Errorf("headers missing %q:\n%s", want, head)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n")
+	for _, line := range lines {
+		if len(line) > 76 {
+			t.Errorf("body line is %d characters, want at most 76", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+	if err != nil || string(decoded) != html {
+		t.Errorf("body decodes to %q, %v", decoded, err)
+	}
+
+	// A subject carrying a line break must not start a header of its own
+	msg = string(buildMessage(from, to, "Hello\r\nBcc: victim@example.com", "<p>hi</p>"))
+	head, _, _ = strings.Cut(msg, "\r\n\r\n")
+	if strings.Contains(head, "\r\nBcc:") {
+		t.Errorf("subject injected a header:\n%s", head)
+	}
+}
@@ -0,0 +1,161 @@
+package gencache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache keeps recent generations by request key
+type Cache interface {
+	Get(ctx context.Context, key string) (*models.Worksheet, bool)
+	Set(ctx context.Context, key string, ws *models.Worksheet)
+}
+
+// maxMemoryEntries bounds the memory cache; the entry closest to expiring is dropped first
+const maxMemoryEntries = 500
+
+type cachedWorksheet struct {
+	worksheet *models.Worksheet
+	expires   time.Time
+}
+
+// MemoryCache keeps generations in process memory
+type MemoryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedWorksheet
+}
+
+// NewMemoryCache creates an empty in-memory cache; with a zero ttl it keeps nothing
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{ttl: ttl, entries: make(map[string]cachedWorksheet)}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (*models.Worksheet, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.worksheet.Clone(), true
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, ws *models.Worksheet) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxMemoryEntries {
+		oldest := ""
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= maxMemoryEntries {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = cachedWorksheet{worksheet: ws.Clone(), expires: now.Add(c.ttl)}
+}
+
+// RedisCache stores generations in Redis with an expiry, so they are shared across instances
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisCache connects to a redis:// URL
+func NewRedisCache(redisURL string, ttl time.Duration) (*RedisCache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisCache{client: redis.NewClient(opts), ttl: ttl}, nil
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (*models.Worksheet, bool) {
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("⚠️ Generation cache read failed: %v", err)
+		}
+		return nil, false
+	}
+	var ws models.Worksheet
+	if err := json.Unmarshal(data, &ws); err != nil {
+		return nil, false
+	}
+	return &ws, true
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, ws *models.Worksheet) {
+	data, err := json.Marshal(ws)
+	if err != nil {
+		return
+	}
+	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+		log.Printf("⚠️ Generation cache write failed: %v", err)
+	}
+}
+
+// call is a generation in flight that later identical requests wait for
+type call struct {
+	done      chan struct{}
+	worksheet *models.Worksheet
+	err       error
+}
+
+// Group coalesces concurrent generations of the same key into one
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// NewGroup creates an empty group
+func NewGroup() *Group {
+	return &Group{calls: make(map[string]*call)}
+}
+
+// Do runs generate once for all concurrent callers with the same key. Every
+// caller gets the same worksheet and must not modify it; shared is true for
+// the callers that waited on another's generation.
+func (g *Group) Do(key string, generate func() (*models.Worksheet, error)) (ws *models.Worksheet, shared bool, err error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-c.done
+		return c.worksheet, true, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		if c.worksheet == nil && c.err == nil {
+			// generate panicked; the waiters still need an answer
+			c.err = errors.New("generation failed")
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.worksheet, c.err = generate()
+	return c.worksheet, false, c.err
+}
@@ -0,0 +1,103 @@
+package gencache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(time.Hour)
+	c.Set(ctx, "k", &models.Worksheet{ID: "ws_1", Title: "Plants"})
+
+	got, ok := c.Get(ctx, "k")
+	if !ok || got.Title != "Plants" {
+		t.Fatalf("Get = %+v, %v", got, ok)
+	}
+	got.Title = "Changed"
+	if again, _ := c.Get(ctx, "k"); again.Title != "Plants" {
+		t.Error("Get returned the cached worksheet itself")
+	}
+
+	c.entries["old"] = cachedWorksheet{worksheet: &models.Worksheet{}, expires: time.Now().Add(-time.Second)}
+	if _, ok := c.Get(ctx, "old"); ok {
+		t.Error("expired entry was returned")
+	}
+	if _, ok := c.entries["old"]; ok {
+		t.Error("expired entry was not deleted")
+	}
+
+	off := NewMemoryCache(0)
+	off.Set(ctx, "k", &models.Worksheet{})
+	if _, ok := off.Get(ctx, "k"); ok {
+		t.Error("zero-ttl cache kept an entry")
+	}
+}
+
+func TestMemoryCacheBounded(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(time.Hour)
+	for i := 0; i < maxMemoryEntries+10; i++ {
+		c.Set(ctx, fmt.Sprint("key", i), &models.Worksheet{})
+	}
+	if len(c.entries) != maxMemoryEntries {
+		t.Errorf("%d entries, want %d", len(c.entries), maxMemoryEntries)
+	}
+	if _, ok := c.Get(ctx, fmt.Sprint("key", maxMemoryEntries+9)); !ok {
+		t.Error("newest entry was evicted")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	g := NewGroup()
+	var calls int32
+	release := make(chan struct{})
+	generate := func() (*models.Worksheet, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &models.Worksheet{ID: "ws_1"}, nil
+	}
+
+	var wg, started sync.WaitGroup
+	var sharedCount int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			ws, shared, err := g.Do("k", generate)
+			if err != nil || ws.ID != "ws_1" {
+				t.Errorf("Do = %v, %v", ws, err)
+			}
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}()
+	}
+	// Let every caller reach Do before the generation finishes
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 || sharedCount != 4 {
+		t.Errorf("%d generations, %d shared results; want 1 and 4", calls, sharedCount)
+	}
+
+	// Failures are shared but not remembered
+	boom := errors.New("boom")
+	if _, _, err := g.Do("k", func() (*models.Worksheet, error) { return nil, boom }); err != boom {
+		t.Errorf("error = %v", err)
+	}
+	if _, _, err := g.Do("k", func() (*models.Worksheet, error) { return &models.Worksheet{}, nil }); err != nil {
+		t.Errorf("failure was remembered: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package gencache reuses recent worksheet generations. Requests are keyed by
+// a hash of their normalized input; a clean result can be served again as a
+// fresh copy with a new ID and reshuffled options, and concurrent identical
+// requests share a single generation.
+package gencache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/makosai/backend/internal/models"
+)
+
+// DefaultTTL is how long a generated worksheet may be served again
+const DefaultTTL = time.Hour
+
+// normalizedInput is what makes two generation requests the same
+type normalizedInput struct {
+	Topic                  string                  `json:"topic"`
+	Subject                string                  `json:"subject"`
+	GradeLevel             string                  `json:"grade_level"`
+	Difficulty             string                  `json:"difficulty"`
+	QuestionCount          int                     `json:"question_count"`
+	QuestionTypes          []string                `json:"question_types"`
+	Language               string                  `json:"language"`
+	IncludeAnswerKey       bool                    `json:"include_answer_key"`
+	AdditionalInstructions string                  `json:"additional_instructions"`
+	IncludePassage         bool                    `json:"include_passage"`
+	Limits                 models.GenerationLimits `json:"limits"`
+}
+
+// Key returns the cache key of a generation request. Requests built on the
+// teacher's own material (a passage, an upload or example questions) are
+// never shared, so ok is false for them.
+func Key(input models.WorksheetGeneratorInput) (key string, ok bool) {
+	if strings.TrimSpace(input.Passage) != "" || input.SourceFile != "" ||
+		len(input.SourceExcerpts) > 0 || len(input.ExampleQuestions) > 0 {
+		return "", false
+	}
+
+	types := make([]string, 0, len(input.QuestionTypes))
+	seen := map[string]bool{}
+	for _, t := range input.QuestionTypes {
+		t = normalize(t)
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+
+	data, err := json.Marshal(normalizedInput{
+		Topic:                  normalize(input.Topic),
+		Subject:                normalize(input.Subject),
+		GradeLevel:             normalize(input.GradeLevel),
+		Difficulty:             normalize(input.Difficulty),
+		QuestionCount:          input.QuestionCount,
+		QuestionTypes:          types,
+		Language:               normalize(input.Language),
+		IncludeAnswerKey:       input.IncludeAnswerKey,
+		AdditionalInstructions: normalize(input.AdditionalInstructions),
+		IncludePassage:         input.IncludePassage,
+		Limits:                 input.Limits,
+	})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return "generation:" + hex.EncodeToString(sum[:16]), true
+}
+
+// normalize lowercases s and collapses its whitespace, so "Photosynthesis " and
+// "photosynthesis" are the same request
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Reusable reports whether a saved worksheet is good enough to hand to another
+// teacher: moderation found nothing, no question carries a warning and any
+// passage reads at the requested grade
+func Reusable(ws *models.Worksheet) bool {
+	if ws == nil || len(ws.Questions) == 0 {
+		return false
+	}
+	if ws.Moderation == nil || ws.Moderation.Status != models.ModerationClear {
+		return false
+	}
+	if ws.Passage != nil && ws.Passage.Readability != nil && !ws.Passage.Readability.WithinRange {
+		return false
+	}
+	for _, q := range ws.Questions {
+		if len(q.Warnings) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// positionalOption matches options that point at other options, like "All of
+// the above" or "Both A and B", which would be wrong after a shuffle
+var positionalOption = regexp.MustCompile(`(?i)\b(above|below)\b|\b[a-e] (and|or) [a-e]\b`)
+
+// Fresh returns a copy of a cached worksheet as a new, unsaved worksheet:
+// a new ID and timestamps, no revision, moderation or usage, and the options
+// of multiple-choice questions in a new order
+func Fresh(ws *models.Worksheet) *models.Worksheet {
+	c := ws.Clone()
+	now := time.Now()
+	c.ID = "ws_" + uuid.New().String()[:8]
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	c.Status = "draft"
+	c.Downloads = 0
+	c.Revision = 0
+	c.Moderation = nil
+	c.Usage = nil
+	for i := range c.Questions {
+		shuffleOptions(&c.Questions[i])
+	}
+	return c
+}
+
+// shuffleOptions reorders a multiple-choice question's options; the correct
+// answer is stored as the option's text, so it still matches
+func shuffleOptions(q *models.Question) {
+	if q.Type != string(models.MultipleChoice) || len(q.Options) < 2 {
+		return
+	}
+	for _, opt := range q.Options {
+		if positionalOption.MatchString(opt) {
+			return
+		}
+	}
+	rand.Shuffle(len(q.Options), func(i, j int) {
+		q.Options[i], q.Options[j] = q.Options[j], q.Options[i]
+	})
+}
@@ -0,0 +1,114 @@
+package gencache
+
+import (
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestKey(t *testing.T) {
+	base := models.WorksheetGeneratorInput{
+		Topic: "Photosynthesis", Subject: "Science", GradeLevel: "5", QuestionCount: 5,
+		QuestionTypes: []string{"multiple_choice", "true_false"},
+	}
+	key, ok := Key(base)
+	if !ok {
+		t.Fatal("plain request is not cacheable")
+	}
+
+	same := base
+	same.Topic = "  photosynthesis "
+	same.Subject = "science"
+	same.QuestionTypes = []string{"true_false", "multiple_choice", "true_false"}
+	if k, _ := Key(same); k != key {
+		t.Error("case, spacing and type order change the key")
+	}
+
+	different := base
+	different.QuestionCount = 6
+	if k, _ := Key(different); k == key {
+		t.Error("question count does not change the key")
+	}
+	limited := base
+	limited.Limits.NoImages = true
+	if k, _ := Key(limited); k == key {
+		t.Error("plan limits do not change the key")
+	}
+
+	for name, edit := range map[string]func(*models.WorksheetGeneratorInput){
+		"passage":  func(in *models.WorksheetGeneratorInput) { in.Passage = "Leaves are green." },
+		"upload":   func(in *models.WorksheetGeneratorInput) { in.SourceFile = "notes.pdf" },
+		"excerpts": func(in *models.WorksheetGeneratorInput) { in.SourceExcerpts = []models.SourceExcerpt{{ID: "S1"}} },
+		"examples": func(in *models.WorksheetGeneratorInput) { in.ExampleQuestions = []models.Question{{}} },
+	} {
+		input := base
+		edit(&input)
+		if _, ok := Key(input); ok {
+			t.Errorf("request with its own %s is cacheable", name)
+		}
+	}
+}
+
+func reusableWorksheet() *models.Worksheet {
+	return &models.Worksheet{
+		ID:         "ws_1",
+		Status:     "published",
+		Downloads:  3,
+		Revision:   2,
+		Moderation: &models.Moderation{Status: models.ModerationClear},
+		Questions: []models.Question{{
+			Type:          string(models.MultipleChoice),
+			Question:      "Which gas do plants take in?",
+			Options:       []string{"Oxygen", "Carbon dioxide", "Nitrogen", "Helium", "Argon", "Neon"},
+			CorrectAnswer: "Carbon dioxide",
+		}},
+	}
+}
+
+func TestReusable(t *testing.T) {
+	if !Reusable(reusableWorksheet()) {
+		t.Fatal("clean worksheet is not reusable")
+	}
+	for name, edit := range map[string]func(*models.Worksheet){
+		"unmoderated":   func(ws *models.Worksheet) { ws.Moderation = nil },
+		"flagged":       func(ws *models.Worksheet) { ws.Moderation.Status = models.ModerationFlagged },
+		"warning":       func(ws *models.Worksheet) { ws.Questions[0].Warnings = []string{"check"} },
+		"no questions":  func(ws *models.Worksheet) { ws.Questions = nil },
+		"wrong reading": func(ws *models.Worksheet) { ws.Passage = &models.Passage{Readability: &models.Readability{}} },
+	} {
+		ws := reusableWorksheet()
+		edit(ws)
+		if Reusable(ws) {
+			t.Errorf("%s worksheet is reusable", name)
+		}
+	}
+}
+
+func TestFresh(t *testing.T) {
+	ws := reusableWorksheet()
+	reordered := false
+	for i := 0; i < 20 && !reordered; i++ {
+		c := Fresh(ws)
+		if c.ID == ws.ID || c.Status != "draft" || c.Downloads != 0 || c.Revision != 0 || c.Moderation != nil {
+			t.Fatalf("copy kept saved state: %+v", c)
+		}
+		q := c.Questions[0]
+		if q.CorrectAnswer != "Carbon dioxide" || len(q.Options) != 6 {
+			t.Fatalf("copy changed the question: %+v", q)
+		}
+		reordered = q.Options[1] != "Carbon dioxide"
+	}
+	if !reordered {
+		t.Error("options were never shuffled")
+	}
+	if ws.Questions[0].Options[1] != "Carbon dioxide" || ws.ID != "ws_1" {
+		t.Error("Fresh changed the cached worksheet")
+	}
+
+	ws.Questions[0].Options = []string{"Oxygen", "Nitrogen", "Carbon dioxide", "All of the above"}
+	for i := 0; i < 10; i++ {
+		if got := Fresh(ws).Questions[0].Options; got[3] != "All of the above" || got[0] != "Oxygen" {
+			t.Fatalf("options referring to others were shuffled: %v", got)
+		}
+	}
+}
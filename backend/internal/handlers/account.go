@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/email"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/store"
+	"github.com/makosai/backend/internal/tokens"
+)
+
+// RequestEmailVerification handles POST /api/auth/verify-email/request
+//
+// Mails the signed-in user a new link to confirm their address.
+func (h *AuthHandler) RequestEmailVerification(c *fiber.Ctx) error {
+	user, ok := h.users.Get(currentUserID(c))
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error":   "Not signed in",
+		})
+	}
+	if user.EmailVerified {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "Email already verified",
+		})
+	}
+	if err := h.sendVerification(*user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to send verification email",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Verification email sent",
+	})
+}
+
+// ConfirmEmailVerification handles POST /api/auth/verify-email/confirm
+func (h *AuthHandler) ConfirmEmailVerification(c *fiber.Ctx) error {
+	var input struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Token is required",
+		})
+	}
+
+	userID, err := h.tokens.Redeem(input.Token, tokens.VerifyEmail, h.emailBinding)
+	if err != nil {
+		return tokenError(c, err)
+	}
+	updated, err := h.users.Update(userID, func(user *models.User) error {
+		user.EmailVerified = true
+		return nil
+	})
+	if errors.Is(err, store.ErrUserNotFound) {
+		return tokenError(c, tokens.ErrInvalid)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to verify email",
+		})
+	}
+	log.Printf("📧 Verified email for %s", updated.ID)
+	return c.JSON(models.AuthResponse{
+		Success: true,
+		User:    updated,
+	})
+}
+
+// RequestPasswordReset handles POST /api/auth/password-reset/request
+//
+// The response is the same whether or not the email has an account, so it
+// can't be used to find out who has signed up.
+func (h *AuthHandler) RequestPasswordReset(c *fiber.Ctx) error {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&input); err != nil || strings.TrimSpace(input.Email) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Email is required",
+		})
+	}
+
+	if user, ok := h.users.GetByEmail(strings.TrimSpace(input.Email)); ok {
+		go h.sendPasswordReset(*user)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "If an account exists for that email, we've sent a link to reset its password",
+	})
+}
+
+// ConfirmPasswordReset handles POST /api/auth/password-reset/confirm
+//
+// Sets the new password and signs the user in. Opening the emailed link also
+// proves the user owns the address, so it is marked verified.
+func (h *AuthHandler) ConfirmPasswordReset(c *fiber.Ctx) error {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Token == "" || input.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.AuthResponse{
+			Success: false,
+			Error:   "Token and password are required",
+		})
+	}
+
+	userID, err := h.tokens.Redeem(input.Token, tokens.ResetPassword, h.passwordBinding)
+	if err != nil {
+		return tokenError(c, err)
+	}
+	updated, err := h.users.Update(userID, func(user *models.User) error {
+		user.Password = input.Password // In production, hash this!
+		user.EmailVerified = true
+		return nil
+	})
+	if errors.Is(err, store.ErrUserNotFound) {
+		return tokenError(c, tokens.ErrInvalid)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.AuthResponse{
+			Success: false,
+			Error:   "Failed to reset password",
+		})
+	}
+	log.Printf("🔑 Password reset for %s", updated.ID)
+	return c.JSON(models.AuthResponse{
+		Success: true,
+		Token:   tokenPrefix + updated.ID,
+		User:    updated,
+	})
+}
+
+// sendVerification mails user a link to confirm their address
+func (h *AuthHandler) sendVerification(user models.User) error {
+	token, err := h.tokens.Issue(tokens.VerifyEmail, user.ID, strings.ToLower(user.Email), tokens.VerifyEmailTTL)
+	if err == nil {
+		err = email.SendVerificationEmail(h.mailer, user.Email, user.Name, h.link("/verify-email", token), formatTTL(tokens.VerifyEmailTTL))
+	}
+	if err != nil {
+		log.Printf("⚠️ Failed to send verification email to %s: %v", user.ID, err)
+	}
+	return err
+}
+
+// sendPasswordReset mails user a link to choose a new password
+func (h *AuthHandler) sendPasswordReset(user models.User) {
+	token, err := h.tokens.Issue(tokens.ResetPassword, user.ID, user.Password, tokens.ResetPasswordTTL)
+	if err == nil {
+		err = email.SendPasswordResetEmail(h.mailer, user.Email, user.Name, h.link("/reset-password", token), formatTTL(tokens.ResetPasswordTTL))
+	}
+	if err != nil {
+		log.Printf("⚠️ Failed to send password reset email to %s: %v", user.ID, err)
+	}
+}
+
+// emailBinding ties verification tokens to the address they were sent to, so
+// changing it invalidates them
+func (h *AuthHandler) emailBinding(userID string) (string, bool) {
+	user, ok := h.users.Get(userID)
+	if !ok {
+		return "", false
+	}
+	return strings.ToLower(user.Email), true
+}
+
+// passwordBinding ties reset tokens to the password they replace, so once
+// one is used every other outstanding reset link stops working
+func (h *AuthHandler) passwordBinding(userID string) (string, bool) {
+	user, ok := h.users.Get(userID)
+	if !ok {
+		return "", false
+	}
+	return user.Password, true
+}
+
+// link builds a frontend URL carrying token
+func (h *AuthHandler) link(path, token string) string {
+	return strings.TrimSuffix(h.appURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// tokenError explains why an emailed link can't be used
+func tokenError(c *fiber.Ctx, err error) error {
+	message := "This link is invalid; request a new one"
+	switch {
+	case errors.Is(err, tokens.ErrExpired):
+		message = "This link has expired; request a new one"
+	case errors.Is(err, tokens.ErrUsed):
+		message = "This link has already been used"
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"success": false,
+		"error":   message,
+	})
+}
+
+// formatTTL describes a token lifetime for an email, e.g. "48 hours" or "1 hour"
+func formatTTL(d time.Duration) string {
+	n, unit := int(d.Minutes()), "minute"
+	if hours := int(d.Hours()); hours >= 1 {
+		n, unit = hours, "hour"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/email"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/store"
+	"github.com/makosai/backend/internal/tokens"
+)
+
+type accountTest struct {
+	t      *testing.T
+	app    *fiber.App
+	mailer *email.CaptureMailer
+	users  *store.MemoryUserStore
+}
+
+func newAccountTest(t *testing.T) *accountTest {
+	t.Helper()
+	users := store.NewMemoryUserStore()
+	quotas, err := quota.New(quota.DefaultPlans, users, store.NewMemoryCounterStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer, err := email.NewCaptureMailer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := tokens.New([]byte("test secret"), store.NewMemoryCounterStore())
+	h := NewAuthHandler(users, quotas, issuer, mailer, "https://app.example/")
+
+	app := fiber.New()
+	app.Post("/register", h.Register)
+	app.Post("/login", h.Login)
+	app.Post("/verify-email/request", h.RequestEmailVerification)
+	app.Post("/verify-email/confirm", h.ConfirmEmailVerification)
+	app.Post("/password-reset/request", h.RequestPasswordReset)
+	app.Post("/password-reset/confirm", h.ConfirmPasswordReset)
+	return &accountTest{t: t, app: app, mailer: mailer, users: users}
+}
+
+// post sends a JSON body and returns the status and decoded response
+func (a *accountTest) post(path, body, userID string) (int, map[string]interface{}) {
+	a.t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set("Authorization", "Bearer "+tokenPrefix+userID)
+	}
+	resp, err := a.app.Test(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		a.t.Fatalf("%s: %v: %s", path, err, data)
+	}
+	return resp.StatusCode, out
+}
+
+var linkToken = regexp.MustCompile(`href="https://app\.example(/[a-z-]+)\?token=([^"]+)"`)
+
+// waitForEmail returns the path and token of the count-th email to an
+// address, waiting for emails sent in the background
+func (a *accountTest) waitForEmail(to string, count int) (subject, path, token string) {
+	a.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var sent []email.Message
+		for _, m := range a.mailer.Sent() {
+			if m.To == to {
+				sent = append(sent, m)
+			}
+		}
+		if len(sent) >= count {
+			m := sent[count-1]
+			match := linkToken.FindStringSubmatch(m.HTML)
+			if match == nil {
+				a.t.Fatalf("no link in email %q", m.Subject)
+			}
+			token, err := url.QueryUnescape(match[2])
+			if err != nil {
+				a.t.Fatal(err)
+			}
+			return m.Subject, match[1], token
+		}
+		if time.Now().After(deadline) {
+			a.t.Fatalf("got %d emails to %s, want %d", len(sent), to, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (a *accountTest) register(address string) string {
+	a.t.Helper()
+	status, body := a.post("/register", `{"email":"`+address+`","name":"Ann","password":"old password"}`, "")
+	if status != fiber.StatusCreated {
+		a.t.Fatalf("register: %d %v", status, body)
+	}
+	return body["user"].(map[string]interface{})["id"].(string)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	a := newAccountTest(t)
+	userID := a.register("ann@example.com")
+
+	subject, path, token := a.waitForEmail("ann@example.com", 1)
+	if subject != "Confirm your Makos.ai email" || path != "/verify-email" {
+		t.Fatalf("email = %q linking %s", subject, path)
+	}
+
+	// A verification token can't reset the password
+	if status, _ := a.post("/password-reset/confirm", `{"token":"`+token+`","password":"x"}`, ""); status != fiber.StatusBadRequest {
+		t.Errorf("reset with a verification token: %d, want 400", status)
+	}
+
+	status, body := a.post("/verify-email/confirm", `{"token":"`+token+`"}`, "")
+	if status != fiber.StatusOK || body["user"].(map[string]interface{})["email_verified"] != true {
+		t.Fatalf("confirm: %d %v", status, body)
+	}
+	if user, _ := a.users.Get(userID); !user.EmailVerified {
+		t.Error("user not verified in the store")
+	}
+
+	status, body = a.post("/verify-email/confirm", `{"token":"`+token+`"}`, "")
+	if status != fiber.StatusBadRequest || body["error"] != "This link has already been used" {
+		t.Errorf("reuse: %d %v", status, body)
+	}
+
+	status, body = a.post("/verify-email/request", `{}`, userID)
+	if status != fiber.StatusOK || body["message"] != "Email already verified" {
+		t.Errorf("request once verified: %d %v", status, body)
+	}
+}
+
+func TestRequestEmailVerification(t *testing.T) {
+	a := newAccountTest(t)
+	userID := a.register("ann@example.com")
+	a.waitForEmail("ann@example.com", 1)
+
+	if status, _ := a.post("/verify-email/request", `{}`, ""); status != fiber.StatusUnauthorized {
+		t.Errorf("signed out: %d, want 401", status)
+	}
+	if status, body := a.post("/verify-email/request", `{}`, userID); status != fiber.StatusOK {
+		t.Fatalf("request: %d %v", status, body)
+	}
+	_, _, token := a.waitForEmail("ann@example.com", 2)
+	if status, body := a.post("/verify-email/confirm", `{"token":"`+token+`"}`, ""); status != fiber.StatusOK {
+		t.Fatalf("confirm resent link: %d %v", status, body)
+	}
+}
+
+func TestVerifyEmailRejectsBadTokens(t *testing.T) {
+	a := newAccountTest(t)
+	a.register("ann@example.com")
+	_, _, token := a.waitForEmail("ann@example.com", 1)
+
+	for name, body := range map[string]string{
+		"missing":  `{}`,
+		"garbage":  `{"token":"abc"}`,
+		"tampered": `{"token":"` + token[:len(token)-4] + `AAAA"}`,
+	} {
+		if status, _ := a.post("/verify-email/confirm", body, ""); status != fiber.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", name, status)
+		}
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	a := newAccountTest(t)
+	userID := a.register("ann@example.com")
+	a.waitForEmail("ann@example.com", 1)
+
+	// Unknown addresses get the same answer and no email
+	_, unknown := a.post("/password-reset/request", `{"email":"nobody@example.com"}`, "")
+	status, known := a.post("/password-reset/request", `{"email":"ANN@example.com"}`, "")
+	if status != fiber.StatusOK || known["message"] != unknown["message"] {
+		t.Fatalf("request: %d %v vs %v", status, known, unknown)
+	}
+	subject, path, first := a.waitForEmail("ann@example.com", 2)
+	if subject != "Reset your Makos.ai password" || path != "/reset-password" {
+		t.Fatalf("email = %q linking %s", subject, path)
+	}
+	a.post("/password-reset/request", `{"email":"ann@example.com"}`, "")
+	_, _, second := a.waitForEmail("ann@example.com", 3)
+	for _, m := range a.mailer.Sent() {
+		if m.To == "nobody@example.com" {
+			t.Error("emailed an address with no account")
+		}
+	}
+
+	status, body := a.post("/password-reset/confirm", `{"token":"`+second+`","password":"new password"}`, "")
+	if status != fiber.StatusOK || body["token"] != tokenPrefix+userID {
+		t.Fatalf("confirm: %d %v", status, body)
+	}
+	if user, _ := a.users.Get(userID); user.Password != "new password" || !user.EmailVerified {
+		t.Errorf("user after reset: password %q, verified %v", user.Password, user.EmailVerified)
+	}
+
+	// The other outstanding link stopped working once the password changed
+	if status, _ := a.post("/password-reset/confirm", `{"token":"`+first+`","password":"hijack"}`, ""); status != fiber.StatusBadRequest {
+		t.Errorf("older link: %d, want 400", status)
+	}
+	if status, _ := a.post("/password-reset/confirm", `{"token":"`+second+`","password":"again"}`, ""); status != fiber.StatusBadRequest {
+		t.Errorf("reused link: %d, want 400", status)
+	}
+	if status, _ := a.post("/login", `{"email":"ann@example.com","password":"new password"}`, ""); status != fiber.StatusOK {
+		t.Errorf("login with the new password: %d", status)
+	}
+}
+
+func TestPasswordResetKeepsConcurrentChanges(t *testing.T) {
+	a := newAccountTest(t)
+	userID := a.register("ann@example.com")
+	a.waitForEmail("ann@example.com", 1)
+	a.post("/password-reset/request", `{"email":"ann@example.com"}`, "")
+	_, _, token := a.waitForEmail("ann@example.com", 2)
+
+	// e.g. the billing webhook upgrading the plan after the link was sent
+	if _, err := a.users.Update(userID, func(u *models.User) error { u.Plan = "pro"; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := a.post("/password-reset/confirm", `{"token":"`+token+`","password":"new password"}`, ""); status != fiber.StatusOK {
+		t.Fatalf("confirm: %d %v", status, body)
+	}
+	if user, _ := a.users.Get(userID); user.Plan != "pro" {
+		t.Errorf("plan = %q after reset, want pro", user.Plan)
+	}
+}
+
+func TestFormatTTL(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		48 * time.Hour:   "48 hours",
+		time.Hour:        "1 hour",
+		30 * time.Minute: "30 minutes",
+		time.Minute:      "1 minute",
+	} {
+		if got := formatTTL(d); got != want {
+			t.Errorf("formatTTL(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
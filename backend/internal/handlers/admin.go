@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/store"
+)
+
+// defaultUsageDays is the report window when no "from" date is given
+const defaultUsageDays = 30
+
+// AdminHandler serves operator reports, guarded by the ADMIN_TOKEN bearer token
+type AdminHandler struct {
+	usage store.UsageStore
+	token string
+}
+
+// NewAdminHandler creates an admin handler; with an empty token every admin route is hidden
+func NewAdminHandler(usage store.UsageStore, token string) *AdminHandler {
+	return &AdminHandler{usage: usage, token: token}
+}
+
+// RequireAdmin rejects requests without the admin token
+func (h *AdminHandler) RequireAdmin(c *fiber.Ctx) error {
+	if h.token == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Not found",
+		})
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "))
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error":   "Admin token required",
+		})
+	}
+	return c.Next()
+}
+
+// userUsage is one user's spend in the report
+type userUsage struct {
+	UserID string `json:"user_id"`
+	models.Usage
+}
+
+// dayUsage is one UTC day's spend in the report
+type dayUsage struct {
+	Date string `json:"date"`
+	models.Usage
+}
+
+// GetUsage handles GET /api/admin/usage
+//
+// Query: from and to as YYYY-MM-DD (UTC, both inclusive; default the last 30
+// days) and an optional user. Users are sorted by cost, highest first.
+func (h *AdminHandler) GetUsage(c *fiber.Ctx) error {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, err := parseDay(c.Query("from"), today.AddDate(0, 0, 1-defaultUsageDays))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "from must be a date like 2024-01-31",
+		})
+	}
+	to, err := parseDay(c.Query("to"), today)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "to must be a date like 2024-01-31",
+		})
+	}
+	if to.Before(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "to must not be before from",
+		})
+	}
+	user := c.Query("user")
+
+	var total models.Usage
+	byUser := map[string]*userUsage{}
+	byDay := map[string]*dayUsage{}
+	for _, rec := range h.usage.List(from, to.AddDate(0, 0, 1)) {
+		if user != "" && rec.UserID != user {
+			continue
+		}
+		total.Add(rec.Usage)
+		if byUser[rec.UserID] == nil {
+			byUser[rec.UserID] = &userUsage{UserID: rec.UserID}
+		}
+		byUser[rec.UserID].Add(rec.Usage)
+		day := rec.CreatedAt.UTC().Format("2006-01-02")
+		if byDay[day] == nil {
+			byDay[day] = &dayUsage{Date: day}
+		}
+		byDay[day].Add(rec.Usage)
+	}
+
+	users := make([]*userUsage, 0, len(byUser))
+	for _, u := range byUser {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CostUSD != users[j].CostUSD {
+			return users[i].CostUSD > users[j].CostUSD
+		}
+		return users[i].UserID < users[j].UserID
+	})
+	days := make([]*dayUsage, 0, len(byDay))
+	for _, d := range byDay {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"from":    from.Format("2006-01-02"),
+		"to":      to.Format("2006-01-02"),
+		"total":   total,
+		"by_user": users,
+		"by_day":  days,
+	})
+}
+
+// parseDay reads a YYYY-MM-DD query value as the start of that UTC day
+func parseDay(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/store"
+)
+
+const testAdminToken = "admin-secret"
+
+type usageReport struct {
+	Success bool         `json:"success"`
+	Error   string       `json:"error"`
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Total   models.Usage `json:"total"`
+	ByUser  []userUsage  `json:"by_user"`
+	ByDay   []dayUsage   `json:"by_day"`
+}
+
+// newAdminApp serves the admin usage report over a memory store holding records
+func newAdminApp(t *testing.T, token string, records ...models.UsageRecord) *fiber.App {
+	t.Helper()
+	usage := store.NewMemoryUsageStore()
+	for i := range records {
+		if err := usage.Record(&records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewAdminHandler(usage, token)
+	app := fiber.New()
+	app.Group("/admin", h.RequireAdmin).Get("/usage", h.GetUsage)
+	return app
+}
+
+func getUsage(t *testing.T, app *fiber.App, query, token string) (int, usageReport) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/admin/usage"+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report usageReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, report
+}
+
+func TestRequireAdmin(t *testing.T) {
+	if status, _ := getUsage(t, newAdminApp(t, ""), "", "anything"); status != fiber.StatusNotFound {
+		t.Errorf("without ADMIN_TOKEN: %d, want 404", status)
+	}
+
+	app := newAdminApp(t, testAdminToken)
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{"", fiber.StatusUnauthorized},
+		{"wrong", fiber.StatusUnauthorized},
+		{testAdminToken + "x", fiber.StatusUnauthorized},
+		{testAdminToken, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		if status, report := getUsage(t, app, "", tt.token); status != tt.status {
+			t.Errorf("token %q: %d %+v, want %d", tt.token, status, report, tt.status)
+		}
+	}
+}
+
+func TestGetUsage(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2024, 3, d, h, 0, 0, 0, time.UTC) }
+	app := newAdminApp(t, testAdminToken,
+		models.UsageRecord{UserID: "user_1", CreatedAt: day(1, 9), Usage: models.Usage{Calls: 1, InputTokens: 100, OutputTokens: 10, CostUSD: 0.5}},
+		models.UsageRecord{UserID: "user_2", CreatedAt: day(1, 23), Usage: models.Usage{Calls: 2, InputTokens: 200, OutputTokens: 20, CostUSD: 2}},
+		models.UsageRecord{UserID: "user_1", CreatedAt: day(2, 0), Usage: models.Usage{Calls: 1, InputTokens: 50, OutputTokens: 5, CostUSD: 0.25}},
+		models.UsageRecord{UserID: "user_1", CreatedAt: day(3, 0), Usage: models.Usage{Calls: 1, InputTokens: 999, CostUSD: 9}},
+		models.UsageRecord{UserID: "user_3", CreatedAt: time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC), Usage: models.Usage{Calls: 1, CostUSD: 9}},
+	)
+
+	status, report := getUsage(t, app, "?from=2024-03-01&to=2024-03-02", testAdminToken)
+	if status != fiber.StatusOK {
+		t.Fatalf("usage: %d %+v", status, report)
+	}
+	if report.From != "2024-03-01" || report.To != "2024-03-02" {
+		t.Errorf("window = %s..%s", report.From, report.To)
+	}
+	if want := (models.Usage{Calls: 4, InputTokens: 350, OutputTokens: 35, CostUSD: 2.75}); report.Total != want {
+		t.Errorf("total = %+v, want %+v", report.Total, want)
+	}
+	if len(report.ByUser) != 2 || report.ByUser[0].UserID != "user_2" || report.ByUser[1].UserID != "user_1" || report.ByUser[1].CostUSD != 0.75 {
+		t.Errorf("by_user = %+v, want user_2 then user_1 at $0.75", report.ByUser)
+	}
+	if len(report.ByDay) != 2 || report.ByDay[0].Date != "2024-03-01" || report.ByDay[0].Calls != 3 || report.ByDay[1].Date != "2024-03-02" || report.ByDay[1].Calls != 1 {
+		t.Errorf("by_day = %+v", report.ByDay)
+	}
+
+	_, report = getUsage(t, app, "?from=2024-03-01&to=2024-03-03&user=user_1", testAdminToken)
+	if report.Total.Calls != 3 || report.Total.CostUSD != 9.75 || len(report.ByUser) != 1 || len(report.ByDay) != 3 {
+		t.Errorf("user_1 report = %+v", report)
+	}
+
+	_, report = getUsage(t, app, "", testAdminToken)
+	if today := time.Now().UTC().Format("2006-01-02"); report.To != today || report.Total.Calls != 0 {
+		t.Errorf("default window ends %s with %d calls, want %s with none", report.To, report.Total.Calls, today)
+	}
+
+	for _, query := range []string{"?from=yesterday", "?to=2024-13-01", "?from=2024-03-02&to=2024-03-01"} {
+		if status, report := getUsage(t, app, query, testAdminToken); status != fiber.StatusBadRequest {
+			t.Errorf("%s: %d %+v, want 400", query, status, report)
+		}
+	}
+}
@@ -1,22 +1,35 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/makosai/backend/internal/email"
 	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/store"
+	"github.com/makosai/backend/internal/tokens"
 )
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	users map[string]*models.User
+	users  store.UserStore
+	quota  *quota.Quota
+	tokens *tokens.Issuer
+	mailer email.Mailer
+	appURL string // the frontend, which serves the pages our email links open
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler() *AuthHandler {
+func NewAuthHandler(users store.UserStore, quota *quota.Quota, tokens *tokens.Issuer, mailer email.Mailer, appURL string) *AuthHandler {
 	return &AuthHandler{
-		users: make(map[string]*models.User),
+		users:  users,
+		quota:  quota,
+		tokens: tokens,
+		mailer: mailer,
+		appURL: appURL,
 	}
 }
 
@@ -42,31 +55,34 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	// Check if user exists
-	for _, user := range h.users {
-		if user.Email == input.Email {
-			return c.Status(fiber.StatusConflict).JSON(models.AuthResponse{
-				Success: false,
-				Error:   "Email already registered",
-			})
-		}
-	}
-
 	// Create user
 	user := &models.User{
 		ID:        "user_" + uuid.New().String()[:8],
 		Email:     input.Email,
 		Name:      input.Name,
 		Password:  input.Password, // In production, hash this!
-		Plan:      "free",
+		Plan:      quota.FreePlan,
 		CreatedAt: time.Now(),
 	}
 
-	h.users[user.ID] = user
+	if err := h.users.Create(user); err != nil {
+		if errors.Is(err, store.ErrEmailTaken) {
+			return c.Status(fiber.StatusConflict).JSON(models.AuthResponse{
+				Success: false,
+				Error:   "Email already registered",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.AuthResponse{
+			Success: false,
+			Error:   "Failed to create account",
+		})
+	}
+
+	go h.sendVerification(*user)
 
 	return c.Status(fiber.StatusCreated).JSON(models.AuthResponse{
 		Success: true,
-		Token:   "demo_token_" + user.ID,
+		Token:   tokenPrefix + user.ID,
 		User:    user,
 	})
 }
@@ -86,15 +102,8 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	// Find user
-	var foundUser *models.User
-	for _, user := range h.users {
-		if user.Email == input.Email && user.Password == input.Password {
-			foundUser = user
-			break
-		}
-	}
-
-	if foundUser == nil {
+	foundUser, ok := h.users.GetByEmail(input.Email)
+	if !ok || foundUser.Password != input.Password {
 		return c.Status(fiber.StatusUnauthorized).JSON(models.AuthResponse{
 			Success: false,
 			Error:   "Invalid email or password",
@@ -103,7 +112,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	return c.JSON(models.AuthResponse{
 		Success: true,
-		Token:   "demo_token_" + foundUser.ID,
+		Token:   tokenPrefix + foundUser.ID,
 		User:    foundUser,
 	})
 }
@@ -117,16 +126,20 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 }
 
 // GetProfile handles GET /api/auth/profile
+//
+// The response includes the plan's allowances and what is left of them this month.
 func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
-	// In production, get user from token
+	user, ok := h.users.Get(currentUserID(c))
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error":   "Not signed in",
+		})
+	}
 	return c.JSON(fiber.Map{
 		"success": true,
-		"user": map[string]interface{}{
-			"id":    "demo_user",
-			"email": "demo@example.com",
-			"name":  "Demo User",
-			"plan":  "free",
-		},
+		"user":    user,
+		"quota":   h.quota.Summary(user.ID),
 	})
 }
 
@@ -137,4 +150,3 @@ func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 		"message": "Profile updated",
 	})
 }
-
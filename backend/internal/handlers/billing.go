@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/billing"
+)
+
+// BillingHandler receives subscription webhooks from the payment provider
+type BillingHandler struct {
+	processor *billing.Processor
+	secret    string
+}
+
+// NewBillingHandler creates a billing handler; with an empty signing secret the webhook is hidden
+func NewBillingHandler(processor *billing.Processor, secret string) *BillingHandler {
+	return &BillingHandler{processor: processor, secret: secret}
+}
+
+// Webhook handles POST /api/billing/webhook
+//
+// The body must be signed with the webhook secret in the Stripe-Signature
+// header. Server errors ask the provider to redeliver the event.
+func (h *BillingHandler) Webhook(c *fiber.Ctx) error {
+	if h.secret == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Not found",
+		})
+	}
+
+	payload := c.Body()
+	if err := billing.Verify(payload, c.Get(billing.SignatureHeader), h.secret, billing.DefaultTolerance, time.Now()); err != nil {
+		log.Printf("🛡️ Rejected billing webhook from %s: %v", c.IP(), err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid signature",
+		})
+	}
+
+	outcome, err := h.processor.Handle(payload)
+	if errors.Is(err, billing.ErrInvalidEvent) {
+		log.Printf("⚠️ Rejected billing webhook: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
+		log.Printf("❌ Billing webhook failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"result":  outcome,
+	})
+}
@@ -1,31 +1,19 @@
 package handlers
 
 import (
-	"os"
-
 	"github.com/gofiber/fiber/v2"
 	"github.com/makosai/backend/internal/email"
 )
 
 // EmailHandler handles email requests
 type EmailHandler struct {
-	resendClient *email.ResendClient
+	mailer email.Mailer
 }
 
 // NewEmailHandler creates a new email handler
-func NewEmailHandler() *EmailHandler {
-	apiKey := os.Getenv("RESEND_API_KEY")
-	fromEmail := os.Getenv("RESEND_FROM_EMAIL")
-
-	if apiKey == "" {
-		apiKey = "re_cLoDy9WK_J4Rq72bHAKaepUEG49bWDN9H"
-	}
-	if fromEmail == "" {
-		fromEmail = "Makos.ai <support@makos.ai>"
-	}
-
+func NewEmailHandler(mailer email.Mailer) *EmailHandler {
 	return &EmailHandler{
-		resendClient: email.NewResendClient(apiKey, fromEmail),
+		mailer: mailer,
 	}
 }
 
@@ -50,7 +38,7 @@ func (h *EmailHandler) SendWelcomeEmail(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.resendClient.SendWelcomeEmail(input.Email, input.Name)
+	err := email.SendWelcomeEmail(h.mailer, input.Email, input.Name)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -63,6 +51,3 @@ func (h *EmailHandler) SendWelcomeEmail(c *fiber.Ctx) error {
 		"message": "Welcome email sent successfully",
 	})
 }
-
-
-
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/makosai/backend/internal/importer"
+	"github.com/makosai/backend/internal/models"
+)
+
+// ImportWorksheet handles POST /api/worksheets/import
+//
+// The request is multipart/form-data with a "file" part in CSV, GIFT or QTI
+// format. The format is taken from the "format" field or the file extension.
+// Optional title, subject, grade_level, difficulty and language fields describe
+// the new worksheet. Items that cannot be imported are reported per row.
+func (h *WorksheetHandler) ImportWorksheet(c *fiber.Ctx) error {
+	filename, data, fileErr := readFormFile(c)
+	if fileErr != nil {
+		return c.Status(fileErr.Code).JSON(fiber.Map{
+			"success": false,
+			"error":   fileErr.Message,
+		})
+	}
+
+	format := strings.ToLower(c.FormValue("format"))
+	if format == "" {
+		format = importer.DetectFormat(filename)
+	}
+	if format == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Could not detect import format, set \"format\" to csv, gift or qti",
+		})
+	}
+
+	result, err := importer.Parse(format, data)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if len(result.Questions) == 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"success": false,
+			"error":   "No questions could be imported",
+			"errors":  result.Errors,
+		})
+	}
+
+	title := c.FormValue("title")
+	if title == "" {
+		title = result.Title
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+
+	worksheet := &models.Worksheet{
+		ID:               "ws_" + uuid.New().String()[:8],
+		Title:            title,
+		Subject:          formValueOr(c, "subject", "general"),
+		Topic:            formValueOr(c, "topic", title),
+		GradeLevel:       formValueOr(c, "grade_level", "5"),
+		Difficulty:       formValueOr(c, "difficulty", "medium"),
+		Language:         formValueOr(c, "language", "en"),
+		SourceFile:       filename,
+		Questions:        result.Questions,
+		IncludeAnswerKey: true,
+		Status:           "draft",
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	if err := h.saveRevision(c, worksheet, "Imported from "+filename, 0); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	log.Printf("📥 Imported %d questions from %s (%s), %d rows rejected", len(result.Questions), filename, format, len(result.Errors))
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success":   true,
+		"worksheet": worksheet,
+		"imported":  len(result.Questions),
+		"errors":    result.Errors,
+		"message":   fmt.Sprintf("Imported %d of %d questions", len(result.Questions), len(result.Questions)+len(result.Errors)),
+	})
+}
+
+func formValueOr(c *fiber.Ctx, key, fallback string) string {
+	if v := strings.TrimSpace(c.FormValue(key)); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/models"
+)
+
+type moderationReviewRequest struct {
+	Decision string `json:"decision"` // only "approve"; flagged content is otherwise fixed by editing
+	Note     string `json:"note"`
+}
+
+// GetModeration handles GET /api/worksheets/:id/moderation
+func (h *WorksheetHandler) GetModeration(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"moderation": worksheet.Moderation,
+	})
+}
+
+// ReviewModeration handles POST /api/worksheets/:id/moderation/review
+//
+// A signed-in teacher approves flagged content they have checked, which unblocks export.
+// The approval stands until an edit raises a flag that wasn't there before.
+func (h *WorksheetHandler) ReviewModeration(c *fiber.Ctx) error {
+	reviewer := currentUserID(c)
+	if reviewer == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error":   "Sign in to approve flagged content",
+		})
+	}
+
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	var req moderationReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body",
+		})
+	}
+	if req.Decision != "approve" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "decision must be \"approve\"; edit the worksheet to remove flagged content instead",
+		})
+	}
+	if !worksheet.Moderation.Blocked() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet has no flagged content awaiting review",
+		})
+	}
+
+	now := time.Now()
+	updated := worksheet.Clone()
+	updated.Moderation.Status = models.ModerationApproved
+	updated.Moderation.ReviewedBy = reviewer
+	updated.Moderation.ReviewedAt = &now
+	updated.Moderation.ReviewNote = strings.TrimSpace(req.Note)
+	updated.UpdatedAt = now
+
+	// Store the flags the teacher saw rather than screening again, which could
+	// reword an LLM excerpt and undo the approval
+	if err := h.storeRevision(c, updated, "Approved flagged content", 0); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"worksheet": updated,
+	})
+}
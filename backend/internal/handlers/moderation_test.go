@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/store"
+)
+
+// seedFlagged stores the test worksheet with a flag awaiting review
+func (w *worksheetTest) seedFlagged() {
+	w.t.Helper()
+	ws := w.seed()
+	ws.Moderation = &models.Moderation{
+		Status: models.ModerationFlagged,
+		Flags:  []models.ModerationFlag{{Field: "title", Category: "violence", Score: 0.5, Excerpt: "kill"}},
+	}
+	if err := w.worksheets.Save(ws); err != nil {
+		w.t.Fatal(err)
+	}
+}
+
+func TestReviewModeration(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seedFlagged()
+
+	if status, _ := w.do("POST", "/worksheets/ws_test/moderation/review", `{"decision":"approve"}`); status != fiber.StatusUnauthorized {
+		t.Errorf("anonymous approve: %d, want 401", status)
+	}
+	if m := w.stored("ws_test").Moderation; !m.Blocked() {
+		t.Fatalf("anonymous approve changed moderation to %q", m.Status)
+	}
+
+	w.user = "user_1"
+	if status, _ := w.do("POST", "/worksheets/ws_test/moderation/review", `{"decision":"reject"}`); status != fiber.StatusBadRequest {
+		t.Errorf("reject: %d, want 400", status)
+	}
+
+	status, body := w.do("POST", "/worksheets/ws_test/moderation/review", `{"decision":"approve","note":" checked "}`)
+	if status != fiber.StatusOK {
+		t.Fatalf("approve: %d %v", status, body)
+	}
+	m := w.stored("ws_test").Moderation
+	if m.Status != models.ModerationApproved || m.ReviewedBy != "user_1" || m.ReviewNote != "checked" || m.ReviewedAt == nil || len(m.Flags) != 1 {
+		t.Errorf("stored moderation = %+v", m)
+	}
+
+	if status, _ := w.do("POST", "/worksheets/ws_test/moderation/review", `{"decision":"approve"}`); status != fiber.StatusConflict {
+		t.Errorf("approving twice: %d, want 409", status)
+	}
+}
+
+func TestReviewModerationSaveFailure(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), failingRevisions{store.NewMemoryRevisionStore()})
+	w.seedFlagged()
+	w.user = "user_1"
+
+	if status, body := w.do("POST", "/worksheets/ws_test/moderation/review", `{"decision":"approve"}`); status != fiber.StatusInternalServerError {
+		t.Fatalf("approve: %d %v, want 500", status, body)
+	}
+	if m := w.stored("ws_test").Moderation; !m.Blocked() {
+		t.Errorf("failed save left moderation %q, want it still flagged", m.Status)
+	}
+}
+
+func TestExportCountsDownload(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seedFlagged()
+
+	if status, _ := w.do("GET", "/worksheets/ws_test/export/pdf", ""); status != fiber.StatusConflict {
+		t.Fatalf("flagged export: %d, want 409", status)
+	}
+
+	w.user = "user_1"
+	if status, body := w.do("POST", "/worksheets/ws_test/moderation/review", `{"decision":"approve"}`); status != fiber.StatusOK {
+		t.Fatalf("approve: %d %v", status, body)
+	}
+	if status, body := w.do("GET", "/worksheets/ws_test/export/pdf", ""); status != fiber.StatusOK {
+		t.Fatalf("export: %d %v", status, body)
+	}
+	ws := w.stored("ws_test")
+	if ws.Downloads != 1 || ws.Moderation.Status != models.ModerationApproved {
+		t.Errorf("after export: downloads %d, moderation %q", ws.Downloads, ws.Moderation.Status)
+	}
+}
@@ -0,0 +1,616 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/diagram"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/moderation"
+)
+
+// maxQuestionsPerRequest caps how many questions can be generated in one call
+const maxQuestionsPerRequest = 20
+
+// RegenerateQuestion handles POST /api/worksheets/:id/questions/:qid/regenerate
+//
+// The optional body {"instruction": "make it harder", "type": "fill_blank"} steers
+// the replacement. The rest of the worksheet is sent as context to avoid duplicates.
+func (h *WorksheetHandler) RegenerateQuestion(c *fiber.Ctx) error {
+	qg, ok := h.generator.(ai.QuestionGenerator)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"success": false,
+			"error":   "Question regeneration is not supported by this generator",
+		})
+	}
+
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	idx := findQuestion(worksheet, c.Params("qid"))
+	if idx == -1 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Question not found",
+		})
+	}
+
+	var input struct {
+		Instruction string `json:"instruction"`
+		Type        string `json:"type"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid request body",
+			})
+		}
+	}
+
+	if inputErr := ai.CheckText("instruction", input.Instruction, ai.MaxInstructionsLength); inputErr != nil {
+		logRejected(c, "regeneration request", inputErr.Error(), input.Instruction)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid " + inputErr.Error(),
+		})
+	}
+
+	qType := input.Type
+	if qType == "" {
+		qType = typeFromInstruction(input.Instruction)
+	}
+	if qType != "" && !models.IsValidQuestionType(qType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("Unknown question type %q", qType),
+		})
+	}
+
+	account := quotaAccount(c, h.quota)
+	replacementType := qType
+	if replacementType == "" {
+		replacementType = worksheet.Questions[idx].Type
+	}
+	// Regenerating doesn't use up the monthly worksheet allowance, but the plan's
+	// question limits still apply and the tokens are recorded as usage
+	if err := h.quota.CheckQuestions(account, len(worksheet.Questions), []string{replacementType}); err != nil {
+		return quotaResponse(c, err)
+	}
+	if flags := h.screenInstruction(c, worksheet, input.Instruction); len(flags) > 0 {
+		return instructionRejected(c, flags)
+	}
+
+	req := ai.QuestionRequest{
+		Worksheet:   worksheet,
+		Replace:     &worksheet.Questions[idx],
+		Count:       1,
+		Instruction: input.Instruction,
+		Limits:      h.quota.Limits(account),
+	}
+	if qType != "" {
+		req.QuestionTypes = []string{qType}
+	}
+
+	log.Printf("🔄 Regenerating question %s on worksheet %s", c.Params("qid"), worksheet.ID)
+	ctx, meter := ai.WithUsageMeter(c.Context())
+	generated, err := qg.GenerateQuestions(ctx, req)
+	usage := h.recordUsage(c, models.UsageQuestions, worksheet.ID, meter)
+	if err != nil {
+		var conformanceErr *ai.ConformanceError
+		if errors.As(err, &conformanceErr) {
+			logRejected(c, "regenerated question", err.Error(), input.Instruction)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"success": false,
+				"error":   "The generated questions didn't match your request; try rewording the instruction",
+			})
+		}
+		log.Printf("❌ Regeneration error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to regenerate question: " + err.Error(),
+		})
+	}
+
+	if len(generated) == 0 {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "The generator did not return a question",
+		})
+	}
+	replacement := generated[0]
+	if err := replacement.Validate(); err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "Generated question was invalid: " + err.Error(),
+		})
+	}
+	replacement.ID = worksheet.Questions[idx].ID
+
+	updated := worksheet.Clone()
+	updated.Questions[idx] = replacement
+	addUsage(updated, usage)
+	if err := h.saveEdit(c, updated, "Regenerated question "+c.Params("qid")); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"question":  replacement,
+		"worksheet": updated,
+	})
+}
+
+// GenerateMoreQuestions handles POST /api/worksheets/:id/questions/generate
+//
+// Body: {"count": 3, "position": 2, "question_types": ["true_false"], "instruction": "..."}.
+// Questions are appended unless a 1-based position is given, then all IDs are renumbered.
+func (h *WorksheetHandler) GenerateMoreQuestions(c *fiber.Ctx) error {
+	qg, ok := h.generator.(ai.QuestionGenerator)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"success": false,
+			"error":   "Question generation is not supported by this generator",
+		})
+	}
+
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	var input struct {
+		Count         int      `json:"count"`
+		Position      int      `json:"position"`
+		QuestionTypes []string `json:"question_types"`
+		Instruction   string   `json:"instruction"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid request body",
+			})
+		}
+	}
+
+	if input.Count == 0 {
+		input.Count = 1
+	}
+	if input.Count < 0 || input.Count > maxQuestionsPerRequest {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("count must be between 1 and %d", maxQuestionsPerRequest),
+		})
+	}
+	if input.Position < 0 || input.Position > len(worksheet.Questions)+1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("position must be between 1 and %d", len(worksheet.Questions)+1),
+		})
+	}
+	for _, t := range input.QuestionTypes {
+		if !models.IsValidQuestionType(t) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   fmt.Sprintf("Unknown question type %q", t),
+			})
+		}
+	}
+
+	if inputErr := ai.CheckText("instruction", input.Instruction, ai.MaxInstructionsLength); inputErr != nil {
+		logRejected(c, "question request", inputErr.Error(), input.Instruction)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid " + inputErr.Error(),
+		})
+	}
+
+	account := quotaAccount(c, h.quota)
+	if err := h.quota.CheckQuestions(account, len(worksheet.Questions)+input.Count, input.QuestionTypes); err != nil {
+		return quotaResponse(c, err)
+	}
+	if flags := h.screenInstruction(c, worksheet, input.Instruction); len(flags) > 0 {
+		return instructionRejected(c, flags)
+	}
+
+	log.Printf("➕ Generating %d more question(s) for worksheet %s", input.Count, worksheet.ID)
+	ctx, meter := ai.WithUsageMeter(c.Context())
+	generated, err := qg.GenerateQuestions(ctx, ai.QuestionRequest{
+		Worksheet:     worksheet,
+		Count:         input.Count,
+		QuestionTypes: input.QuestionTypes,
+		Instruction:   input.Instruction,
+		Limits:        h.quota.Limits(account),
+	})
+	usage := h.recordUsage(c, models.UsageQuestions, worksheet.ID, meter)
+	if err != nil {
+		var conformanceErr *ai.ConformanceError
+		if errors.As(err, &conformanceErr) {
+			logRejected(c, "generated questions", err.Error(), input.Instruction)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"success": false,
+				"error":   "The generated questions didn't match your request; try rewording the instruction",
+			})
+		}
+		log.Printf("❌ Generation error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to generate questions: " + err.Error(),
+		})
+	}
+
+	var added []models.Question
+	for i, q := range generated {
+		if err := q.Validate(); err != nil {
+			log.Printf("⚠️ Dropping invalid generated question %d: %v", i+1, err)
+			continue
+		}
+		added = append(added, q)
+	}
+	if len(added) == 0 {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "The generator did not return any valid questions",
+		})
+	}
+
+	at := len(worksheet.Questions)
+	if input.Position > 0 {
+		at = input.Position - 1
+	}
+	updated := worksheet.Clone()
+	questions := make([]models.Question, 0, len(updated.Questions)+len(added))
+	questions = append(questions, updated.Questions[:at]...)
+	questions = append(questions, added...)
+	questions = append(questions, updated.Questions[at:]...)
+
+	updated.Questions = questions
+	renumberQuestions(updated)
+	addUsage(updated, usage)
+	if err := h.saveEdit(c, updated, fmt.Sprintf("Generated %d question(s)", len(added))); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"added":     len(added),
+		"worksheet": updated,
+	})
+}
+
+// AddQuestion handles POST /api/worksheets/:id/questions
+//
+// The body is a question, optionally with a 1-based "position" to insert at.
+func (h *WorksheetHandler) AddQuestion(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	var input struct {
+		models.Question
+		Position int `json:"position"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body",
+		})
+	}
+
+	q := input.Question
+	normalizeQuestion(&q)
+	if err := checkQuestion(&q); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if input.Position < 0 || input.Position > len(worksheet.Questions)+1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("position must be between 1 and %d", len(worksheet.Questions)+1),
+		})
+	}
+	at := len(worksheet.Questions)
+	if input.Position > 0 {
+		at = input.Position - 1
+	}
+
+	updated := worksheet.Clone()
+	updated.Questions = append(updated.Questions, models.Question{})
+	copy(updated.Questions[at+1:], updated.Questions[at:])
+	updated.Questions[at] = q
+	renumberQuestions(updated)
+
+	if err := h.saveEdit(c, updated, "Added question "+updated.Questions[at].ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success":   true,
+		"question":  updated.Questions[at],
+		"worksheet": updated,
+	})
+}
+
+// UpdateQuestion handles PATCH /api/worksheets/:id/questions/:qid
+//
+// Fields present in the body replace the question's current values as a
+// whole (a new diagram does not merge into the old one, and null clears a
+// field); the result is validated against its (possibly new) type.
+func (h *WorksheetHandler) UpdateQuestion(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	idx := findQuestion(worksheet, c.Params("qid"))
+	if idx == -1 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Question not found",
+		})
+	}
+
+	updated := worksheet.Clone()
+	q, err := patchQuestion(updated.Questions[idx], c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body",
+		})
+	}
+	q.ID = worksheet.Questions[idx].ID
+
+	normalizeQuestion(&q)
+	if err := checkQuestion(&q); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	updated.Questions[idx] = q
+
+	if err := h.saveEdit(c, updated, "Edited question "+q.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"question":  q,
+		"worksheet": updated,
+	})
+}
+
+// DeleteQuestion handles DELETE /api/worksheets/:id/questions/:qid
+func (h *WorksheetHandler) DeleteQuestion(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	idx := findQuestion(worksheet, c.Params("qid"))
+	if idx == -1 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Question not found",
+		})
+	}
+
+	updated := worksheet.Clone()
+	updated.Questions = append(updated.Questions[:idx], updated.Questions[idx+1:]...)
+	renumberQuestions(updated)
+
+	if err := h.saveEdit(c, updated, "Deleted question "+c.Params("qid")); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"worksheet": updated,
+	})
+}
+
+// MoveQuestion handles POST /api/worksheets/:id/questions/:qid/move
+//
+// Body: {"position": 1} — the question's new 1-based position.
+func (h *WorksheetHandler) MoveQuestion(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	idx := findQuestion(worksheet, c.Params("qid"))
+	if idx == -1 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Question not found",
+		})
+	}
+
+	var input struct {
+		Position int `json:"position"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body",
+		})
+	}
+	if input.Position < 1 || input.Position > len(worksheet.Questions) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("position must be between 1 and %d", len(worksheet.Questions)),
+		})
+	}
+
+	updated := worksheet.Clone()
+	q := updated.Questions[idx]
+	updated.Questions = append(updated.Questions[:idx], updated.Questions[idx+1:]...)
+	at := input.Position - 1
+	updated.Questions = append(updated.Questions, models.Question{})
+	copy(updated.Questions[at+1:], updated.Questions[at:])
+	updated.Questions[at] = q
+	renumberQuestions(updated)
+
+	if err := h.saveEdit(c, updated, fmt.Sprintf("Moved question %s to position %d", c.Params("qid"), input.Position)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"worksheet": updated,
+	})
+}
+
+// normalizeQuestion trims an edited question and fills in type-specific defaults
+func normalizeQuestion(q *models.Question) {
+	q.Type = strings.TrimSpace(q.Type)
+	q.Question = strings.TrimSpace(q.Question)
+	switch models.QuestionType(q.Type) {
+	case models.TrueFalse:
+		if len(q.Options) == 0 {
+			q.Options = []string{"True", "False"}
+		}
+	case models.ShortAnswer, models.Essay:
+		q.Options = nil
+	}
+	if q.Points == 0 {
+		q.Points = models.DefaultPoints(q.Type)
+	}
+}
+
+// checkQuestion validates an edited question and renders its diagram spec, if any
+func checkQuestion(q *models.Question) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	return diagram.Apply(q)
+}
+
+// findQuestion returns the index of the question with the given ID, or -1
+func findQuestion(ws *models.Worksheet, qid string) int {
+	for i := range ws.Questions {
+		if ws.Questions[i].ID == qid {
+			return i
+		}
+	}
+	return -1
+}
+
+// renumberQuestions reassigns question IDs to match their order (q_1, q_2, ...)
+func renumberQuestions(ws *models.Worksheet) {
+	for i := range ws.Questions {
+		ws.Questions[i].ID = fmt.Sprintf("q_%d", i+1)
+	}
+}
+
+// screenInstruction moderates a teacher's instruction against the worksheet's
+// grade before a generation is spent on it
+func (h *WorksheetHandler) screenInstruction(c *fiber.Ctx, ws *models.Worksheet, instruction string) []models.ModerationFlag {
+	if strings.TrimSpace(instruction) == "" {
+		return nil
+	}
+	flags := h.moderator.Screen(c.Context(), ws.GradeLevel, []moderation.Text{
+		{Field: "instruction", Content: instruction},
+	})
+	if len(flags) > 0 {
+		log.Printf("🚫 Rejected question request for worksheet %s: %d moderation flag(s)", ws.ID, len(flags))
+	}
+	return flags
+}
+
+func instructionRejected(c *fiber.Ctx, flags []models.ModerationFlag) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"success": false,
+		"error":   "The instruction contains content that isn't suitable for this grade",
+		"flags":   flags,
+	})
+}
+
+// typeFromInstruction picks up requests like "change to fill_blank" in a free-text instruction
+func typeFromInstruction(instruction string) string {
+	lower := strings.ToLower(instruction)
+	for _, t := range models.ValidQuestionTypes {
+		if strings.Contains(lower, string(t)) || strings.Contains(lower, strings.ReplaceAll(string(t), "_", " ")) {
+			return string(t)
+		}
+	}
+	return ""
+}
+
+// patchQuestion replaces each top-level field named in body, so nested values
+// such as a diagram's sub-spec are swapped out rather than merged
+func patchQuestion(q models.Question, body []byte) (models.Question, error) {
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return q, err
+	}
+	current, err := json.Marshal(q)
+	if err != nil {
+		return q, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(current, &fields); err != nil {
+		return q, err
+	}
+	for k, v := range patch {
+		fields[k] = v
+	}
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return q, err
+	}
+	var out models.Question
+	if err := json.Unmarshal(merged, &out); err != nil {
+		return q, err
+	}
+	return out, nil
+}
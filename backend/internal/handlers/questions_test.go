@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/gencache"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/moderation"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/store"
+)
+
+// stubGenerator returns canned questions, or err, for every request
+type stubGenerator struct {
+	ai.MockGenerator
+	questions []models.Question
+	err       error
+	calls     int
+}
+
+func (g *stubGenerator) GenerateQuestions(ctx context.Context, req ai.QuestionRequest) ([]models.Question, error) {
+	g.calls++
+	return g.questions, g.err
+}
+
+// failingRevisions refuses to store revisions, so every save fails
+type failingRevisions struct {
+	*store.MemoryRevisionStore
+}
+
+func (failingRevisions) Append(*models.Revision) error {
+	return errors.New("disk full")
+}
+
+type worksheetTest struct {
+	t          *testing.T
+	app        *fiber.App
+	worksheets *store.MemoryWorksheetStore
+	quotas     *quota.Quota
+	user       string // sent as the signed-in user when set
+}
+
+func newWorksheetTest(t *testing.T, generator ai.Generator, revisions store.RevisionStore) *worksheetTest {
+	t.Helper()
+	quotas, err := quota.New(quota.DefaultPlans, store.NewMemoryUserStore(), store.NewMemoryCounterStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revisions == nil {
+		revisions = store.NewMemoryRevisionStore()
+	}
+	wordlist, err := moderation.NewWordListClassifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	worksheets := store.NewMemoryWorksheetStore()
+	h := NewWorksheetHandler(generator, worksheets, revisions, moderation.New(wordlist), store.NewMemoryUsageStore(), quotas, gencache.NewMemoryCache(0))
+
+	app := fiber.New()
+	app.Get("/worksheets/:id", h.GetWorksheet)
+	app.Get("/worksheets/:id/export/pdf", h.ExportWorksheetPDF)
+	app.Put("/worksheets/:id", h.UpdateWorksheet)
+	app.Patch("/worksheets/:id", h.PatchWorksheet)
+	app.Post("/worksheets/:id/moderation/review", h.ReviewModeration)
+	app.Get("/worksheets/:id/revisions/diff", h.DiffRevisions)
+	app.Post("/worksheets/:id/revisions/:rev/restore", h.RestoreRevision)
+	app.Post("/worksheets/:id/questions", h.AddQuestion)
+	app.Post("/worksheets/:id/questions/generate", h.GenerateMoreQuestions)
+	app.Patch("/worksheets/:id/questions/:qid", h.UpdateQuestion)
+	app.Delete("/worksheets/:id/questions/:qid", h.DeleteQuestion)
+	app.Post("/worksheets/:id/questions/:qid/move", h.MoveQuestion)
+	app.Post("/worksheets/:id/questions/:qid/regenerate", h.RegenerateQuestion)
+	return &worksheetTest{t: t, app: app, worksheets: worksheets, quotas: quotas}
+}
+
+// seed stores a worksheet with two short-answer questions
+func (w *worksheetTest) seed() *models.Worksheet {
+	w.t.Helper()
+	ws := &models.Worksheet{
+		ID:         "ws_test",
+		Title:      "Plants",
+		Subject:    "science",
+		Topic:      "Plants",
+		GradeLevel: "4",
+		Difficulty: "medium",
+		Questions: []models.Question{
+			{ID: "q_1", Type: "short_answer", Question: "What do roots do?", CorrectAnswer: "Absorb water", Points: 2},
+			{ID: "q_2", Type: "short_answer", Question: "What do leaves do?", CorrectAnswer: "Make food", Points: 2},
+		},
+		Status:    "draft",
+		CreatedAt: time.Now(),
+	}
+	if err := w.worksheets.Save(ws); err != nil {
+		w.t.Fatal(err)
+	}
+	return ws
+}
+
+// do sends a request with an optional JSON body and decodes the response
+func (w *worksheetTest) do(method, path, body string) (int, map[string]interface{}) {
+	w.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if w.user != "" {
+		req.Header.Set("Authorization", "Bearer "+tokenPrefix+w.user)
+	}
+	resp, err := w.app.Test(req)
+	if err != nil {
+		w.t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		w.t.Fatalf("%s %s: %v: %s", method, path, err, data)
+	}
+	return resp.StatusCode, out
+}
+
+// stored returns the worksheet as the store currently has it
+func (w *worksheetTest) stored(id string) *models.Worksheet {
+	w.t.Helper()
+	ws, ok := w.worksheets.Get(id)
+	if !ok {
+		w.t.Fatalf("worksheet %s not stored", id)
+	}
+	return ws
+}
+
+func TestRegenerateQuestion(t *testing.T) {
+	replacement := models.Question{ID: "new", Type: "short_answer", Question: "Why are leaves green?", CorrectAnswer: "Chlorophyll", Points: 2}
+	w := newWorksheetTest(t, &stubGenerator{questions: []models.Question{replacement}}, nil)
+	w.seed()
+
+	status, body := w.do("POST", "/worksheets/ws_test/questions/q_2/regenerate", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("regenerate: %d %v", status, body)
+	}
+	q := w.stored("ws_test").Questions[1]
+	if q.ID != "q_2" || q.Question != replacement.Question {
+		t.Errorf("stored question = %+v", q)
+	}
+}
+
+func TestRegenerateQuestionNoResult(t *testing.T) {
+	w := newWorksheetTest(t, &stubGenerator{}, nil)
+	w.seed()
+
+	status, body := w.do("POST", "/worksheets/ws_test/questions/q_1/regenerate", "")
+	if status != fiber.StatusBadGateway || body["success"] != false {
+		t.Fatalf("regenerate: %d %v, want 502", status, body)
+	}
+	if q := w.stored("ws_test").Questions[0]; q.Question != "What do roots do?" {
+		t.Errorf("stored question changed to %q", q.Question)
+	}
+}
+
+func TestRegenerateQuestionSaveFailure(t *testing.T) {
+	replacement := models.Question{Type: "short_answer", Question: "Why are leaves green?", CorrectAnswer: "Chlorophyll", Points: 2}
+	w := newWorksheetTest(t, &stubGenerator{questions: []models.Question{replacement}}, failingRevisions{store.NewMemoryRevisionStore()})
+	w.seed()
+
+	if status, body := w.do("POST", "/worksheets/ws_test/questions/q_2/regenerate", ""); status != fiber.StatusInternalServerError {
+		t.Fatalf("regenerate: %d %v, want 500", status, body)
+	}
+	if q := w.stored("ws_test").Questions[1]; q.Question != "What do leaves do?" {
+		t.Errorf("failed save changed the stored question to %q", q.Question)
+	}
+}
+
+func TestGenerateMoreQuestions(t *testing.T) {
+	added := []models.Question{
+		{Type: "short_answer", Question: "What do stems do?", CorrectAnswer: "Carry water", Points: 2},
+	}
+
+	t.Run("inserted at position", func(t *testing.T) {
+		w := newWorksheetTest(t, &stubGenerator{questions: added}, nil)
+		w.seed()
+		if status, body := w.do("POST", "/worksheets/ws_test/questions/generate", `{"count":1,"position":2}`); status != fiber.StatusOK {
+			t.Fatalf("generate: %d %v", status, body)
+		}
+		got := w.stored("ws_test").Questions
+		if len(got) != 3 || got[1].Question != "What do stems do?" || got[1].ID != "q_2" || got[2].ID != "q_3" {
+			t.Errorf("stored questions = %+v", got)
+		}
+	})
+
+	t.Run("save failure", func(t *testing.T) {
+		w := newWorksheetTest(t, &stubGenerator{questions: added}, failingRevisions{store.NewMemoryRevisionStore()})
+		w.seed()
+		if status, body := w.do("POST", "/worksheets/ws_test/questions/generate", `{"count":1,"position":1}`); status != fiber.StatusInternalServerError {
+			t.Fatalf("generate: %d %v, want 500", status, body)
+		}
+		got := w.stored("ws_test").Questions
+		if len(got) != 2 || got[0].ID != "q_1" || got[0].Question != "What do roots do?" {
+			t.Errorf("failed save changed the stored questions to %+v", got)
+		}
+	})
+}
+
+func TestAddQuestion(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seed()
+
+	status, body := w.do("POST", "/worksheets/ws_test/questions", `{"type":"short_answer","question":"What do stems do?","correct_answer":"Carry water","points":2,"position":1}`)
+	if status != fiber.StatusCreated {
+		t.Fatalf("add: %d %v", status, body)
+	}
+	got := w.stored("ws_test").Questions
+	if len(got) != 3 || got[0].Question != "What do stems do?" || got[0].ID != "q_1" || got[1].ID != "q_2" || got[2].ID != "q_3" {
+		t.Errorf("stored questions = %+v", got)
+	}
+
+	for _, body := range []string{
+		`{"type":"short_answer","question":"Too far","correct_answer":"x","points":1,"position":5}`,
+		`{"type":"essay_plus","question":"Unknown type","points":1}`,
+	} {
+		if status, resp := w.do("POST", "/worksheets/ws_test/questions", body); status != fiber.StatusBadRequest {
+			t.Errorf("add %s: %d %v, want 400", body, status, resp)
+		}
+	}
+}
+
+func TestUpdateQuestion(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	ws := w.seed()
+	ws.Questions[0].Diagram = &models.Diagram{Type: models.DiagramGraph, Graph: &models.Graph{
+		XMin: -5, XMax: 5, YMin: -5, YMax: 5,
+		Functions: []models.GraphFunction{{Expression: "x"}},
+	}}
+	if err := w.worksheets.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := w.do("PATCH", "/worksheets/ws_test/questions/q_1", `{"diagram":{"type":"triangle","shape":{"sides":[3,4,5]}}}`)
+	if status != fiber.StatusOK {
+		t.Fatalf("update: %d %v", status, body)
+	}
+	q := w.stored("ws_test").Questions[0]
+	if q.Diagram == nil || q.Diagram.Type != models.DiagramTriangle || q.Diagram.Graph != nil {
+		t.Errorf("diagram = %+v, want a triangle without the old graph", q.Diagram)
+	}
+	if q.Question != "What do roots do?" || q.Points != 2 || q.CorrectAnswer != "Absorb water" {
+		t.Errorf("fields missing from the body changed: %+v", q)
+	}
+
+	if status, body := w.do("PATCH", "/worksheets/ws_test/questions/q_1", `{"diagram":null,"id":"q_9"}`); status != fiber.StatusOK {
+		t.Fatalf("clear diagram: %d %v", status, body)
+	}
+	if q := w.stored("ws_test").Questions[0]; q.Diagram != nil || q.ID != "q_1" {
+		t.Errorf("after clearing: id %q, diagram %+v", q.ID, q.Diagram)
+	}
+
+	if status, body := w.do("PATCH", "/worksheets/ws_test/questions/q_1", `{"points":"two"}`); status != fiber.StatusBadRequest {
+		t.Errorf("bad points: %d %v, want 400", status, body)
+	}
+	if status, body := w.do("PATCH", "/worksheets/ws_test/questions/q_7", `{"points":1}`); status != fiber.StatusNotFound {
+		t.Errorf("unknown question: %d %v, want 404", status, body)
+	}
+}
+
+func TestDeleteQuestion(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seed()
+
+	if status, body := w.do("DELETE", "/worksheets/ws_test/questions/q_1", ""); status != fiber.StatusOK {
+		t.Fatalf("delete: %d %v", status, body)
+	}
+	got := w.stored("ws_test").Questions
+	if len(got) != 1 || got[0].ID != "q_1" || got[0].Question != "What do leaves do?" {
+		t.Errorf("stored questions = %+v, want the second question renumbered to q_1", got)
+	}
+	if status, body := w.do("DELETE", "/worksheets/ws_test/questions/q_2", ""); status != fiber.StatusNotFound {
+		t.Errorf("delete removed id: %d %v, want 404", status, body)
+	}
+}
+
+func TestMoveQuestion(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seed()
+
+	for _, pos := range []string{"0", "3", "-1"} {
+		if status, body := w.do("POST", "/worksheets/ws_test/questions/q_1/move", `{"position":`+pos+`}`); status != fiber.StatusBadRequest {
+			t.Errorf("move to %s: %d %v, want 400", pos, status, body)
+		}
+	}
+	if w.stored("ws_test").Revision != 0 {
+		t.Error("rejected moves saved a revision")
+	}
+
+	if status, body := w.do("POST", "/worksheets/ws_test/questions/q_1/move", `{"position":2}`); status != fiber.StatusOK {
+		t.Fatalf("move: %d %v", status, body)
+	}
+	got := w.stored("ws_test").Questions
+	if got[0].Question != "What do leaves do?" || got[0].ID != "q_1" || got[1].Question != "What do roots do?" || got[1].ID != "q_2" {
+		t.Errorf("stored questions = %+v", got)
+	}
+}
+
+func TestQuestionInstructionModerated(t *testing.T) {
+	generator := &stubGenerator{questions: []models.Question{
+		{Type: "short_answer", Question: "What do stems do?", CorrectAnswer: "Carry water", Points: 2},
+	}}
+	w := newWorksheetTest(t, generator, nil)
+	w.seed()
+
+	for _, path := range []string{
+		"/worksheets/ws_test/questions/q_1/regenerate",
+		"/worksheets/ws_test/questions/generate",
+	} {
+		status, body := w.do("POST", path, `{"instruction":"ask about making a pipe bomb"}`)
+		if status != fiber.StatusBadRequest || body["flags"] == nil {
+			t.Errorf("%s: %d %v, want 400 with flags", path, status, body)
+		}
+	}
+	if generator.calls != 0 {
+		t.Errorf("generator called %d times for rejected instructions", generator.calls)
+	}
+	if got := w.stored("ws_test"); got.Revision != 0 || len(got.Questions) != 2 {
+		t.Errorf("rejected instructions changed the worksheet: revision %d, %d questions", got.Revision, len(got.Questions))
+	}
+
+	if status, body := w.do("POST", "/worksheets/ws_test/questions/generate", `{"instruction":"ask about how water moves up the stem"}`); status != fiber.StatusOK {
+		t.Errorf("harmless instruction: %d %v", status, body)
+	}
+}
+
+func TestQuestionGenerationQuota(t *testing.T) {
+	generator := &stubGenerator{questions: []models.Question{
+		{Type: "short_answer", Question: "What do stems do?", CorrectAnswer: "Carry water", Points: 2},
+	}}
+	w := newWorksheetTest(t, generator, nil)
+	w.seed()
+
+	if status, body := w.do("POST", "/worksheets/ws_test/questions/q_1/regenerate", ""); status != fiber.StatusOK {
+		t.Fatalf("regenerate: %d %v", status, body)
+	}
+	if status, body := w.do("POST", "/worksheets/ws_test/questions/generate", ""); status != fiber.StatusOK {
+		t.Fatalf("generate: %d %v", status, body)
+	}
+	if used := w.quotas.Summary(w.quotas.Account("", "0.0.0.0")).Worksheets.Used; used != 0 {
+		t.Errorf("question generation used %d of the worksheet allowance", used)
+	}
+
+	// The free plan allows 10 questions per worksheet and 3 are stored now
+	if status, body := w.do("POST", "/worksheets/ws_test/questions/generate", `{"count":8}`); status != fiber.StatusPaymentRequired {
+		t.Errorf("generate past the plan limit: %d %v, want 402", status, body)
+	}
+	if generator.calls != 2 {
+		t.Errorf("generator called %d times, want 2", generator.calls)
+	}
+}
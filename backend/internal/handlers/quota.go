@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/quota"
+)
+
+// quotaAccount is whose allowance a request uses: the signed-in user, or the client IP
+func quotaAccount(c *fiber.Ctx, quotas *quota.Quota) string {
+	return quotas.Account(currentUserID(c), c.IP())
+}
+
+// quotaResponse writes a plan limit as 402, or a used-up monthly allowance as 429 with Retry-After
+func quotaResponse(c *fiber.Ctx, err error) error {
+	var qe *quota.Error
+	if !errors.As(err, &qe) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	body := fiber.Map{
+		"success": false,
+		"error":   qe.Message,
+		"code":    qe.Code,
+		"limit":   qe.Limit,
+		"upgrade": true,
+	}
+	if !qe.ResetAt.IsZero() {
+		wait := math.Ceil(time.Until(qe.ResetAt).Seconds())
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait)))
+		body["resets_at"] = qe.ResetAt
+	}
+	return c.Status(qe.Status).JSON(body)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/ratelimit"
+)
+
+// RateLimit returns middleware that allows each account the policy's rate,
+// keyed like quotas: by signed-in user, or by IP for anonymous requests. If
+// the limiter's backend fails, requests are let through rather than locking
+// everyone out.
+func RateLimit(limiter ratelimit.Limiter, policy ratelimit.Policy, quotas *quota.Quota) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := quotaAccount(c, quotas)
+		result, err := limiter.Allow(c.Context(), key, policy)
+		if err != nil {
+			log.Printf("⚠️ Rate limiter unavailable, allowing request: %v", err)
+			return c.Next()
+		}
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(policy.Burst))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if result.Allowed {
+			return c.Next()
+		}
+
+		wait := int(math.Ceil(result.RetryAfter.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(wait))
+		log.Printf("🐢 Rate limited %s on %s %s (%s)", key, c.Method(), c.Path(), policy.Name)
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"success":     false,
+			"error":       fmt.Sprintf("Too many requests, try again in %d seconds", wait),
+			"retry_after": wait,
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/models"
+)
+
+// GetRevisions handles GET /api/worksheets/:id/revisions
+func (h *WorksheetHandler) GetRevisions(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"current":   worksheet.Revision,
+		"revisions": h.revisions.List(worksheet.ID),
+	})
+}
+
+// GetRevision handles GET /api/worksheets/:id/revisions/:rev
+func (h *WorksheetHandler) GetRevision(c *fiber.Ctx) error {
+	rev, revErr := h.findRevision(c, c.Params("rev"))
+	if revErr != nil {
+		return revisionError(c, revErr)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"revision": rev,
+	})
+}
+
+// DiffRevisions handles GET /api/worksheets/:id/revisions/diff?from=1&to=3
+//
+// "to" defaults to the current revision and "from" to the one before it, or
+// to "to" itself when that is the first revision, giving an empty diff.
+func (h *WorksheetHandler) DiffRevisions(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	to := c.QueryInt("to", worksheet.Revision)
+	from := c.QueryInt("from", to-1)
+	if from < 1 && c.Query("from") == "" {
+		from = to
+	}
+
+	fromRev, revErr := h.findRevision(c, strconv.Itoa(from))
+	if revErr != nil {
+		return revisionError(c, revErr)
+	}
+	toRev, revErr := h.findRevision(c, strconv.Itoa(to))
+	if revErr != nil {
+		return revisionError(c, revErr)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"diff":    models.DiffWorksheets(fromRev.Worksheet, toRev.Worksheet),
+	})
+}
+
+// RestoreRevision handles POST /api/worksheets/:id/revisions/:rev/restore
+//
+// The old snapshot becomes the worksheet's content again as a new revision,
+// so the history in between is kept. Download counts are not rolled back, but
+// Usage is, as it describes the generation the restored content came from.
+func (h *WorksheetHandler) RestoreRevision(c *fiber.Ctx) error {
+	rev, revErr := h.findRevision(c, c.Params("rev"))
+	if revErr != nil {
+		return revisionError(c, revErr)
+	}
+
+	current, _ := h.worksheets.Get(rev.WorksheetID)
+	restored := rev.Worksheet
+	restored.CreatedAt = current.CreatedAt
+	restored.Downloads = current.Downloads
+	restored.UpdatedAt = time.Now()
+
+	summary := fmt.Sprintf("Restored revision %d", rev.Number)
+	if err := h.saveRevision(c, restored, summary, rev.Number); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"worksheet": restored,
+	})
+}
+
+// findRevision looks up a revision of the worksheet named in the route
+func (h *WorksheetHandler) findRevision(c *fiber.Ctx, number string) (*models.Revision, *fiber.Error) {
+	id := c.Params("id")
+	if _, exists := h.worksheets.Get(id); !exists {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Worksheet not found")
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Revision must be a number")
+	}
+
+	rev, exists := h.revisions.Get(id, n)
+	if !exists {
+		return nil, fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("Revision %d not found", n))
+	}
+	return rev, nil
+}
+
+func revisionError(c *fiber.Ctx, revErr *fiber.Error) error {
+	return c.Status(revErr.Code).JSON(fiber.Map{
+		"success": false,
+		"error":   revErr.Message,
+	})
+}
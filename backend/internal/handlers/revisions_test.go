@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/models"
+)
+
+// seedHistory stores the seeded worksheet as revision 1 and a retitled copy as revision 2
+func (w *worksheetTest) seedHistory() {
+	w.t.Helper()
+	w.seed()
+	for _, title := range []string{"Plants", "Plant parts"} {
+		if status, body := w.do("PUT", "/worksheets/ws_test", `{"title":"`+title+`"}`); status != fiber.StatusOK {
+			w.t.Fatalf("update: %d %v", status, body)
+		}
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seedHistory()
+
+	tests := []struct {
+		query    string
+		status   int
+		from, to float64
+		changes  int
+	}{
+		{"", fiber.StatusOK, 1, 2, 1},
+		{"?from=2&to=1", fiber.StatusOK, 2, 1, 1},
+		{"?to=1", fiber.StatusOK, 1, 1, 0},
+		{"?from=0&to=1", fiber.StatusNotFound, 0, 0, 0},
+		{"?to=3", fiber.StatusNotFound, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		status, body := w.do("GET", "/worksheets/ws_test/revisions/diff"+tt.query, "")
+		if status != tt.status {
+			t.Errorf("diff%s: %d %v, want %d", tt.query, status, body, tt.status)
+			continue
+		}
+		if status != fiber.StatusOK {
+			continue
+		}
+		diff := body["diff"].(map[string]interface{})
+		if diff["from"] != tt.from || diff["to"] != tt.to || len(diff["metadata"].([]interface{})) != tt.changes {
+			t.Errorf("diff%s = %v", tt.query, diff)
+		}
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seed()
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := w.worksheets.Update("ws_test", func(ws *models.Worksheet) error {
+		ws.CreatedAt = created
+		ws.Usage = &models.Usage{Calls: 1, InputTokens: 100}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := w.do("PUT", "/worksheets/ws_test", `{"title":"Plants"}`); status != fiber.StatusOK {
+		t.Fatalf("update: %d %v", status, body)
+	}
+
+	// revision 2 comes from a regeneration that spent more tokens, then the worksheet is exported
+	if _, err := w.worksheets.Update("ws_test", func(ws *models.Worksheet) error {
+		ws.Usage.Add(models.Usage{Calls: 1, InputTokens: 50})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := w.do("PUT", "/worksheets/ws_test", `{"title":"Plant parts"}`); status != fiber.StatusOK {
+		t.Fatalf("update: %d %v", status, body)
+	}
+	if status, body := w.do("GET", "/worksheets/ws_test/export/pdf", ""); status != fiber.StatusOK {
+		t.Fatalf("export: %d %v", status, body)
+	}
+
+	if status, body := w.do("POST", "/worksheets/ws_test/revisions/1/restore", ""); status != fiber.StatusOK {
+		t.Fatalf("restore: %d %v", status, body)
+	}
+	got := w.stored("ws_test")
+	if got.Title != "Plants" || got.Revision != 3 {
+		t.Errorf("restored title %q at revision %d, want \"Plants\" at 3", got.Title, got.Revision)
+	}
+	if !got.CreatedAt.Equal(created) || got.Downloads != 1 {
+		t.Errorf("restore changed created_at to %v and downloads to %d", got.CreatedAt, got.Downloads)
+	}
+	if got.Usage == nil || got.Usage.Calls != 1 || got.Usage.InputTokens != 100 {
+		t.Errorf("usage = %+v, want revision 1's", got.Usage)
+	}
+
+	for _, rev := range []string{"4", "0", "latest"} {
+		if status, body := w.do("POST", "/worksheets/ws_test/revisions/"+rev+"/restore", ""); status == fiber.StatusOK {
+			t.Errorf("restore %s: %d %v, want an error", rev, status, body)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// tokenPrefix is the prefix of the demo tokens issued by Register and Login
+const tokenPrefix = "demo_token_"
+
+// currentUserID returns the user ID from the "Authorization: Bearer <token>" header,
+// or "" when the request is not authenticated. The result is copied because fiber
+// reuses header buffers once the handler returns.
+func currentUserID(c *fiber.Ctx) string {
+	auth := strings.Clone(strings.TrimSpace(c.Get(fiber.HeaderAuthorization)))
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(token, tokenPrefix)
+}
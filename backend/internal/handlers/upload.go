@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/source"
+)
+
+// maxUploadSize is the largest source file accepted for generation
+const maxUploadSize = 4 * 1024 * 1024
+
+// MaxBodySize is the request body limit the server needs so that a
+// maxUploadSize file still fits alongside the multipart headers and form fields
+const MaxBodySize = maxUploadSize + 256*1024
+
+// GenerateFromUpload handles POST /api/worksheets/generate/upload
+//
+// The request is multipart/form-data with a "file" part (.txt, .md, .docx or .pdf)
+// and the usual generation fields as form values. Questions are drawn only from
+// the uploaded material and each one records the excerpt it came from.
+func (h *WorksheetHandler) GenerateFromUpload(c *fiber.Ctx) error {
+	filename, data, fileErr := readFormFile(c)
+	if fileErr != nil {
+		return c.Status(fileErr.Code).JSON(models.GenerationResponse{
+			Success: false,
+			Error:   fileErr.Message,
+		})
+	}
+
+	text, err := source.Extract(filename, data)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.GenerationResponse{
+			Success: false,
+			Error:   "Could not extract text: " + err.Error(),
+		})
+	}
+
+	excerpts, truncated := source.Chunk(text)
+	if truncated {
+		log.Printf("⚠️ Source %s truncated to %d excerpts to fit the model context", filename, len(excerpts))
+	}
+
+	input := formGeneratorInput(c)
+	if input.Topic == "" {
+		input.Topic = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+	input.SourceFile = filename
+	input.SourceExcerpts = excerpts
+	applyInputDefaults(&input)
+
+	log.Printf("📎 Grounding generation in %s (%d excerpts)", filename, len(excerpts))
+	return h.generate(c, input)
+}
+
+// readFormFile reads the multipart "file" part, enforcing maxUploadSize
+func readFormFile(c *fiber.Ctx) (string, []byte, *fiber.Error) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return "", nil, fiber.NewError(fiber.StatusBadRequest, "A file is required in the \"file\" field")
+	}
+	if fileHeader.Size > maxUploadSize {
+		return "", nil, fiber.NewError(fiber.StatusRequestEntityTooLarge, "File must be 4 MB or smaller")
+	}
+
+	f, err := fileHeader.Open()
+	if err != nil {
+		return "", nil, fiber.NewError(fiber.StatusBadRequest, "Failed to read uploaded file")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", nil, fiber.NewError(fiber.StatusBadRequest, "Failed to read uploaded file")
+	}
+	return fileHeader.Filename, data, nil
+}
+
+// formGeneratorInput reads generation options from multipart form values
+func formGeneratorInput(c *fiber.Ctx) models.WorksheetGeneratorInput {
+	input := models.WorksheetGeneratorInput{
+		Topic:                  c.FormValue("topic"),
+		Subject:                c.FormValue("subject"),
+		GradeLevel:             c.FormValue("grade_level"),
+		Difficulty:             c.FormValue("difficulty"),
+		Language:               c.FormValue("language"),
+		AdditionalInstructions: c.FormValue("additional_instructions"),
+	}
+
+	input.QuestionCount, _ = strconv.Atoi(c.FormValue("question_count"))
+	input.IncludeAnswerKey, _ = strconv.ParseBool(c.FormValue("include_answer_key"))
+
+	// Accept repeated question_types fields as well as a comma-separated list
+	if form, err := c.MultipartForm(); err == nil {
+		for _, v := range form.Value["question_types"] {
+			for _, t := range strings.Split(v, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					input.QuestionTypes = append(input.QuestionTypes, t)
+				}
+			}
+		}
+	}
+
+	return input
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUploadSizeLimit(t *testing.T) {
+	app := fiber.New(fiber.Config{BodyLimit: MaxBodySize})
+	app.Post("/upload", func(c *fiber.Ctx) error {
+		if _, _, fileErr := readFormFile(c); fileErr != nil {
+			return c.Status(fileErr.Code).SendString(fileErr.Message)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		size   int
+		status int
+	}{
+		{"largest allowed file", maxUploadSize, fiber.StatusOK},
+		{"one byte over", maxUploadSize + 1, fiber.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			for _, field := range []string{"topic", "subject", "grade_level", "difficulty", "question_types"} {
+				form.WriteField(field, strings.Repeat("a", 200))
+			}
+			part, _ := form.CreateFormFile("file", "notes.txt")
+			part.Write(bytes.Repeat([]byte("a"), tt.size))
+			form.Close()
+
+			req := httptest.NewRequest("POST", "/upload", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
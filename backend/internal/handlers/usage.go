@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/models"
+)
+
+// recordUsage logs what a generation request spent against the current user,
+// whether or not it succeeded, and returns it for the worksheet's running total
+func (h *WorksheetHandler) recordUsage(c *fiber.Ctx, kind, worksheetID string, meter *ai.UsageMeter) models.Usage {
+	usage := meter.Usage()
+	if usage.Calls == 0 {
+		return usage
+	}
+	user := currentUserID(c)
+	if user == "" {
+		user = "anonymous"
+	}
+	rec := &models.UsageRecord{
+		UserID:      user,
+		WorksheetID: worksheetID,
+		Kind:        kind,
+		Model:       meter.Model(),
+		Usage:       usage,
+	}
+	if err := h.usage.Record(rec); err != nil {
+		log.Printf("⚠️ Failed to record usage for %s: %v", user, err)
+	}
+	return usage
+}
+
+// addUsage adds a request's usage to the worksheet's running total
+func addUsage(ws *models.Worksheet, usage models.Usage) {
+	if usage.Calls == 0 {
+		return
+	}
+	if ws.Usage == nil {
+		ws.Usage = &models.Usage{}
+	}
+	ws.Usage.Add(usage)
+}
@@ -1,24 +1,44 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/gencache"
 	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/moderation"
+	"github.com/makosai/backend/internal/quota"
+	"github.com/makosai/backend/internal/store"
 )
 
 // WorksheetHandler handles worksheet-related requests
 type WorksheetHandler struct {
 	generator  ai.Generator
-	worksheets map[string]*models.Worksheet
+	worksheets store.WorksheetStore
+	revisions  store.RevisionStore
+	moderator  *moderation.Moderator
+	usage      store.UsageStore
+	quota      *quota.Quota
+	cache      gencache.Cache
+	inflight   *gencache.Group
 }
 
 // NewWorksheetHandler creates a new worksheet handler
-func NewWorksheetHandler(generator ai.Generator) *WorksheetHandler {
+func NewWorksheetHandler(generator ai.Generator, worksheets store.WorksheetStore, revisions store.RevisionStore, moderator *moderation.Moderator, usage store.UsageStore, quota *quota.Quota, cache gencache.Cache) *WorksheetHandler {
 	return &WorksheetHandler{
 		generator:  generator,
-		worksheets: make(map[string]*models.Worksheet),
+		worksheets: worksheets,
+		revisions:  revisions,
+		moderator:  moderator,
+		usage:      usage,
+		quota:      quota,
+		cache:      cache,
+		inflight:   gencache.NewGroup(),
 	}
 }
 
@@ -40,63 +60,185 @@ func (h *WorksheetHandler) GenerateWorksheet(c *fiber.Ctx) error {
 		})
 	}
 
-	// Set defaults
-	if input.Subject == "" {
-		input.Subject = "general"
-	}
-	if input.GradeLevel == "" {
-		input.GradeLevel = "5"
+	applyInputDefaults(&input)
+
+	// Use an existing (e.g. imported) worksheet as style context
+	if input.ContextWorksheetID != "" {
+		contextWorksheet, exists := h.worksheets.Get(input.ContextWorksheetID)
+		if !exists {
+			return c.Status(fiber.StatusNotFound).JSON(models.GenerationResponse{
+				Success: false,
+				Error:   "Context worksheet not found",
+			})
+		}
+		input.ExampleQuestions = contextWorksheet.Questions
 	}
-	if input.Difficulty == "" {
-		input.Difficulty = "medium"
+
+	return h.generate(c, input)
+}
+
+// generate runs the generator and stores the resulting worksheet
+func (h *WorksheetHandler) generate(c *fiber.Ctx, input models.WorksheetGeneratorInput) error {
+	if inputErr := ai.CheckInput(input); inputErr != nil {
+		logRejected(c, "generation request", inputErr.Error(), input.Topic+"\n"+input.AdditionalInstructions)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid " + inputErr.Error(),
+			"field":   inputErr.Field,
+		})
 	}
-	if input.QuestionCount == 0 {
-		input.QuestionCount = 10
+
+	account := quotaAccount(c, h.quota)
+	if err := h.quota.CheckQuestions(account, input.QuestionCount, input.QuestionTypes); err != nil {
+		return quotaResponse(c, err)
 	}
-	if len(input.QuestionTypes) == 0 {
-		input.QuestionTypes = []string{"multiple_choice"}
+	input.Limits = h.quota.Limits(account)
+
+	// Teacher text goes straight into the prompt, so screen it before spending a generation on it
+	flags := h.moderator.Screen(c.Context(), input.GradeLevel, []moderation.Text{
+		{Field: "topic", Content: input.Topic},
+		{Field: "additional_instructions", Content: input.AdditionalInstructions},
+	})
+	if len(flags) > 0 {
+		log.Printf("🚫 Rejected generation request: %d moderation flag(s)", len(flags))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "The topic or instructions contain content that isn't suitable for this grade",
+			"flags":   flags,
+		})
 	}
-	if input.Language == "" {
-		input.Language = "en"
+
+	// Failed generations are handed back to the monthly allowance
+	release, err := h.quota.Use(account, quota.Worksheets)
+	if err != nil {
+		return quotaResponse(c, err)
 	}
 
-	// Generate worksheet
-	log.Printf("🚀 Generating worksheet for topic: %s", input.Topic)
-	worksheet, err := h.generator.GenerateWorksheet(c.Context(), input)
+	key, cacheable := gencache.Key(input)
+	worksheet, cached, err := h.reuseOrGenerate(c, input, key, cacheable)
 	if err != nil {
+		release()
+		var conformanceErr *ai.ConformanceError
+		if errors.As(err, &conformanceErr) {
+			logRejected(c, "generated worksheet", err.Error(), input.Topic+"\n"+input.AdditionalInstructions)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.GenerationResponse{
+				Success: false,
+				Error:   "The generated worksheet didn't match your request; try rewording the topic or instructions",
+			})
+		}
 		log.Printf("❌ Generation error: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(models.GenerationResponse{
 			Success: false,
 			Error:   "Failed to generate worksheet: " + err.Error(),
 		})
 	}
-	log.Printf("✅ Worksheet generated successfully: %s", worksheet.ID)
 
 	// Store worksheet
-	h.worksheets[worksheet.ID] = worksheet
+	if err := h.saveRevision(c, worksheet, "Generated worksheet", 0); err != nil {
+		release()
+		return c.Status(fiber.StatusInternalServerError).JSON(models.GenerationResponse{
+			Success: false,
+			Error:   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+	if cacheable && !cached && gencache.Reusable(worksheet) {
+		h.cache.Set(c.Context(), key, worksheet)
+	}
 
 	return c.JSON(models.GenerationResponse{
 		Success:   true,
 		Worksheet: worksheet,
+		Cached:    cached,
 	})
 }
 
+// reuseOrGenerate serves a fresh copy of a recent identical generation when
+// there is one, joins an identical generation already in flight, or generates
+// a new worksheet. Requests with Fresh set always get a new generation.
+func (h *WorksheetHandler) reuseOrGenerate(c *fiber.Ctx, input models.WorksheetGeneratorInput, key string, cacheable bool) (worksheet *models.Worksheet, cached bool, err error) {
+	if cacheable && !input.Fresh {
+		if hit, ok := h.cache.Get(c.Context(), key); ok {
+			log.Printf("♻️ Serving a fresh copy of cached worksheet %s for topic: %s", hit.ID, input.Topic)
+			return gencache.Fresh(hit), true, nil
+		}
+	}
+
+	ctx, meter := ai.WithUsageMeter(c.Context())
+	generate := func() (*models.Worksheet, error) {
+		log.Printf("🚀 Generating worksheet for topic: %s", input.Topic)
+		return h.generator.GenerateWorksheet(ctx, input)
+	}
+	var generated *models.Worksheet
+	shared := false
+	if cacheable && !input.Fresh {
+		generated, shared, err = h.inflight.Do(key, generate)
+	} else {
+		generated, err = generate()
+	}
+
+	// Only the request that ran the generation is charged for its tokens
+	worksheetID := ""
+	if err == nil && !shared {
+		worksheetID = generated.ID
+	}
+	usage := h.recordUsage(c, models.UsageWorksheet, worksheetID, meter)
+	if err != nil {
+		return nil, false, err
+	}
+	if shared {
+		// The generation is shared with the request that ran it, so take our own copy
+		log.Printf("♻️ Sharing worksheet %s with an identical request for topic: %s", generated.ID, input.Topic)
+		return gencache.Fresh(generated), true, nil
+	}
+	log.Printf("✅ Worksheet generated successfully: %s", generated.ID)
+	worksheet = generated.Clone()
+	addUsage(worksheet, usage)
+	return worksheet, false, nil
+}
+
+// logRejected records refused teacher input and off-request output for later review
+func logRejected(c *fiber.Ctx, what, reason, text string) {
+	user := currentUserID(c)
+	if user == "" {
+		user = "anonymous"
+	}
+	log.Printf("🛡️ Rejected %s from %s (%s): %s: %.500q", what, user, c.IP(), reason, text)
+}
+
+// applyInputDefaults fills in defaults for optional generation fields
+func applyInputDefaults(input *models.WorksheetGeneratorInput) {
+	if input.Subject == "" {
+		input.Subject = "general"
+	}
+	if input.GradeLevel == "" {
+		input.GradeLevel = "5"
+	}
+	if input.Difficulty == "" {
+		input.Difficulty = "medium"
+	}
+	if input.QuestionCount == 0 {
+		input.QuestionCount = 10
+	}
+	if len(input.QuestionTypes) == 0 {
+		input.QuestionTypes = []string{"multiple_choice"}
+	}
+	if input.Language == "" {
+		input.Language = "en"
+	}
+}
+
 // GetWorksheets handles GET /api/worksheets
 func (h *WorksheetHandler) GetWorksheets(c *fiber.Ctx) error {
-	worksheets := make([]*models.Worksheet, 0, len(h.worksheets))
-	for _, ws := range h.worksheets {
-		worksheets = append(worksheets, ws)
-	}
 	return c.JSON(fiber.Map{
 		"success":    true,
-		"worksheets": worksheets,
+		"worksheets": h.worksheets.List(),
 	})
 }
 
 // GetWorksheet handles GET /api/worksheets/:id
 func (h *WorksheetHandler) GetWorksheet(c *fiber.Ctx) error {
 	id := c.Params("id")
-	worksheet, exists := h.worksheets[id]
+	worksheet, exists := h.worksheets.Get(id)
 	if !exists {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"success": false,
@@ -112,7 +254,7 @@ func (h *WorksheetHandler) GetWorksheet(c *fiber.Ctx) error {
 // UpdateWorksheet handles PUT /api/worksheets/:id
 func (h *WorksheetHandler) UpdateWorksheet(c *fiber.Ctx) error {
 	id := c.Params("id")
-	worksheet, exists := h.worksheets[id]
+	worksheet, exists := h.worksheets.Get(id)
 	if !exists {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"success": false,
@@ -129,33 +271,193 @@ func (h *WorksheetHandler) UpdateWorksheet(c *fiber.Ctx) error {
 	}
 
 	// Update fields
+	updated := worksheet.Clone()
 	if updates.Title != "" {
-		worksheet.Title = updates.Title
+		updated.Title = updates.Title
 	}
 	if updates.Status != "" {
-		worksheet.Status = updates.Status
+		updated.Status = updates.Status
 	}
 	if len(updates.Questions) > 0 {
-		worksheet.Questions = updates.Questions
+		updated.Questions = updates.Questions
+	}
+
+	if err := h.saveEdit(c, updated, "Updated worksheet"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
 	}
 
 	return c.JSON(fiber.Map{
 		"success":   true,
-		"worksheet": worksheet,
+		"worksheet": updated,
+	})
+}
+
+// worksheetPatch holds the fields accepted by PATCH; nil means "leave unchanged"
+type worksheetPatch struct {
+	Title            *string            `json:"title"`
+	Subject          *string            `json:"subject"`
+	Topic            *string            `json:"topic"`
+	GradeLevel       *string            `json:"grade_level"`
+	Difficulty       *string            `json:"difficulty"`
+	Language         *string            `json:"language"`
+	Status           *string            `json:"status"`
+	IncludeAnswerKey *bool              `json:"include_answer_key"`
+	Questions        *[]models.Question `json:"questions"`
+}
+
+// PatchWorksheet handles PATCH /api/worksheets/:id
+//
+// Unlike PUT, every field present in the body is applied, so a title can be
+// cleared with "" and all questions removed with [].
+func (h *WorksheetHandler) PatchWorksheet(c *fiber.Ctx) error {
+	worksheet, exists := h.worksheets.Get(c.Params("id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet not found",
+		})
+	}
+
+	var patch worksheetPatch
+	if err := c.BodyParser(&patch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body",
+		})
+	}
+
+	updated := worksheet.Clone()
+	if patch.Title != nil {
+		updated.Title = strings.TrimSpace(*patch.Title)
+	}
+	if patch.Subject != nil {
+		updated.Subject = *patch.Subject
+	}
+	if patch.Topic != nil {
+		updated.Topic = *patch.Topic
+	}
+	if patch.GradeLevel != nil {
+		updated.GradeLevel = *patch.GradeLevel
+	}
+	if patch.Difficulty != nil {
+		updated.Difficulty = *patch.Difficulty
+	}
+	if patch.Language != nil {
+		updated.Language = *patch.Language
+	}
+	if patch.Status != nil {
+		updated.Status = *patch.Status
+	}
+	if patch.IncludeAnswerKey != nil {
+		updated.IncludeAnswerKey = *patch.IncludeAnswerKey
+	}
+	if patch.Questions != nil {
+		updated.Questions = *patch.Questions
+		for i := range updated.Questions {
+			normalizeQuestion(&updated.Questions[i])
+			if err := checkQuestion(&updated.Questions[i]); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"error":   fmt.Sprintf("Question %d: %v", i+1, err),
+				})
+			}
+		}
+		renumberQuestions(updated)
+	}
+
+	if err := validateMetadata(updated); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if err := h.saveEdit(c, updated, "Edited worksheet details"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"worksheet": updated,
 	})
 }
 
+// validateMetadata checks the enumerated worksheet fields
+func validateMetadata(ws *models.Worksheet) error {
+	switch models.Difficulty(ws.Difficulty) {
+	case models.Easy, models.Medium, models.Hard:
+	default:
+		return fmt.Errorf("difficulty must be easy, medium or hard")
+	}
+	switch ws.Status {
+	case "draft", "published":
+	default:
+		return fmt.Errorf("status must be draft or published")
+	}
+	if strings.TrimSpace(ws.GradeLevel) == "" {
+		return fmt.Errorf("grade_level is required")
+	}
+	return nil
+}
+
+// saveEdit stamps UpdatedAt and persists an edited worksheet as a new revision.
+//
+// Edits are last-write-wins: two concurrent edits of the same worksheet both
+// succeed and the later one replaces the earlier, which stays in the history
+// as its own revision and can be restored from there.
+func (h *WorksheetHandler) saveEdit(c *fiber.Ctx, ws *models.Worksheet, summary string) error {
+	ws.UpdatedAt = time.Now()
+	return h.saveRevision(c, ws, summary, 0)
+}
+
+// saveRevision screens ws for unsafe content, then records an immutable
+// snapshot of it authored by the current user and saves it
+func (h *WorksheetHandler) saveRevision(c *fiber.Ctx, ws *models.Worksheet, summary string, restoredFrom int) error {
+	ws.Moderation = h.moderator.Review(c.Context(), ws)
+	if ws.Moderation.Blocked() {
+		log.Printf("🚩 Worksheet %s flagged for review: %d moderation flag(s)", ws.ID, len(ws.Moderation.Flags))
+	}
+	return h.storeRevision(c, ws, summary, restoredFrom)
+}
+
+// storeRevision records an immutable snapshot of ws authored by the current user and saves it
+func (h *WorksheetHandler) storeRevision(c *fiber.Ctx, ws *models.Worksheet, summary string, restoredFrom int) error {
+	author := currentUserID(c)
+	if author == "" {
+		author = "anonymous"
+	}
+
+	rev := &models.Revision{
+		WorksheetID:  ws.ID,
+		Author:       author,
+		Summary:      summary,
+		RestoredFrom: restoredFrom,
+		Worksheet:    ws,
+	}
+	if err := h.revisions.Append(rev); err != nil {
+		return err
+	}
+	ws.Revision = rev.Number
+	return h.worksheets.Save(ws)
+}
+
 // DeleteWorksheet handles DELETE /api/worksheets/:id
 func (h *WorksheetHandler) DeleteWorksheet(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if _, exists := h.worksheets[id]; !exists {
+	if !h.worksheets.Delete(id) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"success": false,
 			"error":   "Worksheet not found",
 		})
 	}
+	h.revisions.DeleteAll(id)
 
-	delete(h.worksheets, id)
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Worksheet deleted",
@@ -163,9 +465,14 @@ func (h *WorksheetHandler) DeleteWorksheet(c *fiber.Ctx) error {
 }
 
 // ExportWorksheetPDF handles GET /api/worksheets/:id/export/pdf
+//
+// Flagged worksheets are refused here so they don't count as an export, but
+// GET /api/worksheets/:id still returns their content for the editor and the
+// review screen. Printing happens in the browser, so the frontend's preview is
+// what actually keeps a flagged worksheet from being printed.
 func (h *WorksheetHandler) ExportWorksheetPDF(c *fiber.Ctx) error {
 	id := c.Params("id")
-	worksheet, exists := h.worksheets[id]
+	worksheet, exists := h.worksheets.Get(id)
 	if !exists {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"success": false,
@@ -173,8 +480,38 @@ func (h *WorksheetHandler) ExportWorksheetPDF(c *fiber.Ctx) error {
 		})
 	}
 
-	// Increment download count
-	worksheet.Downloads++
+	if worksheet.Moderation.Blocked() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "Worksheet is awaiting content review",
+			"flags":   worksheet.Moderation.Flags,
+		})
+	}
+
+	release, err := h.quota.Use(quotaAccount(c, h.quota), quota.Exports)
+	if err != nil {
+		return quotaResponse(c, err)
+	}
+
+	// Count the download on the stored worksheet, not the copy read above, so
+	// an edit or approval saved in the meantime isn't overwritten
+	worksheet, err = h.worksheets.Update(id, func(ws *models.Worksheet) error {
+		ws.Downloads++
+		return nil
+	})
+	if err != nil {
+		release()
+		if errors.Is(err, store.ErrWorksheetNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"error":   "Worksheet not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save worksheet: " + err.Error(),
+		})
+	}
 
 	// For now, return JSON - actual PDF generation would require additional libraries
 	return c.JSON(fiber.Map{
@@ -228,4 +565,3 @@ func (h *WorksheetHandler) GetOptions(c *fiber.Ctx) error {
 		},
 	})
 }
-
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/makosai/backend/internal/ai"
+	"github.com/makosai/backend/internal/store"
+)
+
+func TestUpdateWorksheet(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seed()
+
+	status, body := w.do("PUT", "/worksheets/ws_test", `{"title":"Plant parts","status":"published"}`)
+	if status != fiber.StatusOK {
+		t.Fatalf("update: %d %v", status, body)
+	}
+	got := w.stored("ws_test")
+	if got.Title != "Plant parts" || got.Status != "published" || got.Revision != 1 {
+		t.Errorf("stored worksheet: title %q, status %q, revision %d", got.Title, got.Status, got.Revision)
+	}
+}
+
+func TestUpdateWorksheetSaveFailure(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), failingRevisions{store.NewMemoryRevisionStore()})
+	w.seed()
+
+	if status, body := w.do("PUT", "/worksheets/ws_test", `{"title":"Plant parts"}`); status != fiber.StatusInternalServerError {
+		t.Fatalf("update: %d %v, want 500", status, body)
+	}
+	if got := w.stored("ws_test"); got.Title != "Plants" {
+		t.Errorf("failed save changed the stored title to %q", got.Title)
+	}
+}
+
+func TestPatchWorksheet(t *testing.T) {
+	w := newWorksheetTest(t, ai.NewMockGenerator(), nil)
+	w.seed()
+
+	for _, body := range []string{
+		`{"difficulty":"impossible"}`,
+		`{"status":"archived"}`,
+		`{"grade_level":" "}`,
+		`{"questions":[{"type":"short_answer","question":"","points":1}]}`,
+	} {
+		if status, resp := w.do("PATCH", "/worksheets/ws_test", body); status != fiber.StatusBadRequest {
+			t.Errorf("patch %s: %d %v, want 400", body, status, resp)
+		}
+	}
+	if got := w.stored("ws_test"); got.Revision != 0 || got.Difficulty != "medium" {
+		t.Fatalf("rejected patches changed the worksheet: revision %d, difficulty %q", got.Revision, got.Difficulty)
+	}
+
+	status, body := w.do("PATCH", "/worksheets/ws_test", `{"title":"","difficulty":"hard","questions":[]}`)
+	if status != fiber.StatusOK {
+		t.Fatalf("patch: %d %v", status, body)
+	}
+	got := w.stored("ws_test")
+	if got.Title != "" || got.Difficulty != "hard" || len(got.Questions) != 0 || got.Status != "draft" {
+		t.Errorf("stored worksheet: title %q, difficulty %q, %d questions, status %q", got.Title, got.Difficulty, len(got.Questions), got.Status)
+	}
+}
@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// ParseCSV imports questions from a spreadsheet export.
+//
+// The first row is a header. Recognised columns are type, question, options
+// (values separated by "|") or option_a..option_f, correct_answer (or answer),
+// explanation and points. A multiple-choice answer may be given as the option
+// letter. Row numbers in errors are 1-based and include the header.
+func ParseCSV(data []byte) (*Result, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	cols := make(map[string]int)
+	var optionCols []string
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		cols[name] = i
+		if strings.HasPrefix(name, "option_") || strings.HasPrefix(name, "option ") {
+			optionCols = append(optionCols, name)
+		}
+	}
+	sort.Strings(optionCols)
+
+	if _, ok := cols["question"]; !ok {
+		return nil, fmt.Errorf("CSV header must include a \"question\" column")
+	}
+
+	result := &Result{}
+	row := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			result.addError(row, "malformed row: %v", err)
+			continue
+		}
+
+		get := func(names ...string) string {
+			for _, name := range names {
+				if i, ok := cols[name]; ok && i < len(record) {
+					if v := strings.TrimSpace(record[i]); v != "" {
+						return v
+					}
+				}
+			}
+			return ""
+		}
+
+		if get("question") == "" && strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		q := models.Question{
+			Question:    get("question"),
+			Explanation: get("explanation", "feedback"),
+		}
+
+		if v := get("options", "choices"); v != "" {
+			q.Options = splitList(v)
+		}
+		for _, col := range optionCols {
+			if v := get(col); v != "" {
+				q.Options = append(q.Options, v)
+			}
+		}
+
+		rawType := get("type", "question_type")
+		if rawType == "" {
+			rawType = inferType(q.Options)
+		}
+		qType, ok := normalizeType(rawType)
+		if !ok {
+			result.addError(row, "unknown question type %q", rawType)
+			continue
+		}
+		q.Type = qType
+
+		if v := get("points", "score"); v != "" {
+			points, err := strconv.Atoi(v)
+			if err != nil {
+				result.addError(row, "points must be a whole number, got %q", v)
+				continue
+			}
+			q.Points = points
+		}
+
+		answer := get("correct_answer", "answer", "correct")
+		switch q.Type {
+		case string(models.MultipleChoice):
+			q.CorrectAnswer = resolveLetter(answer, q.Options)
+		case string(models.TrueFalse):
+			q.Options = []string{"True", "False"}
+			q.CorrectAnswer = normalizeTrueFalse(answer)
+		case string(models.Matching):
+			q.CorrectAnswer = splitList(answer)
+		default:
+			if answer != "" {
+				q.CorrectAnswer = answer
+			}
+		}
+
+		result.addQuestion(row, q)
+	}
+
+	return result, nil
+}
+
+// splitList splits a "|"-separated cell into trimmed, non-empty values
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, "|") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// resolveLetter turns an answer given as an option letter ("B") into the option text
+func resolveLetter(answer string, options []string) string {
+	if len(answer) == 1 {
+		idx := int(strings.ToUpper(answer)[0] - 'A')
+		if idx >= 0 && idx < len(options) {
+			return options[idx]
+		}
+	}
+	return answer
+}
+
+// inferType guesses a type for rows without a type column
+func inferType(options []string) string {
+	if len(options) == 0 {
+		return string(models.ShortAnswer)
+	}
+	return string(models.MultipleChoice)
+}
@@ -0,0 +1,291 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// giftBlock is one question in a GIFT file together with the line it starts on
+type giftBlock struct {
+	line int
+	text string
+}
+
+// giftAnswer is a single ~wrong or =right choice inside the answer block
+type giftAnswer struct {
+	correct  bool
+	text     string
+	feedback string
+}
+
+var (
+	giftTitle  = regexp.MustCompile(`^::(.*?)::`)
+	giftFormat = regexp.MustCompile(`^\[(html|moodle|plain|markdown)\]`)
+	giftWeight = regexp.MustCompile(`^%(-?\d+(?:\.\d+)?)%`)
+)
+
+// ParseGIFT imports questions written in Moodle's GIFT format.
+//
+// Multiple choice, true/false, short answer, missing-word, matching, numeric and
+// essay questions are supported. Error rows refer to the line a question starts on.
+func ParseGIFT(data []byte) (*Result, error) {
+	result := &Result{}
+
+	for _, block := range splitGIFT(string(data)) {
+		q, err := parseGIFTQuestion(block.text)
+		if err != "" {
+			result.addError(block.line, "%s", err)
+			continue
+		}
+		result.addQuestion(block.line, q)
+	}
+	return result, nil
+}
+
+// splitGIFT drops comments and category lines and splits questions on blank lines
+func splitGIFT(content string) []giftBlock {
+	content = strings.ReplaceAll(strings.TrimPrefix(content, "\ufeff"), "\r\n", "\n")
+
+	var blocks []giftBlock
+	var current []string
+	start := 0
+
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(current, "\n")); text != "" {
+			blocks = append(blocks, giftBlock{line: start, text: text})
+		}
+		current = nil
+	}
+
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "//"):
+			continue
+		case strings.HasPrefix(trimmed, "$CATEGORY:"):
+			continue
+		case trimmed == "":
+			flush()
+			continue
+		}
+		if len(current) == 0 {
+			start = i + 1
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
+
+// parseGIFTQuestion converts a single GIFT question; the string result is a non-empty error message on failure
+func parseGIFTQuestion(block string) (models.Question, string) {
+	var q models.Question
+
+	block = giftTitle.ReplaceAllString(block, "")
+	block = giftFormat.ReplaceAllString(strings.TrimSpace(block), "")
+
+	open := indexUnescaped(block, '{', 0)
+	if open == -1 {
+		return q, "no answer block {...} found"
+	}
+	closing := indexUnescaped(block, '}', open+1)
+	if closing == -1 {
+		return q, "answer block is not closed with }"
+	}
+
+	before := strings.TrimSpace(block[:open])
+	after := strings.TrimSpace(block[closing+1:])
+	inner := strings.TrimSpace(block[open+1 : closing])
+
+	hasBlank := after != ""
+	if hasBlank {
+		q.Question = unescapeGIFT(before + " __________ " + after)
+	} else {
+		q.Question = unescapeGIFT(before)
+	}
+
+	// General feedback follows "####"
+	if idx := strings.Index(inner, "####"); idx != -1 {
+		q.Explanation = unescapeGIFT(strings.TrimSpace(inner[idx+4:]))
+		inner = strings.TrimSpace(inner[:idx])
+	}
+
+	switch {
+	case inner == "":
+		q.Type = string(models.Essay)
+		return q, ""
+
+	case strings.HasPrefix(inner, "#"):
+		answer := strings.TrimSpace(inner[1:])
+		if idx := indexUnescaped(answer, '#', 0); idx != -1 {
+			answer = strings.TrimSpace(answer[:idx])
+		}
+		answer = strings.TrimPrefix(answer, "=")
+		if idx := strings.Index(answer, ":"); idx != -1 {
+			answer = answer[:idx]
+		}
+		q.CorrectAnswer = strings.TrimSpace(answer)
+		q.Type = string(models.ShortAnswer)
+		if hasBlank {
+			q.Type = string(models.FillBlank)
+		}
+		return q, ""
+	}
+
+	if tf, feedback, ok := parseGIFTBool(inner); ok {
+		q.Type = string(models.TrueFalse)
+		q.Options = []string{"True", "False"}
+		q.CorrectAnswer = tf
+		if q.Explanation == "" {
+			q.Explanation = feedback
+		}
+		return q, ""
+	}
+
+	answers := splitGIFTAnswers(inner)
+	if len(answers) == 0 {
+		return q, "answer block has no answers"
+	}
+
+	hasWrong := false
+	isMatching := true
+	for _, a := range answers {
+		if !a.correct {
+			hasWrong = true
+		}
+		if !a.correct || !strings.Contains(a.text, "->") {
+			isMatching = false
+		}
+	}
+
+	switch {
+	case isMatching:
+		q.Type = string(models.Matching)
+		var pairs []string
+		for i, a := range answers {
+			parts := strings.SplitN(a.text, "->", 2)
+			term, def := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+			q.Options = append(q.Options, term+" → "+def)
+			pairs = append(pairs, fmt.Sprintf("%c-%d", 'A'+i, i+1))
+		}
+		q.CorrectAnswer = pairs
+
+	case hasWrong:
+		q.Type = string(models.MultipleChoice)
+		for _, a := range answers {
+			q.Options = append(q.Options, a.text)
+			if a.correct {
+				if q.CorrectAnswer != nil {
+					return q, "multiple choice question has more than one correct answer"
+				}
+				q.CorrectAnswer = a.text
+				if q.Explanation == "" {
+					q.Explanation = a.feedback
+				}
+			}
+		}
+		if q.CorrectAnswer == nil {
+			return q, "multiple choice question has no correct answer (mark it with =)"
+		}
+
+	default:
+		q.Type = string(models.ShortAnswer)
+		if hasBlank {
+			q.Type = string(models.FillBlank)
+		}
+		q.CorrectAnswer = answers[0].text
+		if q.Explanation == "" {
+			q.Explanation = answers[0].feedback
+		}
+	}
+
+	return q, ""
+}
+
+// parseGIFTBool recognises {T}, {F}, {TRUE} and {FALSE}. Of the optional
+// "#wrong feedback#right feedback" the last one is kept as the explanation.
+func parseGIFTBool(inner string) (answer, feedback string, ok bool) {
+	value := inner
+	if idx := indexUnescaped(inner, '#', 0); idx != -1 {
+		value = inner[:idx]
+		parts := strings.Split(inner[idx+1:], "#")
+		feedback = unescapeGIFT(parts[len(parts)-1])
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "T", "TRUE":
+		return "True", feedback, true
+	case "F", "FALSE":
+		return "False", feedback, true
+	}
+	return "", "", false
+}
+
+// splitGIFTAnswers splits "=right#fb ~wrong ~%50%partial" into answers
+func splitGIFTAnswers(inner string) []giftAnswer {
+	var answers []giftAnswer
+	var current *giftAnswer
+	var sb strings.Builder
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		text := sb.String()
+		if idx := indexUnescaped(text, '#', 0); idx != -1 {
+			current.feedback = unescapeGIFT(strings.TrimSpace(text[idx+1:]))
+			text = text[:idx]
+		}
+		text = strings.TrimSpace(text)
+		if m := giftWeight.FindStringSubmatch(text); m != nil {
+			current.correct = m[1] == "100"
+			text = strings.TrimSpace(text[len(m[0]):])
+		}
+		current.text = unescapeGIFT(text)
+		if current.text != "" {
+			answers = append(answers, *current)
+		}
+		sb.Reset()
+	}
+
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		if c == '\\' && i+1 < len(inner) {
+			sb.WriteByte(c)
+			sb.WriteByte(inner[i+1])
+			i++
+			continue
+		}
+		if c == '=' || c == '~' {
+			finish()
+			current = &giftAnswer{correct: c == '='}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	finish()
+	return answers
+}
+
+// indexUnescaped finds the first occurrence of c at or after from that is not preceded by a backslash
+func indexUnescaped(s string, c byte, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+var giftUnescaper = strings.NewReplacer(`\=`, "=", `\~`, "~", `\#`, "#", `\{`, "{", `\}`, "}", `\:`, ":", `\n`, "\n")
+
+func unescapeGIFT(s string) string {
+	return strings.TrimSpace(giftUnescaper.Replace(s))
+}
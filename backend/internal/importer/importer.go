@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// Supported import formats
+const (
+	FormatCSV  = "csv"
+	FormatGIFT = "gift"
+	FormatQTI  = "qti"
+)
+
+// RowError describes a single item that could not be imported
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"error"`
+}
+
+// Result holds the questions parsed from an import file and any per-item errors
+type Result struct {
+	Title     string            `json:"title,omitempty"`
+	Questions []models.Question `json:"questions"`
+	Errors    []RowError        `json:"errors"`
+}
+
+func (r *Result) addError(row int, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, RowError{Row: row, Message: fmt.Sprintf(format, args...)})
+}
+
+// addQuestion validates q, numbers it and appends it, recording an error instead if it is invalid
+func (r *Result) addQuestion(row int, q models.Question) {
+	if q.Points == 0 {
+		q.Points = models.DefaultPoints(q.Type)
+	}
+	if err := q.Validate(); err != nil {
+		r.addError(row, "%v", err)
+		return
+	}
+	q.ID = fmt.Sprintf("q_%d", len(r.Questions)+1)
+	r.Questions = append(r.Questions, q)
+}
+
+// DetectFormat infers the import format from a file name
+func DetectFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".gift", ".txt":
+		return FormatGIFT
+	case ".xml", ".qti", ".zip":
+		return FormatQTI
+	}
+	return ""
+}
+
+// Parse dispatches to the parser for the given format
+func Parse(format string, data []byte) (*Result, error) {
+	var (
+		result *Result
+		err    error
+	)
+
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		result, err = ParseCSV(data)
+	case FormatGIFT:
+		result, err = ParseGIFT(data)
+	case FormatQTI:
+		result, err = ParseQTI(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q (supported: csv, gift, qti)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Errors == nil {
+		result.Errors = []RowError{}
+	}
+	return result, nil
+}
+
+// typeAliases maps the spellings found in exported quizzes onto our question types
+var typeAliases = map[string]models.QuestionType{
+	"multiple_choice":   models.MultipleChoice,
+	"multiple choice":   models.MultipleChoice,
+	"multiplechoice":    models.MultipleChoice,
+	"mc":                models.MultipleChoice,
+	"mcq":               models.MultipleChoice,
+	"true_false":        models.TrueFalse,
+	"true/false":        models.TrueFalse,
+	"true false":        models.TrueFalse,
+	"truefalse":         models.TrueFalse,
+	"tf":                models.TrueFalse,
+	"fill_blank":        models.FillBlank,
+	"fill in the blank": models.FillBlank,
+	"fill_in_blank":     models.FillBlank,
+	"fill-in-blank":     models.FillBlank,
+	"cloze":             models.FillBlank,
+	"short_answer":      models.ShortAnswer,
+	"short answer":      models.ShortAnswer,
+	"sa":                models.ShortAnswer,
+	"essay":             models.Essay,
+	"long_answer":       models.Essay,
+	"matching":          models.Matching,
+	"match":             models.Matching,
+}
+
+// normalizeType maps a question type spelling to a supported type
+func normalizeType(t string) (string, bool) {
+	qt, ok := typeAliases[strings.ToLower(strings.TrimSpace(t))]
+	return string(qt), ok
+}
+
+// normalizeTrueFalse maps the common spellings of a boolean answer to "True" or "False"
+func normalizeTrueFalse(answer string) string {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "t", "true", "yes", "1":
+		return "True"
+	case "f", "false", "no", "0":
+		return "False"
+	}
+	return answer
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	data := "\xef\xbb\xbftype,question,options,correct_answer,points\n" +
+		"multiple_choice,What is 2+2?,3|4|5,B,2\n" +
+		"tf,The sun is a star.,,true,\n" +
+		"short answer,Capital of France?,,Paris,\n" +
+		"unknown,Broken row,,x,\n"
+
+	result, err := Parse(FormatCSV, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Questions) != 3 {
+		t.Fatalf("got %d questions, want 3 (errors: %v)", len(result.Questions), result.Errors)
+	}
+	mc := result.Questions[0]
+	if mc.Type != "multiple_choice" || mc.CorrectAnswer != "4" || mc.Points != 2 || mc.ID != "q_1" {
+		t.Errorf("multiple choice = %+v", mc)
+	}
+	if tf := result.Questions[1]; tf.Type != "true_false" || tf.CorrectAnswer != "True" {
+		t.Errorf("true/false = %+v", tf)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 5 {
+		t.Errorf("errors = %+v, want one on row 5", result.Errors)
+	}
+
+	if _, err := ParseCSV([]byte("type,answer\nmc,x\n")); err == nil {
+		t.Error("accepted a header without a question column")
+	}
+}
+
+func TestParseGIFT(t *testing.T) {
+	data := `// comment
+::Q1:: Which planet is largest? {=Jupiter ~Mars ~Venus}
+
+The Earth is flat. {F}
+
+Name the process plants use to make food. {=photosynthesis}
+`
+	result, err := Parse(FormatGIFT, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Questions) != 3 {
+		t.Fatalf("got %d questions, want 3 (errors: %v)", len(result.Questions), result.Errors)
+	}
+	want := []struct {
+		qtype  string
+		answer interface{}
+	}{
+		{"multiple_choice", "Jupiter"},
+		{"true_false", "False"},
+		{"short_answer", "photosynthesis"},
+	}
+	for i, w := range want {
+		q := result.Questions[i]
+		if q.Type != w.qtype || !reflect.DeepEqual(q.CorrectAnswer, w.answer) {
+			t.Errorf("question %d = %s %v, want %s %v", i+1, q.Type, q.CorrectAnswer, w.qtype, w.answer)
+		}
+	}
+}
+
+// qtiChoiceItem builds a QTI 1.2 multiple-choice item answered by choice B
+func qtiChoiceItem(title string) string {
+	return fmt.Sprintf(`<item ident="%[1]s" title="%[1]s">
+  <presentation>
+    <material><mattext>%[1]s: which is a mammal?</mattext></material>
+    <response_lid ident="r1"><render_choice>
+      <response_label ident="A"><material><mattext>Shark</mattext></material></response_label>
+      <response_label ident="B"><material><mattext>Whale</mattext></material></response_label>
+    </render_choice></response_lid>
+  </presentation>
+  <resprocessing><respcondition>
+    <conditionvar><varequal respident="r1">B</varequal></conditionvar>
+    <setvar action="Set">100</setvar>
+  </respcondition></resprocessing>
+</item>`, title)
+}
+
+func qtiDocument(items ...string) string {
+	return `<?xml version="1.0"?><questestinterop><assessment title="Animals"><section>` +
+		strings.Join(items, "") + `</section></assessment></questestinterop>`
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseQTI(t *testing.T) {
+	check := func(t *testing.T, result *Result, want int) {
+		t.Helper()
+		if len(result.Questions) != want {
+			t.Fatalf("got %d questions, want %d (errors: %v)", len(result.Questions), want, result.Errors)
+		}
+		q := result.Questions[0]
+		if q.Type != "multiple_choice" || q.CorrectAnswer != "Whale" || len(q.Options) != 2 {
+			t.Errorf("question = %+v", q)
+		}
+		if result.Title != "Animals" {
+			t.Errorf("title = %q", result.Title)
+		}
+	}
+
+	t.Run("xml", func(t *testing.T) {
+		result, err := Parse(FormatQTI, []byte(qtiDocument(qtiChoiceItem("one"))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, result, 1)
+	})
+
+	t.Run("package", func(t *testing.T) {
+		data := buildZip(t, map[string]string{
+			"imsmanifest.xml":    "<manifest/>",
+			"quiz/part1.xml":     qtiDocument(qtiChoiceItem("one")),
+			"quiz/part2.xml":     qtiDocument(qtiChoiceItem("two")),
+			"quiz/media/img.png": "not xml",
+		})
+		result, err := Parse(FormatQTI, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, result, 2)
+	})
+}
+
+func TestParseQTIPackageLimits(t *testing.T) {
+	padding := strings.Repeat(" ", MaxPackageEntryBytes/2)
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "entry too large",
+			files: map[string]string{"quiz.xml": qtiDocument(qtiChoiceItem("one")) + strings.Repeat(" ", MaxPackageEntryBytes)},
+			want:  "quiz.xml expands to more than",
+		},
+		{
+			name:  "package too large",
+			files: map[string]string{},
+			want:  "package expands to more than",
+		},
+		{
+			name:  "too many files",
+			files: map[string]string{},
+			want:  "the limit is",
+		},
+	}
+	for i := 0; i < 2*MaxPackageBytes/MaxPackageEntryBytes+1; i++ {
+		tests[1].files[fmt.Sprintf("part%02d.xml", i)] = qtiDocument(qtiChoiceItem("one")) + padding
+	}
+	for i := 0; i <= MaxPackageFiles; i++ {
+		tests[2].files[fmt.Sprintf("media/%d.png", i)] = ""
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQTI(buildZip(t, tt.files))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
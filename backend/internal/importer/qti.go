@@ -0,0 +1,453 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// qti12Item is an <item> from a QTI 1.2 export (Canvas, Blackboard, Moodle)
+type qti12Item struct {
+	Title    string `xml:"title,attr"`
+	Metadata []struct {
+		Label string `xml:"fieldlabel"`
+		Entry string `xml:"fieldentry"`
+	} `xml:"itemmetadata>qtimetadata>qtimetadatafield"`
+	Text      []string `xml:"presentation>material>mattext"`
+	Responses []struct {
+		Ident    string `xml:"ident,attr"`
+		Material string `xml:"material>mattext"`
+		Labels   []struct {
+			Ident string `xml:"ident,attr"`
+			Text  string `xml:"material>mattext"`
+		} `xml:"render_choice>response_label"`
+	} `xml:"presentation>response_lid"`
+	TextResponses []struct {
+		Ident string `xml:"ident,attr"`
+	} `xml:"presentation>response_str"`
+	Conditions []struct {
+		VarEqual []struct {
+			RespIdent string `xml:"respident,attr"`
+			Value     string `xml:",chardata"`
+		} `xml:"conditionvar>varequal"`
+		SetVar []string `xml:"setvar"`
+	} `xml:"resprocessing>respcondition"`
+	Feedback []string `xml:"itemfeedback>flow_mat>material>mattext"`
+}
+
+// Limits on what a zipped QTI package may unpack to, so a small upload can't
+// expand into gigabytes
+const (
+	MaxPackageFiles      = 1000
+	MaxPackageEntryBytes = 8 << 20
+	MaxPackageBytes      = 32 << 20
+)
+
+// ParseQTI imports questions from a QTI 1.2 or 2.x XML file, or a zipped QTI package.
+// Error rows refer to the item's position in the file (or package).
+func ParseQTI(data []byte) (*Result, error) {
+	result := &Result{}
+
+	if bytes.HasPrefix(data, []byte("PK")) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid QTI package: %w", err)
+		}
+		if len(zr.File) > MaxPackageFiles {
+			return nil, fmt.Errorf("QTI package has %d files, the limit is %d", len(zr.File), MaxPackageFiles)
+		}
+
+		files := make([]*zip.File, 0, len(zr.File))
+		for _, f := range zr.File {
+			name := strings.ToLower(f.Name)
+			if strings.HasSuffix(name, ".xml") && !strings.HasSuffix(name, "imsmanifest.xml") && !strings.Contains(name, "assessment_meta") {
+				files = append(files, f)
+			}
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+		row := 0
+		remaining := int64(MaxPackageBytes)
+		for _, f := range files {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+			}
+			limit := remaining
+			if limit > MaxPackageEntryBytes {
+				limit = MaxPackageEntryBytes
+			}
+			content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+			}
+			if int64(len(content)) > limit {
+				if limit < MaxPackageEntryBytes {
+					return nil, fmt.Errorf("QTI package expands to more than %d MB", MaxPackageBytes>>20)
+				}
+				return nil, fmt.Errorf("%s expands to more than %d MB", f.Name, MaxPackageEntryBytes>>20)
+			}
+			remaining -= int64(len(content))
+			if row, err = parseQTIDocument(content, result, row); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+		}
+		return result, nil
+	}
+
+	if _, err := parseQTIDocument(data, result, 0); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseQTIDocument scans one XML document for QTI 1.2 items and QTI 2.x assessment items
+func parseQTIDocument(data []byte, result *Result, row int) (int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return row, nil
+		}
+		if err != nil {
+			return row, fmt.Errorf("invalid QTI XML: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "assessment":
+			if result.Title == "" {
+				result.Title = attr(start, "title")
+			}
+		case "item":
+			row++
+			var item qti12Item
+			if err := decoder.DecodeElement(&item, &start); err != nil {
+				result.addError(row, "malformed item: %v", err)
+				continue
+			}
+			q, msg := convertQTI12(item)
+			if msg != "" {
+				result.addError(row, "%s", msg)
+				continue
+			}
+			result.addQuestion(row, q)
+		case "assessmentItem":
+			row++
+			if result.Title == "" && attr(start, "title") != "" && len(result.Questions) == 0 {
+				result.Title = attr(start, "title")
+			}
+			q, msg := parseQTI2Item(decoder)
+			if msg != "" {
+				result.addError(row, "%s", msg)
+				continue
+			}
+			result.addQuestion(row, q)
+		}
+	}
+}
+
+func convertQTI12(item qti12Item) (models.Question, string) {
+	q := models.Question{Question: stripHTML(strings.Join(item.Text, " "))}
+	if q.Question == "" {
+		q.Question = item.Title
+	}
+	if len(item.Feedback) > 0 {
+		q.Explanation = stripHTML(item.Feedback[0])
+	}
+
+	var qtype string
+	for _, m := range item.Metadata {
+		switch m.Label {
+		case "question_type", "cc_profile", "qmd_itemtype":
+			qtype = strings.ToLower(strings.TrimSpace(m.Entry))
+		case "points_possible":
+			if v, err := strconv.ParseFloat(strings.TrimSpace(m.Entry), 64); err == nil {
+				q.Points = int(v + 0.5)
+			}
+		}
+	}
+
+	// Values of conditions that award points, per response ident
+	correct := make(map[string][]string)
+	for _, cond := range item.Conditions {
+		if !awardsPoints(cond.SetVar) {
+			continue
+		}
+		for _, v := range cond.VarEqual {
+			correct[v.RespIdent] = append(correct[v.RespIdent], strings.TrimSpace(v.Value))
+		}
+	}
+
+	switch {
+	case strings.Contains(qtype, "matching") || len(item.Responses) > 1:
+		q.Type = string(models.Matching)
+		var answers []string
+		for i, resp := range item.Responses {
+			var match string
+			for _, label := range resp.Labels {
+				for _, id := range correct[resp.Ident] {
+					if label.Ident == id {
+						match = stripHTML(label.Text)
+					}
+				}
+			}
+			if match == "" {
+				return q, fmt.Sprintf("matching pair %d has no correct answer", i+1)
+			}
+			q.Options = append(q.Options, stripHTML(resp.Material)+" → "+match)
+			answers = append(answers, fmt.Sprintf("%c-%d", 'A'+i, i+1))
+		}
+		q.CorrectAnswer = answers
+
+	case len(item.Responses) == 1:
+		resp := item.Responses[0]
+		for _, label := range resp.Labels {
+			text := stripHTML(label.Text)
+			q.Options = append(q.Options, text)
+			for _, id := range correct[resp.Ident] {
+				if label.Ident == id {
+					q.CorrectAnswer = text
+				}
+			}
+		}
+		q.Type = string(models.MultipleChoice)
+		if strings.Contains(qtype, "true_false") || isTrueFalse(q.Options) {
+			q.Type = string(models.TrueFalse)
+			if answer, ok := q.CorrectAnswer.(string); ok {
+				q.CorrectAnswer = normalizeTrueFalse(answer)
+			}
+			q.Options = []string{"True", "False"}
+		} else if strings.Contains(qtype, "multiple_answers") {
+			return q, "multiple-answer questions are not supported"
+		}
+
+	case strings.Contains(qtype, "essay"):
+		q.Type = string(models.Essay)
+
+	case len(item.TextResponses) > 0:
+		q.Type = string(models.ShortAnswer)
+		if strings.Contains(q.Question, "___") || strings.Contains(qtype, "fill_in") {
+			q.Type = string(models.FillBlank)
+		}
+		for _, values := range correct {
+			if len(values) > 0 {
+				q.CorrectAnswer = values[0]
+				break
+			}
+		}
+
+	default:
+		q.Type = string(models.Essay)
+	}
+
+	return q, ""
+}
+
+// parseQTI2Item reads the body of a QTI 2.x <assessmentItem> up to its end tag
+func parseQTI2Item(decoder *xml.Decoder) (models.Question, string) {
+	var (
+		q           models.Question
+		interaction string
+		correct     []string
+		text        strings.Builder
+		prompt      string
+		choiceIDs   []string
+		choiceText  []string
+		setIndex    int
+		inBody      bool
+		inFeedback  bool
+		inCorrect   bool
+		skip        int
+	)
+	matchSide := [2][][2]string{}
+
+	for depth := 1; depth > 0; {
+		tok, err := decoder.Token()
+		if err != nil {
+			return q, "unterminated assessmentItem"
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch t.Name.Local {
+			case "itemBody":
+				inBody = true
+			case "modalFeedback", "feedbackBlock":
+				inFeedback = true
+			case "correctResponse":
+				inCorrect = true
+			case "value":
+				if inCorrect {
+					var v string
+					decoder.DecodeElement(&v, &t)
+					depth--
+					correct = append(correct, strings.TrimSpace(v))
+				}
+			case "prompt":
+				prompt = innerText(decoder, &t)
+				depth--
+			case "choiceInteraction":
+				interaction = "choice"
+				skip++
+			case "textEntryInteraction":
+				interaction = "text"
+				text.WriteString(" __________ ")
+			case "extendedTextInteraction":
+				interaction = "extended"
+			case "matchInteraction":
+				interaction = "match"
+				skip++
+			case "simpleMatchSet":
+				setIndex++
+			case "simpleChoice":
+				choiceIDs = append(choiceIDs, attr(t, "identifier"))
+				choiceText = append(choiceText, innerText(decoder, &t))
+				depth--
+			case "simpleAssociableChoice":
+				side := setIndex - 1
+				if side < 0 || side > 1 {
+					side = 0
+				}
+				matchSide[side] = append(matchSide[side], [2]string{attr(t, "identifier"), innerText(decoder, &t)})
+				depth--
+			}
+		case xml.EndElement:
+			depth--
+			switch t.Name.Local {
+			case "itemBody":
+				inBody = false
+			case "correctResponse":
+				inCorrect = false
+			case "modalFeedback", "feedbackBlock":
+				inFeedback = false
+			case "choiceInteraction", "matchInteraction":
+				skip--
+			}
+		case xml.CharData:
+			switch {
+			case inFeedback:
+				if q.Explanation == "" {
+					q.Explanation = strings.TrimSpace(string(t))
+				}
+			case inBody && skip == 0:
+				text.Write(t)
+			}
+		}
+	}
+
+	q.Question = strings.Join(strings.Fields(strings.TrimSpace(text.String()+" "+prompt)), " ")
+
+	switch interaction {
+	case "choice":
+		for i, id := range choiceIDs {
+			q.Options = append(q.Options, choiceText[i])
+			for _, c := range correct {
+				if c == id {
+					q.CorrectAnswer = choiceText[i]
+				}
+			}
+		}
+		q.Type = string(models.MultipleChoice)
+		if isTrueFalse(q.Options) {
+			q.Type = string(models.TrueFalse)
+			q.Options = []string{"True", "False"}
+			if answer, ok := q.CorrectAnswer.(string); ok {
+				q.CorrectAnswer = normalizeTrueFalse(answer)
+			}
+		}
+	case "text":
+		q.Type = string(models.FillBlank)
+		if len(correct) > 0 {
+			q.CorrectAnswer = correct[0]
+		}
+	case "match":
+		q.Type = string(models.Matching)
+		labels := make(map[string]string)
+		for _, c := range matchSide[1] {
+			labels[c[0]] = c[1]
+		}
+		terms := make(map[string]string)
+		for _, c := range matchSide[0] {
+			terms[c[0]] = c[1]
+		}
+		var answers []string
+		for i, pair := range correct {
+			ids := strings.Fields(pair)
+			if len(ids) != 2 {
+				return q, fmt.Sprintf("malformed match pair %q", pair)
+			}
+			q.Options = append(q.Options, terms[ids[0]]+" → "+labels[ids[1]])
+			answers = append(answers, fmt.Sprintf("%c-%d", 'A'+i, i+1))
+		}
+		q.CorrectAnswer = answers
+	case "extended":
+		q.Type = string(models.Essay)
+	default:
+		return q, "unsupported QTI interaction"
+	}
+
+	return q, ""
+}
+
+// awardsPoints reports whether a respcondition's setvar gives a positive score
+func awardsPoints(setvars []string) bool {
+	for _, v := range setvars {
+		if score, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && score > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isTrueFalse(options []string) bool {
+	if len(options) != 2 {
+		return false
+	}
+	a, b := strings.ToLower(options[0]), strings.ToLower(options[1])
+	return (a == "true" && b == "false") || (a == "false" && b == "true")
+}
+
+// innerText decodes the element that start opened and returns its text content
+func innerText(decoder *xml.Decoder, start *xml.StartElement) string {
+	var inner struct {
+		XML string `xml:",innerxml"`
+	}
+	decoder.DecodeElement(&inner, start)
+	return stripHTML(inner.XML)
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// stripHTML removes markup from QTI mattext, which is usually escaped HTML
+func stripHTML(s string) string {
+	s = html.UnescapeString(s)
+	s = htmlTag.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
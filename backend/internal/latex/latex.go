@@ -0,0 +1,290 @@
+// Package latex compiles TikZ snippets to SVG with a local TeX toolchain.
+//
+// Snippets come from the AI model, so they are treated as untrusted: primitives
+// that read or write files or run commands are rejected, TeX runs with shell
+// escape disabled and file access limited to the working directory, and every
+// run is killed after a timeout.
+package latex
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned when latex or dvisvgm is not installed
+var ErrUnavailable = errors.New("latex: TeX toolchain not installed")
+
+const (
+	maxSnippetBytes = 8 << 10
+	maxConcurrent   = 2
+	defaultTimeout  = 15 * time.Second
+
+	// preambleVersion is part of the cache key; bump it when the document template changes
+	preambleVersion = "1"
+)
+
+const document = `\documentclass[tikz,border=4pt]{standalone}
+\usepackage{amsmath,amssymb}
+\usetikzlibrary{arrows.meta,angles,quotes,calc,positioning,shapes.geometric}
+\begin{document}
+%s
+\end{document}
+`
+
+// forbidden matches TeX primitives and commands that touch the file system,
+// run programs or change how the rest of the input is read
+var forbidden = regexp.MustCompile(`\\(?:(?:write18|immediate|write|openin|openout|read|input|include|includegraphics|` +
+	`InputIfFileExists|usepackage|RequirePackage|documentclass|catcode|def|gdef|edef|xdef|let|csname|` +
+	`directlua|special|pdfprimitive|makeatletter|endlinechar|newlinechar|uppercase|lowercase|scantokens|everyeof)\b|` +
+	`(?:begin|end)\s*\{document\})`)
+
+// hatNotation is TeX's ^^ character notation ("\^^69nput" reads as "\input"),
+// which would let a forbidden name past the pattern above
+const hatNotation = "^^"
+
+// Config locates the toolchain and the on-disk cache
+type Config struct {
+	LatexPath   string        // defaults to "latex" on PATH
+	DvisvgmPath string        // defaults to "dvisvgm" on PATH
+	CacheDir    string        // optional; SVGs are also cached in memory
+	Timeout     time.Duration // per command, default 15s
+}
+
+// ConfigFromEnv reads LATEX_PATH, DVISVGM_PATH, LATEX_CACHE_DIR and LATEX_TIMEOUT
+func ConfigFromEnv() Config {
+	cfg := Config{
+		LatexPath:   os.Getenv("LATEX_PATH"),
+		DvisvgmPath: os.Getenv("DVISVGM_PATH"),
+		CacheDir:    os.Getenv("LATEX_CACHE_DIR"),
+	}
+	if d, err := time.ParseDuration(os.Getenv("LATEX_TIMEOUT")); err == nil && d > 0 {
+		cfg.Timeout = d
+	}
+	return cfg
+}
+
+// Renderer compiles TikZ snippets to SVG and caches the results by content hash
+type Renderer struct {
+	latex    string
+	dvisvgm  string
+	cacheDir string
+	timeout  time.Duration
+	slots    chan struct{}
+
+	mu    sync.RWMutex
+	cache map[string]string
+}
+
+// NewRenderer looks up the toolchain; the renderer still works without one but
+// every uncached snippet fails with ErrUnavailable
+func NewRenderer(cfg Config) *Renderer {
+	r := &Renderer{
+		cacheDir: cfg.CacheDir,
+		timeout:  cfg.Timeout,
+		slots:    make(chan struct{}, maxConcurrent),
+		cache:    make(map[string]string),
+	}
+	if r.timeout == 0 {
+		r.timeout = defaultTimeout
+	}
+	r.latex = lookPath(cfg.LatexPath, "latex")
+	r.dvisvgm = lookPath(cfg.DvisvgmPath, "dvisvgm")
+	if r.cacheDir != "" {
+		if err := os.MkdirAll(r.cacheDir, 0o755); err != nil {
+			r.cacheDir = ""
+		}
+	}
+	return r
+}
+
+func lookPath(configured, name string) string {
+	if configured == "" {
+		configured = name
+	}
+	path, err := exec.LookPath(configured)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// Available reports whether latex and dvisvgm were found
+func (r *Renderer) Available() bool {
+	return r.latex != "" && r.dvisvgm != ""
+}
+
+// Render compiles a TikZ snippet, either a full tikzpicture environment or just
+// its body, and returns the SVG document
+func (r *Renderer) Render(ctx context.Context, snippet string) (string, error) {
+	snippet, err := prepare(snippet)
+	if err != nil {
+		return "", err
+	}
+
+	key := cacheKey(snippet)
+	if svg, ok := r.cached(key); ok {
+		return svg, nil
+	}
+	if !r.Available() {
+		return "", ErrUnavailable
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	svg, err := r.compile(ctx, snippet)
+	if err != nil {
+		return "", err
+	}
+	r.store(key, svg)
+	return svg, nil
+}
+
+// prepare checks a snippet and wraps a bare body in a tikzpicture environment
+func prepare(snippet string) (string, error) {
+	snippet = strings.TrimSpace(snippet)
+	if snippet == "" {
+		return "", fmt.Errorf("latex: empty diagram")
+	}
+	if len(snippet) > maxSnippetBytes {
+		return "", fmt.Errorf("latex: diagram is longer than %d bytes", maxSnippetBytes)
+	}
+	if strings.Contains(snippet, hatNotation) {
+		return "", fmt.Errorf("latex: %s character codes are not allowed in diagrams", hatNotation)
+	}
+	if m := forbidden.FindString(snippet); m != "" {
+		return "", fmt.Errorf("latex: %s is not allowed in diagrams", m)
+	}
+	if !strings.Contains(snippet, `\begin{tikzpicture}`) {
+		snippet = "\\begin{tikzpicture}\n" + snippet + "\n\\end{tikzpicture}"
+	}
+	return snippet, nil
+}
+
+func cacheKey(snippet string) string {
+	sum := sha256.Sum256([]byte(preambleVersion + "\x00" + snippet))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *Renderer) cached(key string) (string, bool) {
+	r.mu.RLock()
+	svg, ok := r.cache[key]
+	r.mu.RUnlock()
+	if ok || r.cacheDir == "" {
+		return svg, ok
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.cacheDir, key+".svg"))
+	if err != nil {
+		return "", false
+	}
+	svg = string(data)
+	r.mu.Lock()
+	r.cache[key] = svg
+	r.mu.Unlock()
+	return svg, true
+}
+
+func (r *Renderer) store(key, svg string) {
+	r.mu.Lock()
+	r.cache[key] = svg
+	r.mu.Unlock()
+	if r.cacheDir == "" {
+		return
+	}
+	// Write a uniquely named file then rename it, so concurrent readers never
+	// see a partial file and concurrent writers never share one
+	tmp, err := os.CreateTemp(r.cacheDir, "tikz-*.tmp")
+	if err != nil {
+		return
+	}
+	if _, err = tmp.WriteString(svg); err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(r.cacheDir, key+".svg"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (r *Renderer) compile(ctx context.Context, snippet string) (string, error) {
+	dir, err := os.MkdirTemp("", "makos-tikz-")
+	if err != nil {
+		return "", fmt.Errorf("latex: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "diagram.tex"), []byte(fmt.Sprintf(document, snippet)), 0o600); err != nil {
+		return "", fmt.Errorf("latex: %w", err)
+	}
+
+	if out, err := r.run(ctx, dir, r.latex, "-no-shell-escape", "-interaction=nonstopmode", "-halt-on-error", "diagram.tex"); err != nil {
+		return "", fmt.Errorf("latex: compile failed: %v%s", err, texError(out))
+	}
+	if out, err := r.run(ctx, dir, r.dvisvgm, "--no-fonts", "--exact-bbox", "--output=diagram.svg", "diagram.dvi"); err != nil {
+		return "", fmt.Errorf("latex: dvisvgm failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	svg, err := os.ReadFile(filepath.Join(dir, "diagram.svg"))
+	if err != nil {
+		return "", fmt.Errorf("latex: %w", err)
+	}
+	// Drop the XML prolog and doctype so the SVG can be inlined like the other diagrams
+	start := bytes.Index(svg, []byte("<svg"))
+	if start < 0 {
+		return "", fmt.Errorf("latex: dvisvgm produced no SVG")
+	}
+	return string(svg[start:]), nil
+}
+
+// run executes a toolchain command in dir with the renderer's timeout. TeX may
+// only open files inside dir and may not run external programs.
+func (r *Renderer) run(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "openin_any=p", "openout_any=p", "shell_escape=f", "TEXMFOUTPUT="+dir)
+	// Don't wait on children that outlive a killed process and hold the output open
+	cmd.WaitDelay = time.Second
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return out.Bytes(), fmt.Errorf("timed out after %s", r.timeout)
+	}
+	return out.Bytes(), err
+}
+
+// texError pulls the first "! ..." error line out of TeX output
+func texError(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "! ") {
+			return ": " + strings.TrimSpace(strings.TrimPrefix(line, "! "))
+		}
+	}
+	return ""
+}
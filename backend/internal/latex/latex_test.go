@@ -0,0 +1,83 @@
+package latex
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		want    string // prepared snippet, or part of the error
+		reject  bool
+	}{
+		{name: "body is wrapped", snippet: `\draw (0,0) -- (1,1);`, want: "\\begin{tikzpicture}\n\\draw (0,0) -- (1,1);\n\\end{tikzpicture}"},
+		{name: "environment kept", snippet: " \\begin{tikzpicture}\\draw (0,0) circle (1);\\end{tikzpicture}\n", want: `\begin{tikzpicture}\draw (0,0) circle (1);\end{tikzpicture}`},
+		{name: "superscript allowed", snippet: `\node {$x^2$};`, want: "\\begin{tikzpicture}\n\\node {$x^2$};\n\\end{tikzpicture}"},
+		{name: "empty", snippet: "  \n", want: "empty", reject: true},
+		{name: "too long", snippet: strings.Repeat("%", maxSnippetBytes+1), want: "longer than", reject: true},
+		{name: "input", snippet: `\input{/etc/passwd}`, want: `\input`, reject: true},
+		{name: "write18", snippet: `\immediate\write18{ls}`, want: `\immediate`, reject: true},
+		{name: "catcode", snippet: `\catcode` + "`" + `\|=0`, want: `\catcode`, reject: true},
+		{name: "document", snippet: `\end {document}`, want: `\end {document}`, reject: true},
+		{name: "hat input", snippet: `\^^69nput{/etc/passwd}`, want: "^^", reject: true},
+		{name: "hat csname", snippet: `\^^63sname input\endcsname{x}`, want: "^^", reject: true},
+		{name: "endlinechar", snippet: `\endlinechar=-1`, want: `\endlinechar`, reject: true},
+		{name: "uppercase", snippet: `\uppercase{\def\x{}}`, want: `\uppercase`, reject: true},
+		{name: "scantokens", snippet: `\scantokens{\input x}`, want: `\scantokens`, reject: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prepare(tt.snippet)
+			if tt.reject {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("error = %v, want one mentioning %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("prepare = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRendererCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := Config{LatexPath: "/nonexistent/latex", DvisvgmPath: "/nonexistent/dvisvgm", CacheDir: dir}
+	r := NewRenderer(cfg)
+	if r.Available() {
+		t.Fatal("renderer found a toolchain that doesn't exist")
+	}
+	if _, err := r.Render(ctx, `\draw (0,0) -- (1,0);`); err != ErrUnavailable {
+		t.Fatalf("uncached render error = %v, want %v", err, ErrUnavailable)
+	}
+
+	snippet, _ := prepare(`\draw (0,0) -- (1,0);`)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.store(cacheKey(snippet), "<svg>line</svg>")
+		}()
+	}
+	wg.Wait()
+	if leftover, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftover) > 0 {
+		t.Errorf("temporary files left behind: %v", leftover)
+	}
+
+	// A new renderer serves the SVG from disk without a toolchain
+	svg, err := NewRenderer(cfg).Render(ctx, "\n"+`\draw (0,0) -- (1,0);`)
+	if err != nil || svg != "<svg>line</svg>" {
+		t.Errorf("cached render = %q, %v", svg, err)
+	}
+}
@@ -0,0 +1,313 @@
+package models
+
+// Diagram types
+const (
+	DiagramTriangle       = "triangle"
+	DiagramRectangle      = "rectangle"
+	DiagramCircle         = "circle"
+	DiagramRegularPolygon = "regular_polygon"
+	DiagramCircuit        = "circuit"
+	DiagramGraph          = "graph"
+
+	// Elementary visual models
+	DiagramNumberLine = "number_line"
+	DiagramFraction   = "fraction"
+	DiagramClock      = "clock"
+	DiagramBaseTen    = "base_ten"
+	DiagramCoins      = "coins"
+	DiagramTally      = "tally"
+
+	// Data displays
+	DiagramChart = "chart"
+)
+
+// Chart kinds
+const (
+	ChartBar        = "bar"
+	ChartPictograph = "pictograph"
+	ChartLinePlot   = "line_plot"
+	ChartHistogram  = "histogram"
+	ChartTable      = "table"
+)
+
+// Circuit component kinds; "series" and "parallel" group other nodes
+const (
+	CircuitSeries    = "series"
+	CircuitParallel  = "parallel"
+	CircuitResistor  = "resistor"
+	CircuitBulb      = "bulb"
+	CircuitSwitch    = "switch"
+	CircuitAmmeter   = "ammeter"
+	CircuitVoltmeter = "voltmeter"
+	CircuitCapacitor = "capacitor"
+)
+
+// Diagram is a structured description of a figure that the backend renders to SVG.
+// Type selects the figure; the matching spec field holds its parameters.
+type Diagram struct {
+	Type    string   `json:"type"`
+	Title   string   `json:"title,omitempty"`
+	Shape   *Shape   `json:"shape,omitempty"`
+	Circuit *Circuit `json:"circuit,omitempty"`
+	Graph   *Graph   `json:"graph,omitempty"`
+
+	NumberLine *NumberLine    `json:"number_line,omitempty"`
+	Fraction   *FractionModel `json:"fraction,omitempty"`
+	Clock      *Clock         `json:"clock,omitempty"`
+	BaseTen    *BaseTen       `json:"base_ten,omitempty"`
+	Coins      *CoinSet       `json:"coins,omitempty"`
+	Tally      *Tally         `json:"tally,omitempty"`
+
+	Chart *Chart `json:"chart,omitempty"`
+}
+
+// Shape describes a geometric figure.
+//
+// Triangle sides are [a, b, c] and angles are [A, B, C] in degrees, where side a
+// is opposite vertex A; unknown values are 0 and are solved for when the rest of
+// the triangle determines them. Rectangles use sides [width, height], regular
+// polygons [side] with NumSides, and circles use Radius.
+type Shape struct {
+	Sides        []float64 `json:"sides,omitempty"`
+	Angles       []float64 `json:"angles,omitempty"`
+	Radius       float64   `json:"radius,omitempty"`
+	NumSides     int       `json:"num_sides,omitempty"`
+	Unit         string    `json:"unit,omitempty"`
+	Labels       []string  `json:"labels,omitempty"`        // vertex labels, e.g. ["A", "B", "C"]
+	SideLabels   []string  `json:"side_labels,omitempty"`   // overrides measured labels, e.g. "x" for the unknown
+	AngleLabels  []string  `json:"angle_labels,omitempty"`  // overrides angle labels, e.g. "θ"
+	RightAngle   string    `json:"right_angle,omitempty"`   // vertex label marked with a right-angle box
+	ShowDiameter bool      `json:"show_diameter,omitempty"` // circles: draw the diameter instead of the radius
+}
+
+// Circuit describes a battery driving a network of components
+type Circuit struct {
+	Voltage  float64          `json:"voltage,omitempty"` // battery voltage in volts, 0 when unknown
+	Network  *CircuitNode     `json:"network"`
+	Analysis *CircuitAnalysis `json:"analysis,omitempty"` // filled in by the backend
+}
+
+// CircuitNode is a component or a series/parallel group of nodes.
+// Value is in ohms for resistors and bulbs and in microfarads for capacitors.
+type CircuitNode struct {
+	Kind     string        `json:"kind"`
+	Label    string        `json:"label,omitempty"`
+	Value    float64       `json:"value,omitempty"`
+	Open     bool          `json:"open,omitempty"` // switches only
+	Elements []CircuitNode `json:"elements,omitempty"`
+}
+
+// CircuitAnalysis holds values computed from a circuit; nil means it could not be determined
+type CircuitAnalysis struct {
+	EquivalentResistance  *float64 `json:"equivalent_resistance,omitempty"`  // ohms
+	Current               *float64 `json:"current,omitempty"`                // amperes drawn from the battery
+	EquivalentCapacitance *float64 `json:"equivalent_capacitance,omitempty"` // microfarads
+}
+
+// Graph describes a coordinate plane with plotted functions and points.
+// Zero ranges default to -10..10 and zero steps to an automatic tick spacing.
+type Graph struct {
+	XMin      float64         `json:"x_min"`
+	XMax      float64         `json:"x_max"`
+	YMin      float64         `json:"y_min"`
+	YMax      float64         `json:"y_max"`
+	XStep     float64         `json:"x_step,omitempty"`
+	YStep     float64         `json:"y_step,omitempty"`
+	XLabel    string          `json:"x_label,omitempty"`
+	YLabel    string          `json:"y_label,omitempty"`
+	Functions []GraphFunction `json:"functions,omitempty"`
+	Points    []GraphPoint    `json:"points,omitempty"`
+}
+
+// GraphFunction is an expression in x such as "2x + 1" or "sin(x)", optionally
+// restricted to a domain
+type GraphFunction struct {
+	Expression string    `json:"expression"`
+	Label      string    `json:"label,omitempty"`
+	Domain     []float64 `json:"domain,omitempty"` // [from, to]
+}
+
+// GraphPoint is a labelled point on a graph
+type GraphPoint struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Label string  `json:"label,omitempty"`
+}
+
+// NumberLine is a number line with marked points and jumps drawn as arcs
+type NumberLine struct {
+	Min    float64           `json:"min"`
+	Max    float64           `json:"max"`
+	Step   float64           `json:"step,omitempty"` // tick spacing, default 1
+	Points []NumberLinePoint `json:"points,omitempty"`
+	Jumps  []NumberLineJump  `json:"jumps,omitempty"`
+}
+
+// NumberLinePoint marks a value on a number line
+type NumberLinePoint struct {
+	Value float64 `json:"value"`
+	Label string  `json:"label,omitempty"`
+}
+
+// NumberLineJump is an arc from one value to another, e.g. "+3"
+type NumberLineJump struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Label string  `json:"label,omitempty"`
+}
+
+// FractionModel shades Numerator parts of wholes split into Denominator parts.
+// Style is "bar" (default) or "circle"; improper fractions draw several wholes.
+type FractionModel struct {
+	Numerator   int    `json:"numerator"`
+	Denominator int    `json:"denominator"`
+	Style       string `json:"style,omitempty"`
+}
+
+// Clock is an analog clock face showing Hour:Minute
+type Clock struct {
+	Hour        int  `json:"hour"`
+	Minute      int  `json:"minute"`
+	HideNumbers bool `json:"hide_numbers,omitempty"`
+	HideHands   bool `json:"hide_hands,omitempty"` // blank face for "draw the time" questions
+}
+
+// BaseTen is a set of base-ten blocks: hundred flats, ten rods and unit cubes
+type BaseTen struct {
+	Hundreds int `json:"hundreds"`
+	Tens     int `json:"tens"`
+	Ones     int `json:"ones"`
+}
+
+// CoinSet is a group of US coins: penny, nickel, dime, quarter, half_dollar, dollar_coin
+type CoinSet struct {
+	Coins []string `json:"coins"`
+}
+
+// Tally is a tally chart with one row per category
+type Tally struct {
+	Rows []TallyRow `json:"rows"`
+}
+
+// TallyRow is one category of a tally chart
+type TallyRow struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// Clone returns a deep copy of the node and its children
+func (n CircuitNode) Clone() CircuitNode {
+	if n.Elements != nil {
+		elements := make([]CircuitNode, len(n.Elements))
+		for i, e := range n.Elements {
+			elements[i] = e.Clone()
+		}
+		n.Elements = elements
+	}
+	return n
+}
+
+// Clone returns a deep copy of the diagram
+func (d *Diagram) Clone() *Diagram {
+	c := *d
+	if d.Shape != nil {
+		s := *d.Shape
+		s.Sides = append([]float64(nil), d.Shape.Sides...)
+		s.Angles = append([]float64(nil), d.Shape.Angles...)
+		s.Labels = append([]string(nil), d.Shape.Labels...)
+		s.SideLabels = append([]string(nil), d.Shape.SideLabels...)
+		s.AngleLabels = append([]string(nil), d.Shape.AngleLabels...)
+		c.Shape = &s
+	}
+	if d.Circuit != nil {
+		circuit := *d.Circuit
+		if d.Circuit.Network != nil {
+			network := d.Circuit.Network.Clone()
+			circuit.Network = &network
+		}
+		if d.Circuit.Analysis != nil {
+			analysis := *d.Circuit.Analysis
+			circuit.Analysis = &analysis
+		}
+		c.Circuit = &circuit
+	}
+	if d.Graph != nil {
+		g := *d.Graph
+		g.Functions = make([]GraphFunction, len(d.Graph.Functions))
+		for i, f := range d.Graph.Functions {
+			f.Domain = append([]float64(nil), f.Domain...)
+			g.Functions[i] = f
+		}
+		g.Points = append([]GraphPoint(nil), d.Graph.Points...)
+		c.Graph = &g
+	}
+	if d.NumberLine != nil {
+		n := *d.NumberLine
+		n.Points = append([]NumberLinePoint(nil), d.NumberLine.Points...)
+		n.Jumps = append([]NumberLineJump(nil), d.NumberLine.Jumps...)
+		c.NumberLine = &n
+	}
+	if d.Fraction != nil {
+		f := *d.Fraction
+		c.Fraction = &f
+	}
+	if d.Clock != nil {
+		clock := *d.Clock
+		c.Clock = &clock
+	}
+	if d.BaseTen != nil {
+		b := *d.BaseTen
+		c.BaseTen = &b
+	}
+	if d.Coins != nil {
+		c.Coins = &CoinSet{Coins: append([]string(nil), d.Coins.Coins...)}
+	}
+	if d.Tally != nil {
+		c.Tally = &Tally{Rows: append([]TallyRow(nil), d.Tally.Rows...)}
+	}
+	if d.Chart != nil {
+		chart := *d.Chart
+		chart.Data = append([]ChartDatum(nil), d.Chart.Data...)
+		chart.Values = append([]float64(nil), d.Chart.Values...)
+		if d.Chart.Statistics != nil {
+			stats := *d.Chart.Statistics
+			stats.Mode = append([]float64(nil), d.Chart.Statistics.Mode...)
+			chart.Statistics = &stats
+		}
+		c.Chart = &chart
+	}
+	return &c
+}
+
+// Chart is a small dataset and the display used to show it. Bar charts,
+// pictographs and tables show labelled Data; line plots and histograms show
+// the raw Values, with histograms grouping them into bins of BinWidth.
+type Chart struct {
+	Kind       string           `json:"kind"`
+	XLabel     string           `json:"x_label,omitempty"`
+	YLabel     string           `json:"y_label,omitempty"`
+	Data       []ChartDatum     `json:"data,omitempty"`
+	Values     []float64        `json:"values,omitempty"`
+	BinWidth   float64          `json:"bin_width,omitempty"`
+	BinStart   float64          `json:"bin_start,omitempty"`
+	PerSymbol  float64          `json:"per_symbol,omitempty"` // pictograph key, default 1
+	Statistics *ChartStatistics `json:"statistics,omitempty"`
+}
+
+// ChartDatum is one labelled value of a chart
+type ChartDatum struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+}
+
+// ChartStatistics holds summary values computed from a chart's dataset
+type ChartStatistics struct {
+	Count  int       `json:"count"`
+	Total  float64   `json:"total"`
+	Mean   float64   `json:"mean"`
+	Median float64   `json:"median"`
+	Mode   []float64 `json:"mode,omitempty"` // empty when no value repeats
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	Range  float64   `json:"range"`
+}
@@ -1,7 +1,9 @@
 package models
 
-import "time"
-
+import (
+	"strings"
+	"time"
+)
 
 // QuestionType represents different types of questions
 type QuestionType string
@@ -19,76 +21,200 @@ const (
 type Difficulty string
 
 const (
-
 	Easy   Difficulty = "easy"
 	Medium Difficulty = "medium"
 	Hard   Difficulty = "hard"
 )
 
-
 // Question represents a single question in a worksheet
 type Question struct {
-	ID            string      `json:"id"`
-	Type          string      `json:"type"`
-	Question      string      `json:"question"`
-	Options       []string    `json:"options,omitempty"`
-	CorrectAnswer interface{} `json:"correct_answer,omitempty"`
-	Explanation   string      `json:"explanation,omitempty"`
-	Points        int         `json:"points"`
-	Image         string      `json:"image,omitempty"`
+	ID            string       `json:"id"`
+	Type          string       `json:"type"`
+	Question      string       `json:"question"`
+	Options       []string     `json:"options,omitempty"`
+	CorrectAnswer interface{}  `json:"correct_answer,omitempty"`
+	Explanation   string       `json:"explanation,omitempty"`
+	Points        int          `json:"points"`
+	Image         string       `json:"image,omitempty"`
+	ImageCredit   *ImageCredit `json:"image_credit,omitempty"`
+	ParagraphRefs []int        `json:"paragraph_refs,omitempty"`
+	SourceExcerpt string       `json:"source_excerpt_id,omitempty"`
+	Diagram       *Diagram     `json:"diagram,omitempty"`
+	LatexDiagram  string       `json:"latex_diagram,omitempty"` // TikZ fallback when no structured diagram fits
+	Warnings      []string     `json:"warnings,omitempty"`
+}
+
+// ImageCredit is the attribution a photo's license requires us to show with it
+type ImageCredit struct {
+	Provider  string `json:"provider"`
+	Author    string `json:"author,omitempty"`
+	AuthorURL string `json:"author_url,omitempty"`
+	SourceURL string `json:"source_url,omitempty"`
 }
 
+// SourceExcerpt represents a chunk of teacher-uploaded material that questions are grounded in
+type SourceExcerpt struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// Passage represents a reading passage that comprehension questions refer to
+type Passage struct {
+	Title       string       `json:"title,omitempty"`
+	Paragraphs  []string     `json:"paragraphs"`
+	Source      string       `json:"source"`
+	Readability *Readability `json:"readability,omitempty"`
+}
+
+// Text returns the passage paragraphs joined as plain text
+func (p *Passage) Text() string {
+	return strings.Join(p.Paragraphs, "\n\n")
+}
+
+// Readability represents the Flesch-Kincaid scoring of a passage against the worksheet grade
+type Readability struct {
+	Words         int     `json:"words"`
+	Sentences     int     `json:"sentences"`
+	Syllables     int     `json:"syllables"`
+	FleschKincaid float64 `json:"flesch_kincaid_grade"`
+	ReadingEase   float64 `json:"flesch_reading_ease"`
+	TargetGrade   string  `json:"target_grade"`
+	WithinRange   bool    `json:"within_range"`
+}
 
 // Worksheet represents a generated worksheet
 type Worksheet struct {
-	ID                     string     `json:"id"`
-	Title                  string     `json:"title"`
-	Subject                string     `json:"subject"`
-	Topic                  string     `json:"topic"`
-	GradeLevel             string     `json:"grade_level"`
-	Difficulty             string     `json:"difficulty"`
-	Language               string     `json:"language"`
-	Questions              []Question `json:"questions"`
-	IncludeAnswerKey       bool       `json:"include_answer_key"`
-	AdditionalInstructions string     `json:"additional_instructions,omitempty"`
-	CreatedAt              time.Time  `json:"created_at"`
-	UpdatedAt              time.Time  `json:"updated_at"`
-	Status                 string     `json:"status"`
-	Downloads              int        `json:"downloads"`
+	ID                     string          `json:"id"`
+	Title                  string          `json:"title"`
+	Subject                string          `json:"subject"`
+	Topic                  string          `json:"topic"`
+	GradeLevel             string          `json:"grade_level"`
+	Difficulty             string          `json:"difficulty"`
+	Language               string          `json:"language"`
+	Passage                *Passage        `json:"passage,omitempty"`
+	SourceFile             string          `json:"source_file,omitempty"`
+	SourceExcerpts         []SourceExcerpt `json:"source_excerpts,omitempty"`
+	Questions              []Question      `json:"questions"`
+	IncludeAnswerKey       bool            `json:"include_answer_key"`
+	AdditionalInstructions string          `json:"additional_instructions,omitempty"`
+	CreatedAt              time.Time       `json:"created_at"`
+	UpdatedAt              time.Time       `json:"updated_at"`
+	Status                 string          `json:"status"`
+	Downloads              int             `json:"downloads"`
+	Revision               int             `json:"revision"`
+	Moderation             *Moderation     `json:"moderation,omitempty"`
+	PromptTemplate         string          `json:"prompt_template,omitempty"` // template ID the worksheet was generated with
+	PromptVersion          int             `json:"prompt_version,omitempty"`
+	Usage                  *Usage          `json:"usage,omitempty"` // tokens spent generating the worksheet and its questions
+}
+
+// Clone returns a deep copy of the worksheet so it can be edited without touching the original
+func (w *Worksheet) Clone() *Worksheet {
+	c := *w
+	if w.Passage != nil {
+		p := *w.Passage
+		p.Paragraphs = append([]string(nil), w.Passage.Paragraphs...)
+		if w.Passage.Readability != nil {
+			r := *w.Passage.Readability
+			p.Readability = &r
+		}
+		c.Passage = &p
+	}
+	if w.Moderation != nil {
+		m := *w.Moderation
+		m.Flags = append([]ModerationFlag(nil), w.Moderation.Flags...)
+		c.Moderation = &m
+	}
+	if w.Usage != nil {
+		u := *w.Usage
+		c.Usage = &u
+	}
+	c.SourceExcerpts = append([]SourceExcerpt(nil), w.SourceExcerpts...)
+	c.Questions = make([]Question, len(w.Questions))
+	for i, q := range w.Questions {
+		c.Questions[i] = q.Clone()
+	}
+	return &c
+}
+
+// Clone returns a deep copy of the question
+func (q Question) Clone() Question {
+	q.Options = append([]string(nil), q.Options...)
+	q.ParagraphRefs = append([]int(nil), q.ParagraphRefs...)
+	q.Warnings = append([]string(nil), q.Warnings...)
+	if q.Diagram != nil {
+		q.Diagram = q.Diagram.Clone()
+	}
+	if q.ImageCredit != nil {
+		credit := *q.ImageCredit
+		q.ImageCredit = &credit
+	}
+	switch v := q.CorrectAnswer.(type) {
+	case []string:
+		q.CorrectAnswer = append([]string(nil), v...)
+	case []interface{}:
+		q.CorrectAnswer = append([]interface{}(nil), v...)
+	}
+	return q
 }
 
 // WorksheetGeneratorInput represents the input for worksheet generation
 type WorksheetGeneratorInput struct {
-	Topic                  string   `json:"topic"`
-	Subject                string   `json:"subject"`
-	GradeLevel             string   `json:"grade_level"`
-	Difficulty             string   `json:"difficulty"`
-	QuestionCount          int      `json:"question_count"`
-	QuestionTypes          []string `json:"question_types"`
-	Language               string   `json:"language"`
-	IncludeAnswerKey       bool     `json:"include_answer_key"`
-	AdditionalInstructions string   `json:"additional_instructions,omitempty"`
+	Topic                  string           `json:"topic"`
+	Subject                string           `json:"subject"`
+	GradeLevel             string           `json:"grade_level"`
+	Difficulty             string           `json:"difficulty"`
+	QuestionCount          int              `json:"question_count"`
+	QuestionTypes          []string         `json:"question_types"`
+	Language               string           `json:"language"`
+	IncludeAnswerKey       bool             `json:"include_answer_key"`
+	AdditionalInstructions string           `json:"additional_instructions,omitempty"`
+	IncludePassage         bool             `json:"include_passage,omitempty"`
+	Passage                string           `json:"passage,omitempty"`
+	SourceFile             string           `json:"source_file,omitempty"`
+	SourceExcerpts         []SourceExcerpt  `json:"source_excerpts,omitempty"`
+	ContextWorksheetID     string           `json:"context_worksheet_id,omitempty"`
+	Fresh                  bool             `json:"fresh,omitempty"` // always generate a new set instead of reusing a recent identical one
+	ExampleQuestions       []Question       `json:"-"`
+	Limits                 GenerationLimits `json:"-"`
 }
 
+// GenerationLimits turn off optional extras for a generation, e.g. on plans without them
+type GenerationLimits struct {
+	NoImages   bool
+	NoDiagrams bool
+}
 
 // GenerationResponse represents the API response for worksheet generation
 type GenerationResponse struct {
 	Success   bool       `json:"success"`
 	Worksheet *Worksheet `json:"worksheet,omitempty"`
+	Cached    bool       `json:"cached,omitempty"` // a fresh copy of a recent identical generation
 	Error     string     `json:"error,omitempty"`
 }
 
-
 // User represents a user account
 type User struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Password  string    `json:"-"`
-	Plan      string    `json:"plan"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Name          string    `json:"name"`
+	Password      string    `json:"-"`
+	Plan          string    `json:"plan"`
+	Billing       *Billing  `json:"billing,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
+// Billing is the state of a user's subscription, kept in sync by the billing webhook
+type Billing struct {
+	CustomerID        string     `json:"customer_id"`
+	SubscriptionID    string     `json:"subscription_id,omitempty"`
+	Status            string     `json:"status"` // the provider's subscription status, e.g. "active", "past_due", "canceled"
+	CurrentPeriodEnd  *time.Time `json:"current_period_end,omitempty"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end,omitempty"`
+	PaymentFailedAt   *time.Time `json:"payment_failed_at,omitempty"`
+	LastEventAt       time.Time  `json:"last_event_at"` // events older than this are stale and ignored
+}
 
 // AuthResponse represents authentication response
 type AuthResponse struct {
@@ -97,4 +223,3 @@ type AuthResponse struct {
 	User    *User  `json:"user,omitempty"`
 	Error   string `json:"error,omitempty"`
 }
-
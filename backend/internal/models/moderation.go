@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Moderation statuses
+const (
+	ModerationClear    = "clear"    // nothing reached the grade's threshold
+	ModerationFlagged  = "flagged"  // export is blocked until a teacher reviews it
+	ModerationApproved = "approved" // a teacher reviewed the flags and released the worksheet
+)
+
+// Moderation is the content-safety screening result for a worksheet
+type Moderation struct {
+	Status     string           `json:"status"`
+	Threshold  float64          `json:"threshold"` // scores at or above this are flagged for the grade
+	Flags      []ModerationFlag `json:"flags,omitempty"`
+	CheckedAt  time.Time        `json:"checked_at"`
+	ReviewedBy string           `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time       `json:"reviewed_at,omitempty"`
+	ReviewNote string           `json:"review_note,omitempty"`
+}
+
+// ModerationFlag is a passage of worksheet text a classifier scored at or above the threshold
+type ModerationFlag struct {
+	Field      string  `json:"field"` // e.g. "questions[q_3].explanation"
+	Category   string  `json:"category"`
+	Score      float64 `json:"score"`
+	Excerpt    string  `json:"excerpt,omitempty"`
+	Classifier string  `json:"classifier"`
+}
+
+// Blocked reports whether the worksheet may not be exported yet
+func (m *Moderation) Blocked() bool {
+	return m != nil && m.Status == ModerationFlagged
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Revision is an immutable snapshot of a worksheet taken after each change
+type Revision struct {
+	WorksheetID  string     `json:"worksheet_id"`
+	Number       int        `json:"number"`
+	Author       string     `json:"author"`
+	Summary      string     `json:"summary"`
+	RestoredFrom int        `json:"restored_from,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Worksheet    *Worksheet `json:"worksheet,omitempty"`
+}
+
+// FieldChange describes one field that differs between two revisions
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// QuestionChange describes how one question differs between two revisions.
+// Status is "added", "removed", "modified" or "moved"; positions are 1-based.
+type QuestionChange struct {
+	Status       string        `json:"status"`
+	FromID       string        `json:"from_id,omitempty"`
+	ToID         string        `json:"to_id,omitempty"`
+	FromPosition int           `json:"from_position,omitempty"`
+	ToPosition   int           `json:"to_position,omitempty"`
+	Question     string        `json:"question"`
+	Changes      []FieldChange `json:"changes,omitempty"`
+}
+
+// WorksheetDiff lists the differences between two revisions of a worksheet
+type WorksheetDiff struct {
+	From      int              `json:"from"`
+	To        int              `json:"to"`
+	Metadata  []FieldChange    `json:"metadata"`
+	Questions []QuestionChange `json:"questions"`
+	Unchanged int              `json:"unchanged_questions"`
+}
+
+// DiffWorksheets compares two worksheet snapshots question by question.
+//
+// Question IDs are renumbered when questions are inserted or moved, so
+// questions are paired by identical text first and by ID second; anything
+// left unpaired is reported as added or removed.
+func DiffWorksheets(from, to *Worksheet) WorksheetDiff {
+	diff := WorksheetDiff{
+		From:      from.Revision,
+		To:        to.Revision,
+		Metadata:  []FieldChange{},
+		Questions: []QuestionChange{},
+	}
+
+	addChange(&diff.Metadata, "title", from.Title, to.Title)
+	addChange(&diff.Metadata, "subject", from.Subject, to.Subject)
+	addChange(&diff.Metadata, "topic", from.Topic, to.Topic)
+	addChange(&diff.Metadata, "grade_level", from.GradeLevel, to.GradeLevel)
+	addChange(&diff.Metadata, "difficulty", from.Difficulty, to.Difficulty)
+	addChange(&diff.Metadata, "language", from.Language, to.Language)
+	addChange(&diff.Metadata, "status", from.Status, to.Status)
+	addChange(&diff.Metadata, "include_answer_key", from.IncludeAnswerKey, to.IncludeAnswerKey)
+	addChange(&diff.Metadata, "passage", passageText(from.Passage), passageText(to.Passage))
+
+	// pair[i] is the index in to.Questions matched with from.Questions[i], or -1
+	pair := make([]int, len(from.Questions))
+	used := make([]bool, len(to.Questions))
+	for i := range pair {
+		pair[i] = -1
+	}
+	for i, fq := range from.Questions {
+		for j, tq := range to.Questions {
+			if !used[j] && fq.Question == tq.Question {
+				pair[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i, fq := range from.Questions {
+		if pair[i] >= 0 {
+			continue
+		}
+		for j, tq := range to.Questions {
+			if !used[j] && fq.ID == tq.ID {
+				pair[i], used[j] = j, true
+				break
+			}
+		}
+	}
+
+	for i, fq := range from.Questions {
+		j := pair[i]
+		if j < 0 {
+			diff.Questions = append(diff.Questions, QuestionChange{
+				Status:       "removed",
+				FromID:       fq.ID,
+				FromPosition: i + 1,
+				Question:     fq.Question,
+			})
+			continue
+		}
+
+		tq := to.Questions[j]
+		changes := diffQuestion(fq, tq)
+		status := "modified"
+		if len(changes) == 0 {
+			if fq.ID == tq.ID && i == j {
+				diff.Unchanged++
+				continue
+			}
+			status = "moved"
+		}
+		diff.Questions = append(diff.Questions, QuestionChange{
+			Status:       status,
+			FromID:       fq.ID,
+			ToID:         tq.ID,
+			FromPosition: i + 1,
+			ToPosition:   j + 1,
+			Question:     tq.Question,
+			Changes:      changes,
+		})
+	}
+
+	for j, tq := range to.Questions {
+		if used[j] {
+			continue
+		}
+		diff.Questions = append(diff.Questions, QuestionChange{
+			Status:     "added",
+			ToID:       tq.ID,
+			ToPosition: j + 1,
+			Question:   tq.Question,
+		})
+	}
+
+	return diff
+}
+
+// diffQuestion lists the content fields that differ between two versions of a question
+func diffQuestion(from, to Question) []FieldChange {
+	var changes []FieldChange
+	addChange(&changes, "type", from.Type, to.Type)
+	addChange(&changes, "question", from.Question, to.Question)
+	addChange(&changes, "options", from.Options, to.Options)
+	addChange(&changes, "correct_answer", from.AnswerText(), to.AnswerText())
+	addChange(&changes, "explanation", from.Explanation, to.Explanation)
+	addChange(&changes, "points", from.Points, to.Points)
+	addChange(&changes, "image", from.Image, to.Image)
+	addChange(&changes, "paragraph_refs", from.ParagraphRefs, to.ParagraphRefs)
+	addChange(&changes, "source_excerpt_id", from.SourceExcerpt, to.SourceExcerpt)
+	return changes
+}
+
+func addChange(changes *[]FieldChange, field string, from, to interface{}) {
+	if equalValues(from, to) {
+		return
+	}
+	*changes = append(*changes, FieldChange{Field: field, From: from, To: to})
+}
+
+// equalValues compares field values, treating nil and empty slices as equal
+func equalValues(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Slice && vb.Kind() == reflect.Slice && va.Len() == 0 && vb.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func passageText(p *Passage) string {
+	if p == nil {
+		return ""
+	}
+	if p.Title != "" {
+		return fmt.Sprintf("%s\n\n%s", p.Title, p.Text())
+	}
+	return p.Text()
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDiffWorksheets(t *testing.T) {
+	roots := Question{ID: "q_1", Type: "short_answer", Question: "What do roots do?", CorrectAnswer: "Absorb water", Points: 2}
+	leaves := Question{ID: "q_2", Type: "short_answer", Question: "What do leaves do?", CorrectAnswer: "Make food", Points: 2}
+	stems := Question{ID: "q_3", Type: "short_answer", Question: "What do stems do?", CorrectAnswer: "Carry water", Points: 2}
+
+	// renumbered returns qs with IDs q_1..q_n, as the editor stores them
+	renumbered := func(qs ...Question) []Question {
+		out := make([]Question, len(qs))
+		for i, q := range qs {
+			q.ID = fmt.Sprintf("q_%d", i+1)
+			out[i] = q
+		}
+		return out
+	}
+	edited := roots
+	edited.Points = 3
+	reworded := roots
+	reworded.Question = "What are roots for?"
+
+	tests := []struct {
+		name      string
+		from, to  []Question
+		want      []QuestionChange
+		unchanged int
+	}{
+		{
+			name:      "identical",
+			from:      []Question{roots, leaves},
+			to:        []Question{roots, leaves},
+			want:      []QuestionChange{},
+			unchanged: 2,
+		},
+		{
+			name: "reorder",
+			from: []Question{roots, leaves},
+			to:   renumbered(leaves, roots),
+			want: []QuestionChange{
+				{Status: "moved", FromID: "q_1", ToID: "q_2", FromPosition: 1, ToPosition: 2, Question: roots.Question},
+				{Status: "moved", FromID: "q_2", ToID: "q_1", FromPosition: 2, ToPosition: 1, Question: leaves.Question},
+			},
+		},
+		{
+			name: "edit answer fields",
+			from: []Question{roots, leaves},
+			to:   []Question{edited, leaves},
+			want: []QuestionChange{
+				{Status: "modified", FromID: "q_1", ToID: "q_1", FromPosition: 1, ToPosition: 1, Question: roots.Question,
+					Changes: []FieldChange{{Field: "points", From: 2, To: 3}}},
+			},
+			unchanged: 1,
+		},
+		{
+			name: "edit text pairs by id",
+			from: []Question{roots, leaves},
+			to:   []Question{reworded, leaves},
+			want: []QuestionChange{
+				{Status: "modified", FromID: "q_1", ToID: "q_1", FromPosition: 1, ToPosition: 1, Question: reworded.Question,
+					Changes: []FieldChange{{Field: "question", From: roots.Question, To: reworded.Question}}},
+			},
+			unchanged: 1,
+		},
+		{
+			name: "insert renumbers but pairs by text",
+			from: []Question{roots, leaves},
+			to:   renumbered(stems, roots, leaves),
+			want: []QuestionChange{
+				{Status: "moved", FromID: "q_1", ToID: "q_2", FromPosition: 1, ToPosition: 2, Question: roots.Question},
+				{Status: "moved", FromID: "q_2", ToID: "q_3", FromPosition: 2, ToPosition: 3, Question: leaves.Question},
+				{Status: "added", ToID: "q_1", ToPosition: 1, Question: stems.Question},
+			},
+		},
+		{
+			name: "remove and add",
+			from: []Question{roots, leaves},
+			to:   []Question{roots, {ID: "q_3", Type: "short_answer", Question: stems.Question, CorrectAnswer: "Carry water", Points: 2}},
+			want: []QuestionChange{
+				{Status: "removed", FromID: "q_2", FromPosition: 2, Question: leaves.Question},
+				{Status: "added", ToID: "q_3", ToPosition: 2, Question: stems.Question},
+			},
+			unchanged: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffWorksheets(&Worksheet{Revision: 1, Questions: tt.from}, &Worksheet{Revision: 2, Questions: tt.to})
+			if diff.From != 1 || diff.To != 2 || len(diff.Metadata) != 0 {
+				t.Errorf("diff header = %d..%d, metadata %+v", diff.From, diff.To, diff.Metadata)
+			}
+			if !reflect.DeepEqual(diff.Questions, tt.want) {
+				t.Errorf("questions =\n%+v\nwant\n%+v", diff.Questions, tt.want)
+			}
+			if diff.Unchanged != tt.unchanged {
+				t.Errorf("unchanged = %d, want %d", diff.Unchanged, tt.unchanged)
+			}
+		})
+	}
+}
+
+func TestDiffWorksheetsMetadata(t *testing.T) {
+	from := &Worksheet{Title: "Plants", Difficulty: "easy", Passage: &Passage{Paragraphs: []string{"Roots drink."}}}
+	to := &Worksheet{Title: "Plant parts", Difficulty: "easy", Passage: &Passage{Paragraphs: []string{"Roots drink water."}}}
+
+	want := []FieldChange{
+		{Field: "title", From: "Plants", To: "Plant parts"},
+		{Field: "passage", From: "Roots drink.", To: "Roots drink water."},
+	}
+	if got := DiffWorksheets(from, to).Metadata; !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata = %+v, want %+v", got, want)
+	}
+}
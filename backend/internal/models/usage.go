@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Usage is the model tokens spent on a request and what they cost
+type Usage struct {
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Add accumulates another request's usage
+func (u *Usage) Add(other Usage) {
+	u.Calls += other.Calls
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CostUSD += other.CostUSD
+}
+
+// Usage record kinds
+const (
+	UsageWorksheet = "worksheet" // a new worksheet, including answer verification
+	UsageQuestions = "questions" // questions added to or regenerated on a worksheet
+)
+
+// UsageRecord is the spend of one generation request, kept for cost reporting.
+// Failed generations are recorded too, without a worksheet ID.
+type UsageRecord struct {
+	UserID      string    `json:"user_id"`
+	WorksheetID string    `json:"worksheet_id,omitempty"`
+	Kind        string    `json:"kind"`
+	Model       string    `json:"model"`
+	CreatedAt   time.Time `json:"created_at"`
+	Usage
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidQuestionTypes lists every supported question type
+var ValidQuestionTypes = []QuestionType{MultipleChoice, FillBlank, TrueFalse, Matching, ShortAnswer, Essay}
+
+// IsValidQuestionType reports whether t is a supported question type
+func IsValidQuestionType(t string) bool {
+	for _, valid := range ValidQuestionTypes {
+		if string(valid) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultPoints returns the default point value for a question type
+func DefaultPoints(qType string) int {
+	switch QuestionType(qType) {
+	case Essay:
+		return 10
+	case ShortAnswer:
+		return 5
+	case Matching:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// AnswerText returns the correct answer as a single string, joining list answers with ", "
+func (q *Question) AnswerText() string {
+	switch v := q.CorrectAnswer.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, p := range v {
+			parts = append(parts, fmt.Sprint(p))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Validate checks that the question is complete and consistent with its type
+func (q *Question) Validate() error {
+	if !IsValidQuestionType(q.Type) {
+		return fmt.Errorf("unknown question type %q", q.Type)
+	}
+	if strings.TrimSpace(q.Question) == "" {
+		return fmt.Errorf("question text is required")
+	}
+	if q.Points < 0 {
+		return fmt.Errorf("points cannot be negative")
+	}
+
+	answer := strings.TrimSpace(q.AnswerText())
+
+	switch QuestionType(q.Type) {
+	case MultipleChoice:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("multiple_choice needs at least 2 options")
+		}
+		if answer == "" {
+			return fmt.Errorf("multiple_choice needs a correct_answer")
+		}
+		matches := 0
+		for _, opt := range q.Options {
+			if strings.EqualFold(strings.TrimSpace(opt), answer) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("correct_answer must match exactly one option")
+		}
+	case TrueFalse:
+		if !strings.EqualFold(answer, "true") && !strings.EqualFold(answer, "false") {
+			return fmt.Errorf("true_false correct_answer must be \"True\" or \"False\"")
+		}
+	case FillBlank:
+		if answer == "" {
+			return fmt.Errorf("fill_blank needs a correct_answer")
+		}
+	case Matching:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("matching needs at least 2 pairs in options")
+		}
+		if answer == "" {
+			return fmt.Errorf("matching needs a correct_answer")
+		}
+	case ShortAnswer, Essay:
+		if len(q.Options) > 0 {
+			return fmt.Errorf("%s questions do not take options", q.Type)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+// Package moderation screens worksheet text before it reaches children. Texts
+// are scored per category by pluggable classifiers and flagged when a score
+// reaches the threshold for the worksheet's grade.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/readability"
+)
+
+// Categories
+const (
+	Sexual       = "sexual"
+	SelfHarm     = "self_harm"
+	Hate         = "hate"
+	Violence     = "violence"
+	Weapons      = "weapons"
+	Drugs        = "drugs"
+	Profanity    = "profanity"
+	PersonalInfo = "personal_info"
+)
+
+// Text is one field of a worksheet to screen
+type Text struct {
+	Field   string
+	Content string
+}
+
+// Finding is a classifier's score for one category in one field, from 0 (harmless) to 1
+type Finding struct {
+	Field    string
+	Category string
+	Score    float64
+	Excerpt  string
+}
+
+// Classifier scores texts per category. It only needs to report categories it
+// found something for.
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, texts []Text) ([]Finding, error)
+}
+
+// Threshold returns the score at which content is flagged for a grade: the
+// younger the students, the lower the bar
+func Threshold(gradeLevel string) float64 {
+	grade, ok := readability.ParseGrade(gradeLevel)
+	switch {
+	case !ok:
+		return 0.4
+	case grade <= 2:
+		return 0.3
+	case grade <= 5:
+		return 0.4
+	case grade <= 8:
+		return 0.6
+	case grade <= 12:
+		return 0.75
+	default:
+		return 0.9
+	}
+}
+
+// Moderator runs every classifier over a worksheet and collects the flags
+type Moderator struct {
+	classifiers []Classifier
+}
+
+func New(classifiers ...Classifier) *Moderator {
+	return &Moderator{classifiers: classifiers}
+}
+
+// Screen scores free-standing texts, such as teacher instructions, and returns
+// the flags at or above the grade's threshold
+func (m *Moderator) Screen(ctx context.Context, gradeLevel string, texts []Text) []models.ModerationFlag {
+	threshold := Threshold(gradeLevel)
+	best := map[string]models.ModerationFlag{}
+
+	for _, c := range m.classifiers {
+		findings, err := c.Classify(ctx, texts)
+		if err != nil {
+			// A failing optional classifier must not block generation; the others still ran
+			log.Printf("⚠️ %s moderation failed: %v", c.Name(), err)
+			continue
+		}
+		for _, f := range findings {
+			if f.Score < threshold {
+				continue
+			}
+			key := f.Field + "\x00" + f.Category
+			if prev, ok := best[key]; ok && prev.Score >= f.Score {
+				continue
+			}
+			best[key] = models.ModerationFlag{
+				Field:      f.Field,
+				Category:   f.Category,
+				Score:      f.Score,
+				Excerpt:    f.Excerpt,
+				Classifier: c.Name(),
+			}
+		}
+	}
+
+	flags := make([]models.ModerationFlag, 0, len(best))
+	for _, f := range best {
+		flags = append(flags, f)
+	}
+	order := fieldOrder(texts)
+	sort.Slice(flags, func(i, j int) bool {
+		if order[flags[i].Field] != order[flags[j].Field] {
+			return order[flags[i].Field] < order[flags[j].Field]
+		}
+		return flags[i].Category < flags[j].Category
+	})
+	return flags
+}
+
+// Review screens every piece of text in a worksheet. A teacher's earlier
+// approval carries over as long as no new flags appear.
+func (m *Moderator) Review(ctx context.Context, ws *models.Worksheet) *models.Moderation {
+	result := &models.Moderation{
+		Status:    models.ModerationClear,
+		Threshold: Threshold(ws.GradeLevel),
+		Flags:     m.Screen(ctx, ws.GradeLevel, WorksheetTexts(ws)),
+		CheckedAt: time.Now(),
+	}
+	if len(result.Flags) == 0 {
+		return result
+	}
+
+	result.Status = models.ModerationFlagged
+	if prev := ws.Moderation; prev != nil && prev.Status == models.ModerationApproved && coveredBy(result.Flags, prev.Flags) {
+		result.Status = models.ModerationApproved
+		result.ReviewedBy, result.ReviewedAt, result.ReviewNote = prev.ReviewedBy, prev.ReviewedAt, prev.ReviewNote
+	}
+	return result
+}
+
+// coveredBy reports whether every flag was already present when the teacher approved
+func coveredBy(flags, approved []models.ModerationFlag) bool {
+	seen := map[string]bool{}
+	for _, f := range approved {
+		seen[f.Field+"\x00"+f.Category+"\x00"+f.Excerpt] = true
+	}
+	for _, f := range flags {
+		if !seen[f.Field+"\x00"+f.Category+"\x00"+f.Excerpt] {
+			return false
+		}
+	}
+	return true
+}
+
+// WorksheetTexts lists the text fields of a worksheet that students will see
+func WorksheetTexts(ws *models.Worksheet) []Text {
+	texts := []Text{{Field: "title", Content: ws.Title}}
+	if ws.Passage != nil {
+		texts = append(texts, Text{Field: "passage.title", Content: ws.Passage.Title})
+		for i, p := range ws.Passage.Paragraphs {
+			texts = append(texts, Text{Field: fmt.Sprintf("passage.paragraphs[%d]", i), Content: p})
+		}
+	}
+	for _, q := range ws.Questions {
+		prefix := fmt.Sprintf("questions[%s].", q.ID)
+		texts = append(texts,
+			Text{Field: prefix + "question", Content: q.Question},
+			Text{Field: prefix + "correct_answer", Content: q.AnswerText()},
+			Text{Field: prefix + "explanation", Content: q.Explanation},
+		)
+		for i, o := range q.Options {
+			texts = append(texts, Text{Field: fmt.Sprintf("%soptions[%d]", prefix, i), Content: o})
+		}
+	}
+
+	nonEmpty := texts[:0]
+	for _, t := range texts {
+		if strings.TrimSpace(t.Content) != "" {
+			nonEmpty = append(nonEmpty, t)
+		}
+	}
+	return nonEmpty
+}
+
+func fieldOrder(texts []Text) map[string]int {
+	order := make(map[string]int, len(texts))
+	for i, t := range texts {
+		order[t.Field] = i
+	}
+	return order
+}
@@ -0,0 +1,110 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rule scores text matching a case-insensitive regular expression
+type Rule struct {
+	Category string  `json:"category"`
+	Pattern  string  `json:"pattern"`
+	Score    float64 `json:"score"`
+}
+
+// defaultRules err towards catching too much for young grades; ambiguous words
+// score low so they only flag at the strict early-grade thresholds
+var defaultRules = []Rule{
+	{Sexual, `porn\w*|erotic\w*|orgasm\w*|masturbat\w*|have sex|having sex|sex(y|ual intercourse)`, 1},
+	{Sexual, `sex(ual)?|nude|naked|strip(per|tease)`, 0.7},
+	{SelfHarm, `kill(ing)? (yourself|myself|himself|herself|themselves)|self[- ]harm\w*|cut(ting)? (yourself|myself)`, 1},
+	{SelfHarm, `suicid\w*`, 0.7},
+	{Hate, `white power|heil hitler|master race|inferior (race|people)|ethnic cleansing`, 0.95},
+	{Violence, `gore|gory|dismember\w*|behead\w*|tortur\w*|mutilat\w*`, 0.8},
+	{Violence, `murder\w*|stab(bed|bing|s)?|massacre\w*|slaughter\w*`, 0.55},
+	{Violence, `kill(s|ed|ing)?|blood(y)?|corpse\w*|dead bod(y|ies)`, 0.35},
+	{Weapons, `(make|build|making|building) a bomb|pipe bomb|explosive device`, 1},
+	{Weapons, `guns?|rifles?|pistols?|shotguns?|bombs?|grenades?`, 0.45},
+	{Drugs, `cocaine|heroin|meth(amphetamine)?|crack pipe|fentanyl|overdos\w*`, 0.7},
+	{Drugs, `beer|wine|vodka|whiskey|alcohol\w*|drunk|cigarettes?|vap(e|ing)|marijuana|cannabis`, 0.45},
+	{Profanity, `fuck\w*|shit\w*|bitch\w*|asshole\w*|bastard\w*|cunt\w*`, 0.95},
+	{Profanity, `damn\w*|crap\w*|piss\w*|sucks`, 0.4},
+	{PersonalInfo, `[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`, 0.5},
+	{PersonalInfo, `\(?\d{3}\)?[ .-]\d{3}[ .-]\d{4}`, 0.5},
+}
+
+// allowedPhrases are removed before matching so curriculum vocabulary such as
+// "sexual reproduction" or "blood cells" doesn't trip the word list
+var allowedPhrases = regexp.MustCompile(`(?i)\b(a?sexual reproduction|sex cells?|sex chromosomes?|` +
+	`blood (cells?|vessels?|types?|pressure|sugar|circulation)|red blood|white blood|` +
+	`kill(s|ed)? (germs|bacteria|weeds|time)|wine ?glass|root beer|crack(ed)? (the|an?) (egg|code)|` +
+	`explosive growth|bath bomb)\b`)
+
+type compiledRule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+// WordListClassifier scores text with regular-expression rules
+type WordListClassifier struct {
+	rules []compiledRule
+}
+
+// NewWordListClassifier uses the built-in rules plus any extra rules given
+func NewWordListClassifier(extra ...Rule) (*WordListClassifier, error) {
+	c := &WordListClassifier{}
+	for _, r := range append(append([]Rule(nil), defaultRules...), extra...) {
+		re, err := regexp.Compile(`(?i)\b(` + r.Pattern + `)\b`)
+		if err != nil {
+			return nil, fmt.Errorf("moderation rule %q: %w", r.Pattern, err)
+		}
+		c.rules = append(c.rules, compiledRule{r, re})
+	}
+	return c, nil
+}
+
+// LoadRules reads extra rules from a JSON file: [{"category", "pattern", "score"}]
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func (c *WordListClassifier) Name() string { return "wordlist" }
+
+func (c *WordListClassifier) Classify(ctx context.Context, texts []Text) ([]Finding, error) {
+	var findings []Finding
+	for _, t := range texts {
+		// Blank out allowed phrases, keeping offsets so excerpts come from the original text
+		content := allowedPhrases.ReplaceAllStringFunc(t.Content, func(m string) string {
+			return strings.Repeat(" ", len(m))
+		})
+		best := map[string]Finding{}
+		for _, r := range c.rules {
+			loc := r.re.FindStringIndex(content)
+			if loc == nil {
+				continue
+			}
+			if prev, ok := best[r.Category]; ok && prev.Score >= r.Score {
+				continue
+			}
+			// Only the matched words, so an approval survives unrelated edits to the same field
+			excerpt := strings.ToLower(t.Content[loc[0]:loc[1]])
+			best[r.Category] = Finding{Field: t.Field, Category: r.Category, Score: r.Score, Excerpt: excerpt}
+		}
+		for _, f := range best {
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
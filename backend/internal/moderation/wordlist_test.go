@@ -0,0 +1,64 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWordListClassifier(t *testing.T) {
+	c, err := NewWordListClassifier(Rule{Category: Drugs, Pattern: `lean`, Score: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text     string
+		category string // empty when nothing should be found
+		score    float64
+		excerpt  string
+	}{
+		{text: "Red blood cells carry oxygen.", category: ""},
+		{text: "Meiosis makes sex cells for sexual reproduction.", category: ""},
+		{text: "Soap kills germs.", category: ""},
+		{text: "The knight KILLED the dragon.", category: Violence, score: 0.35, excerpt: "killed"},
+		{text: "He was stabbed and killed.", category: Violence, score: 0.55, excerpt: "stabbed"},
+		{text: "How to build a bomb", category: Weapons, score: 1, excerpt: "build a bomb"},
+		{text: "Email me at ann@example.com", category: PersonalInfo, score: 0.5, excerpt: "ann@example.com"},
+		{text: "Skill and cleaning", category: ""}, // no partial-word matches
+		{text: "Sipping lean", category: Drugs, score: 0.9, excerpt: "lean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			findings, err := c.Classify(context.Background(), []Text{{Field: "title", Content: tt.text}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.category == "" {
+				if len(findings) != 0 {
+					t.Fatalf("findings = %+v, want none", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings = %+v, want one", findings)
+			}
+			f := findings[0]
+			if f.Category != tt.category || f.Score != tt.score || f.Excerpt != tt.excerpt || f.Field != "title" {
+				t.Errorf("finding = %+v, want %s %.2f %q", f, tt.category, tt.score, tt.excerpt)
+			}
+		})
+	}
+
+	if _, err := NewWordListClassifier(Rule{Pattern: `(`}); err == nil {
+		t.Error("accepted an invalid pattern")
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	for grade, want := range map[string]float64{"K": 0.3, "2": 0.3, "4": 0.4, "7": 0.6, "11": 0.75, "college": 0.9, "": 0.4} {
+		if got := Threshold(grade); got != want {
+			t.Errorf("Threshold(%q) = %v, want %v", grade, got, want)
+		}
+	}
+}
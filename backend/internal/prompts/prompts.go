@@ -0,0 +1,258 @@
+// Package prompts holds the versioned text/template prompts sent to the model.
+// Templates are listed in templates/index.json and chosen per worksheet by
+// subject, grade and question types, so tuning one subject's prompt can't
+// change another's. Every generated worksheet records the template ID and
+// version it came from.
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/makosai/backend/internal/readability"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// commonFile defines the blocks every template starts from
+const commonFile = "common.tmpl"
+
+// Template is one versioned set of prompts. It applies to a worksheet when
+// every criterion it sets matches; the most specific match wins.
+type Template struct {
+	ID            string   `json:"id"`
+	Version       int      `json:"version"` // bump on every change to the file
+	File          string   `json:"file"`
+	Subjects      []string `json:"subjects,omitempty"`
+	MinGrade      string   `json:"min_grade,omitempty"`
+	MaxGrade      string   `json:"max_grade,omitempty"`
+	QuestionTypes []string `json:"question_types,omitempty"` // every requested type must be listed
+
+	set      *template.Template
+	min, max float64
+}
+
+// Registry is the set of templates to choose from
+type Registry struct {
+	templates []*Template
+}
+
+// Embedded returns the templates compiled into the binary
+func Embedded() *Registry {
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		panic(err)
+	}
+	r, err := Load(sub)
+	if err != nil {
+		panic(fmt.Sprintf("prompts: %v", err))
+	}
+	return r
+}
+
+// FromEnv loads templates from PROMPT_TEMPLATE_DIR, or the embedded ones when it is unset
+func FromEnv() (*Registry, error) {
+	dir := os.Getenv("PROMPT_TEMPLATE_DIR")
+	if dir == "" {
+		return Embedded(), nil
+	}
+	return Load(os.DirFS(dir))
+}
+
+// Load parses index.json, common.tmpl and every template file listed in the index
+func Load(fsys fs.FS) (*Registry, error) {
+	data, err := fs.ReadFile(fsys, "index.json")
+	if err != nil {
+		return nil, err
+	}
+	var templates []*Template
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("index.json: %w", err)
+	}
+	common, err := fs.ReadFile(fsys, commonFile)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	hasDefault := false
+	for _, t := range templates {
+		if t.ID == "" || t.File == "" || t.Version < 1 {
+			return nil, fmt.Errorf("index.json: template %q needs an id, a file and a version of at least 1", t.ID)
+		}
+		if seen[t.ID] {
+			return nil, fmt.Errorf("index.json: duplicate template %q", t.ID)
+		}
+		seen[t.ID] = true
+		if t.criteria() == 0 {
+			hasDefault = true
+		}
+
+		if t.min, t.max, err = gradeRange(t.MinGrade, t.MaxGrade); err != nil {
+			return nil, fmt.Errorf("template %s: %w", t.ID, err)
+		}
+		body, err := fs.ReadFile(fsys, t.File)
+		if err != nil {
+			return nil, err
+		}
+		// Parse the template's file after common.tmpl so its blocks replace the shared ones
+		set, err := template.New(t.ID).Option("missingkey=error").Parse(string(common))
+		if err == nil {
+			set, err = set.Parse(string(body))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", t.ID, err)
+		}
+		t.set = set
+	}
+	if !hasDefault {
+		return nil, fmt.Errorf("index.json: no default template (one without subjects, grades or question types)")
+	}
+	return &Registry{templates: templates}, nil
+}
+
+func gradeRange(minGrade, maxGrade string) (float64, float64, error) {
+	lo, hi := 0.0, 99.0
+	if minGrade != "" {
+		g, ok := readability.ParseGrade(minGrade)
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown min_grade %q", minGrade)
+		}
+		lo = g
+	}
+	if maxGrade != "" {
+		g, ok := readability.ParseGrade(maxGrade)
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown max_grade %q", maxGrade)
+		}
+		hi = g
+	}
+	return lo, hi, nil
+}
+
+// criteria counts how many kinds of criteria the template sets
+func (t *Template) criteria() int {
+	n := 0
+	if len(t.Subjects) > 0 {
+		n++
+	}
+	if t.MinGrade != "" || t.MaxGrade != "" {
+		n++
+	}
+	if len(t.QuestionTypes) > 0 {
+		n++
+	}
+	return n
+}
+
+func (t *Template) matches(subject, gradeLevel string, questionTypes []string) bool {
+	if len(t.Subjects) > 0 && !containsFold(t.Subjects, subject) {
+		return false
+	}
+	if t.MinGrade != "" || t.MaxGrade != "" {
+		grade, ok := readability.ParseGrade(gradeLevel)
+		if !ok || grade < t.min || grade > t.max {
+			return false
+		}
+	}
+	if len(t.QuestionTypes) > 0 {
+		if len(questionTypes) == 0 {
+			return false
+		}
+		for _, qt := range questionTypes {
+			if !containsFold(t.QuestionTypes, qt) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Select picks the template that sets the most criteria matching the worksheet,
+// preferring the one listed first on a tie
+func (r *Registry) Select(subject, gradeLevel string, questionTypes []string) *Template {
+	var best *Template
+	for _, t := range r.templates {
+		if t.matches(subject, gradeLevel, questionTypes) && (best == nil || t.criteria() > best.criteria()) {
+			best = t
+		}
+	}
+	return best
+}
+
+// System renders the system prompt with the template's guidance
+func (t *Template) System() (string, error) {
+	guidance, err := t.render("guidance", nil)
+	if err != nil {
+		return "", err
+	}
+	return t.render("system", struct{ Guidance string }{strings.Trim(guidance, "\n")})
+}
+
+// Worksheet renders the prompt for a new worksheet
+func (t *Template) Worksheet(data WorksheetData) (string, error) {
+	return t.render("worksheet", data)
+}
+
+// Questions renders the prompt for adding or replacing questions on a worksheet
+func (t *Template) Questions(data QuestionData) (string, error) {
+	return t.render("questions", data)
+}
+
+func (t *Template) render(name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.set.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("prompt template %s v%d: %w", t.ID, t.Version, err)
+	}
+	return buf.String(), nil
+}
+
+// WorksheetData fills the "worksheet" block. Teacher text arrives already
+// delimited and the optional sections arrive formatted, or empty when unused.
+type WorksheetData struct {
+	Topic         string
+	Subject       string
+	GradeLevel    string
+	Difficulty    string
+	QuestionCount int
+	QuestionTypes string
+	Language      string
+	Instructions  string
+	Passage       string
+	Source        string
+	Examples      string
+	Diagrams      string
+}
+
+// QuestionData fills the "questions" block
+type QuestionData struct {
+	Title         string
+	Topic         string
+	Subject       string
+	GradeLevel    string
+	Difficulty    string
+	Language      string
+	Existing      string
+	Reference     string
+	Task          string
+	QuestionTypes string
+	Instructions  string
+	Diagrams      string
+}
+
+func containsFold(list []string, s string) bool {
+	s = strings.TrimSpace(s)
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
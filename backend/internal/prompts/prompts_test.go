@@ -0,0 +1,91 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSelect(t *testing.T) {
+	r := Embedded()
+	tests := []struct {
+		subject, grade string
+		types          []string
+		want           string
+	}{
+		{"history", "5", []string{"multiple_choice"}, "default"},
+		{"history", "5", nil, "default"},
+		{"math", "5", []string{"multiple_choice"}, "math"},
+		{"Mathematics", "8", nil, "math"},
+		{"math", "1", []string{"multiple_choice"}, "early-grades"},
+		{"math", "K", nil, "early-grades"},
+		{"biology", "10", []string{"true_false"}, "science"},
+		{"science", "2", nil, "early-grades"},
+		{"history", "7", []string{"short_answer", "essay"}, "written-response"},
+		{"history", "7", []string{"short_answer", "multiple_choice"}, "default"},
+		{"science", "7", []string{"essay"}, "science"},
+		{"math", "not a grade", nil, "default"},
+	}
+	for _, tt := range tests {
+		got := r.Select(tt.subject, tt.grade, tt.types)
+		if got == nil || got.ID != tt.want {
+			t.Errorf("Select(%q, %q, %v) = %v, want %s", tt.subject, tt.grade, tt.types, got, tt.want)
+		}
+	}
+}
+
+func TestEmbeddedTemplatesRender(t *testing.T) {
+	for _, tmpl := range Embedded().templates {
+		t.Run(tmpl.ID, func(t *testing.T) {
+			system, err := tmpl.System()
+			if err != nil || strings.TrimSpace(system) == "" {
+				t.Errorf("system prompt: %q, %v", system, err)
+			}
+			worksheet, err := tmpl.Worksheet(WorksheetData{Topic: "<topic>Plants</topic>", Subject: "science", GradeLevel: "4", QuestionCount: 5, QuestionTypes: "multiple_choice", Language: "English"})
+			if err != nil || !strings.Contains(worksheet, "<topic>Plants</topic>") {
+				t.Errorf("worksheet prompt: %v\n%s", err, worksheet)
+			}
+			questions, err := tmpl.Questions(QuestionData{Title: "Plants", Topic: "Plants", Subject: "science", GradeLevel: "4", Task: "Write 2 new questions"})
+			if err != nil || !strings.Contains(questions, "Write 2 new questions") {
+				t.Errorf("questions prompt: %v\n%s", err, questions)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	files := func(index string) fstest.MapFS {
+		return fstest.MapFS{
+			"index.json":  {Data: []byte(index)},
+			"common.tmpl": {Data: []byte(`{{define "system"}}Be helpful. {{.Guidance}}{{end}}{{define "guidance"}}{{end}}`)},
+			"a.tmpl":      {Data: []byte(`{{define "guidance"}}Use simple words.{{end}}`)},
+		}
+	}
+
+	r, err := Load(files(`[{"id": "default", "version": 3, "file": "a.tmpl"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := r.Select("math", "4", nil)
+	if tmpl.ID != "default" || tmpl.Version != 3 {
+		t.Fatalf("selected %s v%d", tmpl.ID, tmpl.Version)
+	}
+	if system, err := tmpl.System(); err != nil || system != "Be helpful. Use simple words." {
+		t.Errorf("system prompt = %q, %v", system, err)
+	}
+
+	tests := []struct {
+		name, index, want string
+	}{
+		{"no default", `[{"id": "math", "version": 1, "file": "a.tmpl", "subjects": ["math"]}]`, "no default template"},
+		{"duplicate", `[{"id": "a", "version": 1, "file": "a.tmpl"}, {"id": "a", "version": 1, "file": "a.tmpl"}]`, "duplicate"},
+		{"no version", `[{"id": "a", "file": "a.tmpl"}]`, "version"},
+		{"bad grade", `[{"id": "a", "version": 1, "file": "a.tmpl", "max_grade": "sixth"}]`, "max_grade"},
+		{"missing file", `[{"id": "a", "version": 1, "file": "missing.tmpl"}]`, "missing.tmpl"},
+	}
+	for _, tt := range tests {
+		if _, err := Load(files(tt.index)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+}
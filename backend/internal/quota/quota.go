@@ -0,0 +1,248 @@
+// Package quota enforces what each subscription plan allows: monthly
+// worksheet and export allowances, the largest worksheet, the question types
+// and the optional image and diagram features. Signed-in users are held to
+// their plan; anonymous requests get the free plan per client IP.
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/store"
+)
+
+// FreePlan is the plan of new users and anonymous requests
+const FreePlan = "free"
+
+// Monthly allowances
+const (
+	Worksheets = "worksheets"
+	Exports    = "exports"
+)
+
+// Plan is what a subscription allows. Zero monthly allowances or
+// MaxQuestions mean unlimited; no QuestionTypes allows every type.
+type Plan struct {
+	Name               string   `json:"name"`
+	WorksheetsPerMonth int      `json:"worksheets_per_month"`
+	ExportsPerMonth    int      `json:"exports_per_month"`
+	MaxQuestions       int      `json:"max_questions"`
+	QuestionTypes      []string `json:"question_types,omitempty"`
+	Images             bool     `json:"images"`
+	Diagrams           bool     `json:"diagrams"`
+}
+
+// DefaultPlans mirrors the plans sold on the pricing page
+var DefaultPlans = map[string]Plan{
+	FreePlan:  {Name: "Free", WorksheetsPerMonth: 5, ExportsPerMonth: 20, MaxQuestions: 10, Diagrams: true},
+	"starter": {Name: "Starter", WorksheetsPerMonth: 100, MaxQuestions: 25, Images: true, Diagrams: true},
+	"pro":     {Name: "Pro", WorksheetsPerMonth: 200, MaxQuestions: 50, Images: true, Diagrams: true},
+	"ultra":   {Name: "Ultra", WorksheetsPerMonth: 400, MaxQuestions: 50, Images: true, Diagrams: true},
+}
+
+// LoadPlans reads a JSON object of plans keyed by plan ID, e.g. {"free": {...}}
+func LoadPlans(path string) (map[string]Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plans map[string]Plan
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for id, p := range plans {
+		for _, t := range p.QuestionTypes {
+			if !models.IsValidQuestionType(t) {
+				return nil, fmt.Errorf("%s: plan %s: unknown question type %q", path, id, t)
+			}
+		}
+	}
+	return plans, nil
+}
+
+// Error is a request the account's plan doesn't allow
+type Error struct {
+	Status  int    // 402 when the plan doesn't include it, 429 when the month's allowance is used up
+	Code    string // e.g. "worksheet_limit", "question_count", "question_type"
+	Message string
+	Limit   int
+	ResetAt time.Time // when a monthly allowance starts over, zero for plan limits
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Quota checks requests against plans and counts monthly usage
+type Quota struct {
+	plans    map[string]Plan
+	users    store.UserStore
+	counters store.CounterStore
+}
+
+// New creates a quota checker; plans must include the free plan
+func New(plans map[string]Plan, users store.UserStore, counters store.CounterStore) (*Quota, error) {
+	if _, ok := plans[FreePlan]; !ok {
+		return nil, fmt.Errorf("quota: no %q plan", FreePlan)
+	}
+	return &Quota{plans: plans, users: users, counters: counters}, nil
+}
+
+// Account is whose allowance a request uses: a registered user's ID, or
+// "ip:<address>" for anonymous requests and tokens naming no known user
+func (q *Quota) Account(userID, ip string) string {
+	if _, ok := q.users.Get(userID); ok && userID != "" {
+		return userID
+	}
+	return "ip:" + ip
+}
+
+// PlanFor returns the plan of a user ID, or the free plan for anonymous accounts
+func (q *Quota) PlanFor(account string) (string, Plan) {
+	id := FreePlan
+	if user, ok := q.users.Get(account); ok && user.Plan != "" {
+		id = user.Plan
+	}
+	plan, ok := q.plans[id]
+	if !ok {
+		log.Printf("⚠️ Unknown plan %q for %s, using the free plan", id, account)
+		return FreePlan, q.plans[FreePlan]
+	}
+	return id, plan
+}
+
+// Limits returns the generation features the account's plan leaves out
+func (q *Quota) Limits(account string) models.GenerationLimits {
+	_, plan := q.PlanFor(account)
+	return models.GenerationLimits{NoImages: !plan.Images, NoDiagrams: !plan.Diagrams}
+}
+
+// CheckQuestions rejects a worksheet that would have more questions than the
+// plan allows, or question types it doesn't include
+func (q *Quota) CheckQuestions(account string, total int, questionTypes []string) error {
+	_, plan := q.PlanFor(account)
+	if plan.MaxQuestions > 0 && total > plan.MaxQuestions {
+		return &Error{
+			Status:  http.StatusPaymentRequired,
+			Code:    "question_count",
+			Message: fmt.Sprintf("The %s plan allows up to %d questions per worksheet", plan.Name, plan.MaxQuestions),
+			Limit:   plan.MaxQuestions,
+		}
+	}
+	if len(plan.QuestionTypes) > 0 {
+		var missing []string
+		for _, t := range questionTypes {
+			if !contains(plan.QuestionTypes, t) {
+				missing = append(missing, t)
+			}
+		}
+		if len(missing) > 0 {
+			return &Error{
+				Status:  http.StatusPaymentRequired,
+				Code:    "question_type",
+				Message: fmt.Sprintf("The %s plan doesn't include %s questions", plan.Name, strings.Join(missing, ", ")),
+			}
+		}
+	}
+	return nil
+}
+
+// Use takes one from the account's monthly allowance of kind (Worksheets or
+// Exports). Call release if the request fails so it isn't charged.
+func (q *Quota) Use(account, kind string) (release func(), err error) {
+	_, plan := q.PlanFor(account)
+	limit := plan.allowance(kind)
+	now := time.Now().UTC()
+	key := counterKey(account, kind, now)
+	if _, ok := q.counters.Increment(key, limit); !ok {
+		return func() {}, &Error{
+			Status:  http.StatusTooManyRequests,
+			Code:    strings.TrimSuffix(kind, "s") + "_limit",
+			Message: fmt.Sprintf("You've used all %d %s included in the %s plan this month", limit, kind, plan.Name),
+			Limit:   limit,
+			ResetAt: nextMonth(now),
+		}
+	}
+	return func() { q.counters.Decrement(key) }, nil
+}
+
+// Allowance is one monthly allowance on the profile
+type Allowance struct {
+	Used      int  `json:"used"`
+	Limit     int  `json:"limit"` // 0 when unlimited
+	Remaining int  `json:"remaining"`
+	Unlimited bool `json:"unlimited,omitempty"`
+}
+
+// Summary is the account's plan and what is left of it this month
+type Summary struct {
+	Plan          string    `json:"plan"`
+	PlanName      string    `json:"plan_name"`
+	Period        string    `json:"period"` // e.g. "2024-09"
+	ResetsAt      time.Time `json:"resets_at"`
+	Worksheets    Allowance `json:"worksheets"`
+	Exports       Allowance `json:"exports"`
+	MaxQuestions  int       `json:"max_questions"`
+	QuestionTypes []string  `json:"question_types,omitempty"`
+	Images        bool      `json:"images"`
+	Diagrams      bool      `json:"diagrams"`
+}
+
+// Summary reports the account's remaining allowances
+func (q *Quota) Summary(account string) Summary {
+	id, plan := q.PlanFor(account)
+	now := time.Now().UTC()
+	allowance := func(kind string) Allowance {
+		a := Allowance{Used: q.counters.Get(counterKey(account, kind, now)), Limit: plan.allowance(kind)}
+		if a.Limit == 0 {
+			a.Unlimited = true
+		} else {
+			a.Remaining = max(0, a.Limit-a.Used)
+		}
+		return a
+	}
+	return Summary{
+		Plan:          id,
+		PlanName:      plan.Name,
+		Period:        now.Format("2006-01"),
+		ResetsAt:      nextMonth(now),
+		Worksheets:    allowance(Worksheets),
+		Exports:       allowance(Exports),
+		MaxQuestions:  plan.MaxQuestions,
+		QuestionTypes: plan.QuestionTypes,
+		Images:        plan.Images,
+		Diagrams:      plan.Diagrams,
+	}
+}
+
+func (p Plan) allowance(kind string) int {
+	switch kind {
+	case Worksheets:
+		return p.WorksheetsPerMonth
+	case Exports:
+		return p.ExportsPerMonth
+	}
+	return 0
+}
+
+// counterKey names an account's counter for the calendar month (UTC) of t
+func counterKey(account, kind string, t time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:%s", account, kind, t.Format("2006-01"))
+}
+
+func nextMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
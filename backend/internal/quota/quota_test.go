@@ -0,0 +1,151 @@
+package quota
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+	"github.com/makosai/backend/internal/store"
+)
+
+func newQuota(t *testing.T) *Quota {
+	t.Helper()
+	plans := map[string]Plan{
+		FreePlan: {Name: "Free", WorksheetsPerMonth: 2, ExportsPerMonth: 1, MaxQuestions: 10, QuestionTypes: []string{"multiple_choice", "true_false"}},
+		"pro":    {Name: "Pro", MaxQuestions: 50, Images: true, Diagrams: true},
+	}
+	users := store.NewMemoryUserStore()
+	if err := users.Create(&models.User{ID: "user_pro", Email: "pro@example.com", Plan: "pro"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Create(&models.User{ID: "user_old", Email: "old@example.com", Plan: "retired"}); err != nil {
+		t.Fatal(err)
+	}
+	q, err := New(plans, users, store.NewMemoryCounterStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestNewRequiresFreePlan(t *testing.T) {
+	if _, err := New(map[string]Plan{"pro": {}}, store.NewMemoryUserStore(), store.NewMemoryCounterStore()); err == nil {
+		t.Error("accepted plans without a free plan")
+	}
+}
+
+func TestAccountAndPlan(t *testing.T) {
+	q := newQuota(t)
+	if got := q.Account("user_pro", "1.2.3.4"); got != "user_pro" {
+		t.Errorf("Account(known user) = %q", got)
+	}
+	if got := q.Account("user_gone", "1.2.3.4"); got != "ip:1.2.3.4" {
+		t.Errorf("Account(unknown user) = %q", got)
+	}
+	if got := q.Account("", "1.2.3.4"); got != "ip:1.2.3.4" {
+		t.Errorf("Account(anonymous) = %q", got)
+	}
+
+	tests := map[string]string{"user_pro": "pro", "user_old": FreePlan, "ip:1.2.3.4": FreePlan}
+	for account, want := range tests {
+		if id, _ := q.PlanFor(account); id != want {
+			t.Errorf("PlanFor(%s) = %s, want %s", account, id, want)
+		}
+	}
+	if limits := q.Limits("ip:1.2.3.4"); !limits.NoImages || !limits.NoDiagrams {
+		t.Errorf("free limits = %+v", limits)
+	}
+	if limits := q.Limits("user_pro"); limits.NoImages || limits.NoDiagrams {
+		t.Errorf("pro limits = %+v", limits)
+	}
+}
+
+func TestCheckQuestions(t *testing.T) {
+	q := newQuota(t)
+	tests := []struct {
+		account string
+		total   int
+		types   []string
+		code    string
+	}{
+		{"ip:1", 10, []string{"multiple_choice"}, ""},
+		{"ip:1", 11, []string{"multiple_choice"}, "question_count"},
+		{"ip:1", 5, []string{"multiple_choice", "essay"}, "question_type"},
+		{"user_pro", 50, []string{"essay"}, ""},
+		{"user_pro", 51, nil, "question_count"},
+	}
+	for _, tt := range tests {
+		err := q.CheckQuestions(tt.account, tt.total, tt.types)
+		if tt.code == "" {
+			if err != nil {
+				t.Errorf("%s %d %v: %v", tt.account, tt.total, tt.types, err)
+			}
+			continue
+		}
+		qe, ok := err.(*Error)
+		if !ok || qe.Code != tt.code || qe.Status != http.StatusPaymentRequired {
+			t.Errorf("%s %d %v: error = %v, want %s", tt.account, tt.total, tt.types, err, tt.code)
+		}
+	}
+}
+
+func TestUse(t *testing.T) {
+	q := newQuota(t)
+	for i := 0; i < 2; i++ {
+		if _, err := q.Use("ip:1", Worksheets); err != nil {
+			t.Fatalf("worksheet %d: %v", i+1, err)
+		}
+	}
+	_, err := q.Use("ip:1", Worksheets)
+	qe, ok := err.(*Error)
+	if !ok || qe.Code != "worksheet_limit" || qe.Status != http.StatusTooManyRequests || qe.ResetAt.IsZero() {
+		t.Fatalf("third worksheet: %v, want worksheet_limit", err)
+	}
+
+	// Released uses are not charged
+	release, err := q.Use("ip:1", Exports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if s := q.Summary("ip:1"); s.Exports.Used != 0 || s.Exports.Remaining != 1 || s.Worksheets.Remaining != 0 {
+		t.Errorf("summary = %+v", s)
+	}
+
+	// Other accounts and unlimited plans are unaffected
+	if _, err := q.Use("ip:2", Worksheets); err != nil {
+		t.Errorf("another account: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := q.Use("user_pro", Worksheets); err != nil {
+			t.Fatalf("unlimited plan: %v", err)
+		}
+	}
+	if s := q.Summary("user_pro"); !s.Worksheets.Unlimited || s.Worksheets.Used != 5 {
+		t.Errorf("pro summary = %+v", s.Worksheets)
+	}
+}
+
+func TestLoadPlans(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "plans.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	plans, err := LoadPlans(write(`{"free": {"name": "Free", "worksheets_per_month": 3, "question_types": ["essay"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := plans[FreePlan]; p.WorksheetsPerMonth != 3 || p.QuestionTypes[0] != "essay" {
+		t.Errorf("plan = %+v", p)
+	}
+	if _, err := LoadPlans(write(`{"free": {"question_types": ["crossword"]}}`)); err == nil {
+		t.Error("accepted an unknown question type")
+	}
+}
@@ -0,0 +1,128 @@
+// Package ratelimit is a token-bucket rate limiter with in-memory and Redis
+// backends. Each key (a user or an IP) gets a bucket of Burst tokens that
+// refills at Limit tokens per Period; a request spends one token.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy is the rate for one group of routes
+type Policy struct {
+	Name   string // prefixes bucket keys, so groups don't share buckets
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// ParsePolicy reads "LIMIT/PERIOD[,BURST]", e.g. "6/1m,3" for six requests a
+// minute with at most three at once. The burst defaults to the limit.
+func ParsePolicy(name, s string) (Policy, error) {
+	p := Policy{Name: name}
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(s), ",")
+	limit, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return p, fmt.Errorf("rate limit %q: want LIMIT/PERIOD[,BURST], e.g. 6/1m,3", s)
+	}
+	var err error
+	if p.Limit, err = strconv.Atoi(strings.TrimSpace(limit)); err != nil || p.Limit < 1 {
+		return p, fmt.Errorf("rate limit %q: limit must be a positive number", s)
+	}
+	if p.Period, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || p.Period <= 0 {
+		return p, fmt.Errorf("rate limit %q: period must be a duration like 1m", s)
+	}
+	p.Burst = p.Limit
+	if hasBurst {
+		if p.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || p.Burst < 1 {
+			return p, fmt.Errorf("rate limit %q: burst must be a positive number", s)
+		}
+	}
+	return p, nil
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%d/%s,%d", p.Limit, p.Period, p.Burst)
+}
+
+// refillPerSecond is how many tokens the bucket gains each second
+func (p Policy) refillPerSecond() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Result is the outcome of spending a token
+type Result struct {
+	Allowed    bool
+	Remaining  int           // whole tokens left after this request
+	RetryAfter time.Duration // until a token is available, when not allowed
+}
+
+// Limiter spends tokens from per-key buckets
+type Limiter interface {
+	Allow(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// take applies one request to a bucket holding tokens last updated at updated,
+// returning the new token count and the result
+func take(p Policy, tokens float64, updated, now time.Time) (float64, Result) {
+	rate := p.refillPerSecond()
+	elapsed := now.Sub(updated).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(p.Burst), tokens+elapsed*rate)
+	}
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+	return tokens, Result{RetryAfter: wait}
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryLimiter keeps buckets in process memory, so limits are per instance
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	lastScan time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), lastScan: time.Now()}
+}
+
+// idleAfter drops buckets untouched this long; a full refill takes at most a period
+const idleAfter = time.Hour
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastScan) > idleAfter {
+		for k, b := range l.buckets {
+			if now.Sub(b.updated) > idleAfter {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastScan = now
+	}
+
+	key = p.Name + ":" + key
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(p.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	tokens, result := take(p, b.tokens, b.updated, now)
+	b.tokens, b.updated = tokens, now
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Policy
+		invalid bool
+	}{
+		{in: "6/1m,3", want: Policy{Name: "generate", Limit: 6, Period: time.Minute, Burst: 3}},
+		{in: " 100/1h ", want: Policy{Name: "generate", Limit: 100, Period: time.Hour, Burst: 100}},
+		{in: "6", invalid: true},
+		{in: "0/1m", invalid: true},
+		{in: "6/soon", invalid: true},
+		{in: "6/-1m", invalid: true},
+		{in: "6/1m,0", invalid: true},
+		{in: "6/1m,x", invalid: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePolicy("generate", tt.in)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("ParsePolicy(%q) accepted %+v", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestTake(t *testing.T) {
+	p := Policy{Limit: 6, Period: time.Minute, Burst: 3} // one token every 10s
+	start := time.Unix(1700000000, 0)
+
+	tokens, now := float64(p.Burst), start
+	for i := 0; i < 3; i++ {
+		var r Result
+		tokens, r = take(p, tokens, now, now)
+		if !r.Allowed || r.Remaining != 2-i {
+			t.Fatalf("request %d = %+v", i+1, r)
+		}
+	}
+	tokens, r := take(p, tokens, now, now)
+	if r.Allowed || r.RetryAfter != 10*time.Second {
+		t.Fatalf("over burst = %+v, want retry after 10s", r)
+	}
+
+	// Half a token refills in 5s, so the wait halves
+	if _, r := take(p, tokens, now, now.Add(5*time.Second)); r.Allowed || r.RetryAfter != 5*time.Second {
+		t.Errorf("after 5s = %+v", r)
+	}
+	if _, r := take(p, tokens, now, now.Add(10*time.Second)); !r.Allowed || r.Remaining != 0 {
+		t.Errorf("after 10s = %+v", r)
+	}
+	// A long pause refills only up to the burst
+	if _, r := take(p, tokens, now, now.Add(time.Hour)); !r.Allowed || r.Remaining != p.Burst-1 {
+		t.Errorf("after an hour = %+v", r)
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLimiter()
+	generate := Policy{Name: "generate", Limit: 2, Period: time.Hour, Burst: 2}
+	export := Policy{Name: "export", Limit: 1, Period: time.Hour, Burst: 1}
+
+	allow := func(key string, p Policy) bool {
+		t.Helper()
+		r, err := l.Allow(ctx, key, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Allowed
+	}
+	if !allow("ip:1", generate) || !allow("ip:1", generate) {
+		t.Fatal("burst was not allowed")
+	}
+	if allow("ip:1", generate) {
+		t.Error("request over the burst was allowed")
+	}
+	if !allow("ip:2", generate) {
+		t.Error("another key shares the bucket")
+	}
+	if !allow("ip:1", export) {
+		t.Error("another policy shares the bucket")
+	}
+
+	// Idle buckets are dropped on the next scan
+	l.buckets["generate:ip:old"] = &bucket{updated: time.Now().Add(-2 * idleAfter)}
+	l.lastScan = time.Now().Add(-2 * idleAfter)
+	allow("ip:2", generate)
+	if _, ok := l.buckets["generate:ip:old"]; ok {
+		t.Error("idle bucket was not dropped")
+	}
+	if _, ok := l.buckets["generate:ip:1"]; !ok {
+		t.Error("active bucket was dropped")
+	}
+}
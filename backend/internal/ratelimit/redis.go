@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLimiter keeps buckets in Redis, so limits hold across instances. The
+// refill and spend run in one script, so concurrent requests can't overspend.
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter connects to a redis:// URL
+func NewRedisLimiter(redisURL string) (*RedisLimiter, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisLimiter{client: redis.NewClient(opts)}, nil
+}
+
+// tokenBucket refills KEYS[1] at ARGV[1] tokens per ms up to ARGV[2], then
+// spends one token if it can. ARGV[3] is now in ms, ARGV[4] the key's TTL in ms.
+// Returns {allowed, tokens left as a string}.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+if now > updated then
+  tokens = math.min(burst, tokens + (now - updated) * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	rate := p.refillPerSecond() / 1000
+	// Keep the key until the bucket would be full again anyway
+	ttl := time.Duration(float64(p.Burst)/p.refillPerSecond()*float64(time.Second)) + time.Second
+	res, err := tokenBucket.Run(ctx, l.client, []string{"ratelimit:" + p.Name + ":" + key},
+		rate, p.Burst, time.Now().UnixMilli(), ttl.Milliseconds()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := res[0].(int64)
+	left, _ := res[1].(string)
+	tokens, _ := strconv.ParseFloat(left, 64)
+	if allowed == 1 {
+		return Result{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	wait := time.Duration((1 - tokens) / p.refillPerSecond() * float64(time.Second))
+	return Result{RetryAfter: wait}, nil
+}
@@ -0,0 +1,182 @@
+package readability
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GradeTolerance is how far (in grade levels) a passage may drift from the
+// target grade before it is reported as out of range
+const GradeTolerance = 2.0
+
+// Stats holds the raw counts and Flesch scores for a piece of text
+type Stats struct {
+	Words       int     `json:"words"`
+	Sentences   int     `json:"sentences"`
+	Syllables   int     `json:"syllables"`
+	GradeLevel  float64 `json:"flesch_kincaid_grade"`
+	ReadingEase float64 `json:"flesch_reading_ease"`
+}
+
+// Analyze computes Flesch-Kincaid statistics for English text
+func Analyze(text string) Stats {
+	var stats Stats
+
+	for _, sentence := range splitSentences(text) {
+		words := splitWords(sentence)
+		if len(words) == 0 {
+			continue
+		}
+		stats.Sentences++
+		stats.Words += len(words)
+		for _, w := range words {
+			stats.Syllables += CountSyllables(w)
+		}
+	}
+
+	if stats.Words == 0 || stats.Sentences == 0 {
+		return stats
+	}
+
+	wordsPerSentence := float64(stats.Words) / float64(stats.Sentences)
+	syllablesPerWord := float64(stats.Syllables) / float64(stats.Words)
+
+	stats.GradeLevel = round1(0.39*wordsPerSentence + 11.8*syllablesPerWord - 15.59)
+	stats.ReadingEase = round1(206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord)
+	return stats
+}
+
+// CountSyllables estimates the number of syllables in an English word
+func CountSyllables(word string) int {
+	word = strings.ToLower(word)
+	word = strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })
+	if word == "" {
+		return 0
+	}
+	if len(word) <= 3 {
+		return 1
+	}
+
+	// Silent trailing "e" ("make") but not "-le" ("table")
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") {
+		word = word[:len(word)-1]
+	}
+	// "-ed" and "-es" endings are usually not their own syllable
+	if strings.HasSuffix(word, "ed") || strings.HasSuffix(word, "es") {
+		if len(word) > 3 && !strings.HasSuffix(word, "ted") && !strings.HasSuffix(word, "ded") {
+			word = word[:len(word)-2]
+		}
+	}
+
+	count := 0
+	prevVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// ParseGrade converts a worksheet grade level ("k", "5", "10th", "college")
+// into a numeric US grade
+func ParseGrade(level string) (float64, bool) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case "k", "kindergarten", "pre-k", "prek":
+		return 0, true
+	case "college":
+		return 14, true
+	case "adult":
+		return 13, true
+	}
+
+	level = strings.TrimRight(level, "stndrh ")
+	level = strings.TrimPrefix(level, "grade ")
+	n, err := strconv.Atoi(level)
+	if err != nil {
+		return 0, false
+	}
+	return float64(n), true
+}
+
+// WithinGrade reports whether a Flesch-Kincaid grade is close enough to the
+// target grade level
+func WithinGrade(score float64, gradeLevel string) bool {
+	target, ok := ParseGrade(gradeLevel)
+	if !ok {
+		return true
+	}
+	return math.Abs(score-target) <= GradeTolerance
+}
+
+// abbreviations are words whose trailing period doesn't end a sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true, "jr": true,
+	"st": true, "mt": true, "vs": true, "etc": true, "e.g": true, "i.e": true, "approx": true,
+	"no": true, "fig": true, "u.s": true, "a.m": true, "p.m": true,
+}
+
+// splitSentences breaks text on '!', '?', line breaks, and periods that end a
+// sentence. Periods inside numbers ("3.5") or after abbreviations ("Dr.",
+// "e.g.") and initials ("J. K.") don't count.
+func splitSentences(text string) []string {
+	runes := []rune(text)
+	var sentences []string
+	start := 0
+	for i, r := range runes {
+		switch {
+		case r == '!' || r == '?' || r == '\n':
+		case r == '.' && endsSentence(runes, i):
+		default:
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start:i])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = i + 1
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// endsSentence reports whether the period at runes[i] closes a sentence
+func endsSentence(runes []rune, i int) bool {
+	if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && !strings.ContainsRune(`"')]”’`, runes[i+1]) {
+		return false // "3.5", "example.com", or the first dot of "e.g."
+	}
+	start := i
+	for start > 0 && !unicode.IsSpace(runes[start-1]) && runes[start-1] != '(' && runes[start-1] != '"' {
+		start--
+	}
+	word := string(runes[start:i])
+	if w := []rune(word); len(w) == 1 && unicode.IsUpper(w[0]) {
+		return false
+	}
+	return !abbreviations[strings.ToLower(word)]
+}
+
+func splitWords(sentence string) []string {
+	var words []string
+	for _, w := range strings.Fields(sentence) {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
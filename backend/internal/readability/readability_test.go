@@ -0,0 +1,86 @@
+package readability
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	// 9 one-syllable words in 2 sentences:
+	// grade = 0.39*4.5 + 11.8*1 - 15.59, ease = 206.835 - 1.015*4.5 - 84.6*1
+	got := Analyze("The cat sat on the mat. The dog ran!")
+	want := Stats{Words: 9, Sentences: 2, Syllables: 9, GradeLevel: -2.0, ReadingEase: 117.7}
+	if got != want {
+		t.Errorf("Analyze = %+v, want %+v", got, want)
+	}
+
+	if got := Analyze("  "); got != (Stats{}) {
+		t.Errorf("Analyze(blank) = %+v", got)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"One. Two! Three?", []string{"One", "Two", "Three"}},
+		{"The rock weighs 3.5 kg. It is heavy.", []string{"The rock weighs 3.5 kg", "It is heavy"}},
+		{"Dr. Lee met Mrs. Park at 9 a.m. on Monday.", []string{"Dr. Lee met Mrs. Park at 9 a.m. on Monday"}},
+		{"Mammals (e.g. whales) breathe air. Fish do not.", []string{"Mammals (e.g. whales) breathe air", "Fish do not"}},
+		{"J. K. Rowling wrote it. Visit example.com today.", []string{"J. K. Rowling wrote it", "Visit example.com today"}},
+		{`He said "Stop." Then he left`, []string{`He said "Stop`, `" Then he left`}},
+		{"Line one\nLine two", []string{"Line one", "Line two"}},
+	}
+	for _, tt := range tests {
+		if got := splitSentences(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitSentences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountSyllables(t *testing.T) {
+	tests := map[string]int{
+		"cat": 1, "make": 1, "table": 2, "jumped": 1, "wanted": 2,
+		"photosynthesis": 5, "Beautiful,": 3, "42": 0,
+	}
+	for word, want := range tests {
+		if got := CountSyllables(word); got != want {
+			t.Errorf("CountSyllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestParseGrade(t *testing.T) {
+	tests := []struct {
+		level string
+		want  float64
+		ok    bool
+	}{
+		{"k", 0, true},
+		{"Kindergarten", 0, true},
+		{"5", 5, true},
+		{"3rd", 3, true},
+		{"10th", 10, true},
+		{"Grade 7", 7, true},
+		{"college", 14, true},
+		{"adult", 13, true},
+		{"middle school", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseGrade(tt.level)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseGrade(%q) = %v, %v, want %v, %v", tt.level, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWithinGrade(t *testing.T) {
+	if !WithinGrade(6.5, "5") || WithinGrade(7.5, "5") {
+		t.Error("tolerance is not GradeTolerance grades")
+	}
+	if !WithinGrade(20, "mixed") {
+		t.Error("an unknown grade level should not be judged")
+	}
+}
@@ -0,0 +1,87 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+const (
+	// ExcerptChars is the target size of a single excerpt
+	ExcerptChars = 1200
+	// MaxContextChars caps the total source text sent to the model (~12k tokens)
+	MaxContextChars = 48000
+)
+
+// Chunk splits extracted text into numbered excerpts of roughly ExcerptChars,
+// breaking on paragraph and sentence boundaries. Excerpts beyond
+// MaxContextChars are dropped, and truncated reports whether that happened.
+func Chunk(text string) (excerpts []models.SourceExcerpt, truncated bool) {
+	var current strings.Builder
+	total := 0
+
+	flush := func() bool {
+		body := strings.TrimSpace(current.String())
+		current.Reset()
+		if body == "" {
+			return true
+		}
+		if total+len(body) > MaxContextChars {
+			return false
+		}
+		total += len(body)
+		excerpts = append(excerpts, models.SourceExcerpt{
+			ID:   fmt.Sprintf("S%d", len(excerpts)+1),
+			Text: body,
+		})
+		return true
+	}
+
+	for _, para := range splitUnits(text) {
+		if current.Len() > 0 && current.Len()+len(para) > ExcerptChars {
+			if !flush() {
+				return excerpts, true
+			}
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(para)
+	}
+	if !flush() {
+		return excerpts, true
+	}
+	return excerpts, false
+}
+
+// splitUnits returns paragraphs, further split into sentences when a paragraph
+// is longer than a single excerpt
+func splitUnits(text string) []string {
+	var units []string
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if len(para) <= ExcerptChars {
+			units = append(units, para)
+			continue
+		}
+
+		var sentence strings.Builder
+		for _, word := range strings.Fields(para) {
+			sentence.WriteString(word)
+			sentence.WriteByte(' ')
+			end := strings.HasSuffix(word, ".") || strings.HasSuffix(word, "?") || strings.HasSuffix(word, "!")
+			if (end && sentence.Len() > ExcerptChars/4) || sentence.Len() >= ExcerptChars {
+				units = append(units, strings.TrimSpace(sentence.String()))
+				sentence.Reset()
+			}
+		}
+		if sentence.Len() > 0 {
+			units = append(units, strings.TrimSpace(sentence.String()))
+		}
+	}
+	return units
+}
@@ -0,0 +1,80 @@
+package source
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// extractDOCX reads the text runs from word/document.xml inside a .docx archive
+func extractDOCX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX file: %w", err)
+	}
+
+	var doc *zip.File
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+			break
+		}
+	}
+	if doc == nil {
+		return "", fmt.Errorf("invalid DOCX file: word/document.xml not found")
+	}
+
+	rc, err := doc.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open DOCX body: %w", err)
+	}
+	body, err := readCapped(rc, MaxDecompressedBytes)
+	rc.Close()
+	if err == errTooLarge {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read DOCX body: %w", err)
+	}
+
+	var sb strings.Builder
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	inText := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse DOCX body: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteString("\t")
+			case "br", "cr":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n\n")
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
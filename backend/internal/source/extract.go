@@ -0,0 +1,108 @@
+package source
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SupportedExtensions lists the file types that can be uploaded as source material
+var SupportedExtensions = []string{".txt", ".md", ".markdown", ".docx", ".pdf"}
+
+// MaxDecompressedBytes caps how much a DOCX body or a PDF's streams may inflate
+// to while being parsed, so a small upload can't expand into gigabytes
+const MaxDecompressedBytes = 32 << 20
+
+var errTooLarge = fmt.Errorf("file expands to more than %d MB when decompressed", MaxDecompressedBytes>>20)
+
+// readCapped reads r to the end, failing once more than limit bytes come out
+func readCapped(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(data)) > limit {
+		return nil, errTooLarge
+	}
+	return data, err
+}
+
+// Extract returns the plain text of an uploaded file, picking the parser from its extension
+func Extract(filename string, data []byte) (string, error) {
+	var (
+		text string
+		err  error
+	)
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", "":
+		text, err = extractPlain(data)
+	case ".md", ".markdown":
+		text, err = extractPlain(data)
+		text = stripMarkdown(text)
+	case ".docx":
+		text, err = extractDOCX(data)
+	case ".pdf":
+		text, err = extractPDF(data)
+	default:
+		return "", fmt.Errorf("unsupported file type %q (supported: %s)", filepath.Ext(filename), strings.Join(SupportedExtensions, ", "))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = normalizeWhitespace(text)
+	if text == "" {
+		return "", fmt.Errorf("no text found in %s", filename)
+	}
+	return text, nil
+}
+
+func extractPlain(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("file is not valid UTF-8 text")
+	}
+	return strings.TrimPrefix(string(data), "\ufeff"), nil
+}
+
+var (
+	mdFence    = regexp.MustCompile("(?m)^```.*$")
+	mdHeading  = regexp.MustCompile(`(?m)^#{1,6}\s*`)
+	mdImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdEmphasis = regexp.MustCompile(`(\*\*|__|\*|~~|` + "`" + `)`)
+	mdList     = regexp.MustCompile(`(?m)^\s*([-*+]|\d+\.)\s+`)
+	mdQuote    = regexp.MustCompile(`(?m)^>\s?`)
+)
+
+// stripMarkdown removes Markdown syntax while keeping the readable text
+func stripMarkdown(text string) string {
+	text = mdFence.ReplaceAllString(text, "")
+	text = mdHeading.ReplaceAllString(text, "")
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllString(text, "$1")
+	text = mdList.ReplaceAllString(text, "")
+	text = mdQuote.ReplaceAllString(text, "")
+	return mdEmphasis.ReplaceAllString(text, "")
+}
+
+// normalizeWhitespace collapses runs of spaces and keeps at most one blank line between paragraphs
+func normalizeWhitespace(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var out []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,152 @@
+package source
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"testing"
+)
+
+// buildDOCX zips document as word/document.xml
+func buildDOCX(t *testing.T, document string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(document)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF wraps content streams in just enough PDF for extractPDF; compressed
+// streams are FlateDecoded
+func buildPDF(t *testing.T, compress bool, streams ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	for i, content := range streams {
+		data := []byte(content)
+		dict := "<< /Length 0 >>"
+		if compress {
+			var z bytes.Buffer
+			zw := zlib.NewWriter(&z)
+			zw.Write(data)
+			zw.Close()
+			data, dict = z.Bytes(), "<< /Length 0 /Filter /FlateDecode >>"
+		}
+		buf.WriteString(string(rune('1'+i)) + " 0 obj\n" + dict + "\nstream\n")
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+	buf.WriteString("%%EOF\n")
+	return buf.Bytes()
+}
+
+func TestExtractDOCX(t *testing.T) {
+	doc := buildDOCX(t, `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Plants make </w:t></w:r><w:r><w:t>food.</w:t></w:r></w:p>
+<w:p><w:r><w:t>Step</w:t><w:tab/><w:t>one</w:t></w:r></w:p>
+</w:body></w:document>`)
+
+	text, err := Extract("notes.docx", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Plants make food.\n\nStep one"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "literal", content: "BT /F1 12 Tf (Hello, world) Tj ET", want: "Hello, world"},
+		{name: "TJ array", content: "BT [(Pho) -20 (tosynthesis)] TJ ET", want: "Photosynthesis"},
+		{name: "nested parens and escapes", content: `BT (f\(x\) = \(a\)) Tj T* (line\\two) Tj ET`, want: "f(x) = (a)\nline\\two"},
+		{name: "hex", content: "BT <48656C6C6F> Tj <20776F726C64> Tj ET", want: "Hello world"},
+		{name: "hex with spaces and odd length", content: "BT <4869 2> Tj ET", want: "Hi"},
+		{name: "octal Latin-1", content: `BT (caf\351) Tj ET`, want: "café"},
+		{name: "octal PDFDocEncoding", content: `BT (\215quoted\216 \204 dash) Tj ET`, want: "“quoted” — dash"},
+		{name: "UTF-16", content: "BT <FEFF00E9006C00E8007600650073> Tj ET", want: "élèves"},
+		{name: "marked content", content: "/Span << /MCID 0 >> BDC BT (Tagged) Tj ET EMC", want: "Tagged"},
+	}
+
+	for _, tt := range tests {
+		for _, compress := range []bool{false, true} {
+			t.Run(tt.name, func(t *testing.T) {
+				text, err := Extract("notes.pdf", buildPDF(t, compress, tt.content))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if text != tt.want {
+					t.Errorf("text = %q, want %q", text, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestExtractPDFNoText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "scanned", content: "q 612 0 0 792 0 0 cm /Im0 Do Q", want: "scanned document"},
+		{name: "CID font", content: "BT /F1 12 Tf <002B00480044005700030057005400480044> Tj ET", want: "encoding we can't read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract("notes.pdf", buildPDF(t, true, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDecompressionCap(t *testing.T) {
+	huge := strings.Repeat(" ", MaxDecompressedBytes+1)
+
+	if _, err := Extract("bomb.docx", buildDOCX(t, huge)); err != errTooLarge {
+		t.Errorf("DOCX error = %v, want %v", err, errTooLarge)
+	}
+
+	// The cap covers all of a PDF's streams together, not each one
+	half := "BT (x) Tj ET" + strings.Repeat(" ", MaxDecompressedBytes/2)
+	if _, err := Extract("bomb.pdf", buildPDF(t, true, half, half, half)); err != errTooLarge {
+		t.Errorf("PDF error = %v, want %v", err, errTooLarge)
+	}
+	if _, err := Extract("ok.pdf", buildPDF(t, true, half)); err != nil {
+		t.Errorf("PDF under the cap: %v", err)
+	}
+}
+
+func TestExtractPlain(t *testing.T) {
+	text, err := Extract("notes.md", []byte("\ufeff# Cells\n\nThe **nucleus**   holds [DNA](https://x).\n\n\n> Quoted"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Cells\n\nThe nucleus holds DNA.\n\nQuoted"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+
+	if _, err := Extract("notes.txt", []byte{0xff, 0xfe, 0x00}); err == nil {
+		t.Error("accepted invalid UTF-8")
+	}
+	if _, err := Extract("notes.rtf", []byte("x")); err == nil {
+		t.Error("accepted an unsupported extension")
+	}
+}
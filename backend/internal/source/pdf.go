@@ -0,0 +1,261 @@
+package source
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfStream matches each content stream together with the dictionary that precedes it
+var pdfStream = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n(.*?)\r?\nendstream`)
+
+// extractPDF pulls text shown with Tj/TJ/'/" operators out of a PDF's content streams.
+// It handles uncompressed and FlateDecode streams with literal or hex strings in
+// single-byte font encodings. Scanned PDFs have no text layer, and text drawn
+// with embedded CID fonts can't be mapped back to characters without the font's
+// CMap; both are rejected.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", fmt.Errorf("invalid PDF file")
+	}
+
+	var sb strings.Builder
+	budget := int64(MaxDecompressedBytes)
+	unreadable := false
+	for _, m := range pdfStream.FindAllSubmatch(data, -1) {
+		dict, content := m[1], m[2]
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			r, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			decoded, err := readCapped(r, budget)
+			r.Close()
+			if err == errTooLarge {
+				return "", err
+			}
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			budget -= int64(len(decoded))
+			content = decoded
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Images and other encodings carry no text we can read
+			continue
+		}
+
+		text, skipped := pdfContentText(content)
+		sb.WriteString(text)
+		unreadable = unreadable || skipped
+	}
+
+	text := strings.TrimSpace(sb.String())
+	if text == "" && unreadable {
+		return "", fmt.Errorf("PDF has no extractable text (its fonts use an encoding we can't read; try uploading it as DOCX or text)")
+	}
+	if text == "" {
+		return "", fmt.Errorf("PDF has no extractable text (it may be a scanned document)")
+	}
+	return text, nil
+}
+
+// pdfContentText walks a content stream and collects string operands of text
+// operators. skipped reports strings that didn't decode to text, such as the
+// glyph IDs shown with CID fonts.
+func pdfContentText(content []byte) (text string, skipped bool) {
+	var sb strings.Builder
+	var pending []string
+
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '(' || (c == '<' && (i+1 >= len(content) || content[i+1] != '<')):
+			var raw []byte
+			var end int
+			if c == '(' {
+				raw, end = readPDFString(content, i)
+			} else {
+				raw, end = readPDFHexString(content, i)
+			}
+			if s, ok := decodePDFText(raw); ok {
+				pending = append(pending, s)
+			} else {
+				skipped = true
+			}
+			i = end
+		case c == '<':
+			// Inline dictionary, e.g. marked-content properties
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFLetter(c) || c == '\'' || c == '"':
+			start := i
+			for i < len(content) && (isPDFLetter(content[i]) || content[i] == '*' || content[i] == '\'' || content[i] == '"') {
+				i++
+			}
+			op := string(content[start:i])
+			i--
+
+			switch op {
+			case "Tj", "TJ":
+				sb.WriteString(strings.Join(pending, ""))
+			case "'", "\"":
+				sb.WriteString("\n" + strings.Join(pending, ""))
+			case "T*", "Td", "TD":
+				sb.WriteString("\n")
+			case "ET":
+				sb.WriteString("\n\n")
+			}
+			if op != "" {
+				pending = pending[:0]
+			}
+		}
+	}
+	return sb.String(), skipped
+}
+
+// readPDFString reads a literal string starting at the opening parenthesis and
+// returns its bytes with escapes resolved and the index of the closing parenthesis
+func readPDFString(content []byte, start int) ([]byte, int) {
+	var sb bytes.Buffer
+	depth := 0
+
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; j++ {
+						n = n*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					sb.WriteByte(byte(n))
+				} else {
+					sb.WriteByte(e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				sb.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return sb.Bytes(), i
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.Bytes(), len(content) - 1
+}
+
+// readPDFHexString reads a hex string such as <48656C6C6F> starting at the
+// opening bracket and returns its bytes and the index of the closing bracket
+func readPDFHexString(content []byte, start int) ([]byte, int) {
+	var out []byte
+	var digits []byte
+	end := len(content) - 1
+	for i := start + 1; i < len(content); i++ {
+		if content[i] == '>' {
+			end = i
+			break
+		}
+		if isHexDigit(content[i]) {
+			digits = append(digits, content[i])
+		}
+	}
+	if len(digits)%2 == 1 {
+		// A missing final digit is taken as 0
+		digits = append(digits, '0')
+	}
+	for i := 0; i < len(digits); i += 2 {
+		out = append(out, hexValue(digits[i])<<4|hexValue(digits[i+1]))
+	}
+	return out, end
+}
+
+// decodePDFText converts string bytes to UTF-8: UTF-16BE when the string starts
+// with a byte order mark, otherwise PDFDocEncoding. ok is false when the bytes
+// hold control codes, which means they are glyph IDs rather than characters.
+func decodePDFText(raw []byte) (string, bool) {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units)), true
+	}
+
+	var sb strings.Builder
+	for _, b := range raw {
+		switch {
+		case b == '\t' || b == '\n' || b == '\r':
+			sb.WriteByte(b)
+		case b < 0x18:
+			return "", false
+		case b < 0x20:
+			sb.WriteRune(pdfDocLow[b-0x18])
+		case b < 0x80:
+			sb.WriteByte(b)
+		case b < 0xA1:
+			if r := pdfDocHigh[b-0x80]; r != 0 {
+				sb.WriteRune(r)
+			}
+		case b == 0xAD:
+			// Undefined in PDFDocEncoding
+		default:
+			sb.WriteRune(rune(b)) // Latin-1
+		}
+	}
+	return sb.String(), true
+}
+
+// PDFDocEncoding characters that differ from Latin-1, for 0x18-0x1F and 0x80-0xA0
+var (
+	pdfDocLow  = [8]rune{'˘', 'ˇ', 'ˆ', '˙', '˝', '˛', '˚', '˜'}
+	pdfDocHigh = [33]rune{
+		'•', '†', '‡', '…', '—', '–', 'ƒ', '⁄', '‹', '›', '−', '‰', '„', '“', '”', '‘',
+		'’', '‚', '™', 'ﬁ', 'ﬂ', 'Ł', 'Œ', 'Š', 'Ÿ', 'Ž', 'ı', 'ł', 'œ', 'š', 'ž', 0,
+		'€',
+	}
+)
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+func isPDFLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,55 @@
+package store
+
+import "sync"
+
+// CounterStore keeps named counters, such as a user's worksheets this month
+type CounterStore interface {
+	// Increment adds one unless the count has reached limit (0 means no limit),
+	// and returns the count afterwards and whether it was added
+	Increment(key string, limit int) (int, bool)
+	Decrement(key string)
+	Get(key string) int
+}
+
+// MemoryCounterStore keeps counters in memory
+type MemoryCounterStore struct {
+	mu       sync.Mutex
+	counters map[string]int
+}
+
+// NewMemoryCounterStore creates an empty in-memory counter store
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{
+		counters: make(map[string]int),
+	}
+}
+
+// Increment adds one to a counter if it is below the limit
+func (s *MemoryCounterStore) Increment(key string, limit int) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.counters[key]
+	if limit > 0 && n >= limit {
+		return n, false
+	}
+	s.counters[key] = n + 1
+	return n + 1, true
+}
+
+// Decrement takes one off a counter, never going below zero
+func (s *MemoryCounterStore) Decrement(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counters[key] <= 1 {
+		delete(s.counters, key)
+		return
+	}
+	s.counters[key]--
+}
+
+// Get returns a counter's value
+func (s *MemoryCounterStore) Get(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters[key]
+}
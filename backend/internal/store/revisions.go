@@ -0,0 +1,83 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// RevisionStore keeps the append-only revision history of each worksheet
+type RevisionStore interface {
+	// Append numbers the revision, stamps CreatedAt and stores a copy of its worksheet snapshot
+	Append(rev *models.Revision) error
+	// List returns a worksheet's revisions, oldest first, without snapshots
+	List(worksheetID string) []*models.Revision
+	Get(worksheetID string, number int) (*models.Revision, bool)
+	DeleteAll(worksheetID string)
+}
+
+// MemoryRevisionStore keeps revisions in memory
+type MemoryRevisionStore struct {
+	mu        sync.RWMutex
+	revisions map[string][]*models.Revision
+}
+
+// NewMemoryRevisionStore creates an empty in-memory revision store
+func NewMemoryRevisionStore() *MemoryRevisionStore {
+	return &MemoryRevisionStore{
+		revisions: make(map[string][]*models.Revision),
+	}
+}
+
+// Append adds a revision to the end of the worksheet's history
+func (s *MemoryRevisionStore) Append(rev *models.Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.revisions[rev.WorksheetID]
+	rev.Number = len(history) + 1
+	rev.CreatedAt = time.Now()
+
+	stored := *rev
+	stored.Worksheet = rev.Worksheet.Clone()
+	stored.Worksheet.Revision = rev.Number
+	s.revisions[rev.WorksheetID] = append(history, &stored)
+	return nil
+}
+
+// List returns revision metadata for a worksheet, oldest first
+func (s *MemoryRevisionStore) List(worksheetID string) []*models.Revision {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := s.revisions[worksheetID]
+	revisions := make([]*models.Revision, len(history))
+	for i, rev := range history {
+		summary := *rev
+		summary.Worksheet = nil
+		revisions[i] = &summary
+	}
+	return revisions
+}
+
+// Get returns a copy of a single revision including its snapshot
+func (s *MemoryRevisionStore) Get(worksheetID string, number int) (*models.Revision, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := s.revisions[worksheetID]
+	if number < 1 || number > len(history) {
+		return nil, false
+	}
+	rev := *history[number-1]
+	rev.Worksheet = rev.Worksheet.Clone()
+	return &rev, true
+}
+
+// DeleteAll drops a worksheet's history
+func (s *MemoryRevisionStore) DeleteAll(worksheetID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.revisions, worksheetID)
+}
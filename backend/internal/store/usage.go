@@ -0,0 +1,54 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// UsageStore keeps the spend log of generation requests
+type UsageStore interface {
+	// Record stamps CreatedAt when it is unset and appends the record
+	Record(rec *models.UsageRecord) error
+	// List returns the records created in [from, to), oldest first
+	List(from, to time.Time) []*models.UsageRecord
+}
+
+// MemoryUsageStore keeps usage records in memory
+type MemoryUsageStore struct {
+	mu      sync.RWMutex
+	records []*models.UsageRecord
+}
+
+// NewMemoryUsageStore creates an empty in-memory usage store
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{}
+}
+
+// Record appends a usage record
+func (s *MemoryUsageStore) Record(rec *models.UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	stored := *rec
+	s.records = append(s.records, &stored)
+	return nil
+}
+
+// List returns the records in a time range
+func (s *MemoryUsageStore) List(from, to time.Time) []*models.UsageRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var records []*models.UsageRecord
+	for _, rec := range s.records {
+		if !rec.CreatedAt.Before(from) && rec.CreatedAt.Before(to) {
+			copied := *rec
+			records = append(records, &copied)
+		}
+	}
+	return records
+}
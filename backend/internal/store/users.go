@@ -0,0 +1,111 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// ErrEmailTaken is returned when creating a user whose email is already registered
+var ErrEmailTaken = errors.New("email already registered")
+
+// ErrUserNotFound is returned when updating a user that doesn't exist
+var ErrUserNotFound = errors.New("user not found")
+
+// UserStore persists user accounts
+type UserStore interface {
+	Create(user *models.User) error
+	Get(id string) (*models.User, bool)
+	GetByEmail(email string) (*models.User, bool)
+	// GetByBillingCustomer finds the user linked to a payment provider's customer ID
+	GetByBillingCustomer(customerID string) (*models.User, bool)
+	Save(user *models.User) error
+	// Update applies fn to a copy of the current user and stores it, atomically,
+	// so concurrent updates of different fields don't undo each other. An error
+	// from fn leaves the user unchanged.
+	Update(id string, fn func(user *models.User) error) (*models.User, error)
+}
+
+// MemoryUserStore keeps users in memory
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[string]*models.User
+}
+
+// NewMemoryUserStore creates an empty in-memory user store
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{
+		users: make(map[string]*models.User),
+	}
+}
+
+// Create adds a new user, rejecting duplicate emails
+func (s *MemoryUserStore) Create(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, user.Email) {
+			return ErrEmailTaken
+		}
+	}
+	s.users[user.ID] = user
+	return nil
+}
+
+// Get returns a user by ID
+func (s *MemoryUserStore) Get(id string) (*models.User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[id]
+	return user, ok
+}
+
+// GetByEmail returns a user by email, ignoring case
+func (s *MemoryUserStore) GetByEmail(email string) (*models.User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// GetByBillingCustomer returns the user whose billing state has the customer ID
+func (s *MemoryUserStore) GetByBillingCustomer(customerID string) (*models.User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.Billing != nil && u.Billing.CustomerID == customerID {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// Save replaces an existing user
+func (s *MemoryUserStore) Save(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user.ID] = user
+	return nil
+}
+
+// Update changes a user under the store's lock
+func (s *MemoryUserStore) Update(id string, fn func(user *models.User) error) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	updated := *current
+	if err := fn(&updated); err != nil {
+		return nil, err
+	}
+	s.users[id] = &updated
+	return &updated, nil
+}
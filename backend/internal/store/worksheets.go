@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+// ErrWorksheetNotFound is returned when updating a worksheet that doesn't exist
+var ErrWorksheetNotFound = errors.New("worksheet not found")
+
+// WorksheetStore persists worksheets. Worksheets are stored and returned by
+// value, so editing one never changes the stored copy until it is saved.
+type WorksheetStore interface {
+	Save(ws *models.Worksheet) error
+	Get(id string) (*models.Worksheet, bool)
+	// Update applies fn to a copy of the current worksheet and stores it,
+	// atomically, so a change made meanwhile by another request isn't
+	// overwritten. An error from fn leaves the worksheet unchanged.
+	Update(id string, fn func(ws *models.Worksheet) error) (*models.Worksheet, error)
+	List() []*models.Worksheet
+	Delete(id string) bool
+}
+
+// MemoryWorksheetStore keeps worksheets in memory
+type MemoryWorksheetStore struct {
+	mu         sync.RWMutex
+	worksheets map[string]*models.Worksheet
+}
+
+// NewMemoryWorksheetStore creates an empty in-memory worksheet store
+func NewMemoryWorksheetStore() *MemoryWorksheetStore {
+	return &MemoryWorksheetStore{
+		worksheets: make(map[string]*models.Worksheet),
+	}
+}
+
+// Save inserts or replaces a worksheet
+func (s *MemoryWorksheetStore) Save(ws *models.Worksheet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.worksheets[ws.ID] = ws.Clone()
+	return nil
+}
+
+// Get returns a copy of a worksheet by ID
+func (s *MemoryWorksheetStore) Get(id string) (*models.Worksheet, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ws, ok := s.worksheets[id]
+	if !ok {
+		return nil, false
+	}
+	return ws.Clone(), true
+}
+
+// Update changes a worksheet under the store's lock
+func (s *MemoryWorksheetStore) Update(id string, fn func(ws *models.Worksheet) error) (*models.Worksheet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.worksheets[id]
+	if !ok {
+		return nil, ErrWorksheetNotFound
+	}
+	updated := current.Clone()
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	s.worksheets[id] = updated
+	return updated.Clone(), nil
+}
+
+// List returns all worksheets, newest first
+func (s *MemoryWorksheetStore) List() []*models.Worksheet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	worksheets := make([]*models.Worksheet, 0, len(s.worksheets))
+	for _, ws := range s.worksheets {
+		worksheets = append(worksheets, ws.Clone())
+	}
+	sort.Slice(worksheets, func(i, j int) bool {
+		return worksheets[i].CreatedAt.After(worksheets[j].CreatedAt)
+	})
+	return worksheets
+}
+
+// Delete removes a worksheet and reports whether it existed
+func (s *MemoryWorksheetStore) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.worksheets[id]; !ok {
+		return false
+	}
+	delete(s.worksheets, id)
+	return true
+}
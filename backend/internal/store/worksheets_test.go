@@ -0,0 +1,65 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/makosai/backend/internal/models"
+)
+
+func TestMemoryWorksheetStoreCopies(t *testing.T) {
+	s := NewMemoryWorksheetStore()
+	ws := &models.Worksheet{ID: "ws_1", Title: "Plants", Questions: []models.Question{{ID: "q_1", Question: "Roots?"}}}
+	if err := s.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	// Editing the saved value or a fetched one leaves the stored worksheet alone
+	ws.Title = "changed after save"
+	got, ok := s.Get("ws_1")
+	if !ok {
+		t.Fatal("worksheet not found")
+	}
+	got.Questions[0].Question = "changed after get"
+	s.List()[0].Title = "changed after list"
+
+	again, _ := s.Get("ws_1")
+	if again.Title != "Plants" || again.Questions[0].Question != "Roots?" {
+		t.Errorf("stored worksheet = %q %q", again.Title, again.Questions[0].Question)
+	}
+
+	if _, ok := s.Get("missing"); ok {
+		t.Error("found a worksheet that was never saved")
+	}
+}
+
+func TestMemoryWorksheetStoreUpdate(t *testing.T) {
+	s := NewMemoryWorksheetStore()
+	if err := s.Save(&models.Worksheet{ID: "ws_1", Title: "Plants"}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := s.Update("ws_1", func(ws *models.Worksheet) error {
+		ws.Downloads++
+		return nil
+	})
+	if err != nil || updated.Downloads != 1 {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	updated.Title = "changed after update"
+
+	boom := errors.New("boom")
+	if _, err := s.Update("ws_1", func(ws *models.Worksheet) error {
+		ws.Title = "half done"
+		return boom
+	}); err != boom {
+		t.Errorf("error = %v, want %v", err, boom)
+	}
+	if got, _ := s.Get("ws_1"); got.Title != "Plants" || got.Downloads != 1 {
+		t.Errorf("stored worksheet = %q, %d downloads", got.Title, got.Downloads)
+	}
+
+	if _, err := s.Update("missing", func(*models.Worksheet) error { return nil }); err != ErrWorksheetNotFound {
+		t.Errorf("missing worksheet: %v", err)
+	}
+}
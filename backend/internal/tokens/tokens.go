@@ -0,0 +1,130 @@
+// Package tokens issues the signed, expiring, single-use tokens mailed to
+// users to verify their email address or reset their password.
+//
+// A token is base64url(claims) + "." + base64url(HMAC-SHA256 signature). The
+// signature also covers a binding the caller supplies, such as the address
+// being verified or the current password, so a token stops working once the
+// thing it was issued for changes.
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/makosai/backend/internal/store"
+)
+
+// Purposes a token can be issued for; a token only redeems for its own purpose
+const (
+	VerifyEmail   = "verify_email"
+	ResetPassword = "reset_password"
+)
+
+// Lifetimes of the tokens we mail
+const (
+	VerifyEmailTTL   = 48 * time.Hour
+	ResetPasswordTTL = time.Hour
+)
+
+var (
+	ErrInvalid = errors.New("invalid token")
+	ErrExpired = errors.New("token expired")
+	ErrUsed    = errors.New("token already used")
+)
+
+// claims is the signed part of a token
+type claims struct {
+	Purpose string `json:"p"`
+	UserID  string `json:"u"`
+	Expires int64  `json:"e"`
+	Nonce   string `json:"n"`
+}
+
+// Issuer signs and redeems tokens
+type Issuer struct {
+	secret []byte
+	used   store.CounterStore
+	now    func() time.Time
+}
+
+// New creates an issuer; used remembers redeemed tokens so each works once
+func New(secret []byte, used store.CounterStore) *Issuer {
+	return &Issuer{secret: secret, used: used, now: time.Now}
+}
+
+// RandomSecret returns a new signing secret, for when none is configured.
+// Tokens signed with it stop working when the process restarts.
+func RandomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+// Issue returns a token for userID that expires after ttl
+func (i *Issuer) Issue(purpose, userID, binding string, ttl time.Duration) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims{
+		Purpose: purpose,
+		UserID:  userID,
+		Expires: i.now().Add(ttl).Unix(),
+		Nonce:   hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(i.sign(encoded, binding)), nil
+}
+
+// Redeem checks a token and marks it used, returning the user it was issued
+// to. binding looks up the user's current binding; a user that no longer
+// exists makes the token invalid.
+func (i *Issuer) Redeem(token, purpose string, binding func(userID string) (string, bool)) (string, error) {
+	encoded, sig, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return "", ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Purpose != purpose || c.UserID == "" || c.Nonce == "" {
+		return "", ErrInvalid
+	}
+	bound, ok := binding(c.UserID)
+	if !ok || !hmac.Equal(signature, i.sign(encoded, bound)) {
+		return "", ErrInvalid
+	}
+	if i.now().Unix() >= c.Expires {
+		return "", ErrExpired
+	}
+	if _, first := i.used.Increment("token-used:"+c.Nonce, 1); !first {
+		return "", ErrUsed
+	}
+	return c.UserID, nil
+}
+
+func (i *Issuer) sign(encoded, binding string) []byte {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(encoded))
+	mac.Write([]byte{0})
+	mac.Write([]byte(binding))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,120 @@
+package tokens
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makosai/backend/internal/store"
+)
+
+func TestRedeem(t *testing.T) {
+	now := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	bindings := map[string]string{"user_1": "ann@example.com", "user_2": "bob@example.com"}
+	lookup := func(userID string) (string, bool) {
+		b, ok := bindings[userID]
+		return b, ok
+	}
+
+	tests := []struct {
+		name    string
+		purpose string // redeemed for; issued for VerifyEmail
+		userID  string
+		binding string // at issue time
+		ttl     time.Duration
+		after   time.Duration // time between issue and redeem
+		tamper  func(token string) string
+		want    error
+	}{
+		{name: "valid", purpose: VerifyEmail, userID: "user_1", binding: "ann@example.com", ttl: time.Hour},
+		{name: "just before expiry", purpose: VerifyEmail, userID: "user_1", binding: "ann@example.com", ttl: time.Hour, after: time.Hour - time.Second},
+		{name: "expired", purpose: VerifyEmail, userID: "user_1", binding: "ann@example.com", ttl: time.Hour, after: time.Hour, want: ErrExpired},
+		{name: "other purpose", purpose: ResetPassword, userID: "user_1", binding: "ann@example.com", ttl: time.Hour, want: ErrInvalid},
+		{name: "binding changed", purpose: VerifyEmail, userID: "user_1", binding: "old@example.com", ttl: time.Hour, want: ErrInvalid},
+		{name: "unknown user", purpose: VerifyEmail, userID: "user_9", binding: "", ttl: time.Hour, want: ErrInvalid},
+		{
+			name: "tampered signature", purpose: VerifyEmail, userID: "user_1", binding: "ann@example.com", ttl: time.Hour,
+			tamper: func(token string) string {
+				payload, sig, _ := strings.Cut(token, ".")
+				flipped := []byte(sig)
+				flipped[0] ^= 1
+				return payload + "." + string(flipped)
+			},
+			want: ErrInvalid,
+		},
+		{
+			name: "payload swapped", purpose: VerifyEmail, userID: "user_1", binding: "ann@example.com", ttl: time.Hour,
+			tamper: func(token string) string {
+				other, err := New([]byte("secret"), store.NewMemoryCounterStore()).Issue(VerifyEmail, "user_2", "bob@example.com", time.Hour)
+				if err != nil {
+					panic(err)
+				}
+				payload, _, _ := strings.Cut(other, ".")
+				_, sig, _ := strings.Cut(token, ".")
+				return payload + "." + sig
+			},
+			want: ErrInvalid,
+		},
+		{name: "malformed", purpose: VerifyEmail, userID: "user_1", binding: "ann@example.com", ttl: time.Hour,
+			tamper: func(string) string { return "not-a-token" }, want: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := New([]byte("secret"), store.NewMemoryCounterStore())
+			issuer.now = func() time.Time { return now }
+			token, err := issuer.Issue(VerifyEmail, tt.userID, tt.binding, tt.ttl)
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+			if tt.tamper != nil {
+				token = tt.tamper(token)
+			}
+			issuer.now = func() time.Time { return now.Add(tt.after) }
+
+			userID, err := issuer.Redeem(token, tt.purpose, lookup)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Redeem error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && userID != tt.userID {
+				t.Errorf("Redeem user = %q, want %q", userID, tt.userID)
+			}
+		})
+	}
+}
+
+func TestRedeemOnce(t *testing.T) {
+	issuer := New([]byte("secret"), store.NewMemoryCounterStore())
+	lookup := func(string) (string, bool) { return "pw", true }
+	token, err := issuer.Issue(ResetPassword, "user_1", "pw", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Redeem(token, ResetPassword, lookup); err != nil {
+		t.Fatalf("first redeem: %v", err)
+	}
+	if _, err := issuer.Redeem(token, ResetPassword, lookup); !errors.Is(err, ErrUsed) {
+		t.Fatalf("second redeem error = %v, want %v", err, ErrUsed)
+	}
+
+	// A second token for the same user is independent of the first
+	again, err := issuer.Issue(ResetPassword, "user_1", "pw", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Redeem(again, ResetPassword, lookup); err != nil {
+		t.Fatalf("new token: %v", err)
+	}
+}
+
+func TestRedeemOtherSecret(t *testing.T) {
+	token, err := New([]byte("secret"), store.NewMemoryCounterStore()).Issue(VerifyEmail, "user_1", "b", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := New([]byte("another secret"), store.NewMemoryCounterStore())
+	if _, err := other.Redeem(token, VerifyEmail, func(string) (string, bool) { return "b", true }); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalid)
+	}
+}
@@ -119,6 +119,7 @@ INSTRUCTIONS:
           onChange={(e) => setFormData({ ...formData, topic: e.target.value })}
           placeholder="e.g., Photosynthesis, Fractions, World War II..."
           className="input-field text-lg py-4"
+          maxLength={200}
           required
         />
       </div>
@@ -262,6 +263,7 @@ INSTRUCTIONS:
           onChange={(e) => setFormData({ ...formData, additional_instructions: e.target.value })}
           placeholder="Examples:&#10;• Focus on real-world applications&#10;• Include word problems about shopping&#10;• Make questions progressively harder&#10;• Use simple vocabulary for ESL students"
           className="input-field min-h-[120px] resize-none"
+          maxLength={2000}
         />
       </div>
 
@@ -290,6 +292,19 @@ INSTRUCTIONS:
               Include answer key with explanations
             </span>
           </label>
+
+          {/* Always generate a brand-new set */}
+          <label className="flex items-center gap-3 cursor-pointer group">
+            <input
+              type="checkbox"
+              checked={!!formData.fresh}
+              onChange={(e) => setFormData({ ...formData, fresh: e.target.checked })}
+              className="checkbox-custom"
+            />
+            <span className="text-gray-700 font-medium group-hover:text-teal-700 transition-colors">
+              Always write brand-new questions (don&apos;t reuse a recent identical worksheet)
+            </span>
+          </label>
         </div>
       )}
 
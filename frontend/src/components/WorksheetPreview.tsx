@@ -2,13 +2,14 @@
 
 import { useState, useEffect } from 'react';
 import { Worksheet, Question } from '@/lib/types';
-import { Download, Printer, Share2, CheckCircle, BookOpen, FileText, Pencil, Check, X, Play, ChevronDown, Info } from 'lucide-react';
+import { Download, Printer, Share2, CheckCircle, BookOpen, FileText, Pencil, Check, X, Play, ChevronDown, Info, ShieldAlert } from 'lucide-react';
 import { useModal } from '@/components/Modal';
 import Link from 'next/link';
 import { LatexRenderer } from './LatexRenderer';
 
 type ExportOption = 'questions' | 'answers' | 'both';
 import { exportToHtml, exportToPdf } from '@/lib/export';
+import { approveModeration } from '@/lib/api';
 
 interface WorksheetPreviewProps {
   worksheet: Worksheet;
@@ -18,18 +19,43 @@ interface WorksheetPreviewProps {
 }
 
 export function WorksheetPreview({ worksheet, showActions = true, onTitleChange, onQuestionsChange }: WorksheetPreviewProps) {
-  const { showInfo, showSuccess } = useModal();
+  const { showInfo, showSuccess, showError } = useModal();
   const [isEditingTitle, setIsEditingTitle] = useState(false);
   const [editedTitle, setEditedTitle] = useState(worksheet.title);
   const [openDropdown, setOpenDropdown] = useState<string | null>(null);
   const [localQuestions, setLocalQuestions] = useState<Question[]>(worksheet.questions);
   const [showPdfInfo, setShowPdfInfo] = useState(false);
+  const [moderation, setModeration] = useState(worksheet.moderation);
 
   // Sync localQuestions with worksheet.questions when worksheet changes
   useEffect(() => {
     setLocalQuestions(worksheet.questions);
   }, [worksheet.questions]);
 
+  useEffect(() => {
+    setModeration(worksheet.moderation);
+  }, [worksheet.moderation]);
+
+  const exportBlocked = moderation?.status === 'flagged';
+
+  // Flagged content must be reviewed before it can leave the app
+  const guardExport = () => {
+    if (exportBlocked) {
+      showInfo('Review the flagged content below before exporting this worksheet.', 'Awaiting Review');
+      setOpenDropdown(null);
+    }
+    return exportBlocked;
+  };
+
+  const handleApprove = async () => {
+    try {
+      setModeration(await approveModeration(worksheet.id));
+      showSuccess('Worksheet approved for export', 'Approved');
+    } catch (error) {
+      showError(error instanceof Error ? error.message : 'Failed to approve worksheet');
+    }
+  };
+
   // Calculate total points
   const totalPoints = localQuestions.reduce((sum, q) => sum + q.points, 0);
 
@@ -42,6 +68,7 @@ export function WorksheetPreview({ worksheet, showActions = true, onTitleChange,
   };
 
   const handleExportHtml = (option: ExportOption) => {
+    if (guardExport()) return;
     const exportWorksheet = {
       ...worksheet,
       title: editedTitle,
@@ -61,6 +88,7 @@ export function WorksheetPreview({ worksheet, showActions = true, onTitleChange,
   };
 
   const handleExportPdf = (option: ExportOption) => {
+    if (guardExport()) return;
     const content = option === 'answers' ? 'answer_key' : option === 'questions' ? 'questions' : 'both';
     const exportWorksheet = { ...worksheet, title: editedTitle, questions: localQuestions };
     exportToPdf(exportWorksheet, content);
@@ -68,6 +96,7 @@ export function WorksheetPreview({ worksheet, showActions = true, onTitleChange,
   };
 
   const handlePrint = (option: ExportOption) => {
+    if (guardExport()) return;
     // For print, we'll show an info message since we can't dynamically change print content easily
     let message = '';
     if (option === 'questions') {
@@ -204,6 +233,29 @@ export function WorksheetPreview({ worksheet, showActions = true, onTitleChange,
         </div>
       </div>
 
+      {/* Content review */}
+      {exportBlocked && (
+        <div className="flex items-start gap-3 p-5 bg-amber-50 border-b border-amber-200 text-amber-900">
+          <ShieldAlert className="w-5 h-5 flex-shrink-0 mt-0.5" />
+          <div className="flex-1">
+            <p className="font-semibold">This worksheet needs a review before it can be exported</p>
+            <ul className="mt-2 text-sm space-y-1">
+              {moderation?.flags?.map((flag) => (
+                <li key={`${flag.field}-${flag.category}`}>
+                  <span className="font-medium">{flag.category.replace('_', ' ')}</span> in {flag.field}
+                  {flag.excerpt && <>: &ldquo;{flag.excerpt}&rdquo;</>}
+                </li>
+              ))}
+            </ul>
+          </div>
+          {showActions && (
+            <button onClick={handleApprove} className="btn-primary btn-sm flex-shrink-0">
+              Approve for export
+            </button>
+          )}
+        </div>
+      )}
+
       {/* Actions */}
       {showActions && (
         <div className="flex flex-wrap gap-3 p-5 bg-gradient-to-r from-gray-50 to-teal-50/30 border-b border-gray-100">
@@ -545,14 +597,28 @@ function QuestionCard({ question, number, onPointsChange, editable = false }: Qu
               dangerouslySetInnerHTML={{ __html: question.image }}
             />
           ) : question.image.trim().startsWith('http') ? (
-            <img
-              src={question.image}
-              alt="Question illustration"
-              className="max-w-full h-auto rounded-xl shadow-md max-h-48 object-cover"
-              onError={(e) => {
-                (e.target as HTMLImageElement).style.display = 'none';
-              }}
-            />
+            <figure className="flex flex-col items-center">
+              <img
+                src={question.image}
+                alt="Question illustration"
+                className="max-w-full h-auto rounded-xl shadow-md max-h-48 object-cover"
+                onError={(e) => {
+                  (e.target as HTMLImageElement).style.display = 'none';
+                }}
+              />
+              {question.image_credit?.author && (
+                <figcaption className="mt-1 text-xs text-gray-400">
+                  Photo by{' '}
+                  <a href={question.image_credit.author_url} target="_blank" rel="noopener noreferrer" className="underline">
+                    {question.image_credit.author}
+                  </a>{' '}
+                  on{' '}
+                  <a href={question.image_credit.source_url} target="_blank" rel="noopener noreferrer" className="underline">
+                    {question.image_credit.provider}
+                  </a>
+                </figcaption>
+              )}
+            </figure>
           ) : null}
         </div>
       )}
@@ -1,4 +1,4 @@
-import { Worksheet, WorksheetGeneratorInput, Question, QuestionType } from './types';
+import { Worksheet, WorksheetGeneratorInput, Question, QuestionType, Moderation } from './types';
 import { getSupabase } from './supabase';
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
@@ -433,6 +433,23 @@ export function exportWorksheetPDF(worksheet: Worksheet): void {
   window.print();
 }
 
+// Approve a worksheet's flagged content so it can be exported
+export async function approveModeration(id: string, note?: string): Promise<Moderation> {
+  const response = await fetch(`${API_URL}/api/worksheets/${id}/moderation/review`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ decision: 'approve', note: note || '' }),
+  });
+
+  const data = await response.json();
+  if (!response.ok || !data.success) {
+    throw new Error(data.error || 'Failed to approve worksheet');
+  }
+  return data.worksheet.moderation;
+}
+
 // Send welcome email to new user
 export async function sendWelcomeEmail(email: string, name?: string): Promise<boolean> {
   try {
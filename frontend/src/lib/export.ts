@@ -1,4 +1,4 @@
-import { Worksheet } from './types';
+import { ImageCredit, Passage, Worksheet } from './types';
 import katex from 'katex';
 
 // DOM-based modal for non-React contexts
@@ -385,6 +385,7 @@ function generatePrintReadyHtml(worksheet: Worksheet, content: 'questions' | 'an
   </div>
 
   ${showQuestions ? `
+    ${renderPassage(worksheet.passage)}
     <h2 class="section-title">Questions</h2>
     ${worksheet.questions.map((q, index) => `
       <div class="question">
@@ -394,7 +395,8 @@ function generatePrintReadyHtml(worksheet: Worksheet, content: 'questions' | 'an
           <span class="question-pts">${getQuestionPoints(index)} pts</span>
         </div>
         <p class="question-text">${renderLatexToHtml(q.question)}</p>
-        ${q.image ? renderPrintImage(q.image) : ''}
+        ${renderParagraphRefs(q.paragraph_refs)}
+        ${q.image ? renderPrintImage(q.image, q.image_credit) : ''}
         ${renderPrintOptions(q)}
       </div>
     `).join('')}
@@ -444,7 +446,32 @@ function renderPrintOptions(q: { type: string; options?: string[] }): string {
   return '';
 }
 
-function renderPrintImage(image: string): string {
+// Render a reading passage with numbered paragraphs so questions can refer to them
+function renderPassage(passage: Passage | undefined): string {
+  if (!passage || !passage.paragraphs?.length) return '';
+
+  return `
+    <div class="passage" style="border: 1px solid #e5e7eb; border-radius: 8px; padding: 16px 20px; margin: 20px 0; page-break-inside: avoid;">
+      ${passage.title ? `<h3 style="margin: 0 0 12px 0; font-size: 16px;">${renderLatexToHtml(passage.title)}</h3>` : ''}
+      ${passage.paragraphs.map((para, i) => `
+        <p style="margin: 0 0 10px 0; line-height: 1.7;">
+          <span style="font-weight: 700; color: #0d9488; margin-right: 6px;">[${i + 1}]</span>${renderLatexToHtml(para)}
+        </p>
+      `).join('')}
+    </div>
+  `;
+}
+
+function renderParagraphRefs(refs: number[] | undefined): string {
+  if (!refs || refs.length === 0) return '';
+  return `<p style="font-size: 12px; color: #6b7280; margin: 4px 0;">See paragraph${refs.length > 1 ? 's' : ''} ${refs.join(', ')}</p>`;
+}
+
+function escapeHtml(text: string): string {
+  return text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
+
+function renderPrintImage(image: string, credit?: ImageCredit): string {
   if (!image) return '';
 
   // Check if it's an SVG
@@ -454,7 +481,10 @@ function renderPrintImage(image: string): string {
 
   // Check if it's a URL (Unsplash image)
   if (image.trim().startsWith('http')) {
-    return `<div style="text-align: center; margin: 15px 0;"><img src="${image}" alt="Question illustration" style="max-width: 250px; max-height: 180px; border-radius: 8px;" /></div>`;
+    const caption = credit?.author
+      ? `<div style="font-size: 9px; color: #94a3b8; margin-top: 3px;">Photo by ${escapeHtml(credit.author)} on ${escapeHtml(credit.provider)}</div>`
+      : '';
+    return `<div style="text-align: center; margin: 15px 0;"><img src="${image}" alt="Question illustration" style="max-width: 250px; max-height: 180px; border-radius: 8px;" />${caption}</div>`;
   }
 
   return '';
@@ -1636,6 +1666,7 @@ export function exportToHtml(worksheet: Worksheet, content: 'questions' | 'answe
         </div>
 
         ${showQuestions ? `
+        ${renderPassage(worksheet.passage)}
         <h2 class="questions-header">Questions</h2>
         <form id="quizForm">
         ${worksheet.questions.map((q, index) => `
@@ -1645,6 +1676,7 @@ export function exportToHtml(worksheet: Worksheet, content: 'questions' | 'answe
                     <span class="points-badge">${getQuestionPoints(index)} pts</span>
                 </div>
                 <p class="question-text">${renderText(q.question)}</p>
+                ${renderParagraphRefs(q.paragraph_refs)}
                 ${renderImage(q.image)}
                 ${renderInteractiveQuestionInputHtml(q, index, renderText)}
                 <div class="feedback" id="feedback-${index}"></div>
@@ -21,6 +21,149 @@ export interface Question {
   explanation?: string;
   points: number;
   image?: string;
+  image_credit?: ImageCredit;
+  paragraph_refs?: number[];
+  diagram?: Diagram;
+  latex_diagram?: string;
+  warnings?: string[];
+}
+
+export interface ImageCredit {
+  provider: string;
+  author?: string;
+  author_url?: string;
+  source_url?: string;
+}
+
+export interface DiagramShape {
+  sides?: number[];
+  angles?: number[];
+  radius?: number;
+  num_sides?: number;
+  unit?: string;
+  labels?: string[];
+  side_labels?: string[];
+  angle_labels?: string[];
+  right_angle?: string;
+  show_diameter?: boolean;
+}
+
+export interface Diagram {
+  type:
+    | 'triangle'
+    | 'rectangle'
+    | 'circle'
+    | 'regular_polygon'
+    | 'circuit'
+    | 'graph'
+    | 'number_line'
+    | 'fraction'
+    | 'clock'
+    | 'base_ten'
+    | 'coins'
+    | 'tally'
+    | 'chart';
+  title?: string;
+  shape?: DiagramShape;
+  circuit?: Circuit;
+  graph?: Graph;
+  number_line?: NumberLine;
+  fraction?: FractionModel;
+  clock?: Clock;
+  base_ten?: { hundreds: number; tens: number; ones: number };
+  coins?: { coins: ('penny' | 'nickel' | 'dime' | 'quarter' | 'half_dollar' | 'dollar_coin')[] };
+  tally?: { rows: { label: string; count: number }[] };
+  chart?: Chart;
+}
+
+export interface Chart {
+  kind: 'bar' | 'pictograph' | 'line_plot' | 'histogram' | 'table';
+  x_label?: string;
+  y_label?: string;
+  data?: { label: string; value: number }[];
+  values?: number[];
+  bin_width?: number;
+  bin_start?: number;
+  per_symbol?: number;
+  statistics?: {
+    count: number;
+    total: number;
+    mean: number;
+    median: number;
+    mode?: number[];
+    min: number;
+    max: number;
+    range: number;
+  };
+}
+
+export interface NumberLine {
+  min: number;
+  max: number;
+  step?: number;
+  points?: { value: number; label?: string }[];
+  jumps?: { from: number; to: number; label?: string }[];
+}
+
+export interface FractionModel {
+  numerator: number;
+  denominator: number;
+  style?: 'bar' | 'circle';
+}
+
+export interface Clock {
+  hour: number;
+  minute: number;
+  hide_numbers?: boolean;
+  hide_hands?: boolean;
+}
+
+export interface Graph {
+  x_min: number;
+  x_max: number;
+  y_min: number;
+  y_max: number;
+  x_step?: number;
+  y_step?: number;
+  x_label?: string;
+  y_label?: string;
+  functions?: { expression: string; label?: string; domain?: [number, number] }[];
+  points?: { x: number; y: number; label?: string }[];
+}
+
+export interface CircuitNode {
+  kind: 'series' | 'parallel' | 'resistor' | 'bulb' | 'switch' | 'ammeter' | 'voltmeter' | 'capacitor';
+  label?: string;
+  value?: number;
+  open?: boolean;
+  elements?: CircuitNode[];
+}
+
+export interface Circuit {
+  voltage?: number;
+  network: CircuitNode;
+  analysis?: {
+    equivalent_resistance?: number;
+    current?: number;
+    equivalent_capacitance?: number;
+  };
+}
+
+export interface Readability {
+  words: number;
+  sentences: number;
+  syllables: number;
+  flesch_kincaid_grade: number;
+  flesch_reading_ease: number;
+  target_grade: string;
+  within_range: boolean;
+}
+
+export interface Passage {
+  title?: string;
+  paragraphs: string[];
+  source: 'generated' | 'teacher';
+  readability?: Readability;
 }
 
 export interface Worksheet {
@@ -31,6 +174,7 @@ export interface Worksheet {
   grade_level: string;
   difficulty: Difficulty;
   language: string;
+  passage?: Passage;
   questions: Question[];
   include_answer_key: boolean;
   additional_instructions?: string;
@@ -38,6 +182,38 @@ export interface Worksheet {
   updated_at: string;
   status: WorksheetStatus;
   downloads: number;
+  moderation?: Moderation;
+  prompt_template?: string; // ID of the prompt template the worksheet was generated with
+  prompt_version?: number;
+  usage?: Usage; // tokens spent generating the worksheet and its questions
+}
+
+export interface Usage {
+  calls: number;
+  input_tokens: number;
+  output_tokens: number;
+  cost_usd: number;
+}
+
+// Content-safety screening; a flagged worksheet can't be exported until a teacher approves it
+export type ModerationStatus = 'clear' | 'flagged' | 'approved';
+
+export interface ModerationFlag {
+  field: string; // e.g. "questions[q_3].explanation"
+  category: string;
+  score: number;
+  excerpt?: string;
+  classifier: string;
+}
+
+export interface Moderation {
+  status: ModerationStatus;
+  threshold: number;
+  flags?: ModerationFlag[];
+  checked_at: string;
+  reviewed_by?: string;
+  reviewed_at?: string;
+  review_note?: string;
 }
 
 export interface WorksheetGeneratorInput {
@@ -51,6 +227,9 @@ export interface WorksheetGeneratorInput {
   language: string;
   include_answer_key: boolean;
   additional_instructions?: string;
+  include_passage?: boolean;
+  passage?: string;
+  fresh?: boolean; // skip reusing a recent identical worksheet
 }
 
 // Subject options